@@ -50,7 +50,7 @@ Use --all to approve all merge-ready PRs.`,
 func approveAll(states []*run.State, store run.StateStore) error {
 	count := 0
 	for _, s := range states {
-		if s.PRURL == nil || s.MergedAt != nil {
+		if s.PRURL == nil || s.MergedAt != nil || s.ClosedAt != nil {
 			continue
 		}
 		if s.Approved != nil && *s.Approved {
@@ -0,0 +1,396 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/patflynn/klaus/internal/event"
+	"github.com/patflynn/klaus/internal/git"
+	gh "github.com/patflynn/klaus/internal/github"
+	"github.com/patflynn/klaus/internal/pipeline"
+	"github.com/patflynn/klaus/internal/project"
+	"github.com/patflynn/klaus/internal/run"
+	"github.com/patflynn/klaus/internal/verify"
+	"github.com/spf13/cobra"
+)
+
+// maxBackportHunkBytes bounds the conflicting hunks embedded in a backport
+// agent's prompt.
+const maxBackportHunkBytes = 8000
+
+var backportCmd = &cobra.Command{
+	Use:   "backport <pr-number> [<branch>...]",
+	Short: "Cherry-pick a merged PR onto release branches",
+	Long: `Backports a merged PR onto each release branch named by its
+"backport <branch>" labels, or onto the branches given as arguments.
+
+For each branch klaus cherry-picks the PR's merge commit (git cherry-pick -x)
+onto a new backport/<pr>-<branch> branch in a temporary worktree. A clean
+pick is pushed and opened as a PR against the release branch right away. A
+pick that conflicts is handed to an agent (klaus launch --base <branch>) with
+the conflicting hunks in its prompt. Either way the backport PR is recorded
+as a run linked to the original, enters the pipeline like any other PR, and
+pr:backport-opened is emitted once it's open.
+
+Branches that already have a backport of the PR are skipped. The pipeline
+runs this automatically when it sees a PR merge.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		prNum := strings.TrimPrefix(args[0], "#")
+		repoFlag, _ := cmd.Flags().GetString("repo")
+
+		store, err := sessionStore()
+		if err != nil {
+			return err
+		}
+		if err := store.EnsureDirs(); err != nil {
+			return err
+		}
+		dir := buildRepoDirResolver(store)(prNum)
+		slug := buildRepoResolver(store, repoFlag)(prNum)
+		if !strings.Contains(slug, "/") {
+			owner, name, err := gh.NewGHCLIClient("").GetRepoOwnerAndNameFromDir(cmd.Context(), dir)
+			if err != nil {
+				return fmt.Errorf("could not determine the repo of PR #%s; use --repo owner/repo", prNum)
+			}
+			slug = owner + "/" + name
+		}
+
+		return newBackportRunner(os.Stdout, store, dir).run(prNum, slug, args[1:])
+	},
+}
+
+// backportSource is the merged PR being backported.
+type backportSource struct {
+	Number   string
+	Title    string
+	URL      string
+	MergeSHA string
+	Labels   []string
+}
+
+// backportPick is the result of cherry-picking a PR onto a release branch.
+type backportPick struct {
+	Branch    string
+	Worktree  string   // checkout of Branch with the pick committed; empty on conflict
+	Conflicts []string // files the pick conflicted in
+	Hunks     string   // the conflicted diff, for the agent's prompt
+}
+
+// backportRunner holds the dependencies of the backport workflow. Fields are
+// functions to allow testing with mocks.
+type backportRunner struct {
+	out    io.Writer
+	states []*run.State
+
+	fetchSource func(prNumber, slug string) (*backportSource, error)
+	// pick cherry-picks src onto origin/<onto> as branch. cleanup removes
+	// the worktree a clean pick leaves behind.
+	pick    func(src *backportSource, onto, branch string) (*backportPick, error)
+	cleanup func(pick *backportPick)
+	publish func(slug string, src *backportSource, onto string, pick *backportPick) (prURL string, err error)
+	launch  func(slug, onto, prNumber, prompt string) error
+	record  func(s *run.State) error
+}
+
+func newBackportRunner(out io.Writer, store run.StateStore, repoRoot string) *backportRunner {
+	ctx := context.TODO()
+	r := &backportRunner{out: out}
+	r.states, _ = store.List()
+	r.fetchSource = func(pr, slug string) (*backportSource, error) {
+		return fetchBackportSource(ctx, gh.NewGHCLIClient(""), slug, pr)
+	}
+	r.pick = func(src *backportSource, onto, branch string) (*backportPick, error) {
+		if repoRoot == "" {
+			return nil, fmt.Errorf("could not determine git repository root")
+		}
+		gitClient := git.NewExecClient()
+		if err := gitClient.FetchBranch(ctx, repoRoot, onto); err != nil {
+			return nil, fmt.Errorf("fetching %s: %w", onto, err)
+		}
+		if err := gitClient.FetchBranch(ctx, repoRoot, src.MergeSHA); err != nil {
+			return nil, fmt.Errorf("fetching merge commit %s: %w", shortSHA(src.MergeSHA), err)
+		}
+		return pickBackport(ctx, repoRoot, src.MergeSHA, "origin/"+onto, branch)
+	}
+	r.cleanup = func(pick *backportPick) {
+		removeBackportWorktree(repoRoot, pick)
+	}
+	r.publish = func(slug string, src *backportSource, onto string, pick *backportPick) (string, error) {
+		return publishBackport(ctx, gh.NewGHCLIClient(""), slug, src, onto, pick)
+	}
+	r.launch = func(slug, onto, pr, prompt string) error {
+		out, err := exec.Command("klaus", "launch", "--repo", slug, "--base", onto, "--backport-of", pr, prompt).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("klaus launch: %w: %s", err, verify.LastLine(strings.TrimSpace(string(out))))
+		}
+		return nil
+	}
+	r.record = func(s *run.State) error {
+		if err := store.Save(s); err != nil {
+			return err
+		}
+		if hds, ok := store.(*run.HomeDirStore); ok {
+			emitBackportOpened(hds.BaseDir(), s)
+		}
+		return nil
+	}
+	return r
+}
+
+// run backports prNumber onto branches, or onto the branches its labels
+// name when none are given. A branch that fails doesn't stop the others.
+func (r *backportRunner) run(prNumber, slug string, branches []string) error {
+	src, err := r.fetchSource(prNumber, slug)
+	if err != nil {
+		return err
+	}
+	if len(branches) == 0 {
+		branches = pipeline.BackportBranches(src.Labels)
+	}
+	if len(branches) == 0 {
+		fmt.Fprintf(r.out, "PR #%s has no %q labels; nothing to backport.\n", prNumber, pipeline.BackportLabelPrefix+" <branch>")
+		return nil
+	}
+
+	var failed []string
+	for _, onto := range branches {
+		if existing := r.existingBackport(prNumber, onto); existing != nil {
+			fmt.Fprintf(r.out, "PR #%s: already backported to %s (run %s)\n", prNumber, onto, shortID(existing.ID))
+			continue
+		}
+		fmt.Fprintf(r.out, "Backporting PR #%s to %s...\n", prNumber, onto)
+		if err := r.backportOne(slug, src, onto); err != nil {
+			fmt.Fprintf(r.out, "  Failed: %v\n", err)
+			failed = append(failed, onto)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("backport of PR #%s failed for %s", prNumber, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (r *backportRunner) backportOne(slug string, src *backportSource, onto string) error {
+	branch := backportBranchName(src.Number, onto)
+	pick, err := r.pick(src, onto, branch)
+	if err != nil {
+		return err
+	}
+	if len(pick.Conflicts) > 0 {
+		fmt.Fprintf(r.out, "  Cherry-pick conflicts in %s; dispatching an agent...\n", strings.Join(pick.Conflicts, ", "))
+		if err := r.launch(slug, onto, src.Number, backportPrompt(src, onto, pick)); err != nil {
+			return err
+		}
+		fmt.Fprintf(r.out, "  Agent dispatched; it will open the backport PR.\n")
+		return nil
+	}
+	defer r.cleanup(pick)
+
+	prURL, err := r.publish(slug, src, onto, pick)
+	if err != nil {
+		return err
+	}
+	reg, _ := project.Load()
+	target := project.NormalizeRepoName(slug, reg)
+	id, err := run.GenID()
+	if err != nil {
+		return err
+	}
+	st := &run.State{
+		ID:         id,
+		Prompt:     backportTitle(src, onto),
+		Branch:     branch,
+		PRURL:      &prURL,
+		Type:       "track",
+		TargetRepo: &target,
+		BaseBranch: &onto,
+		BackportOf: &src.Number,
+		CreatedAt:  time.Now().Format(time.RFC3339),
+	}
+	if err := r.record(st); err != nil {
+		return fmt.Errorf("recording backport: %w", err)
+	}
+	fmt.Fprintf(r.out, "  Opened %s\n", prURL)
+	return nil
+}
+
+// existingBackport returns the run that already backports prNumber onto
+// branch, whether its PR is open yet or its agent is still working.
+func (r *backportRunner) existingBackport(prNumber, branch string) *run.State {
+	for _, s := range r.states {
+		if s.BackportOf != nil && *s.BackportOf == prNumber && s.BaseBranch != nil && *s.BaseBranch == branch {
+			return s
+		}
+	}
+	return nil
+}
+
+func backportBranchName(prNumber, onto string) string {
+	return fmt.Sprintf("backport/%s-%s", prNumber, onto)
+}
+
+func backportTitle(src *backportSource, onto string) string {
+	return fmt.Sprintf("[%s] %s", onto, src.Title)
+}
+
+// fetchBackportSource fetches the merged PR's title, merge commit and labels.
+func fetchBackportSource(ctx context.Context, client gh.Client, slug, prNumber string) (*backportSource, error) {
+	data, err := client.APIGet(ctx, fmt.Sprintf("repos/%s/pulls/%s", slug, prNumber))
+	if err != nil {
+		return nil, err
+	}
+	var pr struct {
+		Title          string `json:"title"`
+		HTMLURL        string `json:"html_url"`
+		Merged         bool   `json:"merged"`
+		MergeCommitSHA string `json:"merge_commit_sha"`
+		Labels         []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+	}
+	if err := json.Unmarshal(data, &pr); err != nil {
+		return nil, fmt.Errorf("parsing PR #%s: %w", prNumber, err)
+	}
+	if !pr.Merged || pr.MergeCommitSHA == "" {
+		return nil, fmt.Errorf("PR #%s is not merged", prNumber)
+	}
+	src := &backportSource{Number: prNumber, Title: pr.Title, URL: pr.HTMLURL, MergeSHA: pr.MergeCommitSHA}
+	for _, l := range pr.Labels {
+		src.Labels = append(src.Labels, l.Name)
+	}
+	return src, nil
+}
+
+// pickBackport cherry-picks sha onto start as a new branch in a temporary
+// worktree of repoRoot. On a clean pick the worktree is left for the caller
+// to push from (see removeBackportWorktree); on a conflict the pick is
+// aborted, the worktree and branch are removed, and the conflicting files
+// and hunks are returned instead.
+func pickBackport(ctx context.Context, repoRoot, sha, start, branch string) (*backportPick, error) {
+	tmpDir, err := os.MkdirTemp("", "klaus-backport-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating temp dir: %w", err)
+	}
+	pick := &backportPick{Branch: branch, Worktree: filepath.Join(tmpDir, "backport")}
+	if out, err := verify.Exec(ctx, repoRoot, "git", "worktree", "add", "-B", branch, pick.Worktree, start); err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, fmt.Errorf("creating worktree on %s: %s", start, verify.LastLine(out))
+	}
+
+	args := []string{"cherry-pick", "-x"}
+	// A merge commit (the "merge" merge method) is picked relative to the
+	// branch it merged into.
+	if parents, _ := verify.Exec(ctx, repoRoot, "git", "rev-list", "--parents", "-n", "1", sha); len(strings.Fields(parents)) > 2 {
+		args = append(args, "-m", "1")
+	}
+	out, err := verify.Exec(ctx, pick.Worktree, "git", append(args, sha)...)
+	if err == nil {
+		return pick, nil
+	}
+
+	conflicted, _ := verify.Exec(ctx, pick.Worktree, "git", "diff", "--name-only", "--diff-filter=U")
+	pick.Conflicts = strings.Fields(conflicted)
+	if len(pick.Conflicts) == 0 {
+		removeBackportWorktree(repoRoot, pick)
+		return nil, fmt.Errorf("cherry-pick failed: %s", verify.LastLine(out))
+	}
+	pick.Hunks, _ = verify.Exec(ctx, pick.Worktree, "git", "diff")
+	_, _ = verify.Exec(ctx, pick.Worktree, "git", "cherry-pick", "--abort")
+	removeBackportWorktree(repoRoot, pick)
+	pick.Worktree = ""
+	return pick, nil
+}
+
+// removeBackportWorktree removes a pick's worktree, its temp directory and
+// its local branch. The pushed branch, if any, is untouched.
+func removeBackportWorktree(repoRoot string, pick *backportPick) {
+	if pick == nil || pick.Worktree == "" {
+		return
+	}
+	ctx := context.Background()
+	gitClient := git.NewExecClient()
+	if err := gitClient.WorktreeRemove(ctx, repoRoot, pick.Worktree); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to remove worktree: %v\n", err)
+	}
+	_ = os.RemoveAll(filepath.Dir(pick.Worktree))
+	_ = gitClient.BranchDelete(ctx, repoRoot, pick.Branch)
+}
+
+// publishBackport pushes a clean pick and opens its PR against onto.
+func publishBackport(ctx context.Context, client gh.Client, slug string, src *backportSource, onto string, pick *backportPick) (string, error) {
+	if out, err := verify.Exec(ctx, pick.Worktree, "git", "push", "--force", "-u", "origin", pick.Branch); err != nil {
+		return "", fmt.Errorf("pushing %s: %s", pick.Branch, verify.LastLine(out))
+	}
+	body := fmt.Sprintf("Backport of #%s to `%s`.\n\nCherry-picked from %s with `git cherry-pick -x`.", src.Number, onto, src.MergeSHA)
+	data, err := client.APIPostJSON(ctx, fmt.Sprintf("repos/%s/pulls", slug), map[string]string{
+		"title": backportTitle(src, onto),
+		"head":  pick.Branch,
+		"base":  onto,
+		"body":  body,
+	})
+	if err != nil {
+		return "", fmt.Errorf("opening backport PR: %w", err)
+	}
+	var pr struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.Unmarshal(data, &pr); err != nil || pr.HTMLURL == "" {
+		return "", fmt.Errorf("parsing created PR: %v", err)
+	}
+	return pr.HTMLURL, nil
+}
+
+// backportPrompt asks an agent to finish a backport whose cherry-pick
+// conflicted, with the conflicting hunks inline so it can start resolving
+// without reproducing the pick first.
+func backportPrompt(src *backportSource, onto string, pick *backportPick) string {
+	hunks := pick.Hunks
+	if len(hunks) > maxBackportHunkBytes {
+		hunks = hunks[:maxBackportHunkBytes] + "\n... (truncated; run the cherry-pick to see the rest)"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Backport PR #%s (%q) to the %s branch. Your branch starts from %s. ", src.Number, src.Title, onto, onto)
+	fmt.Fprintf(&b, "Cherry-pick its merge commit with `git cherry-pick -x %s` (add `-m 1` if git says it is a merge), ", src.MergeSHA)
+	fmt.Fprintf(&b, "resolve the conflicts so the change applies to %s without pulling in unrelated work from the default branch, and run the tests. ", onto)
+	fmt.Fprintf(&b, "Open the PR against %s titled %q, and say in its body that it backports #%s.\n\n", onto, backportTitle(src, onto), src.Number)
+	fmt.Fprintf(&b, "The cherry-pick conflicts in %s:\n\n```diff\n%s\n```\n", strings.Join(pick.Conflicts, ", "), hunks)
+	return b.String()
+}
+
+// emitBackportOpened emits pr:backport-opened for a run whose PR backports
+// another.
+func emitBackportOpened(baseDir string, s *run.State) {
+	if s.PRURL == nil || s.BackportOf == nil {
+		return
+	}
+	data := map[string]interface{}{
+		"id":          s.ID,
+		"pr_number":   extractPRNumberFromURL(*s.PRURL),
+		"pr_url":      *s.PRURL,
+		"backport_of": *s.BackportOf,
+	}
+	if s.BaseBranch != nil {
+		data["branch"] = *s.BaseBranch
+	}
+	emitEvent(baseDir, s.ID, event.PRBackportOpened, data)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+func init() {
+	backportCmd.Flags().String("repo", "", "Target repo (owner/repo)")
+	rootCmd.AddCommand(backportCmd)
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/patflynn/klaus/internal/run"
+	"github.com/patflynn/klaus/internal/verify"
+)
+
+// setupBackportRepo creates a repo whose release branch and main both
+// diverge from a shared commit, and returns it with the SHA of a commit on
+// main that touches the given files.
+func setupBackportRepo(t *testing.T, release, fix map[string]string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	runGitT(t, dir, "init", "-q", "--initial-branch=main")
+	runGitT(t, dir, "config", "user.email", "t@t.com")
+	runGitT(t, dir, "config", "user.name", "T")
+	commit := func(files map[string]string, msg string) {
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		runGitT(t, dir, "add", "-A")
+		runGitT(t, dir, "commit", "-q", "-m", msg)
+	}
+	commit(map[string]string{"a.txt": "one\n", "b.txt": "two\n"}, "initial")
+	runGitT(t, dir, "branch", "release")
+	runGitT(t, dir, "checkout", "-q", "release")
+	commit(release, "release fix")
+	runGitT(t, dir, "checkout", "-q", "main")
+	commit(fix, "fix the bug")
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, strings.TrimSpace(string(out))
+}
+
+func TestPickBackportClean(t *testing.T) {
+	dir, sha := setupBackportRepo(t,
+		map[string]string{"b.txt": "two, patched\n"},
+		map[string]string{"a.txt": "one, fixed\n"},
+	)
+
+	pick, err := pickBackport(context.Background(), dir, sha, "release", "backport/7-release")
+	if err != nil {
+		t.Fatalf("pickBackport() error = %v", err)
+	}
+	defer removeBackportWorktree(dir, pick)
+	if len(pick.Conflicts) != 0 || pick.Worktree == "" {
+		t.Fatalf("pick = %+v, want a clean pick with a worktree", pick)
+	}
+	data, err := os.ReadFile(filepath.Join(pick.Worktree, "a.txt"))
+	if err != nil || string(data) != "one, fixed\n" {
+		t.Errorf("a.txt = %q, %v", data, err)
+	}
+	msg, _ := verify.Exec(context.Background(), pick.Worktree, "git", "log", "-1", "--format=%B")
+	if !strings.Contains(msg, "cherry picked from commit "+sha) {
+		t.Errorf("commit message should record the source commit:\n%s", msg)
+	}
+
+	removeBackportWorktree(dir, pick)
+	if _, err := os.Stat(pick.Worktree); !os.IsNotExist(err) {
+		t.Errorf("worktree %s should be removed", pick.Worktree)
+	}
+	if out, _ := verify.Exec(context.Background(), dir, "git", "branch", "--list", pick.Branch); out != "" {
+		t.Errorf("local branch should be deleted, got %q", out)
+	}
+}
+
+func TestPickBackportConflict(t *testing.T) {
+	dir, sha := setupBackportRepo(t,
+		map[string]string{"a.txt": "one, patched on release\n"},
+		map[string]string{"a.txt": "one, fixed\n"},
+	)
+
+	pick, err := pickBackport(context.Background(), dir, sha, "release", "backport/7-release")
+	if err != nil {
+		t.Fatalf("pickBackport() error = %v", err)
+	}
+	if !reflect.DeepEqual(pick.Conflicts, []string{"a.txt"}) {
+		t.Errorf("Conflicts = %v, want [a.txt]", pick.Conflicts)
+	}
+	if !strings.Contains(pick.Hunks, "<<<<<<<") || !strings.Contains(pick.Hunks, "one, fixed") {
+		t.Errorf("Hunks should carry the conflict:\n%s", pick.Hunks)
+	}
+	if pick.Worktree != "" {
+		t.Errorf("Worktree = %q, want it removed after a conflict", pick.Worktree)
+	}
+	if out, _ := verify.Exec(context.Background(), dir, "git", "worktree", "list", "--porcelain"); strings.Count(out, "worktree ") != 1 {
+		t.Errorf("temporary worktree left behind:\n%s", out)
+	}
+}
+
+func TestBackportRunner(t *testing.T) {
+	withTempHome(t)
+	var buf bytes.Buffer
+	var calls []string
+	var recorded []*run.State
+	existing, of := "release-1.3", "42"
+	r := &backportRunner{
+		out:    &buf,
+		states: []*run.State{{ID: "20260101-0000-aaaa", BaseBranch: &existing, BackportOf: &of}},
+		fetchSource: func(pr, slug string) (*backportSource, error) {
+			return &backportSource{
+				Number:   pr,
+				Title:    "Fix the bug",
+				MergeSHA: "abc1234def",
+				Labels:   []string{"bug", "backport release-1.3", "backport release-1.4", "backport:release-1.5"},
+			}, nil
+		},
+		pick: func(src *backportSource, onto, branch string) (*backportPick, error) {
+			calls = append(calls, "pick "+onto+" as "+branch)
+			if onto == "release-1.5" {
+				return &backportPick{Branch: branch, Conflicts: []string{"a.go"}, Hunks: "<<<<<<< HEAD"}, nil
+			}
+			return &backportPick{Branch: branch, Worktree: "/tmp/wt"}, nil
+		},
+		cleanup: func(pick *backportPick) { calls = append(calls, "cleanup "+pick.Branch) },
+		publish: func(slug string, src *backportSource, onto string, pick *backportPick) (string, error) {
+			calls = append(calls, "publish "+onto)
+			return "https://github.com/owner/repo/pull/50", nil
+		},
+		launch: func(slug, onto, pr, prompt string) error {
+			calls = append(calls, "launch "+onto)
+			if !strings.Contains(prompt, "<<<<<<< HEAD") || !strings.Contains(prompt, "[release-1.5] Fix the bug") {
+				t.Errorf("prompt should carry the hunks and PR title:\n%s", prompt)
+			}
+			return nil
+		},
+		record: func(s *run.State) error {
+			recorded = append(recorded, s)
+			return nil
+		},
+	}
+
+	if err := r.run("42", "owner/repo", nil); err != nil {
+		t.Fatalf("run() error = %v\n%s", err, buf.String())
+	}
+	want := []string{
+		"pick release-1.4 as backport/42-release-1.4", "publish release-1.4", "cleanup backport/42-release-1.4",
+		"pick release-1.5 as backport/42-release-1.5", "launch release-1.5",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if !strings.Contains(buf.String(), "already backported to release-1.3") {
+		t.Errorf("output should note the existing backport:\n%s", buf.String())
+	}
+	if len(recorded) != 1 {
+		t.Fatalf("recorded %d runs, want 1", len(recorded))
+	}
+	s := recorded[0]
+	if s.Type != "track" || *s.BackportOf != "42" || *s.BaseBranch != "release-1.4" || *s.PRURL != "https://github.com/owner/repo/pull/50" {
+		t.Errorf("recorded run = %+v", s)
+	}
+}
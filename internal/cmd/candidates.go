@@ -0,0 +1,724 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/patflynn/klaus/internal/config"
+	"github.com/patflynn/klaus/internal/event"
+	"github.com/patflynn/klaus/internal/git"
+	gh "github.com/patflynn/klaus/internal/github"
+	"github.com/patflynn/klaus/internal/pipeline"
+	"github.com/patflynn/klaus/internal/review"
+	"github.com/patflynn/klaus/internal/run"
+	"github.com/patflynn/klaus/internal/tmux"
+	"github.com/patflynn/klaus/internal/verify"
+	"github.com/spf13/cobra"
+)
+
+// maxCandidates bounds --candidates: each candidate is a full agent with
+// its own budget.
+const maxCandidates = 5
+
+// Candidate scoring. Passing the build and tests outweighs everything else;
+// pre-review findings and failed linters then cost points by severity. Of
+// two candidates with the same score the smaller diff wins.
+const (
+	verifiedScore = 100
+	lintPenalty   = 20
+)
+
+var findingPenalty = map[string]int{"critical": 40, "high": 20, "medium": 5, "low": 1}
+
+// candidateRef is a run's place in a best-of-N launch (--candidate).
+type candidateRef struct {
+	Group string
+	Index int // 1-based
+	Count int
+}
+
+// parseCandidateRef parses --candidate's <group-id>/<n>/<count>.
+func parseCandidateRef(s string) (*candidateRef, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) == 3 && parts[0] != "" {
+		index, err1 := strconv.Atoi(parts[1])
+		count, err2 := strconv.Atoi(parts[2])
+		if err1 == nil && err2 == nil && index >= 1 && index <= count {
+			return &candidateRef{Group: parts[0], Index: index, Count: count}, nil
+		}
+	}
+	return nil, fmt.Errorf("--candidate must be <group-id>/<n>/<count>")
+}
+
+func (c *candidateRef) String() string {
+	return fmt.Sprintf("%s/%d/%d", c.Group, c.Index, c.Count)
+}
+
+// candidatePrompt tells a candidate to keep its work local. It goes after
+// the repo's prompt so it overrides that prompt's push and PR steps.
+func candidatePrompt(c *candidateRef, branch string) string {
+	return fmt.Sprintf(`
+## Best-of-%d candidate
+You are candidate %d of %d: other agents are doing the same task in their own worktrees. When all of you have finished, klaus builds and tests each result, runs the pre-PR review on it and opens a PR for the best one. This overrides the workflow above:
+- Commit your work on %s, but do NOT push it and do NOT create a PR.
+- Write your first commit's subject and body as the PR's title and description; klaus uses them if yours wins.
+- Make sure the build and tests pass before you finish. Failing tests and review findings cost you, and of two otherwise equal results the smaller diff wins.
+`, c.Count, c.Index, c.Count, branch)
+}
+
+// candidateLauncher launches one candidate and returns its run ID.
+type candidateLauncher func(ref *candidateRef, flags []string, prompt string) (string, error)
+
+// launchCandidate launches a candidate with 'klaus launch'. Candidates run
+// locally: their worktrees have to be here to be compared.
+func launchCandidate(ref *candidateRef, flags []string, prompt string) (string, error) {
+	args := append([]string{"launch", "--local", "--candidate", ref.String()}, flags...)
+	args = append(args, prompt)
+	out, err := exec.Command("klaus", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("klaus launch: %w: %s", err, verify.LastLine(strings.TrimSpace(string(out))))
+	}
+	return pipeline.ExtractAgentID(string(out)), nil
+}
+
+// runLaunchCandidates is 'klaus launch --candidates N'.
+func runLaunchCandidates(cmd *cobra.Command, prompt string, n int) error {
+	if !tmux.InSession() {
+		return fmt.Errorf("klaus launch must be run inside a tmux session")
+	}
+	if n < 2 || n > maxCandidates {
+		return fmt.Errorf("--candidates must be between 2 and %d", maxCandidates)
+	}
+	for _, name := range []string{"pr", "base", "backport-of", "plan-task", "issue-label", "candidate", "resume-from", "host", "replay", "no-replay"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s can't be combined with --candidates", name)
+		}
+	}
+	var flags []string
+	for _, name := range []string{"issue", "repo", "budget", "profile"} {
+		if v, _ := cmd.Flags().GetString(name); v != "" {
+			flags = append(flags, "--"+name, v)
+		}
+	}
+	group, err := run.GenID()
+	if err != nil {
+		return err
+	}
+	return launchCandidates(cmd.OutOrStdout(), group, n, flags, prompt, launchCandidate)
+}
+
+// launchCandidates launches n candidates on the same prompt.
+func launchCandidates(out io.Writer, group string, n int, flags []string, prompt string, launch candidateLauncher) error {
+	fmt.Fprintf(out, "Best of %d (group %s): candidates commit locally; when all have finished klaus compares them and opens a PR for the best\n", n, group)
+	failed := 0
+	for i := 1; i <= n; i++ {
+		runID, err := launch(&candidateRef{Group: group, Index: i, Count: n}, flags, prompt)
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "  candidate %d: launching failed: %v\n", i, err)
+			continue
+		}
+		fmt.Fprintf(out, "  candidate %d: agent %s\n", i, runID)
+	}
+	switch {
+	case failed == n:
+		return fmt.Errorf("no candidate launched")
+	case failed > 0:
+		fmt.Fprintf(out, "  %d candidate(s) didn't launch; once the others finish, compare them with 'klaus compare %s'\n", failed, group)
+	}
+	return nil
+}
+
+// finishCandidate runs in a candidate's _finalize in place of the usual
+// worktree cleanup: the worktree stays for the comparison, which the last
+// candidate of the group to finish runs.
+func finishCandidate(ctx context.Context, store run.StateStore, baseDir string, state *run.State) {
+	state.CandidateDone = true
+	if err := store.Save(state); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: saving candidate state: %v\n", err)
+		return
+	}
+	states, err := store.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: listing runs: %v\n", err)
+		return
+	}
+	members := candidatesOf(states, *state.CandidateGroup)
+	done := 0
+	for _, s := range members {
+		if s.CandidateResult != nil {
+			return // already compared
+		}
+		if s.CandidateDone {
+			done++
+		}
+	}
+	if done < state.CandidateCount {
+		fmt.Printf("Candidate %s finished; %d of %d done, the last one compares them\n", state.ID, done, state.CandidateCount)
+		return
+	}
+	if err := compareCandidates(ctx, os.Stdout, store, baseDir, *state.CandidateGroup); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: comparing candidates: %v\n", err)
+	}
+	// The comparison saved this run too; don't let the rest of _finalize
+	// write back the copy it started with.
+	if fresh, err := store.Load(state.ID); err == nil {
+		*state = *fresh
+	}
+}
+
+// candidateGroupDone reports whether every candidate of a group is done
+// and the group hasn't been compared yet.
+func candidateGroupDone(states []*run.State, group string) bool {
+	members := candidatesOf(states, group)
+	if len(members) == 0 {
+		return false
+	}
+	done := 0
+	for _, s := range members {
+		if s.CandidateResult != nil {
+			return false
+		}
+		if s.CandidateDone {
+			done++
+		}
+	}
+	return done >= members[0].CandidateCount
+}
+
+// compareStaleGroup compares a group whose last candidate was finalized as
+// stale (see finalizeStaleRuns): that candidate never ran the _finalize that
+// would have compared the group. The outcome is reported as a pipeline
+// action; the details are in the candidates' results and the
+// candidates:compared event.
+func compareStaleGroup(ctx context.Context, store run.StateStore, group string) pipeline.Action {
+	baseDir := ""
+	if hds, ok := store.(*run.HomeDirStore); ok {
+		baseDir = hds.BaseDir()
+	}
+	if err := compareCandidates(ctx, io.Discard, store, baseDir, group); err != nil {
+		return pipeline.Action{
+			Type:   "error",
+			Detail: fmt.Sprintf("Comparing candidates %s (rerun with 'klaus compare %s')", group, group),
+			Error:  err.Error(),
+		}
+	}
+	return pipeline.Action{Type: "compare", Detail: fmt.Sprintf("Compared candidates %s", group)}
+}
+
+// compareCandidatesCmd compares groups whose last candidate was finalized as
+// stale off the dashboard's update loop, since a comparison builds and tests
+// every candidate.
+func compareCandidatesCmd(store run.StateStore, groups []string) tea.Cmd {
+	if len(groups) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		var actions []pipeline.Action
+		for _, group := range groups {
+			actions = append(actions, compareStaleGroup(context.Background(), store, group))
+		}
+		return pipelineActionMsg{actions: actions}
+	}
+}
+
+// candidatesOf returns the runs of a best-of-N group, in launch order.
+func candidatesOf(states []*run.State, group string) []*run.State {
+	var out []*run.State
+	for _, s := range states {
+		if s.CandidateGroup != nil && *s.CandidateGroup == group {
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt < out[j].CreatedAt
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// compareCandidates builds, tests and pre-reviews every candidate of a
+// group, ranks them, and opens a PR for the winner. Each candidate's result
+// is recorded on its run, the losers' worktrees are removed with their
+// branches kept, and candidates:compared is emitted.
+func compareCandidates(ctx context.Context, out io.Writer, store run.StateStore, baseDir, group string) error {
+	if baseDir != "" {
+		unlock, err := lockCandidateGroup(baseDir, group)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	states, err := store.List()
+	if err != nil {
+		return err
+	}
+	members := candidatesOf(states, group)
+	if len(members) == 0 {
+		return fmt.Errorf("no candidates in group %s", group)
+	}
+	for _, s := range members {
+		if s.CandidateResult != nil {
+			return fmt.Errorf("group %s was already compared", group)
+		}
+	}
+
+	root := candidateRepoRoot(ctx, members)
+	cfg, err := config.Load(root)
+	if err != nil {
+		return err
+	}
+	base := "origin/" + cfg.DefaultBranch
+
+	fmt.Fprintf(out, "Comparing %d candidates of %s against %s...\n", len(members), group, base)
+	results := make([]*run.CandidateResult, len(members))
+	for i, s := range members {
+		fmt.Fprintf(out, "  scoring %s...\n", s.ID)
+		results[i] = scoreCandidate(ctx, s, root, base, cfg)
+	}
+	rankCandidates(members, results)
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i, s := range members {
+		results[i].ComparedAt = now
+		s.CandidateResult = results[i]
+	}
+
+	var winner *run.State
+	if r := members[0].CandidateResult; r.Rank == 1 && r.Disqualified == "" {
+		winner = members[0]
+	}
+	var prURL string
+	var prErr error
+	if winner != nil {
+		prURL, prErr = publishCandidate(ctx, winner, members, root, cfg)
+		if prErr == nil {
+			winner.PRURL = &prURL
+		}
+	}
+
+	gitClient := git.NewExecClient()
+	for _, s := range members {
+		if s.Worktree != "" {
+			if err := gitClient.WorktreeRemove(ctx, root, s.Worktree); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: removing %s's worktree: %v\n", s.ID, err)
+			} else {
+				s.Worktree = ""
+			}
+		}
+		// The winner's branch is pushed, so its local copy goes like that
+		// of any agent that opened a PR.
+		if s == winner && prErr == nil {
+			if err := gitClient.BranchDelete(ctx, root, s.Branch); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: deleting branch %s: %v\n", s.Branch, err)
+			}
+		}
+		if err := store.Save(s); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: saving %s: %v\n", s.ID, err)
+		}
+	}
+
+	for _, s := range members {
+		fmt.Fprintf(out, "  %s\n", candidateLine(s))
+	}
+	switch {
+	case winner == nil:
+		fmt.Fprintf(out, "No candidate can win; their branches are kept.\n")
+	case prErr != nil:
+		fmt.Fprintf(out, "Opening a PR for %s failed: %v\nIts branch %s is kept; push it and open the PR by hand.\n", winner.ID, prErr, winner.Branch)
+	default:
+		fmt.Fprintf(out, "Opened %s for %s; the other candidates' branches are kept.\n", prURL, winner.ID)
+	}
+
+	if baseDir != "" {
+		emitCandidatesCompared(baseDir, group, members, winner)
+	}
+	if prErr != nil {
+		return prErr
+	}
+	return nil
+}
+
+// lockCandidateGroup claims a group's comparison, so two candidates
+// finishing at once don't both run it.
+func lockCandidateGroup(baseDir, group string) (func(), error) {
+	dir := filepath.Join(baseDir, "candidates")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, group+".lock")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, fmt.Errorf("group %s is already being compared (remove %s if that comparison died)", group, path)
+		}
+		return nil, err
+	}
+	f.Close()
+	return func() { os.Remove(path) }, nil
+}
+
+// candidateRepoRoot returns the checkout the candidates' worktrees belong
+// to.
+func candidateRepoRoot(ctx context.Context, members []*run.State) string {
+	for _, s := range members {
+		if s.CloneDir != nil {
+			return *s.CloneDir
+		}
+		if s.Worktree != "" {
+			if out, err := verify.Exec(ctx, s.Worktree, "git", "rev-parse", "--path-format=absolute", "--git-common-dir"); err == nil {
+				return filepath.Dir(out)
+			}
+		}
+	}
+	root, _ := git.RepoRoot()
+	return root
+}
+
+var shortstatRegex = regexp.MustCompile(`(\d+) (insertion|deletion)`)
+
+// scoreCandidate builds, tests and pre-reviews a candidate's commits.
+func scoreCandidate(ctx context.Context, s *run.State, root, base string, cfg config.Config) *run.CandidateResult {
+	r := &run.CandidateResult{}
+	if s.Worktree == "" {
+		r.Disqualified = "its worktree is gone"
+		return r
+	}
+	if _, err := os.Stat(s.Worktree); err != nil {
+		r.Disqualified = "its worktree is gone"
+		return r
+	}
+	count, err := verify.Exec(ctx, s.Worktree, "git", "rev-list", "--count", base+"..HEAD")
+	if err != nil {
+		r.Disqualified = "git rev-list: " + verify.LastLine(count)
+		return r
+	}
+	r.Commits, _ = strconv.Atoi(count)
+	if r.Commits == 0 {
+		r.Disqualified = "it made no commits"
+		return r
+	}
+	if stat, err := verify.Exec(ctx, s.Worktree, "git", "diff", "--shortstat", base+"...HEAD"); err == nil {
+		for _, m := range shortstatRegex.FindAllStringSubmatch(stat, -1) {
+			n, _ := strconv.Atoi(m[1])
+			r.DiffLines += n
+		}
+	}
+
+	r.Verified = true
+	for _, res := range verify.Run(s.Worktree, verifyCommands(root, s.Worktree)) {
+		if !res.Passed {
+			r.Verified = false
+			r.VerifyFailed = res.Command
+		}
+	}
+
+	if cfg.PreReviewEnabled() {
+		if linters := cfg.PreReviewLinters(); len(linters) > 0 {
+			results, err := review.RunLinters(s.Worktree, linters)
+			if err != nil {
+				r.ReviewError = err.Error()
+			}
+			for _, l := range results {
+				if !l.Passed {
+					r.LintFailed = append(r.LintFailed, l.Command)
+				}
+			}
+		}
+		res, err := review.ReviewDiff(s.Worktree, review.ReviewConfig{
+			Model:        cfg.PreReviewModel(),
+			MaxFixRounds: cfg.PreReviewMaxFixRounds(),
+		}, base)
+		if err != nil {
+			r.ReviewError = err.Error()
+		} else {
+			for _, f := range res.Findings {
+				if r.Findings == nil {
+					r.Findings = make(map[string]int)
+				}
+				r.Findings[strings.ToLower(f.Severity)]++
+			}
+		}
+	}
+	r.Score = candidateScore(r)
+	return r
+}
+
+// candidateScore scores a candidate's build, test and review results.
+func candidateScore(r *run.CandidateResult) int {
+	score := 0
+	if r.Verified {
+		score += verifiedScore
+	}
+	score -= lintPenalty * len(r.LintFailed)
+	for sev, n := range r.Findings {
+		score -= findingPenalty[sev] * n
+	}
+	return score
+}
+
+// rankCandidates sorts the candidates (and their results alongside) best
+// first and numbers their ranks: disqualified candidates last, then by
+// score, then by the smaller diff. Ties keep launch order.
+func rankCandidates(states []*run.State, results []*run.CandidateResult) {
+	idx := make([]int, len(states))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		ra, rb := results[idx[a]], results[idx[b]]
+		if (ra.Disqualified == "") != (rb.Disqualified == "") {
+			return ra.Disqualified == ""
+		}
+		if ra.Score != rb.Score {
+			return ra.Score > rb.Score
+		}
+		return ra.DiffLines < rb.DiffLines
+	})
+	sortedStates := make([]*run.State, len(states))
+	sortedResults := make([]*run.CandidateResult, len(results))
+	for i, j := range idx {
+		sortedStates[i], sortedResults[i] = states[j], results[j]
+		sortedResults[i].Rank = i + 1
+	}
+	copy(states, sortedStates)
+	copy(results, sortedResults)
+}
+
+// publishCandidate pushes the winner's branch and opens its PR against the
+// default branch. The title and description come from its first commit,
+// followed by the comparison and the usual run footer.
+func publishCandidate(ctx context.Context, s *run.State, ranked []*run.State, root string, cfg config.Config) (string, error) {
+	slug := resolveGHRepo("", root)
+	if slug == "" {
+		return "", fmt.Errorf("can't tell the GitHub repo of %s", root)
+	}
+	if out, err := verify.Exec(ctx, s.Worktree, "git", "push", "-u", "origin", s.Branch); err != nil {
+		return "", fmt.Errorf("pushing %s: %s", s.Branch, verify.LastLine(out))
+	}
+
+	base := "origin/" + cfg.DefaultBranch
+	first, _ := verify.Exec(ctx, s.Worktree, "git", "log", "--reverse", "--format=%H", base+"..HEAD")
+	sha, _, _ := strings.Cut(first, "\n")
+	title, _ := verify.Exec(ctx, s.Worktree, "git", "log", "-1", "--format=%s", sha)
+	body, _ := verify.Exec(ctx, s.Worktree, "git", "log", "-1", "--format=%b", sha)
+	if title == "" {
+		title = s.Prompt
+	}
+
+	client := gh.NewGHCLIClient(slug)
+	data, err := client.APIPostJSON(ctx, fmt.Sprintf("repos/%s/pulls", slug), map[string]string{
+		"title": truncateLine(title, 100),
+		"head":  s.Branch,
+		"base":  cfg.DefaultBranch,
+		"body":  candidatePRBody(body, s, ranked),
+	})
+	if err != nil {
+		return "", fmt.Errorf("opening PR: %w", err)
+	}
+	var pr struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.Unmarshal(data, &pr); err != nil || pr.HTMLURL == "" {
+		return "", fmt.Errorf("parsing created PR: %v", err)
+	}
+	if cfg.PRReviewer != "" {
+		if _, err := client.APIPostJSON(ctx, fmt.Sprintf("repos/%s/pulls/%d/requested_reviewers", slug, pr.Number), map[string][]string{"reviewers": {cfg.PRReviewer}}); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: requesting review from %s: %v\n", cfg.PRReviewer, err)
+		}
+	}
+	return pr.HTMLURL, nil
+}
+
+// candidatePRBody is the winner's PR description: its commit's body, the
+// comparison table and the run footer.
+func candidatePRBody(commitBody string, winner *run.State, ranked []*run.State) string {
+	var b strings.Builder
+	if commitBody = strings.TrimSpace(commitBody); commitBody != "" {
+		b.WriteString(commitBody + "\n\n")
+	}
+	fmt.Fprintf(&b, "## Best of %d\n\n", len(ranked))
+	fmt.Fprintf(&b, "klaus ran %d agents on this task and opened this PR for the best result. The others are kept as local branches.\n\n", len(ranked))
+	b.WriteString("| Rank | Run | Score | Build & tests | Diff | Pre-review |\n|---|---|---|---|---|---|\n")
+	for _, s := range ranked {
+		r := s.CandidateResult
+		id := s.ID
+		if s == winner {
+			id = "**" + id + "** (this PR)"
+		}
+		if r.Disqualified != "" {
+			fmt.Fprintf(&b, "| %d | %s | – | disqualified: %s | | |\n", r.Rank, id, r.Disqualified)
+			continue
+		}
+		fmt.Fprintf(&b, "| %d | %s | %d | %s | %d lines | %s |\n", r.Rank, id, r.Score, verifySummary(r), r.DiffLines, reviewSummary(r))
+	}
+	fmt.Fprintf(&b, "\nRun: %s", winner.ID)
+	if winner.Issue != nil && *winner.Issue != "" {
+		fmt.Fprintf(&b, "\nFixes #%s", strings.TrimPrefix(*winner.Issue, "#"))
+	}
+	return b.String()
+}
+
+func verifySummary(r *run.CandidateResult) string {
+	if r.Verified {
+		return "passed"
+	}
+	return "failed: " + r.VerifyFailed
+}
+
+// reviewSummary lists a candidate's pre-review findings by severity, e.g.
+// "1 high, 2 low".
+func reviewSummary(r *run.CandidateResult) string {
+	var parts []string
+	for _, sev := range []string{"critical", "high", "medium", "low"} {
+		if n := r.Findings[sev]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, sev))
+		}
+	}
+	if len(r.LintFailed) > 0 {
+		parts = append(parts, "lint failed: "+strings.Join(r.LintFailed, ", "))
+	}
+	if r.ReviewError != "" {
+		parts = append(parts, "review didn't run")
+	}
+	if len(parts) == 0 {
+		return "no findings"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// candidateLine is one candidate's row in the comparison printed by
+// 'klaus compare'.
+func candidateLine(s *run.State) string {
+	r := s.CandidateResult
+	if r == nil {
+		return fmt.Sprintf("%s  not compared", s.ID)
+	}
+	line := fmt.Sprintf("#%d %s  ", r.Rank, s.ID)
+	if r.Disqualified != "" {
+		line += "disqualified: " + r.Disqualified
+	} else {
+		line += fmt.Sprintf("score %d · build & tests %s · %d commit(s), %d lines · %s", r.Score, verifySummary(r), r.Commits, r.DiffLines, reviewSummary(r))
+	}
+	switch {
+	case s.PRURL != nil:
+		line += " · " + *s.PRURL
+	case s.Branch != "":
+		line += " · branch " + s.Branch
+	}
+	return line
+}
+
+// emitCandidatesCompared records the comparison in the event log, with an
+// agent:pr-created for the winner's PR so the pipeline picks it up.
+func emitCandidatesCompared(baseDir, group string, members []*run.State, winner *run.State) {
+	var candidates []map[string]interface{}
+	for _, s := range members {
+		r := s.CandidateResult
+		c := map[string]interface{}{
+			"id":         s.ID,
+			"rank":       r.Rank,
+			"score":      r.Score,
+			"verified":   r.Verified,
+			"commits":    r.Commits,
+			"diff_lines": r.DiffLines,
+			"branch":     s.Branch,
+		}
+		if len(r.Findings) > 0 {
+			c["findings"] = r.Findings
+		}
+		if r.Disqualified != "" {
+			c["disqualified"] = r.Disqualified
+		}
+		candidates = append(candidates, c)
+	}
+	data := map[string]interface{}{"group": group, "candidates": candidates}
+	runID := group
+	if winner != nil {
+		runID = winner.ID
+		data["winner"] = winner.ID
+		if winner.PRURL != nil {
+			prNumber := extractPRNumberFromURL(*winner.PRURL)
+			data["pr_url"] = *winner.PRURL
+			data["pr_number"] = prNumber
+			emitEvent(baseDir, winner.ID, event.AgentPRCreated, map[string]interface{}{
+				"id":        winner.ID,
+				"pr_url":    *winner.PRURL,
+				"pr_number": prNumber,
+			})
+		}
+	}
+	emitEvent(baseDir, runID, event.CandidatesCompared, data)
+}
+
+var compareCmd = &cobra.Command{
+	Use:   "compare <group-id>",
+	Short: "Compare a best-of-N launch's candidates and open a PR for the best",
+	Long: `Builds, tests and pre-reviews each candidate of a best-of-N launch
+(klaus launch --candidates), ranks them and opens a PR for the winner. The
+other candidates' branches are kept.
+
+This runs on its own when the last candidate finishes. Run it by hand when a
+candidate never finished (it died, or failed to launch); the group is then
+compared without it. Once a group has been compared, this prints the result.
+The argument is the group ID or any candidate's run ID.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := sessionStore()
+		if err != nil {
+			return err
+		}
+		states, err := store.List()
+		if err != nil {
+			return err
+		}
+		group := args[0]
+		for _, s := range states {
+			if s.ID == group && s.CandidateGroup != nil {
+				group = *s.CandidateGroup
+			}
+		}
+		members := candidatesOf(states, group)
+		if len(members) == 0 {
+			return fmt.Errorf("no best-of-N candidates in group %s", args[0])
+		}
+		out := cmd.OutOrStdout()
+		if members[0].CandidateResult != nil {
+			sort.SliceStable(members, func(i, j int) bool {
+				return members[i].CandidateResult.Rank < members[j].CandidateResult.Rank
+			})
+			fmt.Fprintf(out, "Group %s, compared %s:\n", group, members[0].CandidateResult.ComparedAt)
+			for _, s := range members {
+				fmt.Fprintf(out, "  %s\n", candidateLine(s))
+			}
+			return nil
+		}
+		for _, s := range members {
+			if s.IsAgentRunning() {
+				return fmt.Errorf("candidate %s is still running", s.ID)
+			}
+		}
+		baseDir := ""
+		if hds, ok := store.(*run.HomeDirStore); ok {
+			baseDir = hds.BaseDir()
+		}
+		return compareCandidates(cmd.Context(), out, store, baseDir, group)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+}
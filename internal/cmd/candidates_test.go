@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/patflynn/klaus/internal/event"
+	"github.com/patflynn/klaus/internal/run"
+)
+
+func TestParseCandidateRef(t *testing.T) {
+	ref, err := parseCandidateRef("20260101-1200-abcd1234/2/3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.Group != "20260101-1200-abcd1234" || ref.Index != 2 || ref.Count != 3 {
+		t.Errorf("parsed %+v", ref)
+	}
+	if ref.String() != "20260101-1200-abcd1234/2/3" {
+		t.Errorf("String() = %q", ref.String())
+	}
+	for _, bad := range []string{"", "g", "g/1", "/1/2", "g/0/2", "g/3/2", "g/x/2", "g/1/2/3"} {
+		if _, err := parseCandidateRef(bad); err == nil {
+			t.Errorf("parseCandidateRef(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestRankCandidates(t *testing.T) {
+	states := []*run.State{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}}
+	results := []*run.CandidateResult{
+		{Verified: true, DiffLines: 10, Findings: map[string]int{"high": 1}},
+		{Verified: true, DiffLines: 50},
+		{Verified: true, DiffLines: 20},
+		{Disqualified: "it made no commits"},
+	}
+	for _, r := range results {
+		r.Score = candidateScore(r)
+	}
+	rankCandidates(states, results)
+
+	var got []string
+	for i, s := range states {
+		got = append(got, s.ID)
+		if results[i].Rank != i+1 {
+			t.Errorf("%s rank = %d, want %d", s.ID, results[i].Rank, i+1)
+		}
+	}
+	// c and b pass with no findings, and c's diff is smaller; a's high
+	// finding costs it; d can't win.
+	if strings.Join(got, ",") != "c,b,a,d" {
+		t.Errorf("ranked %v, want [c b a d]", got)
+	}
+	if results[2].Score != 80 {
+		t.Errorf("score with a high finding = %d, want 80", results[2].Score)
+	}
+}
+
+func TestLaunchCandidates(t *testing.T) {
+	var refs []string
+	launch := func(ref *candidateRef, flags []string, prompt string) (string, error) {
+		refs = append(refs, ref.String())
+		if ref.Index == 2 {
+			return "", os.ErrPermission
+		}
+		return "run-" + ref.String(), nil
+	}
+	var out bytes.Buffer
+	if err := launchCandidates(&out, "g", 3, []string{"--budget", "2"}, "fix it", launch); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(refs, " ") != "g/1/3 g/2/3 g/3/3" {
+		t.Errorf("launched %v", refs)
+	}
+	if !strings.Contains(out.String(), "1 candidate(s) didn't launch") || !strings.Contains(out.String(), "klaus compare g") {
+		t.Errorf("output doesn't point at klaus compare:\n%s", out.String())
+	}
+
+	fail := func(*candidateRef, []string, string) (string, error) { return "", os.ErrPermission }
+	if err := launchCandidates(&out, "g", 2, nil, "fix it", fail); err == nil {
+		t.Error("expected an error when no candidate launched")
+	}
+}
+
+// TestCompareCandidates compares three candidates in a real repo: one
+// whose tests fail, one that passes and one that made no commits.
+func TestCompareCandidates(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	repo := initReplayTestRepo(t)
+	origin := filepath.Join(t.TempDir(), "origin.git")
+	gitRun(t, "", "init", "--bare", origin)
+	gitRun(t, repo, "remote", "add", "origin", origin)
+	gitRun(t, repo, "push", "-q", "origin", "main")
+	gitRun(t, repo, "fetch", "-q", "origin")
+	if err := os.MkdirAll(filepath.Join(repo, ".klaus"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := `{"verify_commands": ["test ! -f broken"], "pre_review": {"enabled": false}}`
+	if err := os.WriteFile(filepath.Join(repo, ".klaus", "config.json"), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := run.NewHomeDirStoreFromPath(t.TempDir())
+	if err := store.EnsureDirs(); err != nil {
+		t.Fatal(err)
+	}
+	group := "g"
+	files := map[string]string{"c1": "broken", "c2": "feature.txt", "c3": ""}
+	for i, id := range []string{"c1", "c2", "c3"} {
+		wt := filepath.Join(t.TempDir(), id)
+		gitRun(t, repo, "worktree", "add", "-q", "-b", "agent/"+id, wt, "origin/main")
+		if name := files[id]; name != "" {
+			if err := os.WriteFile(filepath.Join(wt, name), []byte("a\nb\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			gitRun(t, wt, "add", name)
+			gitRun(t, wt, "commit", "-q", "-m", "Add "+name)
+		}
+		s := &run.State{
+			ID: id, Branch: "agent/" + id, Worktree: wt, CreatedAt: "2026-01-01T00:00:0" + string(rune('0'+i)) + "Z",
+			CandidateGroup: &group, CandidateCount: 3, CandidateDone: true,
+		}
+		if err := store.Save(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	err := compareCandidates(context.Background(), &out, store, store.BaseDir(), group)
+	// There's no GitHub repo to open the winner's PR in.
+	if err == nil || !strings.Contains(out.String(), "Opening a PR for c2 failed") {
+		t.Fatalf("err = %v, output:\n%s", err, out.String())
+	}
+
+	want := map[string]struct {
+		rank         int
+		verified     bool
+		disqualified string
+	}{
+		"c2": {1, true, ""},
+		"c1": {2, false, ""},
+		"c3": {3, false, "it made no commits"},
+	}
+	for id, w := range want {
+		s, err := store.Load(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := s.CandidateResult
+		if r == nil || r.Rank != w.rank || r.Verified != w.verified || r.Disqualified != w.disqualified {
+			t.Errorf("%s result = %+v, want %+v", id, r, w)
+			continue
+		}
+		if s.Worktree != "" {
+			t.Errorf("%s worktree %s not removed", id, s.Worktree)
+		}
+		// Every branch is kept: the winner's PR never opened.
+		if exec.Command("git", "-C", repo, "rev-parse", "--verify", "--quiet", s.Branch).Run() != nil {
+			t.Errorf("branch %s was deleted", s.Branch)
+		}
+	}
+	if s, _ := store.Load("c1"); s.CandidateResult.VerifyFailed != "test ! -f broken" || s.CandidateResult.DiffLines != 2 {
+		t.Errorf("c1 result = %+v", s.CandidateResult)
+	}
+
+	data, err := os.ReadFile(event.NewLog(store.BaseDir()).Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"type":"candidates:compared"`) || !strings.Contains(string(data), `"winner":"c2"`) {
+		t.Errorf("event log has no candidates:compared won by c2:\n%s", data)
+	}
+
+	if err := compareCandidates(context.Background(), &out, store, store.BaseDir(), group); err == nil {
+		t.Error("comparing a group twice succeeded")
+	}
+}
+
+// TestFinalizeStaleRunsReturnsCompletedGroups checks that a candidate
+// finalized as stale hands its group over for comparison once every other
+// candidate is done, and only then.
+func TestFinalizeStaleRunsReturnsCompletedGroups(t *testing.T) {
+	store := run.NewHomeDirStoreFromPath(t.TempDir())
+	if err := store.EnsureDirs(); err != nil {
+		t.Fatal(err)
+	}
+	old := "2026-01-01T00:00:00Z"
+	cost := 1.0
+	candidate := func(id, group string) *run.State {
+		return &run.State{ID: id, CreatedAt: old, CandidateGroup: &group, CandidateCount: 2}
+	}
+	done := func(s *run.State) *run.State {
+		s.CostUSD, s.CandidateDone = &cost, true
+		return s
+	}
+	stale := func(s *run.State) *run.State {
+		s.TmuxPane = strPtr("gone")
+		return s
+	}
+	running := func(s *run.State) *run.State {
+		s.TmuxPane = strPtr("%1")
+		return s
+	}
+	compared := done(candidate("k2", "k"))
+	compared.CandidateResult = &run.CandidateResult{Rank: 1}
+	states := []*run.State{
+		done(candidate("g1", "g")), stale(candidate("g2", "g")),
+		stale(candidate("h1", "h")), running(candidate("h2", "h")),
+		stale(candidate("k1", "k")), compared,
+	}
+	for _, s := range states {
+		if err := store.Save(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	groups := finalizeStaleRuns(store, states, testDashboardTmuxDeps())
+	if strings.Join(groups, ",") != "g" {
+		t.Errorf("groups = %v, want [g]", groups)
+	}
+	if s, err := store.Load("h1"); err != nil || !s.CandidateDone {
+		t.Errorf("stale candidate h1 not marked done: %+v, %v", s, err)
+	}
+}
+
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
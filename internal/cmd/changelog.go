@@ -0,0 +1,532 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/patflynn/klaus/internal/config"
+	"github.com/patflynn/klaus/internal/git"
+	gh "github.com/patflynn/klaus/internal/github"
+	"github.com/patflynn/klaus/internal/run"
+	"github.com/patflynn/klaus/internal/verify"
+	"github.com/spf13/cobra"
+)
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog [<tag> | <from>..<to>]",
+	Short: "Render release notes from merged PRs and klaus runs",
+	Long: `Renders Markdown release notes for a release: the PRs merged in a tag
+range or date range, grouped by conventional-commit type (default), label or
+issue, each attributed to an agent or a human with what klaus spent on it.
+
+The PRs come from the default branch's history: squash merges ending in
+"(#N)" and "Merge pull request #N" commits. Attribution, cost and issue come
+from the runs recorded on the data ref (refs/klaus/data), which is fetched
+first. A PR with no agent run behind it is a human's; agents that only fixed
+CI or review comments on it are counted as fixes.
+
+  klaus changelog                  # since the latest tag (unreleased)
+  klaus changelog v1.4.0           # the previous tag..v1.4.0
+  klaus changelog v1.3.0..v1.4.0
+  klaus changelog --since 2026-10-01 --until 2026-10-15
+
+--group-by label fetches each PR's labels from GitHub. --json prints the
+same data as JSON.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, _ := cmd.Flags().GetString("since")
+		until, _ := cmd.Flags().GetString("until")
+		groupBy, _ := cmd.Flags().GetString("group-by")
+		jsonOut, _ := cmd.Flags().GetBool("json")
+		if groupBy != "type" && groupBy != "label" && groupBy != "issue" {
+			return fmt.Errorf("--group-by must be type, label or issue, got %q", groupBy)
+		}
+		var rangeArg string
+		if len(args) == 1 {
+			rangeArg = args[0]
+		}
+
+		root, err := git.RepoRoot()
+		if err != nil {
+			return fmt.Errorf("not inside a git repository")
+		}
+		cfg, err := config.Load(root)
+		if err != nil {
+			return err
+		}
+		rng, err := resolveChangelogRange(cmd.Context(), root, rangeArg, since, until)
+		if err != nil {
+			return err
+		}
+		merges, err := rangeMerges(root, rng)
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		gitClient := git.NewExecClient()
+		// Best-effort: pick up runs finalized on other machines.
+		_ = gitClient.FetchDataRef(ctx, root, cfg.DataRef)
+		runs, err := loadDataRefRuns(ctx, gitClient, root, cfg.DataRef)
+		if err != nil {
+			return err
+		}
+
+		var labels func(prNumber string) []string
+		if groupBy == "label" {
+			client := gh.NewGHCLIClient("")
+			labels = func(pr string) []string { return client.GetLabels(ctx, pr) }
+		}
+		cl := buildChangelog(rng, merges, runs, remoteSlug(gitRemoteURL(root)), groupBy, labels)
+
+		if jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(cl)
+		}
+		fmt.Print(renderChangelog(cl))
+		return nil
+	},
+}
+
+// changelogRange is the slice of history a changelog covers: the commits in
+// From..To, further limited to Since..Until when those are set.
+type changelogRange struct {
+	Release string
+	From    string // exclusive; empty means from the start of history
+	To      string
+	Since   time.Time
+	Until   time.Time
+}
+
+// mergedPR is a PR merge found in the range's history.
+type mergedPR struct {
+	Number   string
+	Title    string
+	SHA      string
+	MergedAt time.Time
+}
+
+// changelog is a rendered release's data, and the --json output.
+type changelog struct {
+	Release string           `json:"release"`
+	From    string           `json:"from,omitempty"`
+	To      string           `json:"to"`
+	Start   *time.Time       `json:"start,omitempty"`
+	End     time.Time        `json:"end"`
+	GroupBy string           `json:"group_by"`
+	Groups  []changelogGroup `json:"groups"`
+	Totals  changelogTotals  `json:"totals"`
+}
+
+type changelogGroup struct {
+	Name    string           `json:"name"`
+	Entries []changelogEntry `json:"entries"`
+}
+
+type changelogEntry struct {
+	PR         string    `json:"pr"`
+	URL        string    `json:"url,omitempty"`
+	Title      string    `json:"title"`
+	Type       string    `json:"type,omitempty"` // conventional-commit type
+	Breaking   bool      `json:"breaking,omitempty"`
+	Labels     []string  `json:"labels,omitempty"`
+	Issue      string    `json:"issue,omitempty"`
+	Author     string    `json:"author"`                // "agent" or "human"
+	AgentFixes int       `json:"agent_fixes,omitempty"` // fix, rebase and review agents run on the PR
+	Runs       []string  `json:"runs,omitempty"`
+	CostUSD    float64   `json:"cost_usd"`
+	SHA        string    `json:"sha"`
+	MergedAt   time.Time `json:"merged_at"`
+}
+
+type changelogTotals struct {
+	PRs      int     `json:"prs"`
+	AgentPRs int     `json:"agent_prs"`
+	HumanPRs int     `json:"human_prs"`
+	CostUSD  float64 `json:"cost_usd"`
+}
+
+// changelogTypes are the conventional-commit types with their headings, in
+// the order they're rendered. Anything else goes under "Other".
+var changelogTypes = []struct{ Type, Heading string }{
+	{"feat", "Features"},
+	{"fix", "Fixes"},
+	{"perf", "Performance"},
+	{"refactor", "Refactoring"},
+	{"docs", "Documentation"},
+	{"test", "Tests"},
+	{"build", "Build"},
+	{"ci", "CI"},
+	{"chore", "Chores"},
+}
+
+var (
+	squashSubjectRe = regexp.MustCompile(`^(.*\S)\s+\(#(\d+)\)$`)
+	mergeSubjectRe  = regexp.MustCompile(`^Merge pull request #(\d+) from `)
+	conventionalRe  = regexp.MustCompile(`^([a-zA-Z]+)(\([^)]*\))?(!)?:\s*(.+)$`)
+)
+
+// resolveChangelogRange turns the command's range argument and date flags
+// into a changelogRange. A single tag covers the commits since the tag
+// before it; no argument covers the commits since the latest tag, unless
+// --since bounds it instead.
+func resolveChangelogRange(ctx context.Context, root, arg, since, until string) (changelogRange, error) {
+	r := changelogRange{Release: "Unreleased", To: "HEAD"}
+	switch {
+	case strings.Contains(arg, ".."):
+		from, to, _ := strings.Cut(arg, "..")
+		r.From = from
+		if to != "" {
+			r.To, r.Release = to, to
+		}
+	case arg != "":
+		r.To, r.Release = arg, arg
+		r.From = previousTag(ctx, root, arg+"^")
+	case since == "":
+		r.From = previousTag(ctx, root, "HEAD")
+	}
+	for _, rev := range []string{r.From, r.To} {
+		if rev == "" {
+			continue
+		}
+		if _, err := verify.Exec(ctx, root, "git", "rev-parse", "--verify", "--quiet", rev+"^{commit}"); err != nil {
+			return r, fmt.Errorf("unknown revision %q", rev)
+		}
+	}
+
+	var err error
+	if r.Since, err = parseChangelogDate(since); err != nil {
+		return r, fmt.Errorf("--since: %w", err)
+	}
+	if r.Until, err = parseChangelogDate(until); err != nil {
+		return r, fmt.Errorf("--until: %w", err)
+	}
+	if !r.Until.IsZero() && len(until) == len("2006-01-02") {
+		// A bare date includes that whole day.
+		r.Until = r.Until.Add(24*time.Hour - time.Second)
+	}
+	return r, nil
+}
+
+func parseChangelogDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date (YYYY-MM-DD) or RFC 3339 time", s)
+	}
+	return t, nil
+}
+
+// previousTag returns the latest tag reachable from rev, or "" when there
+// is none.
+func previousTag(ctx context.Context, root, rev string) string {
+	out, err := verify.Exec(ctx, root, "git", "describe", "--tags", "--abbrev=0", rev)
+	if err != nil {
+		return ""
+	}
+	return out
+}
+
+// rangeMerges lists the PRs merged in the range, oldest first, from the
+// first-parent history of its end.
+func rangeMerges(root string, r changelogRange) ([]mergedPR, error) {
+	args := []string{"log", "--first-parent", "--reverse", "--format=%H%x00%cI%x00%s%x00%b%x1e"}
+	if !r.Since.IsZero() {
+		args = append(args, "--since="+r.Since.Format(time.RFC3339))
+	}
+	if !r.Until.IsZero() {
+		args = append(args, "--until="+r.Until.Format(time.RFC3339))
+	}
+	if r.From != "" {
+		args = append(args, r.From+".."+r.To)
+	} else {
+		args = append(args, r.To)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = root
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+
+	var merges []mergedPR
+	for _, rec := range strings.Split(string(out), "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(rec), "\x00", 4)
+		if len(fields) < 3 {
+			continue
+		}
+		m := mergedPR{SHA: fields[0]}
+		m.MergedAt, _ = time.Parse(time.RFC3339, fields[1])
+		subject := fields[2]
+		if sm := squashSubjectRe.FindStringSubmatch(subject); sm != nil {
+			m.Title, m.Number = sm[1], sm[2]
+		} else if mm := mergeSubjectRe.FindStringSubmatch(subject); mm != nil {
+			// The merge commit's body starts with the PR title.
+			m.Number, m.Title = mm[1], subject
+			if len(fields) == 4 {
+				if body := strings.TrimSpace(fields[3]); body != "" {
+					m.Title, _, _ = strings.Cut(body, "\n")
+				}
+			}
+		} else {
+			continue // pushed directly, not through a PR
+		}
+		merges = append(merges, m)
+	}
+	return merges, nil
+}
+
+// loadDataRefRuns reads every run state recorded on the data ref.
+func loadDataRefRuns(ctx context.Context, gitClient git.Client, root, dataRef string) ([]*run.State, error) {
+	paths, err := gitClient.ListDataRefFiles(ctx, root, dataRef, "runs")
+	if err != nil {
+		return nil, err
+	}
+	var states []*run.State
+	for _, p := range paths {
+		if !strings.HasSuffix(p, ".json") {
+			continue
+		}
+		data, err := gitClient.ReadDataRefFile(ctx, root, dataRef, p)
+		if err != nil {
+			continue
+		}
+		var s run.State
+		if err := json.Unmarshal(data, &s); err != nil {
+			continue
+		}
+		states = append(states, &s)
+	}
+	return states, nil
+}
+
+// buildChangelog joins the range's merges with the runs behind them and
+// groups the result. slug is the repo's owner/repo, used to ignore runs for
+// other repos and to link PRs that no run recorded; labels fetches a PR's
+// labels and is only needed to group by label.
+func buildChangelog(r changelogRange, merges []mergedPR, runs []*run.State, slug, groupBy string, labels func(prNumber string) []string) *changelog {
+	cl := &changelog{Release: r.Release, From: r.From, To: r.To, GroupBy: groupBy, End: time.Now().UTC()}
+	if !r.Since.IsZero() {
+		start := r.Since.UTC()
+		cl.Start = &start
+	}
+	if len(merges) > 0 {
+		if cl.Start == nil {
+			start := merges[0].MergedAt.UTC()
+			cl.Start = &start
+		}
+		if r.To != "HEAD" || !r.Until.IsZero() {
+			cl.End = merges[len(merges)-1].MergedAt.UTC()
+		}
+	}
+
+	byPR := make(map[string][]*run.State)
+	for _, s := range runs {
+		pr := s.PRNumber()
+		if pr == "" || s.Type == "session" {
+			continue
+		}
+		if prSlug := gh.OwnerRepoFromPRURL(*s.PRURL); slug != "" && prSlug != "" && !strings.EqualFold(prSlug, slug) {
+			continue
+		}
+		byPR[pr] = append(byPR[pr], s)
+	}
+
+	groups := make(map[string]*changelogGroup)
+	var order []string
+	add := func(name string, e changelogEntry) {
+		g, ok := groups[name]
+		if !ok {
+			g = &changelogGroup{Name: name}
+			groups[name] = g
+			order = append(order, name)
+		}
+		g.Entries = append(g.Entries, e)
+	}
+
+	for _, m := range merges {
+		e := changelogEntry{PR: m.Number, Title: m.Title, SHA: m.SHA, MergedAt: m.MergedAt, Author: "human"}
+		if cm := conventionalRe.FindStringSubmatch(m.Title); cm != nil {
+			e.Type = strings.ToLower(cm[1])
+			e.Breaking = cm[3] == "!"
+			e.Title = cm[4]
+		}
+		for _, s := range byPR[m.Number] {
+			e.Runs = append(e.Runs, s.ID)
+			if s.CostUSD != nil {
+				e.CostUSD += *s.CostUSD
+			}
+			if e.URL == "" && s.PRURL != nil {
+				e.URL = *s.PRURL
+			}
+			if e.Issue == "" && s.Issue != nil {
+				e.Issue = strings.TrimPrefix(*s.Issue, "#")
+			}
+			if authoredByAgent(s) {
+				e.Author = "agent"
+			} else if s.Type == "pr-fix" {
+				e.AgentFixes++
+			}
+		}
+		if e.URL == "" && slug != "" {
+			e.URL = fmt.Sprintf("https://github.com/%s/pull/%s", slug, m.Number)
+		}
+		if labels != nil {
+			e.Labels = labels(m.Number)
+		}
+
+		cl.Totals.PRs++
+		cl.Totals.CostUSD += e.CostUSD
+		if e.Author == "agent" {
+			cl.Totals.AgentPRs++
+		} else {
+			cl.Totals.HumanPRs++
+		}
+		add(changelogGroupName(e, groupBy), e)
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return changelogGroupRank(order[i], groupBy) < changelogGroupRank(order[j], groupBy)
+	})
+	for _, name := range order {
+		cl.Groups = append(cl.Groups, *groups[name])
+	}
+	return cl
+}
+
+// authoredByAgent reports whether a run opened its PR: an agent launch, or
+// a backport klaus cherry-picked itself. Tracked PRs are a human's, and
+// pr-fix runs only pushed fixes.
+func authoredByAgent(s *run.State) bool {
+	switch s.Type {
+	case "track":
+		return s.BackportOf != nil
+	case "pr-fix":
+		return false
+	}
+	return true
+}
+
+func changelogGroupName(e changelogEntry, groupBy string) string {
+	switch groupBy {
+	case "label":
+		for _, l := range e.Labels {
+			// klaus's own labels say nothing about the change.
+			if !strings.HasPrefix(l, "klaus:") {
+				return l
+			}
+		}
+		return "Unlabelled"
+	case "issue":
+		if e.Issue == "" {
+			return "No issue"
+		}
+		return "#" + e.Issue
+	}
+	for _, t := range changelogTypes {
+		if t.Type == e.Type {
+			return t.Heading
+		}
+	}
+	return "Other"
+}
+
+// changelogGroupRank orders groups: types in changelogTypes order, issues
+// by number, labels alphabetically, with the catch-all group last.
+func changelogGroupRank(name, groupBy string) string {
+	switch name {
+	case "Other", "Unlabelled", "No issue":
+		return "\xff"
+	}
+	switch groupBy {
+	case "type":
+		for i, t := range changelogTypes {
+			if t.Heading == name {
+				return fmt.Sprintf("%02d", i)
+			}
+		}
+	case "issue":
+		n, _ := strconv.Atoi(strings.TrimPrefix(name, "#"))
+		return fmt.Sprintf("%010d", n)
+	}
+	return strings.ToLower(name)
+}
+
+// renderChangelog renders a changelog as Markdown.
+func renderChangelog(cl *changelog) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", cl.Release)
+	var span string
+	if cl.Start != nil {
+		span = cl.Start.Format("2006-01-02") + " – "
+	}
+	span += cl.End.Format("2006-01-02")
+	fmt.Fprintf(&b, "_%s · %d PR(s): %d by agents, %d by humans · agent spend $%.2f_\n",
+		span, cl.Totals.PRs, cl.Totals.AgentPRs, cl.Totals.HumanPRs, cl.Totals.CostUSD)
+	if cl.Totals.PRs == 0 {
+		b.WriteString("\nNo PRs merged.\n")
+		return b.String()
+	}
+
+	for _, g := range cl.Groups {
+		fmt.Fprintf(&b, "\n### %s\n\n", g.Name)
+		for _, e := range g.Entries {
+			b.WriteString("- ")
+			if e.Breaking {
+				b.WriteString("**Breaking:** ")
+			}
+			b.WriteString(e.Title)
+			if e.URL != "" {
+				fmt.Fprintf(&b, " ([#%s](%s))", e.PR, e.URL)
+			} else {
+				fmt.Fprintf(&b, " (#%s)", e.PR)
+			}
+			attribution := []string{e.Author}
+			if e.AgentFixes > 0 {
+				attribution = append(attribution, fmt.Sprintf("%d agent fix(es)", e.AgentFixes))
+			}
+			if e.CostUSD > 0 {
+				attribution = append(attribution, fmt.Sprintf("$%.2f", e.CostUSD))
+			}
+			if e.Issue != "" && cl.GroupBy != "issue" {
+				attribution = append(attribution, "issue #"+e.Issue)
+			}
+			fmt.Fprintf(&b, " — %s\n", strings.Join(attribution, ", "))
+		}
+	}
+	return b.String()
+}
+
+// remoteSlug returns the owner/repo of a GitHub remote URL (https or ssh),
+// or "" when it isn't one.
+func remoteSlug(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimSpace(remote), ".git")
+	for _, prefix := range []string{"https://github.com/", "http://github.com/", "ssh://git@github.com/", "git@github.com:"} {
+		if rest, ok := strings.CutPrefix(remote, prefix); ok && strings.Count(rest, "/") == 1 {
+			return rest
+		}
+	}
+	return ""
+}
+
+func init() {
+	changelogCmd.Flags().String("since", "", "Only PRs merged on or after this date (YYYY-MM-DD or RFC 3339)")
+	changelogCmd.Flags().String("until", "", "Only PRs merged on or before this date (YYYY-MM-DD or RFC 3339)")
+	changelogCmd.Flags().String("group-by", "type", "Group PRs by conventional-commit type, label or issue")
+	changelogCmd.Flags().Bool("json", false, "Output the changelog as JSON")
+	rootCmd.AddCommand(changelogCmd)
+}
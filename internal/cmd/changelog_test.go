@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patflynn/klaus/internal/run"
+)
+
+// setupChangelogRepo creates a repo with a v1.0 tag, three PRs merged (two
+// squashed, one merge commit) and a direct push before v1.1, and one more
+// PR after it.
+func setupChangelogRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	runGitT(t, dir, "init", "-q", "--initial-branch=main")
+	runGitT(t, dir, "config", "user.email", "t@t.com")
+	runGitT(t, dir, "config", "user.name", "T")
+	n := 0
+	commit := func(msg ...string) {
+		n++
+		if err := os.WriteFile(filepath.Join(dir, "f"), []byte(strings.Repeat("x", n)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		runGitT(t, dir, "add", "-A")
+		args := []string{"commit", "-q"}
+		for _, m := range msg {
+			args = append(args, "-m", m)
+		}
+		runGitT(t, dir, args...)
+	}
+	commit("initial")
+	runGitT(t, dir, "tag", "v1.0")
+	commit("feat: add the widget (#1)")
+	commit("tweak whitespace")
+	commit("fix(api)!: return 404 for missing runs (#2)")
+	runGitT(t, dir, "checkout", "-q", "-b", "docs")
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("docs"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGitT(t, dir, "add", "-A")
+	runGitT(t, dir, "commit", "-q", "-m", "docs")
+	runGitT(t, dir, "checkout", "-q", "main")
+	runGitT(t, dir, "merge", "-q", "--no-ff", "-m", "Merge pull request #3 from someone/docs", "-m", "docs: tidy the README", "docs")
+	runGitT(t, dir, "tag", "v1.1")
+	commit("chore: bump deps (#4)")
+	return dir
+}
+
+func mergeNumbers(merges []mergedPR) []string {
+	var out []string
+	for _, m := range merges {
+		out = append(out, m.Number)
+	}
+	return out
+}
+
+func TestChangelogRangeMerges(t *testing.T) {
+	dir := setupChangelogRepo(t)
+
+	tests := []struct {
+		arg      string
+		wantFrom string
+		wantPRs  []string
+	}{
+		{"v1.1", "v1.0", []string{"1", "2", "3"}},
+		{"v1.0..v1.1", "v1.0", []string{"1", "2", "3"}},
+		{"", "v1.1", []string{"4"}},
+	}
+	for _, tt := range tests {
+		rng, err := resolveChangelogRange(context.Background(), dir, tt.arg, "", "")
+		if err != nil {
+			t.Fatalf("resolveChangelogRange(%q) error = %v", tt.arg, err)
+		}
+		if rng.From != tt.wantFrom {
+			t.Errorf("resolveChangelogRange(%q).From = %q, want %q", tt.arg, rng.From, tt.wantFrom)
+		}
+		merges, err := rangeMerges(dir, rng)
+		if err != nil {
+			t.Fatalf("rangeMerges(%q) error = %v", tt.arg, err)
+		}
+		if got := mergeNumbers(merges); !reflect.DeepEqual(got, tt.wantPRs) {
+			t.Errorf("rangeMerges(%q) = %v, want %v", tt.arg, got, tt.wantPRs)
+		}
+	}
+
+	rng, _ := resolveChangelogRange(context.Background(), dir, "v1.1", "", "")
+	merges, _ := rangeMerges(dir, rng)
+	if merges[0].Title != "feat: add the widget" {
+		t.Errorf("squash title = %q", merges[0].Title)
+	}
+	if merges[2].Title != "docs: tidy the README" {
+		t.Errorf("merge commit title = %q, want the PR title from its body", merges[2].Title)
+	}
+
+	if _, err := resolveChangelogRange(context.Background(), dir, "v9.9", "", ""); err == nil {
+		t.Error("expected an error for an unknown tag")
+	}
+	if _, err := resolveChangelogRange(context.Background(), dir, "", "last week", ""); err == nil {
+		t.Error("expected an error for an unparseable date")
+	}
+}
+
+func TestBuildChangelog(t *testing.T) {
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	merges := []mergedPR{
+		{Number: "1", Title: "feat: add the widget", SHA: "a1", MergedAt: at},
+		{Number: "2", Title: "fix(api)!: return 404 for missing runs", SHA: "b2", MergedAt: at.Add(time.Hour)},
+		{Number: "3", Title: "Tidy the README", SHA: "c3", MergedAt: at.Add(2 * time.Hour)},
+	}
+	cost := func(v float64) *float64 { return &v }
+	url := func(repo, pr string) *string { u := "https://github.com/" + repo + "/pull/" + pr; return &u }
+	runs := []*run.State{
+		{ID: "r1", PRURL: url("owner/repo", "1"), CostUSD: cost(1.5), Issue: stringPtr("12")},
+		{ID: "r2", PRURL: url("owner/repo", "2"), Type: "track"},
+		{ID: "r2-fix", PRURL: url("owner/repo", "2"), Type: "pr-fix", CostUSD: cost(0.25)},
+		{ID: "other", PRURL: url("owner/other", "3"), CostUSD: cost(9)},
+	}
+
+	cl := buildChangelog(changelogRange{Release: "v1.1", From: "v1.0", To: "v1.1"}, merges, runs, "owner/repo", "type", nil)
+
+	var names []string
+	for _, g := range cl.Groups {
+		names = append(names, g.Name)
+	}
+	if want := []string{"Features", "Fixes", "Other"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("groups = %v, want %v", names, want)
+	}
+	widget := cl.Groups[0].Entries[0]
+	if widget.Author != "agent" || widget.CostUSD != 1.5 || widget.Issue != "12" || widget.Title != "add the widget" {
+		t.Errorf("widget entry = %+v", widget)
+	}
+	fix := cl.Groups[1].Entries[0]
+	if fix.Author != "human" || fix.AgentFixes != 1 || !fix.Breaking || fix.CostUSD != 0.25 {
+		t.Errorf("fix entry = %+v", fix)
+	}
+	readme := cl.Groups[2].Entries[0]
+	if readme.Author != "human" || readme.CostUSD != 0 || readme.URL != "https://github.com/owner/repo/pull/3" {
+		t.Errorf("README entry = %+v (runs for other repos must not count)", readme)
+	}
+	if want := (changelogTotals{PRs: 3, AgentPRs: 1, HumanPRs: 2, CostUSD: 1.75}); cl.Totals != want {
+		t.Errorf("totals = %+v, want %+v", cl.Totals, want)
+	}
+
+	md := renderChangelog(cl)
+	for _, want := range []string{
+		"## v1.1",
+		"3 PR(s): 1 by agents, 2 by humans · agent spend $1.75",
+		"### Features\n\n- add the widget ([#1](https://github.com/owner/repo/pull/1)) — agent, $1.50, issue #12",
+		"- **Breaking:** return 404 for missing runs ([#2](https://github.com/owner/repo/pull/2)) — human, 1 agent fix(es), $0.25",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestBuildChangelogGroupByIssueAndLabel(t *testing.T) {
+	merges := []mergedPR{{Number: "1", Title: "a"}, {Number: "2", Title: "b"}, {Number: "3", Title: "c"}}
+	runs := []*run.State{
+		{ID: "r1", PRURL: stringPtr("https://github.com/o/r/pull/1"), Issue: stringPtr("20")},
+		{ID: "r2", PRURL: stringPtr("https://github.com/o/r/pull/2"), Issue: stringPtr("9")},
+	}
+	groupNames := func(cl *changelog) []string {
+		var out []string
+		for _, g := range cl.Groups {
+			out = append(out, g.Name)
+		}
+		return out
+	}
+
+	cl := buildChangelog(changelogRange{}, merges, runs, "o/r", "issue", nil)
+	if got, want := groupNames(cl), []string{"#9", "#20", "No issue"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("issue groups = %v, want %v", got, want)
+	}
+
+	labels := map[string][]string{"1": {"klaus:in-progress", "ui"}, "2": {"api"}}
+	cl = buildChangelog(changelogRange{}, merges, runs, "o/r", "label", func(pr string) []string { return labels[pr] })
+	if got, want := groupNames(cl), []string{"api", "ui", "Unlabelled"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("label groups = %v, want %v", got, want)
+	}
+}
+
+func TestRemoteSlug(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/owner/repo.git": "owner/repo",
+		"git@github.com:owner/repo.git":     "owner/repo",
+		"ssh://git@github.com/owner/repo":   "owner/repo",
+		"https://gitlab.com/owner/repo.git": "",
+		"":                                  "",
+	}
+	for in, want := range tests {
+		if got := remoteSlug(in); got != want {
+			t.Errorf("remoteSlug(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -19,10 +19,19 @@ var cleanupCmd = &cobra.Command{
 deleting local branches, and removing state files.
 
 Use --all to clean up all runs. Runs with active tmux panes are skipped
-by default; pass --force to remove them anyway.`,
+by default; pass --force to remove them anyway.
+
+The --keep-* flags leave parts of a run in place, and --remote-branch also
+deletes the run's branch from origin. The pipeline uses these to apply the
+pipeline.closed_cleanup setting when a PR is closed without merging.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		all, _ := cmd.Flags().GetBool("all")
-		force, _ := cmd.Flags().GetBool("force")
+		var opts cleanupOptions
+		opts.Force, _ = cmd.Flags().GetBool("force")
+		opts.KeepWorktree, _ = cmd.Flags().GetBool("keep-worktree")
+		opts.KeepBranch, _ = cmd.Flags().GetBool("keep-branch")
+		opts.KeepState, _ = cmd.Flags().GetBool("keep-state")
+		opts.RemoteBranch, _ = cmd.Flags().GetBool("remote-branch")
 		ctx := cmd.Context()
 		tmuxClient := tmux.NewExecClient()
 		gitClient := git.NewExecClient()
@@ -37,18 +46,28 @@ by default; pass --force to remove them anyway.`,
 		}
 
 		if all {
-			return cleanupAll(ctx, root, store, gitClient, force, deps, tmuxClient)
+			return cleanupAll(ctx, root, store, gitClient, opts, deps, tmuxClient)
 		}
 
 		if len(args) != 1 {
 			return fmt.Errorf("usage: klaus cleanup <run-id> or klaus cleanup --all")
 		}
 
-		return cleanupOne(ctx, root, store, gitClient, args[0], force, deps, tmuxClient)
+		return cleanupOne(ctx, root, store, gitClient, args[0], opts, deps, tmuxClient)
 	},
 }
 
-func cleanupAll(ctx context.Context, root string, store run.StateStore, gitClient git.Client, force bool, deps CleanupDeps, tc tmux.Client) error {
+// cleanupOptions selects what cleanupOne removes. The zero value removes
+// the worktree, local branch and state of idle runs.
+type cleanupOptions struct {
+	Force        bool // clean up runs that are still running
+	KeepWorktree bool
+	KeepBranch   bool
+	KeepState    bool
+	RemoteBranch bool // also delete the branch from origin
+}
+
+func cleanupAll(ctx context.Context, root string, store run.StateStore, gitClient git.Client, opts cleanupOptions, deps CleanupDeps, tc tmux.Client) error {
 	states, err := store.List()
 	if err != nil {
 		return err
@@ -61,7 +80,7 @@ func cleanupAll(ctx context.Context, root string, store run.StateStore, gitClien
 		if err := ctx.Err(); err != nil {
 			return err
 		}
-		if err := cleanupOne(ctx, root, store, gitClient, s.ID, force, deps, tc); err != nil {
+		if err := cleanupOne(ctx, root, store, gitClient, s.ID, opts, deps, tc); err != nil {
 			fmt.Printf("  warning: failed to clean up %s: %v\n", s.ID, err)
 		}
 	}
@@ -101,13 +120,13 @@ func defaultIsRunActive(state *run.State, tc tmux.Client) bool {
 	return false
 }
 
-func cleanupOne(ctx context.Context, root string, store run.StateStore, gitClient git.Client, id string, force bool, deps CleanupDeps, tc tmux.Client) error {
+func cleanupOne(ctx context.Context, root string, store run.StateStore, gitClient git.Client, id string, opts cleanupOptions, deps CleanupDeps, tc tmux.Client) error {
 	state, err := store.Load(id)
 	if err != nil {
 		return fmt.Errorf("no run found with id: %s", id)
 	}
 
-	if !force && deps.IsRunActive(state) {
+	if !opts.Force && deps.IsRunActive(state) {
 		fmt.Printf("skipping %s (still running) — use --force to remove\n", id)
 		return nil
 	}
@@ -139,7 +158,7 @@ func cleanupOne(ctx context.Context, root string, store run.StateStore, gitClien
 	}
 
 	// Remove worktree
-	if state.Worktree != "" {
+	if state.Worktree != "" && !opts.KeepWorktree {
 		if err := gitClient.WorktreeRemove(ctx, gitRoot, state.Worktree); err == nil {
 			fmt.Println("  removed worktree")
 		} else {
@@ -148,7 +167,7 @@ func cleanupOne(ctx context.Context, root string, store run.StateStore, gitClien
 	}
 
 	// Delete local branch
-	if state.Branch != "" {
+	if state.Branch != "" && !opts.KeepBranch {
 		if err := gitClient.BranchDelete(ctx, gitRoot, state.Branch); err == nil {
 			fmt.Println("  deleted local branch")
 		} else {
@@ -156,11 +175,23 @@ func cleanupOne(ctx context.Context, root string, store run.StateStore, gitClien
 		}
 	}
 
+	// Delete remote branch. Only launched agents push branches klaus
+	// created; fix and tracked runs work on someone else's PR branch.
+	if state.Branch != "" && opts.RemoteBranch && state.Type == "" {
+		if err := gitClient.DeleteRemoteBranch(ctx, gitRoot, state.Branch); err == nil {
+			fmt.Println("  deleted remote branch")
+		} else {
+			slog.Warn("failed to delete remote branch", "id", id, "branch", state.Branch, "err", err)
+		}
+	}
+
 	// Remove state file
-	if err := store.Delete(id); err == nil {
-		fmt.Println("  removed state file")
-	} else {
-		slog.Warn("failed to remove state file", "id", id, "err", err)
+	if !opts.KeepState {
+		if err := store.Delete(id); err == nil {
+			fmt.Println("  removed state file")
+		} else {
+			slog.Warn("failed to remove state file", "id", id, "err", err)
+		}
 	}
 
 	fmt.Println("  done.")
@@ -170,5 +201,9 @@ func cleanupOne(ctx context.Context, root string, store run.StateStore, gitClien
 func init() {
 	cleanupCmd.Flags().Bool("all", false, "Clean up all runs")
 	cleanupCmd.Flags().Bool("force", false, "Remove runs even if they are still running")
+	cleanupCmd.Flags().Bool("keep-worktree", false, "Leave the worktree in place")
+	cleanupCmd.Flags().Bool("keep-branch", false, "Leave the local branch in place")
+	cleanupCmd.Flags().Bool("keep-state", false, "Leave the state file in place")
+	cleanupCmd.Flags().Bool("remote-branch", false, "Also delete the run's branch from origin")
 	rootCmd.AddCommand(cleanupCmd)
 }
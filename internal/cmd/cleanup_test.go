@@ -27,7 +27,7 @@ func TestCleanupAllSkipsActiveRuns(t *testing.T) {
 	deps := CleanupDeps{IsRunActive: func(s *run.State) bool { return s.ID == "run-2" }}
 
 	output := captureStdout(t, func() {
-		if err := cleanupAll(ctx, "", store, git.NewExecClient(), false, deps, tc); err != nil {
+		if err := cleanupAll(ctx, "", store, git.NewExecClient(), cleanupOptions{}, deps, tc); err != nil {
 			t.Fatalf("cleanupAll() error: %v", err)
 		}
 	})
@@ -60,7 +60,7 @@ func TestCleanupAllForceRemovesActiveRuns(t *testing.T) {
 	deps := CleanupDeps{IsRunActive: func(s *run.State) bool { return s.ID == "run-2" }}
 
 	output := captureStdout(t, func() {
-		if err := cleanupAll(ctx, "", store, git.NewExecClient(), true, deps, tc); err != nil {
+		if err := cleanupAll(ctx, "", store, git.NewExecClient(), cleanupOptions{Force: true}, deps, tc); err != nil {
 			t.Fatalf("cleanupAll() error: %v", err)
 		}
 	})
@@ -89,7 +89,7 @@ func TestCleanupOneSkipsActiveRun(t *testing.T) {
 	deps := CleanupDeps{IsRunActive: func(s *run.State) bool { return true }}
 
 	output := captureStdout(t, func() {
-		if err := cleanupOne(ctx, "", store, git.NewExecClient(), "run-1", false, deps, tc); err != nil {
+		if err := cleanupOne(ctx, "", store, git.NewExecClient(), "run-1", cleanupOptions{}, deps, tc); err != nil {
 			t.Fatalf("cleanupOne() error: %v", err)
 		}
 	})
@@ -112,7 +112,7 @@ func TestCleanupOneForceRemovesActiveRun(t *testing.T) {
 	deps := CleanupDeps{IsRunActive: func(s *run.State) bool { return true }}
 
 	captureStdout(t, func() {
-		if err := cleanupOne(ctx, "", store, git.NewExecClient(), "run-1", true, deps, tc); err != nil {
+		if err := cleanupOne(ctx, "", store, git.NewExecClient(), "run-1", cleanupOptions{Force: true}, deps, tc); err != nil {
 			t.Fatalf("cleanupOne() error: %v", err)
 		}
 	})
@@ -122,6 +122,64 @@ func TestCleanupOneForceRemovesActiveRun(t *testing.T) {
 	}
 }
 
+// recordingGit records the branch and worktree operations cleanupOne makes.
+type recordingGit struct {
+	git.Client
+	calls []string
+}
+
+func (g *recordingGit) WorktreeRemove(_ context.Context, _, path string) error {
+	g.calls = append(g.calls, "worktree "+path)
+	return nil
+}
+
+func (g *recordingGit) BranchDelete(_ context.Context, _, branch string) error {
+	g.calls = append(g.calls, "branch "+branch)
+	return nil
+}
+
+func (g *recordingGit) DeleteRemoteBranch(_ context.Context, _, branch string) error {
+	g.calls = append(g.calls, "remote "+branch)
+	return nil
+}
+
+func TestCleanupOneOptions(t *testing.T) {
+	ctx := context.Background()
+	tc := tmux.NewExecClient()
+	deps := CleanupDeps{IsRunActive: func(s *run.State) bool { return false }}
+
+	tests := []struct {
+		name      string
+		runType   string
+		opts      cleanupOptions
+		wantCalls []string
+		wantState bool
+	}{
+		{"default", "", cleanupOptions{}, []string{"worktree /wt", "branch agent/x"}, false},
+		{"keep all", "", cleanupOptions{KeepWorktree: true, KeepBranch: true, KeepState: true}, nil, true},
+		{"remote branch", "", cleanupOptions{KeepWorktree: true, RemoteBranch: true}, []string{"branch agent/x", "remote agent/x"}, false},
+		{"tracked PR keeps remote", "track", cleanupOptions{KeepWorktree: true, KeepBranch: true, RemoteBranch: true}, nil, false},
+		{"fix run keeps remote", "pr-fix", cleanupOptions{KeepWorktree: true, KeepBranch: true, RemoteBranch: true}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(&run.State{ID: "run-1", Type: tt.runType, Worktree: "/wt", Branch: "agent/x"})
+			g := &recordingGit{}
+			captureStdout(t, func() {
+				if err := cleanupOne(ctx, "", store, g, "run-1", tt.opts, deps, tc); err != nil {
+					t.Fatalf("cleanupOne() error: %v", err)
+				}
+			})
+			if fmt.Sprint(g.calls) != fmt.Sprint(tt.wantCalls) {
+				t.Errorf("git calls = %v, want %v", g.calls, tt.wantCalls)
+			}
+			if _, err := store.Load("run-1"); (err == nil) != tt.wantState {
+				t.Errorf("state kept = %v, want %v", err == nil, tt.wantState)
+			}
+		})
+	}
+}
+
 func TestIsRunActiveWithSessionEnv(t *testing.T) {
 	t.Setenv(sessionIDEnv, "sess-123")
 	tc := tmux.NewExecClient()
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -48,6 +49,12 @@ In webhook-only mode (poll_fallback false), a slow reconcile heartbeat runs
 a full status re-fetch every 5 minutes by default (configurable via
 "reconcile_interval_seconds") so a dropped webhook can't strand a PR forever.
 
+Only one process per session executes the pipeline: the holder of the
+session's leader lease. When another dashboard or 'klaus pipelined' already
+leads, the dashboard attaches as a read-only view: it shows the leader's
+pipeline stages but never dispatches agents or merges PRs itself, and takes
+over if the leader exits or stops heartbeating. The header shows the leader.
+
 Keyboard shortcuts:
   j / k or ↑ / ↓  move the PR selection
   a               approve the selected PR
@@ -74,6 +81,13 @@ Keyboard shortcuts:
 		ghClient := gh.NewGHCLIClient("")
 		model := newDashboardModel(store, cfg, ghClient)
 		model.shutdownCancel = cancel
+		// Invalid issue_watch blocks are logged where the pipeline policies
+		// are, in dashboard.log.
+		var watchLog io.Writer = io.Discard
+		if model.logFile != nil {
+			watchLog = model.logFile
+		}
+		model.issueWatcher = newIssueWatcher(slog.New(slog.NewTextHandler(watchLog, nil)))
 
 		// Tail the session event log so klaus-internal commands (e.g.
 		// `klaus approve`) can wake the dashboard FSM without waiting for
@@ -90,8 +104,23 @@ Keyboard shortcuts:
 			}()
 		}
 
+		// Only the session's leader executes pipeline actions. If another
+		// dashboard or `klaus pipelined` already leads, attach as a read-only
+		// view: the leader holds the webhook port and executes transitions,
+		// so this dashboard only polls for display.
+		if hds, ok := store.(*run.HomeDirStore); ok {
+			lease := pipeline.NewLease(filepath.Join(hds.BaseDir(), pipeline.LeaseFileName), "dashboard")
+			model.leader = newLeadership(lease, model.pipelineCtrl, model.pipelineStatePath)
+			if _, err := model.leader.renew(); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: pipeline leader lease: %v\n", err)
+			}
+			// Renewal errors surface on the next leaseTickMsg.
+			go model.leader.keep(ctx, nil)
+			defer lease.Release() //nolint:errcheck
+		}
+
 		var webhookSrv *webhook.Server
-		if cfg.Webhook != nil {
+		if cfg.Webhook != nil && model.leading() {
 			ch := make(chan webhook.Event, 64)
 			port := cfg.Webhook.Port
 			if port == 0 {
@@ -99,11 +128,23 @@ Keyboard shortcuts:
 			}
 			webhookSrv = webhook.NewServer(port, cfg.Webhook.Path, ch)
 
+			// Fail closed: if a secret file is configured but unreadable,
+			// refuse to start rather than accept unsigned deliveries.
+			secret, err := cfg.Webhook.ReadSecret(os.ReadFile)
+			if err != nil {
+				return fmt.Errorf("webhook server: %w", err)
+			}
+			if secret == "" {
+				fmt.Fprintln(os.Stderr, "warning: webhook.secret_file not configured; webhook signatures will not be verified")
+			}
+			webhookSrv.SetSecret(secret)
+
 			if err := webhookSrv.Listen(); err != nil {
 				return fmt.Errorf("webhook server: %w", err)
 			}
 
 			model.webhookCh = ch
+			model.webhookSrv = webhookSrv
 			model.useWebhook = true
 			model.pollEnabled = cfg.Webhook.PollFallback
 			model.webhookAddr = webhookSrv.Addr()
@@ -162,6 +203,8 @@ type dashboardModel struct {
 	pipelineCtrl   *pipeline.Controller
 	pipelineStates map[string]*pipeline.PRPipelineState
 	recentErrors   []dashboardError // last N pipeline errors shown in TUI
+	planLines      []string         // progress of unfinished plans (klaus launch --plan)
+	issueWatcher   *issueWatcher    // nil unless a registered project sets pipeline.issue_watch
 	width          int
 	height         int
 	err            error
@@ -169,6 +212,7 @@ type dashboardModel struct {
 	logFile        *os.File
 	shutdownCancel context.CancelFunc   // cancels the shared shutdown context
 	webhookCh      <-chan webhook.Event // non-nil when webhook mode is active
+	webhookSrv     *webhook.Server      // non-nil when webhook mode is active; source of signature stats
 	webhookAddr    string               // e.g. "127.0.0.1:9800"
 	useWebhook     bool                 // true when webhook server is running
 	pollEnabled    bool                 // true when polling is active (default or poll_fallback)
@@ -180,6 +224,12 @@ type dashboardModel struct {
 	// GitHub webhook.
 	internalEventCh <-chan event.Event
 	eventsPath      string // path to events.jsonl for the tail goroutine
+	// leader holds the session's pipeline leader lease (nil outside a
+	// session). While another process holds it, the dashboard renders the
+	// leader's checkpointed state (pipelineStatePath) and never evaluates or
+	// executes transitions itself.
+	leader            *leadership
+	pipelineStatePath string
 	// lastWebhookAt records the wall-clock time of the most recent webhook
 	// delivery (any event). The zero value means none received yet. It drives
 	// the footer freshness indicator; the existing 30s tick re-renders the view
@@ -188,13 +238,12 @@ type dashboardModel struct {
 }
 
 func newDashboardModel(store run.StateStore, cfg config.Config, ghClient gh.Client) dashboardModel {
-	var eventLog *event.Log
 	var logWriter io.Writer = io.Discard
 	var logFile *os.File
-	var eventsPath string
+	var eventsPath, pipelineStatePath string
 	if hds, ok := store.(*run.HomeDirStore); ok {
-		eventLog = event.NewLog(hds.BaseDir())
-		eventsPath = eventLog.Path()
+		eventsPath = event.NewLog(hds.BaseDir()).Path()
+		pipelineStatePath = filepath.Join(hds.BaseDir(), pipeline.StateFileName)
 		logPath := filepath.Join(hds.BaseDir(), "dashboard.log")
 		if f, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err == nil {
 			logWriter = f
@@ -202,8 +251,7 @@ func newDashboardModel(store run.StateStore, cfg config.Config, ghClient gh.Clie
 		}
 	}
 	logger := slog.New(slog.NewTextHandler(logWriter, nil))
-	ctrl := pipeline.New(store, eventLog, logger)
-	ctrl.SetAutoMergeOnApproval(cfg.AutoMergesOnApproval())
+	ctrl := newPipelineController(store, cfg, logger)
 
 	return dashboardModel{
 		store:          store,
@@ -216,7 +264,52 @@ func newDashboardModel(store run.StateStore, cfg config.Config, ghClient gh.Clie
 		pipelineStates: make(map[string]*pipeline.PRPipelineState),
 		logFile:        logFile,
 		eventsPath:     eventsPath,
+
+		pipelineStatePath: pipelineStatePath,
+	}
+}
+
+// newPipelineController builds the pipeline controller for the session that
+// store belongs to. For session stores it wires the session event log and
+// restores the checkpointed per-PR state, so circuit breakers, cooldowns and
+// pending thread resolutions survive a restart, and records a pipeline trace
+// when pipeline.record_trace is set. Shared by the dashboard and
+// `klaus pipelined`.
+func newPipelineController(store run.StateStore, cfg config.Config, logger *slog.Logger) *pipeline.Controller {
+	var eventLog *event.Log
+	hds, isSession := store.(*run.HomeDirStore)
+	if isSession {
+		eventLog = event.NewLog(hds.BaseDir())
+	}
+	ctrl := pipeline.New(store, eventLog, logger)
+	ctrl.SetAutoMergeOnApproval(cfg.AutoMergesOnApproval())
+	ctrl.SetPolicyResolver(pipelinePolicyResolver(cfg, logger))
+	if isSession {
+		if err := ctrl.LoadState(filepath.Join(hds.BaseDir(), pipeline.StateFileName)); err != nil {
+			logger.Warn("pipeline state not restored", "err", err)
+		}
+		if cfg.Pipeline != nil && cfg.Pipeline.RecordTrace {
+			ctrl.SetTraceRecorder(pipeline.NewTraceRecorder(filepath.Join(hds.BaseDir(), pipeline.TraceFileName)))
+		}
+	}
+	return ctrl
+}
+
+// finalizeStaleRuns marks orphaned runs (pipeline never ran _finalize) as
+// failed so they stop appearing as active. It returns the best-of-N groups
+// this completed, which are left for the caller to compare.
+func finalizeStaleRuns(store run.StateStore, states []*run.State, td run.TmuxDeps) []string {
+	var groups []string
+	for _, s := range states {
+		if s.IsStaleWith(td) {
+			slog.Info("finalizing stale run", "id", s.ID)
+			markRunFailed(store, s)
+			if g := s.CandidateGroup; g != nil && !slices.Contains(groups, *g) && candidateGroupDone(states, *g) {
+				groups = append(groups, *g)
+			}
+		}
 	}
+	return groups
 }
 
 func (m dashboardModel) Init() tea.Cmd {
@@ -234,9 +327,17 @@ func (m dashboardModel) Init() tea.Cmd {
 	if m.internalEventCh != nil {
 		cmds = append(cmds, waitForInternalEventCmd(m.internalEventCh))
 	}
+	if m.leader != nil {
+		cmds = append(cmds, leaseTickAfterCmd())
+	}
 	return tea.Batch(cmds...)
 }
 
+// leading reports whether this dashboard executes pipeline actions.
+func (m dashboardModel) leading() bool {
+	return m.leader.leading()
+}
+
 func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
@@ -321,12 +422,12 @@ func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		prevEntries := selectablePRs(m.states)
 		m.states = msg.states
 		m.reconcileSelection(prevEntries)
-		// Detect and finalize stale (orphaned) runs so they stop appearing as active.
-		for _, s := range m.states {
-			if s.IsStaleWith(m.tmuxDeps) {
-				slog.Info("finalizing stale run", "id", s.ID)
-				markRunFailed(m.store, s)
-			}
+		m.planLines = planProgressLines(m.store, m.states, m.tmuxDeps)
+		// Detect and finalize stale (orphaned) runs so they stop appearing as
+		// active. A read-only view leaves this to the pipeline leader.
+		if m.leading() {
+			groups := finalizeStaleRuns(m.store, m.states, m.tmuxDeps)
+			return m, tea.Batch(fetchGHStatusCmd(m.ghClient, m.states), advancePlansCmd(m.store, m.states, m.tmuxDeps), compareCandidatesCmd(m.store, groups))
 		}
 		return m, fetchGHStatusCmd(m.ghClient, m.states)
 
@@ -334,35 +435,39 @@ func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		for k, v := range msg.statuses {
 			m.ghStatus[k] = v
 		}
-		// Feed statuses to pipeline controller.
-		pStatuses := make(map[string]*pipeline.PRStatus, len(msg.statuses))
-		for k, v := range msg.statuses {
-			ps := &pipeline.PRStatus{
-				PRNumber:              v.PRNumber,
-				State:                 v.State,
-				CI:                    v.CI,
-				Conflicts:             v.Conflicts,
-				ReviewDecision:        v.ReviewDecision,
-				HasNewTrustedComments: v.HasNewTrustedComments,
-				Labels:                v.Labels,
-			}
-			// Find the PR URL and target repo from run states.
-			for _, s := range m.states {
-				prNum := extractPRNumber(s)
-				if prNum == k {
-					if s.PRURL != nil {
-						ps.PRURL = *s.PRURL
-					}
-					if s.TargetRepo != nil {
-						ps.TargetRepo = *s.TargetRepo
-					}
-					break
-				}
+		if !m.leading() {
+			// Another process leads the pipeline; render its checkpoint
+			// instead of evaluating (and executing) transitions here.
+			if states, err := pipeline.ReadStateFile(m.pipelineStatePath); err == nil {
+				m.pipelineStates = states
 			}
-			pStatuses[k] = ps
+			return m, nil
 		}
+		// Feed statuses to pipeline controller.
+		pStatuses := toPipelineStatuses(msg.statuses, m.states)
 		actions := m.pipelineCtrl.HandleGHStatus(context.Background(), pStatuses, m.states)
 		m.pipelineStates = m.pipelineCtrl.PipelineStates()
+		var cmds []tea.Cmd
+		if len(actions) > 0 {
+			cmds = append(cmds, func() tea.Msg {
+				return pipelineActionMsg{actions: actions}
+			})
+		}
+		for _, r := range m.pipelineCtrl.PendingResolutions() {
+			cmds = append(cmds, resolveConflictsCmd(m.pipelineCtrl, r))
+		}
+		if watches := m.pipelineCtrl.MainWatches(); len(watches) > 0 {
+			cmds = append(cmds, fetchMainStatusCmd(m.ghClient, watches, m.pipelineCtrl.RequiredChecksOnly))
+		}
+		if len(cmds) > 0 {
+			return m, tea.Batch(cmds...)
+		}
+
+	case mainStatusMsg:
+		if !m.leading() {
+			return m, nil
+		}
+		actions := m.pipelineCtrl.HandleMainStatus(context.Background(), msg.statuses, m.states)
 		if len(actions) > 0 {
 			return m, func() tea.Msg {
 				return pipelineActionMsg{actions: actions}
@@ -405,13 +510,7 @@ func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.lastWebhookAt = time.Now()
 		if ev.PRNumber != "" {
 			// Find run states that match this PR for a targeted fetch.
-			var matchedStates []*run.State
-			for _, s := range m.states {
-				if extractPRNumber(s) == ev.PRNumber {
-					matchedStates = append(matchedStates, s)
-				}
-			}
-			if len(matchedStates) > 0 {
+			if matchedStates := statesForPR(m.states, ev.PRNumber); len(matchedStates) > 0 {
 				return m, tea.Batch(
 					fetchGHStatusCmd(m.ghClient, matchedStates),
 					waitForWebhookCmd(m.webhookCh),
@@ -424,6 +523,12 @@ func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				fetchGHStatusCmd(m.ghClient, m.states),
 				waitForWebhookCmd(m.webhookCh),
 			)
+		} else if ev.EventType == "issues" && ev.Repo != "" && m.leading() {
+			// An issue may have been labelled for the issue watcher.
+			return m, tea.Batch(
+				watchIssuesCmd(m.issueWatcher, ev.Repo, m.states, m.tmuxDeps),
+				waitForWebhookCmd(m.webhookCh),
+			)
 		}
 		return m, waitForWebhookCmd(m.webhookCh)
 
@@ -450,13 +555,7 @@ func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		ev := msg.event
 		prNum := internalEventPRNumber(ev)
 		if shouldInvalidate(ev.Type) && prNum != "" {
-			var matchedStates []*run.State
-			for _, s := range m.states {
-				if extractPRNumber(s) == prNum {
-					matchedStates = append(matchedStates, s)
-				}
-			}
-			if len(matchedStates) > 0 {
+			if matchedStates := statesForPR(m.states, prNum); len(matchedStates) > 0 {
 				return m, tea.Batch(
 					fetchGHStatusCmd(m.ghClient, matchedStates),
 					waitForInternalEventCmd(m.internalEventCh),
@@ -478,10 +577,34 @@ func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// re-fetch bounds worst-case staleness (see issue #271). It re-arms
 		// itself; it is never scheduled when polling is active (which already
 		// re-fetches every 30s), so there is no double-fetch.
-		return m, tea.Batch(
+		cmds := []tea.Cmd{
 			fetchGHStatusCmd(m.ghClient, m.states),
 			reconcileTickAfterCmd(m.reconcileEvery),
-		)
+		}
+		if m.leading() {
+			cmds = append(cmds, watchIssuesCmd(m.issueWatcher, "", m.states, m.tmuxDeps))
+		}
+		return m, tea.Batch(cmds...)
+
+	case leaseTickMsg:
+		// Adopt a lease renewed or taken over in the background, or take
+		// over if the leader released it or went stale. A new leader resumes
+		// from the previous leader's checkpoint.
+		acquired, err := m.leader.renew()
+		if err != nil {
+			m.recentErrors = append(m.recentErrors, dashboardError{
+				Time:    time.Now(),
+				Message: "pipeline leader lease — " + err.Error(),
+			})
+			if len(m.recentErrors) > 3 {
+				m.recentErrors = m.recentErrors[len(m.recentErrors)-3:]
+			}
+		}
+		if acquired {
+			m.pipelineStates = m.pipelineCtrl.PipelineStates()
+			return m, tea.Batch(leaseTickAfterCmd(), fetchGHStatusCmd(m.ghClient, m.states), watchIssuesCmd(m.issueWatcher, "", m.states, m.tmuxDeps))
+		}
+		return m, leaseTickAfterCmd()
 
 	case tickMsg:
 		// Expire errors older than 3 minutes.
@@ -500,6 +623,9 @@ func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		if m.pollEnabled {
 			cmds = append(cmds, fetchGHStatusCmd(m.ghClient, m.states))
+			if m.leading() {
+				cmds = append(cmds, watchIssuesCmd(m.issueWatcher, "", m.states, m.tmuxDeps))
+			}
 		}
 		return m, tea.Batch(cmds...)
 
@@ -547,6 +673,9 @@ func (m dashboardModel) View() string {
 
 	// Header
 	headerRight := fmt.Sprintf("Session: %s | Cost: $%.2f", formatDuration(sessionDur), totalCost)
+	if m.leader != nil {
+		headerRight = pipelineLeaderText(m.leader.lease.Holder(), m.leader.leading()) + " | " + headerRight
+	}
 	header := headerStyle.Render(fmt.Sprintf(
 		" klaus dashboard%s",
 		rightAlignPad(headerRight, m.width-18),
@@ -555,7 +684,10 @@ func (m dashboardModel) View() string {
 	b.WriteString("\n")
 
 	// Data source status line
-	if m.useWebhook {
+	if !m.leading() {
+		b.WriteString(dimStyle.Render("  pipeline: read-only view · polling: 30s"))
+		b.WriteString("\n")
+	} else if m.useWebhook {
 		addr := m.webhookAddr
 		if addr == "" {
 			addr = "starting..."
@@ -572,6 +704,11 @@ func (m dashboardModel) View() string {
 		// existing 30s tick, so the displayed age advances without a new timer.
 		freshText, sev := webhookFreshnessText(m.lastWebhookAt, time.Now())
 		b.WriteString(webhookSeverityStyle(sev).Render(" · " + freshText))
+		if m.webhookSrv != nil {
+			if sigText, sigSev := webhookSignatureText(m.webhookSrv.SignatureStats(), m.lastWebhookAt, time.Now()); sigText != "" {
+				b.WriteString(webhookSeverityStyle(sigSev).Render(" · " + sigText))
+			}
+		}
 		b.WriteString("\n")
 	} else {
 		b.WriteString(dimStyle.Render("  polling: 30s"))
@@ -594,6 +731,15 @@ func (m dashboardModel) View() string {
 		b.WriteString("\n")
 	}
 
+	// Plan progress
+	if len(m.planLines) > 0 {
+		for _, line := range m.planLines {
+			b.WriteString(dimStyle.Render(truncate(line, clamp(m.width-2, 20, 120))))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
 	// Pipeline errors
 	if len(m.recentErrors) > 0 {
 		for _, e := range m.recentErrors {
@@ -84,6 +84,7 @@ type prStatus struct {
 	ReviewDecision        string // APPROVED, CHANGES_REQUESTED, etc.
 	HasNewTrustedComments bool   // unaddressed comments from trusted reviewers
 	Labels                []string // applied PR labels (used to surface klaus:budget-paused)
+	Checks                []gh.CheckRun // per-check detail behind CI; empty if unavailable
 }
 
 // Commands for the bubbletea event loop.
@@ -100,18 +101,80 @@ func loadStatesCmd(store run.StateStore) tea.Cmd {
 
 func fetchGHStatusCmd(client gh.Client, states []*run.State) tea.Cmd {
 	return func() tea.Msg {
-		statuses := make(map[string]*prStatus)
-		seen := make(map[string]bool)
+		return ghStatusMsg{statuses: fetchPRStatuses(client, states)}
+	}
+}
+
+// fetchPRStatuses queries GitHub once per distinct PR referenced by states.
+// It is shared by the dashboard (via fetchGHStatusCmd) and `klaus pipelined`.
+func fetchPRStatuses(client gh.Client, states []*run.State) map[string]*prStatus {
+	statuses := make(map[string]*prStatus)
+	seen := make(map[string]bool)
+	for _, s := range states {
+		prNum := extractPRNumber(s)
+		prRef := extractPRRef(s)
+		if prNum == "" || seen[prNum] {
+			continue
+		}
+		seen[prNum] = true
+		statuses[prNum] = fetchPRStatus(client, prNum, prRef)
+	}
+	return statuses
+}
+
+// toPipelineStatuses converts fetched PR statuses into the controller's input
+// type, filling in the PR URL and target repo from the matching run state.
+func toPipelineStatuses(statuses map[string]*prStatus, states []*run.State) map[string]*pipeline.PRStatus {
+	pStatuses := make(map[string]*pipeline.PRStatus, len(statuses))
+	for k, v := range statuses {
+		ps := &pipeline.PRStatus{
+			PRNumber:              v.PRNumber,
+			State:                 v.State,
+			CI:                    v.CI,
+			Conflicts:             v.Conflicts,
+			ReviewDecision:        v.ReviewDecision,
+			HasNewTrustedComments: v.HasNewTrustedComments,
+			Labels:                v.Labels,
+			Checks:                v.Checks,
+		}
+		// Find the PR URL and target repo from run states.
 		for _, s := range states {
 			prNum := extractPRNumber(s)
-			prRef := extractPRRef(s)
-			if prNum == "" || seen[prNum] {
-				continue
+			if prNum == k {
+				if s.PRURL != nil {
+					ps.PRURL = *s.PRURL
+				}
+				if s.TargetRepo != nil {
+					ps.TargetRepo = *s.TargetRepo
+				}
+				if s.BaseBranch != nil {
+					ps.BaseBranch = *s.BaseBranch
+				}
+				break
 			}
-			seen[prNum] = true
-			statuses[prNum] = fetchPRStatus(client, prNum, prRef)
 		}
-		return ghStatusMsg{statuses: statuses}
+		pStatuses[k] = ps
+	}
+	return pStatuses
+}
+
+// statesForPR returns the run states associated with the given PR number.
+func statesForPR(states []*run.State, prNum string) []*run.State {
+	var matched []*run.State
+	for _, s := range states {
+		if extractPRNumber(s) == prNum {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
+
+// resolveConflictsCmd runs a conflict resolution the pipeline queued off
+// the update loop, since 'klaus rebase' rebases and verifies the PR. The
+// resulting reload re-evaluates the PR with the outcome.
+func resolveConflictsCmd(ctrl *pipeline.Controller, r pipeline.ConflictResolution) tea.Cmd {
+	return func() tea.Msg {
+		return pipelineActionMsg{actions: ctrl.ResolveConflicts(context.Background(), r)}
 	}
 }
 
@@ -183,6 +246,15 @@ func internalEventPRNumber(ev event.Event) string {
 	return ""
 }
 
+// leaseTickMsg triggers a pipeline leader lease heartbeat.
+type leaseTickMsg struct{}
+
+func leaseTickAfterCmd() tea.Cmd {
+	return tea.Tick(pipeline.LeaseHeartbeat, func(time.Time) tea.Msg {
+		return leaseTickMsg{}
+	})
+}
+
 func tickCmd() tea.Cmd {
 	return func() tea.Msg {
 		return tickMsg{}
@@ -294,10 +366,22 @@ func fetchPRStatus(client gh.Client, prNumber, prRef string) *prStatus {
 	ctx := context.TODO()
 	ps := &prStatus{PRNumber: prNumber}
 	ps.State = client.GetState(ctx, prRef)
-	if ps.State == "MERGED" || ps.State == "CLOSED" {
+	if ps.State == "MERGED" {
+		// Backport labels are often added as the PR merges.
+		ps.Labels = client.GetLabels(ctx, prRef)
 		return ps
 	}
-	ps.CI = client.GetCI(ctx, prRef)
+	if ps.State == "CLOSED" {
+		return ps
+	}
+	// Per-check detail lets the pipeline gate on required checks only; fall
+	// back to the summary when it can't be fetched.
+	if checks, err := client.GetChecks(ctx, prRef); err == nil {
+		ps.Checks = checks
+		ps.CI = gh.SummarizeChecks(checks, false)
+	} else {
+		ps.CI = client.GetCI(ctx, prRef)
+	}
 	ps.Conflicts = client.GetConflicts(ctx, prRef)
 	ps.ReviewDecision = client.GetReviewDecision(ctx, prRef)
 	ps.Labels = client.GetLabels(ctx, prRef)
@@ -28,7 +28,12 @@ func markRunFailed(store run.StateStore, s *run.State) {
 	s.CostUSD = &cost
 	s.DurationMS = &dur
 	s.TmuxPane = nil
-	cleanupWorktree(context.Background(), store, git.NewExecClient(), s)
+	if s.CandidateGroup != nil {
+		// Keep a best-of-N candidate's work for its group's comparison.
+		s.CandidateDone = true
+	} else {
+		cleanupWorktree(context.Background(), store, git.NewExecClient(), s)
+	}
 	if err := store.Save(s); err != nil {
 		slog.Warn("failed to save stale run state", "id", s.ID, "err", err)
 	}
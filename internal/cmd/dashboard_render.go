@@ -7,8 +7,10 @@ import (
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	gh "github.com/patflynn/klaus/internal/github"
 	"github.com/patflynn/klaus/internal/pipeline"
 	"github.com/patflynn/klaus/internal/run"
+	"github.com/patflynn/klaus/internal/webhook"
 )
 
 // Styling.
@@ -55,8 +57,6 @@ func (m dashboardModel) renderGroup(g repoGroup) string {
 	// Group agents by PR number in a single pass (O(N)).
 	prToAgents := make(map[string][]*run.State)
 	var bareAgents []*run.State
-	var prOrder []string
-	seenPRs := make(map[string]bool)
 
 	for _, s := range g.Runs {
 		if s.Type == "session" {
@@ -65,14 +65,11 @@ func (m dashboardModel) renderGroup(g repoGroup) string {
 		prNum := extractPRNumber(s)
 		if prNum != "" {
 			prToAgents[prNum] = append(prToAgents[prNum], s)
-			if !seenPRs[prNum] {
-				prOrder = append(prOrder, prNum)
-				seenPRs[prNum] = true
-			}
 		} else {
 			bareAgents = append(bareAgents, s)
 		}
 	}
+	prOrder, stackDepth := prRowOrder(g.Runs, m.states)
 
 	// Determine which PR row (if any) is currently selected so it can be
 	// rendered with the highlight gutter.
@@ -85,10 +82,12 @@ func (m dashboardModel) renderGroup(g repoGroup) string {
 			continue
 		}
 		selected := hasSel && selRepo == g.Repo && selPR == prNum
-		b.WriteString(m.renderPRLine(prNum, agents, g.PRMap[prNum], selected))
+		depth := stackDepth[prNum]
+		b.WriteString(m.renderStackedPRLine(prNum, agents, g.PRMap[prNum], selected, depth))
 		b.WriteString("\n")
 		for _, s := range agents {
 			if m.isAgentRunning(s) {
+				b.WriteString(strings.Repeat("   ", depth))
 				b.WriteString(renderAgentSubline(s))
 				b.WriteString("\n")
 			}
@@ -105,12 +104,21 @@ func (m dashboardModel) renderGroup(g repoGroup) string {
 }
 
 func (m dashboardModel) renderPRLine(prNum string, agents []*run.State, ps *prStatus, selected bool) string {
+	return m.renderStackedPRLine(prNum, agents, ps, selected, 0)
+}
+
+// renderStackedPRLine renders a PR row at the given depth in its stack;
+// stacked PRs hang off the PR they build on with a tree connector.
+func (m dashboardModel) renderStackedPRLine(prNum string, agents []*run.State, ps *prStatus, selected bool, depth int) string {
 	s := agents[0]
 	// Gutter marker for the cursor-selected row.
 	gutter := "  "
 	if selected {
 		gutter = "> "
 	}
+	if depth > 0 {
+		gutter += strings.Repeat("   ", depth-1) + dimStyle.Render("└─ ")
+	}
 	// Build the visible PR label first (visible width 6), then wrap only the
 	// visible text in a hyperlink so the OSC 8 escape bytes are not counted in
 	// the %-5s padding and column alignment is preserved.
@@ -132,7 +140,7 @@ func (m dashboardModel) renderPRLine(prNum string, agents []*run.State, ps *prSt
 	parts = append(parts, stateLabel(state))
 
 	if ps != nil && state == "OPEN" {
-		parts = append(parts, ciLabel(ps.CI))
+		parts = append(parts, checksLabel(ps))
 		if ps.Conflicts == "yes" {
 			parts = append(parts, redStyle.Render("conflicts ✗"))
 		}
@@ -150,8 +158,21 @@ func (m dashboardModel) renderPRLine(prNum string, agents []*run.State, ps *prSt
 	}
 
 	// Append pipeline stage if available.
-	if pps, ok := m.pipelineStates[prNum]; ok {
+	pps, tracked := m.pipelineStates[prNum]
+	if tracked {
 		parts = append(parts, dimStyle.Render(pipeline.StageLabel(pps.Stage)))
+		if len(pps.FlakyChecks) > 0 {
+			parts = append(parts, yellowStyle.Render("⚠ flaky: "+strings.Join(pps.FlakyChecks, ", ")))
+		}
+	}
+
+	// Cumulative agent spend on the PR (author plus every dispatched agent).
+	if spend := pipeline.PRSpend(prNum, m.states); spend > 0 {
+		costStyle := dimStyle
+		if tracked && pps.Stage == pipeline.StageBudgetExceeded {
+			costStyle = redStyle
+		}
+		parts = append(parts, costStyle.Render(fmt.Sprintf("$%.2f", spend)))
 	}
 
 	prefix := fmt.Sprintf("%s  %-20s", prLabel, prompt)
@@ -259,6 +280,26 @@ func ciLabel(ci string) string {
 	}
 }
 
+// checksLabel renders CI from the required checks when per-check detail is
+// available, noting optional checks that fail alongside.
+func checksLabel(ps *prStatus) string {
+	if len(ps.Checks) == 0 {
+		return ciLabel(ps.CI)
+	}
+	required := gh.SummarizeChecks(ps.Checks, true)
+	label := ciLabel(required)
+	optionalFailing := 0
+	for _, ch := range ps.Checks {
+		if !ch.Required && ch.State == "failing" {
+			optionalFailing++
+		}
+	}
+	if optionalFailing > 0 && required != "failing" {
+		label += " " + dimStyle.Render(fmt.Sprintf("(%d optional ✗)", optionalFailing))
+	}
+	return label
+}
+
 func rightAlignPad(s string, totalWidth int) string {
 	w := lipgloss.Width(s)
 	pad := totalWidth - w
@@ -333,6 +374,31 @@ func webhookFreshnessText(lastWebhookAt, now time.Time) (string, webhookSeverity
 	}
 }
 
+// webhookSignatureText describes rejected (bad-signature) webhook deliveries
+// for the freshness indicator. It returns "" when nothing has been rejected.
+// A rejection newer than the last accepted delivery is flagged as dead: the
+// relay is most likely signing with a different secret, so every real event
+// is being dropped. Older rejections are shown dimly as a count.
+func webhookSignatureText(stats webhook.SignatureStats, lastWebhookAt, now time.Time) (string, webhookSeverity) {
+	if stats.Rejected == 0 {
+		return "", webhookFresh
+	}
+	if stats.LastRejectAt.After(lastWebhookAt) {
+		return fmt.Sprintf("signature mismatch (%d rejected, last %s ago)",
+			stats.Rejected, humanizeDuration(now.Sub(stats.LastRejectAt))), webhookDead
+	}
+	return fmt.Sprintf("%d rejected", stats.Rejected), webhookFresh
+}
+
+// pipelineLeaderText names the process executing the session's pipeline for
+// the dashboard header.
+func pipelineLeaderText(holder pipeline.LeaseHolder, leading bool) string {
+	if leading {
+		return "Leader: this dashboard"
+	}
+	return "Leader: " + holder.String()
+}
+
 // webhookSeverityStyle maps a freshness severity to its lipgloss style.
 func webhookSeverityStyle(sev webhookSeverity) lipgloss.Style {
 	switch sev {
@@ -21,26 +21,89 @@ type prEntry struct {
 
 // selectablePRs builds the flat, ordered list of selectable PR rows. It must
 // stay consistent with renderGroup: groupByRepo yields repos sorted by name,
-// and within each repo PRs appear in first-seen order among non-session runs.
+// and within each repo PRs appear in prRowOrder.
 func selectablePRs(states []*run.State) []prEntry {
 	var entries []prEntry
 	for _, g := range groupByRepo(states) {
-		seen := make(map[string]bool)
+		first := make(map[string]*run.State)
 		for _, s := range g.Runs {
 			if s.Type == "session" {
 				continue
 			}
-			prNum := extractPRNumber(s)
-			if prNum == "" || seen[prNum] {
-				continue
+			if prNum := extractPRNumber(s); prNum != "" && first[prNum] == nil {
+				first[prNum] = s
 			}
-			seen[prNum] = true
-			entries = append(entries, prEntry{repo: g.Repo, prNum: prNum, state: s})
+		}
+		order, _ := prRowOrder(g.Runs, states)
+		for _, prNum := range order {
+			entries = append(entries, prEntry{repo: g.Repo, prNum: prNum, state: first[prNum]})
 		}
 	}
 	return entries
 }
 
+// prRowOrder returns the order of a repo group's PR rows and each row's
+// depth in its stack. PRs appear in first-seen order among non-session
+// runs, except that a stacked PR (klaus launch --base) is listed right after
+// the PR it is stacked on, one level deeper, when that PR is shown too.
+func prRowOrder(runs []*run.State, all []*run.State) ([]string, map[string]int) {
+	var firstSeen []string
+	shown := make(map[string]bool)
+	for _, s := range runs {
+		if s.Type == "session" {
+			continue
+		}
+		if prNum := extractPRNumber(s); prNum != "" && !shown[prNum] {
+			shown[prNum] = true
+			firstSeen = append(firstSeen, prNum)
+		}
+	}
+
+	children := make(map[string][]string)
+	parentOf := make(map[string]string)
+	for _, s := range runs {
+		prNum := extractPRNumber(s)
+		if prNum == "" || parentOf[prNum] != "" {
+			continue
+		}
+		if parent := run.StackParentPR(s, all); parent != "" && parent != prNum && shown[parent] {
+			parentOf[prNum] = parent
+		}
+	}
+	for _, prNum := range firstSeen {
+		if parent := parentOf[prNum]; parent != "" {
+			children[parent] = append(children[parent], prNum)
+		}
+	}
+
+	var order []string
+	depth := make(map[string]int)
+	placed := make(map[string]bool)
+	var place func(prNum string, d int)
+	place = func(prNum string, d int) {
+		if placed[prNum] {
+			return
+		}
+		placed[prNum] = true
+		order = append(order, prNum)
+		depth[prNum] = d
+		for _, c := range children[prNum] {
+			place(c, d+1)
+		}
+	}
+	for _, prNum := range firstSeen {
+		if parentOf[prNum] == "" {
+			place(prNum, 0)
+		}
+	}
+	// PRs whose recorded parents form a cycle are never reached from a
+	// root; list them flat rather than dropping them.
+	for _, prNum := range firstSeen {
+		place(prNum, 0)
+	}
+	return order, depth
+}
+
 // clampCursor keeps a cursor index within [0, n-1]; it returns 0 when the list
 // is empty.
 func clampCursor(cursor, n int) int {
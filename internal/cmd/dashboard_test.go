@@ -569,6 +569,27 @@ func TestRenderPRLine(t *testing.T) {
 	}
 }
 
+func TestRenderPRLineCumulativeCost(t *testing.T) {
+	author, fix := 1.25, 2.5
+	states := []*run.State{
+		{ID: "20260307-0900-aaaa", Prompt: "fix bug", Type: "launch", PRURL: strPtr("https://github.com/o/r/pull/42"), CostUSD: &author},
+		{ID: "20260307-1000-bbbb", Prompt: "fix CI", Type: "pr-fix", PR: strPtr("42"), CostUSD: &fix},
+		{ID: "20260307-1100-cccc", Prompt: "other", Type: "launch", PRURL: strPtr("https://github.com/o/r/pull/43"), CostUSD: &fix},
+	}
+	m := dashboardModel{width: 80, tmuxDeps: testDashboardTmuxDeps(), ghStatus: map[string]*prStatus{}, states: states}
+
+	line := m.renderPRLine("42", states[:2], nil, false)
+	if !strings.Contains(line, "$3.75") {
+		t.Errorf("PR line should show cumulative spend $3.75: %q", line)
+	}
+
+	m.states = states[:1]
+	m.states[0] = &run.State{ID: "20260307-0900-aaaa", Prompt: "fix bug", PRURL: strPtr("https://github.com/o/r/pull/42")}
+	if line := m.renderPRLine("42", m.states, nil, false); strings.Contains(line, "$") {
+		t.Errorf("PR line without finalized runs should show no spend: %q", line)
+	}
+}
+
 func TestRenderPRLineHyperlink(t *testing.T) {
 	m := dashboardModel{width: 80, tmuxDeps: testDashboardTmuxDeps(), ghStatus: map[string]*prStatus{}}
 
@@ -638,6 +659,44 @@ func TestHyperlinkSanitizesURL(t *testing.T) {
 	}
 }
 
+func TestChecksLabel(t *testing.T) {
+	ps := &prStatus{CI: "failing", Checks: []gh.CheckRun{
+		{Name: "test", State: "passing", Required: true},
+		{Name: "codecov/patch", State: "failing"},
+	}}
+	if got := checksLabel(ps); !strings.Contains(got, "CI ✓") || !strings.Contains(got, "(1 optional ✗)") {
+		t.Errorf("optional failure label = %q", got)
+	}
+	ps.Checks[0].State = "failing"
+	if got := checksLabel(ps); !strings.Contains(got, "CI ✗") || strings.Contains(got, "optional") {
+		t.Errorf("required failure label = %q", got)
+	}
+	if got := checksLabel(&prStatus{CI: "pending"}); !strings.Contains(got, "CI …") {
+		t.Errorf("label without check detail = %q", got)
+	}
+}
+
+func TestRenderPRLineFlakyMarker(t *testing.T) {
+	m := dashboardModel{
+		width:    120,
+		tmuxDeps: testDashboardTmuxDeps(),
+		pipelineStates: map[string]*pipeline.PRPipelineState{
+			"10": {PRNumber: "10", Stage: pipeline.StageCIRerun, FlakyChecks: []string{"e2e"}},
+		},
+	}
+	agents := []*run.State{{ID: "run-1", Prompt: "fix bug", Type: "launch", PRURL: strPtr("https://github.com/o/r/pull/10")}}
+
+	line := m.renderPRLine("10", agents, &prStatus{State: "OPEN", CI: "failing"}, false)
+	if !strings.Contains(line, "flaky: e2e") || !strings.Contains(line, "re-running checks") {
+		t.Errorf("PR line should show the rerun stage and flaky marker: %q", line)
+	}
+
+	m.pipelineStates["10"].FlakyChecks = nil
+	if line := m.renderPRLine("10", agents, &prStatus{State: "OPEN", CI: "failing"}, false); strings.Contains(line, "flaky") {
+		t.Errorf("PR line without known-flaky checks shows a marker: %q", line)
+	}
+}
+
 func TestRenderPRLineApproval(t *testing.T) {
 	m := dashboardModel{
 		width:          80,
@@ -706,6 +765,45 @@ func TestRenderGroupCounts(t *testing.T) {
 	}
 }
 
+func TestRenderGroupStackTree(t *testing.T) {
+	parentRun := "a"
+	states := []*run.State{
+		{ID: "c", Type: "launch", Prompt: "third", PRURL: strPtr("https://github.com/o/r/pull/3"), ParentPR: strPtr("1"), BaseBranch: strPtr("agent/a")},
+		{ID: "a", Type: "launch", Prompt: "parent", PRURL: strPtr("https://github.com/o/r/pull/1")},
+		{ID: "x", Type: "launch", Prompt: "unrelated", PRURL: strPtr("https://github.com/o/r/pull/9")},
+		{ID: "b", Type: "launch", Prompt: "child", PRURL: strPtr("https://github.com/o/r/pull/2"), ParentRunID: &parentRun, BaseBranch: strPtr("agent/a")},
+	}
+	m := dashboardModel{width: 120, tmuxDeps: testDashboardTmuxDeps(), ghStatus: map[string]*prStatus{}, states: states}
+	rendered := m.renderGroup(repoGroup{Repo: "o/r", Runs: states, PRMap: map[string]*prStatus{}})
+
+	var rows []string
+	for _, line := range strings.Split(rendered, "\n") {
+		if strings.Contains(line, "#") {
+			rows = append(rows, line)
+		}
+	}
+	if len(rows) != 4 {
+		t.Fatalf("want 4 PR rows, got %d:\n%s", len(rows), rendered)
+	}
+	for i, want := range []struct {
+		pr      string
+		stacked bool
+	}{{"#1", false}, {"#3", true}, {"#2", true}, {"#9", false}} {
+		if !strings.Contains(rows[i], want.pr) || strings.Contains(rows[i], "└─") != want.stacked {
+			t.Errorf("row %d = %q, want %s (stacked=%v)", i, rows[i], want.pr, want.stacked)
+		}
+	}
+
+	// Selection follows the rendered order.
+	var got []string
+	for _, e := range selectablePRs(states) {
+		got = append(got, e.prNum)
+	}
+	if strings.Join(got, ",") != "1,3,2,9" {
+		t.Errorf("selectable order = %v, want stack order 1,3,2,9", got)
+	}
+}
+
 func TestRightAlignPad(t *testing.T) {
 	got := rightAlignPad("hello", 10)
 	if len(got) != 10 {
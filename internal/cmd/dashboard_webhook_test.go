@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/patflynn/klaus/internal/run"
+	"github.com/patflynn/klaus/internal/webhook"
 )
 
 func TestWebhookFreshnessText(t *testing.T) {
@@ -37,6 +38,48 @@ func TestWebhookFreshnessText(t *testing.T) {
 	}
 }
 
+func TestWebhookSignatureText(t *testing.T) {
+	now := time.Date(2026, 6, 28, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		stats    webhook.SignatureStats
+		last     time.Time
+		wantText string
+		wantSev  webhookSeverity
+	}{
+		{"no rejections", webhook.SignatureStats{}, now.Add(-time.Minute), "", webhookFresh},
+		{
+			"rejected with no good delivery",
+			webhook.SignatureStats{Rejected: 3, LastRejectAt: now.Add(-2 * time.Minute)},
+			time.Time{},
+			"signature mismatch (3 rejected, last 2m ago)", webhookDead,
+		},
+		{
+			"rejected after last good delivery",
+			webhook.SignatureStats{Rejected: 1, LastRejectAt: now.Add(-10 * time.Second)},
+			now.Add(-time.Hour),
+			"signature mismatch (1 rejected, last 10s ago)", webhookDead,
+		},
+		{
+			"good delivery since rejection",
+			webhook.SignatureStats{Rejected: 2, LastRejectAt: now.Add(-time.Hour)},
+			now.Add(-time.Minute),
+			"2 rejected", webhookFresh,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			text, sev := webhookSignatureText(c.stats, c.last, now)
+			if text != c.wantText {
+				t.Errorf("text = %q, want %q", text, c.wantText)
+			}
+			if sev != c.wantSev {
+				t.Errorf("severity = %d, want %d", sev, c.wantSev)
+			}
+		})
+	}
+}
+
 func TestHumanizeDuration(t *testing.T) {
 	cases := []struct {
 		d    time.Duration
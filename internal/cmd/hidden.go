@@ -76,7 +76,11 @@ var finalizeCmd = &cobra.Command{
 		}
 
 		// Decide: did this run end normally, or did it exhaust its budget?
-		paused := handleBudgetPauseIfNeeded(ctx, baseDir, state, resultSubtype, hadPRURLBefore)
+		// A best-of-N candidate never opens a PR of its own, paused or not.
+		paused := false
+		if state.CandidateGroup == nil {
+			paused = handleBudgetPauseIfNeeded(ctx, baseDir, state, resultSubtype, hadPRURLBefore)
+		}
 
 		// Sync to data ref — use the target repo's clone dir if available,
 		// otherwise fall back to the current git repo.
@@ -113,9 +117,16 @@ var finalizeCmd = &cobra.Command{
 		if baseDir != "" && !paused {
 			emitFinalizeEvents(baseDir, state)
 		}
+		if !hadPRURLBefore {
+			commentOnIssuePR(ctx, state)
+		}
 		syncRunToDataRef(ctx, syncRoot, store, gitClient, cfg.DataRef, state)
 
-		cleanupWorktree(ctx, store, gitClient, state)
+		if state.CandidateGroup != nil {
+			finishCandidate(ctx, store, baseDir, state)
+		} else {
+			cleanupWorktree(ctx, store, gitClient, state)
+		}
 
 		// Kill the tmux pane — _finalize is the last command in the pipeline,
 		// so this is safe. The pane would otherwise stay open indefinitely.
@@ -129,7 +140,7 @@ var finalizeCmd = &cobra.Command{
 // run. A crashed run (FailureReason set) emits agent:needs-attention and
 // nothing else, so a pipeline never mistakes a crash for a completed,
 // PR-creating run. A normal run emits agent:completed plus agent:pr-created
-// when a PR URL is known.
+// when a PR URL is known, and pr:backport-opened when that PR is a backport.
 func emitFinalizeEvents(baseDir string, state *run.State) {
 	if state == nil || baseDir == "" {
 		return
@@ -157,6 +168,9 @@ func emitFinalizeEvents(baseDir string, state *run.State) {
 			"pr_url":    *state.PRURL,
 			"pr_number": extractPRNumberFromURL(*state.PRURL),
 		})
+		if state.BackportOf != nil {
+			emitBackportOpened(baseDir, state)
+		}
 	}
 }
 
@@ -212,6 +226,9 @@ func handleBudgetPauseIfNeeded(ctx context.Context, baseDir string, state *run.S
 		BudgetUSD:  budgetUSD,
 		ExistingPR: existingPR,
 	}
+	if state.BaseBranch != nil {
+		in.BaseBranch = *state.BaseBranch
+	}
 
 	out, err := draft.HandleBudgetPause(ctx, budgetPauseRunner, in)
 	if err != nil {
@@ -588,6 +588,26 @@ func TestEmitFinalizeEvents(t *testing.T) {
 		}
 	})
 
+	t.Run("backport run emits backport-opened", func(t *testing.T) {
+		baseDir := t.TempDir()
+		branch, of := "release-1.4", "42"
+		state := &run.State{
+			ID:         "20260628-0903-bp",
+			CostUSD:    &cost,
+			DurationMS: &dur,
+			PRURL:      &prURL,
+			BaseBranch: &branch,
+			BackportOf: &of,
+		}
+
+		emitFinalizeEvents(baseDir, state)
+
+		types := eventTypesFor(t, baseDir, state.ID)
+		if !containsEvent(types, event.AgentPRCreated) || !containsEvent(types, event.PRBackportOpened) {
+			t.Errorf("expected %q and %q in %v", event.AgentPRCreated, event.PRBackportOpened, types)
+		}
+	})
+
 	t.Run("successful run without PR emits completed only", func(t *testing.T) {
 		baseDir := t.TempDir()
 		state := &run.State{
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/patflynn/klaus/internal/config"
+	gh "github.com/patflynn/klaus/internal/github"
+	"github.com/patflynn/klaus/internal/run"
+	"github.com/patflynn/klaus/internal/scan"
+)
+
+// Limits on how much of an --issue thread goes into the agent's prompt.
+const (
+	issueBodyLimit    = 8000 // bytes of the issue body
+	issueCommentLimit = 2000 // bytes of each comment
+	issueMaxComments  = 10   // most recent comments kept
+)
+
+// applyIssueVars fills the prompt's issue variables from the fetched
+// issue. Long text is truncated; a body or comment that trips the
+// sensitivity scan is withheld, and each withheld part is returned so the
+// caller can say so.
+func applyIssueVars(vars *config.PromptVars, is *gh.Issue) (withheld []string) {
+	if is == nil {
+		return nil
+	}
+	vars.IssueURL = is.URL
+	vars.IssueLabels = strings.Join(is.Labels, ", ")
+
+	title, findings := screenIssueText(is.Title, 200)
+	if len(findings) > 0 {
+		withheld = append(withheld, "the title ("+findingCategories(findings)+")")
+		title = "(title withheld)"
+	}
+	vars.IssueTitle = title
+
+	body, findings := screenIssueText(is.Body, issueBodyLimit)
+	switch {
+	case len(findings) > 0:
+		withheld = append(withheld, "the body ("+findingCategories(findings)+")")
+		body = fmt.Sprintf("(The issue body was withheld because it looks like it contains %s. Read it with `gh issue view %s` if you need it, and don't copy secrets into code, commits or the PR.)", findingCategories(findings), vars.Issue)
+	case strings.TrimSpace(body) == "":
+		body = "(no description)"
+	}
+	vars.IssueBody = body
+
+	comments := is.Comments
+	if len(comments) > issueMaxComments {
+		comments = comments[len(comments)-issueMaxComments:]
+	}
+	var parts []string
+	if n := len(is.Comments) - len(comments); n > 0 {
+		parts = append(parts, fmt.Sprintf("(%d earlier comment(s) omitted)", n))
+	}
+	for _, c := range comments {
+		text, findings := screenIssueText(c.Body, issueCommentLimit)
+		if len(findings) > 0 {
+			withheld = append(withheld, fmt.Sprintf("a comment by @%s (%s)", c.Author, findingCategories(findings)))
+			text = "(withheld: looks like it contains " + findingCategories(findings) + ")"
+		}
+		header := "@" + c.Author
+		if c.CreatedAt != "" {
+			header += " (" + dateOnly(c.CreatedAt) + ")"
+		}
+		parts = append(parts, header+":\n"+strings.TrimSpace(text))
+	}
+	if len(parts) > 0 {
+		vars.IssueComments = strings.Join(parts, "\n\n") + "\n"
+	}
+	return withheld
+}
+
+// screenIssueText truncates text to limit bytes and runs the sensitivity
+// scan over what's left.
+func screenIssueText(text string, limit int) (string, []scan.Finding) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if len(text) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "\n[… truncated]"
+	}
+	return text, scan.CheckSensitivity(strings.NewReader(text))
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func findingCategories(findings []scan.Finding) string {
+	cats := make([]string, len(findings))
+	for i, f := range findings {
+		cats[i] = f.Category
+	}
+	return strings.Join(cats, ", ")
+}
+
+// dateOnly trims an RFC 3339 timestamp to its date.
+func dateOnly(ts string) string {
+	if len(ts) >= 10 {
+		return ts[:10]
+	}
+	return ts
+}
+
+// fetchIssueVars fetches the launch's --issue and fills the prompt's issue
+// variables. A failed fetch only warns: the agent can still read the issue
+// itself.
+func fetchIssueVars(ctx context.Context, client *gh.GHCLIClient, vars *config.PromptVars) {
+	if vars.Issue == "" {
+		return
+	}
+	is, err := client.FetchIssue(ctx, vars.Issue)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: could not fetch issue #%s, the agent will have to read it itself: %v\n", vars.Issue, err)
+		return
+	}
+	for _, w := range applyIssueVars(vars, is) {
+		fmt.Fprintf(os.Stderr, "warning: issue #%s: %s withheld from the prompt\n", vars.Issue, w)
+	}
+}
+
+// commentOnIssueLaunch tells the issue an agent started on it and returns
+// the comment's URL, or "" when the comment couldn't be posted.
+func commentOnIssueLaunch(ctx context.Context, client *gh.GHCLIClient, issue, runID, prNumber string) string {
+	body := fmt.Sprintf("klaus run `%s` is working on this issue; it will link its PR here.", runID)
+	if prNumber != "" {
+		body = fmt.Sprintf("klaus run `%s` is working on this issue, pushing to #%s.", runID, prNumber)
+	}
+	url, err := client.CommentOnIssue(ctx, issue, body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: could not comment on issue #%s: %v\n", issue, err)
+		return ""
+	}
+	return url
+}
+
+// commentOnIssuePR comments on the run's issue with the PR the agent
+// opened. It only does so for runs that commented at launch (issue_comments)
+// and whose PR is new.
+func commentOnIssuePR(ctx context.Context, state *run.State) {
+	if state.IssueComment == nil || state.Issue == nil || state.PRURL == nil || *state.PRURL == "" {
+		return
+	}
+	client := gh.NewGHCLIClient(gh.OwnerRepoFromPRURL(*state.IssueComment))
+	body := fmt.Sprintf("klaus run `%s` opened %s.", state.ID, *state.PRURL)
+	if _, err := client.CommentOnIssue(ctx, *state.Issue, body); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: could not comment on issue #%s: %v\n", *state.Issue, err)
+	}
+}
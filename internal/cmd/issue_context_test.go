@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/patflynn/klaus/internal/config"
+	gh "github.com/patflynn/klaus/internal/github"
+)
+
+func TestApplyIssueVars(t *testing.T) {
+	is := &gh.Issue{
+		Number: 12,
+		Title:  "Login fails on Safari",
+		URL:    "https://github.com/owner/repo/issues/12",
+		Body:   strings.Repeat("x", issueBodyLimit+100),
+		Labels: []string{"bug", "auth"},
+	}
+	for i := 1; i <= issueMaxComments+2; i++ {
+		is.Comments = append(is.Comments, gh.IssueComment{Author: "alice", Body: fmt.Sprintf("comment %d", i), CreatedAt: "2026-10-01T12:00:00Z"})
+	}
+	is.Comments[len(is.Comments)-1].Body = "repro with password: hunter2"
+
+	vars := config.PromptVars{RunID: "run-1", Issue: "12"}
+	withheld := applyIssueVars(&vars, is)
+
+	if len(withheld) != 1 || !strings.Contains(withheld[0], "a comment by @alice") {
+		t.Errorf("withheld = %v, want the last comment", withheld)
+	}
+	if !strings.HasSuffix(vars.IssueBody, "[… truncated]") || len(vars.IssueBody) > issueBodyLimit+20 {
+		t.Errorf("body not truncated: %d bytes", len(vars.IssueBody))
+	}
+	for _, want := range []string{"(2 earlier comment(s) omitted)", "@alice (2026-10-01):\ncomment 3", "(withheld: looks like it contains credential"} {
+		if !strings.Contains(vars.IssueComments, want) {
+			t.Errorf("comments missing %q:\n%s", want, vars.IssueComments)
+		}
+	}
+	if strings.Contains(vars.IssueComments, "hunter2") || strings.Contains(vars.IssueComments, "comment 2\n") {
+		t.Errorf("comments kept withheld or omitted text:\n%s", vars.IssueComments)
+	}
+
+	prompt, err := config.RenderPrompt("", vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## Issue #12: Login fails on Safari\nhttps://github.com/owner/repo/issues/12\nLabels: bug, auth\n", "### Recent comments\n(2 earlier"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+
+	// Without a fetched issue the prompt has no issue section.
+	prompt, _ = config.RenderPrompt("", config.PromptVars{RunID: "run-1", Issue: "12"})
+	if strings.Contains(prompt, "## Issue") {
+		t.Errorf("prompt has an issue section without a fetched issue:\n%s", prompt)
+	}
+}
@@ -0,0 +1,329 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/patflynn/klaus/internal/config"
+	gh "github.com/patflynn/klaus/internal/github"
+	"github.com/patflynn/klaus/internal/pipeline"
+	"github.com/patflynn/klaus/internal/project"
+	"github.com/patflynn/klaus/internal/run"
+	"github.com/patflynn/klaus/internal/verify"
+)
+
+// Issue watcher defaults (pipeline.issue_watch).
+const (
+	defaultIssueWatchLabel      = "klaus:auto"
+	defaultIssueInProgressLabel = "klaus:in-progress"
+
+	// issueLaunchRetryAfter is how long an issue whose launch failed is
+	// left alone before the watcher tries it again.
+	issueLaunchRetryAfter = 10 * time.Minute
+)
+
+// issueWatch is a registered project's issue watch settings, resolved from
+// its pipeline.issue_watch block.
+type issueWatch struct {
+	Project         string // registered project name
+	Slug            string // owner/repo
+	Label           string
+	InProgressLabel string
+	MaxAgents       int
+	MaxSpendUSD     float64
+	Budget          string // per agent
+	Profile         string
+}
+
+// issueWatchClient is the GitHub side of the issue watcher.
+type issueWatchClient interface {
+	ListLabeledIssues(ctx context.Context, label string) ([]gh.Issue, error)
+	SwapIssueLabel(ctx context.Context, number, remove, add string) error
+	EnsureLabel(ctx context.Context, name, description, color string) error
+}
+
+// issueAgentLauncher launches an agent for an issue and returns its run ID.
+type issueAgentLauncher func(w issueWatch, is gh.Issue) (string, error)
+
+// issueWatcher launches agents for labelled issues in the registered
+// projects that opted in. Only the pipeline leader runs it.
+type issueWatcher struct {
+	watches []issueWatch
+	reg     *project.Registry
+	client  func(slug string) issueWatchClient
+	launch  issueAgentLauncher
+
+	mu       sync.Mutex
+	labelled map[string]bool      // repos whose in-progress label was ensured
+	retryAt  map[string]time.Time // "<owner/repo>#<n>" → when a failed launch may be retried
+	capped   map[string]bool      // repos whose spend cap was reported and still holds
+	// launched maps the runs launched by this watcher to their issue
+	// ("<owner/repo>#<n>") until the states passed in include them, so a
+	// pass working from older states still counts them.
+	launched map[string]string
+}
+
+func newIssueWatcherWith(watches []issueWatch, reg *project.Registry, client func(string) issueWatchClient, launch issueAgentLauncher) *issueWatcher {
+	return &issueWatcher{
+		watches:  watches,
+		reg:      reg,
+		client:   client,
+		launch:   launch,
+		labelled: make(map[string]bool),
+		retryAt:  make(map[string]time.Time),
+		capped:   make(map[string]bool),
+		launched: make(map[string]string),
+	}
+}
+
+// newIssueWatcher reads the issue_watch settings of every registered
+// project. It returns nil when no project opted in. Like pipeline
+// policies, the settings are read once; restart to pick up edits.
+func newIssueWatcher(logger *slog.Logger) *issueWatcher {
+	reg, _ := project.Load()
+	if reg == nil {
+		return nil
+	}
+	projects := reg.List()
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var watches []issueWatch
+	for _, name := range names {
+		root := projects[name]
+		cfg, err := config.Load(root)
+		if err != nil {
+			logger.Warn("loading project config for issue watch", "project", name, "err", err)
+			continue
+		}
+		if cfg.Pipeline == nil || cfg.Pipeline.IssueWatch == nil {
+			continue
+		}
+		w, err := resolveIssueWatch(cfg, name, resolveGHRepo("", root))
+		if err != nil {
+			logger.Error("ignoring invalid issue_watch config", "project", name, "err", err)
+			continue
+		}
+		watches = append(watches, w)
+	}
+	if len(watches) == 0 {
+		return nil
+	}
+	client := func(slug string) issueWatchClient { return gh.NewGHCLIClient(slug) }
+	return newIssueWatcherWith(watches, reg, client, launchIssueAgent)
+}
+
+// resolveIssueWatch fills in a project's issue_watch defaults.
+func resolveIssueWatch(cfg config.Config, name, slug string) (issueWatch, error) {
+	iw := cfg.Pipeline.IssueWatch
+	if slug == "" {
+		return issueWatch{}, fmt.Errorf("can't tell the GitHub repo from its origin remote")
+	}
+	profile, err := cfg.Profile(iw.Profile)
+	if err != nil {
+		return issueWatch{}, err
+	}
+	w := issueWatch{
+		Project:         name,
+		Slug:            slug,
+		Label:           iw.Label,
+		InProgressLabel: iw.InProgressLabel,
+		MaxAgents:       iw.MaxAgents,
+		MaxSpendUSD:     iw.MaxSpendUSD,
+		Budget:          iw.Budget,
+		Profile:         iw.Profile,
+	}
+	if w.Label == "" {
+		w.Label = defaultIssueWatchLabel
+	}
+	if w.InProgressLabel == "" {
+		w.InProgressLabel = defaultIssueInProgressLabel
+	}
+	if w.Label == w.InProgressLabel {
+		return issueWatch{}, fmt.Errorf("label and in_progress_label are both %q", w.Label)
+	}
+	if w.MaxAgents <= 0 {
+		w.MaxAgents = 1
+	}
+	if w.Budget == "" {
+		w.Budget = profileBudget(profile)
+	}
+	if w.Budget == "" {
+		w.Budget = cfg.DefaultBudget
+	}
+	if _, err := strconv.ParseFloat(w.Budget, 64); err != nil {
+		return issueWatch{}, fmt.Errorf("budget %q is not a number", w.Budget)
+	}
+	return w, nil
+}
+
+// launchIssueAgent launches an agent for an issue with 'klaus launch'. The
+// issue's thread goes into the agent's prompt (see fetchIssueVars).
+func launchIssueAgent(w issueWatch, is gh.Issue) (string, error) {
+	args := []string{"launch", "--repo", w.Project, "--issue", strconv.Itoa(is.Number), "--issue-label", w.Label, "--budget", w.Budget}
+	if w.Profile != "" {
+		args = append(args, "--profile", w.Profile)
+	}
+	args = append(args, fmt.Sprintf("Resolve issue #%d: %s", is.Number, is.Title))
+	out, err := exec.Command("klaus", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("klaus launch: %w: %s", err, verify.LastLine(strings.TrimSpace(string(out))))
+	}
+	return pipeline.ExtractAgentID(string(out)), nil
+}
+
+// Watch launches agents for the labelled issues of the watched repos, or
+// of repo alone when it is set (an issues webhook). running says whether a
+// run's agent is still working.
+func (w *issueWatcher) Watch(ctx context.Context, repo string, states []*run.State, running func(*run.State) bool) []pipeline.Action {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var actions []pipeline.Action
+	for _, iw := range w.watches {
+		if repo != "" && !strings.EqualFold(repo, iw.Slug) {
+			continue
+		}
+		actions = append(actions, w.watchRepo(ctx, iw, states, running)...)
+	}
+	return actions
+}
+
+func (w *issueWatcher) watchRepo(ctx context.Context, iw issueWatch, states []*run.State, running func(*run.State) bool) []pipeline.Action {
+	client := w.client(iw.Slug)
+	issues, err := client.ListLabeledIssues(ctx, iw.Label)
+	if err != nil {
+		return []pipeline.Action{{Type: "error", Detail: fmt.Sprintf("Issue watch %s: listing %s issues failed", iw.Slug, iw.Label), Error: err.Error()}}
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+
+	// An issue with a running agent, whoever launched it, never gets a
+	// second one. The watcher's own runs count towards its limits.
+	busy := make(map[string]bool)
+	active, spent := 0, 0.0
+	for _, s := range states {
+		if repoFromState(s, w.reg) != iw.Project {
+			continue
+		}
+		isRunning := running(s)
+		if isRunning && s.Issue != nil {
+			busy[strings.TrimPrefix(*s.Issue, "#")] = true
+		}
+		if s.IssueLabel == nil {
+			continue
+		}
+		switch {
+		case s.CostUSD != nil && *s.CostUSD > 0:
+			spent += *s.CostUSD
+		case isRunning && s.Budget != nil:
+			budget, _ := strconv.ParseFloat(*s.Budget, 64)
+			spent += budget
+		}
+		if isRunning {
+			active++
+		}
+	}
+
+	budget, _ := strconv.ParseFloat(iw.Budget, 64)
+	known := make(map[string]bool, len(states))
+	for _, s := range states {
+		known[s.ID] = true
+	}
+	for runID, key := range w.launched {
+		switch {
+		case known[runID]:
+			delete(w.launched, runID)
+		case strings.HasPrefix(key, iw.Slug+"#"):
+			busy[strings.TrimPrefix(key, iw.Slug+"#")] = true
+			active++
+			spent += budget
+		}
+	}
+
+	now := time.Now()
+	var actions []pipeline.Action
+	for _, is := range issues {
+		num := strconv.Itoa(is.Number)
+		key := iw.Slug + "#" + num
+		if busy[num] || now.Before(w.retryAt[key]) {
+			continue
+		}
+		if active >= iw.MaxAgents {
+			break
+		}
+		if iw.MaxSpendUSD > 0 && spent+budget > iw.MaxSpendUSD {
+			if !w.capped[iw.Slug] {
+				w.capped[iw.Slug] = true
+				actions = append(actions, pipeline.Action{
+					Type:   "error",
+					Detail: fmt.Sprintf("Issue watch %s: spend cap reached, %s waits", iw.Slug, key),
+					Error:  fmt.Sprintf("agents spent $%.2f of the $%.2f cap", spent, iw.MaxSpendUSD),
+				})
+			}
+			break
+		}
+		w.capped[iw.Slug] = false
+
+		if !w.labelled[iw.Slug] {
+			if err := client.EnsureLabel(ctx, iw.InProgressLabel, "A klaus agent is working on this issue", "1D76DB"); err != nil {
+				return append(actions, pipeline.Action{Type: "error", Detail: fmt.Sprintf("Issue watch %s: creating the %s label failed", iw.Slug, iw.InProgressLabel), Error: err.Error()})
+			}
+			w.labelled[iw.Slug] = true
+		}
+		// Swapping the label first claims the issue, so the next pass (or
+		// another session watching the repo) doesn't launch for it again.
+		if err := client.SwapIssueLabel(ctx, num, iw.Label, iw.InProgressLabel); err != nil {
+			w.retryAt[key] = now.Add(issueLaunchRetryAfter)
+			actions = append(actions, pipeline.Action{Type: "error", Detail: fmt.Sprintf("Issue %s: relabelling failed", key), Error: err.Error()})
+			continue
+		}
+		runID, err := w.launch(iw, is)
+		if err != nil {
+			w.retryAt[key] = now.Add(issueLaunchRetryAfter)
+			if rerr := client.SwapIssueLabel(ctx, num, iw.InProgressLabel, iw.Label); rerr != nil {
+				err = fmt.Errorf("%w (restoring %s: %v)", err, iw.Label, rerr)
+			}
+			actions = append(actions, pipeline.Action{Type: "error", Detail: fmt.Sprintf("Issue %s: launching an agent failed", key), Error: err.Error()})
+			continue
+		}
+		delete(w.retryAt, key)
+		if runID != "" {
+			w.launched[runID] = key
+		}
+		active++
+		spent += budget
+		actions = append(actions, pipeline.Action{Type: "issue", Detail: fmt.Sprintf("Issue %s: launched agent %s", key, shortID(runID))})
+	}
+	return actions
+}
+
+// watchIssuesCmd runs the issue watcher off the dashboard's update loop,
+// since launching takes a while. What it launched is reported as pipeline
+// actions, which also reloads run states.
+func watchIssuesCmd(w *issueWatcher, repo string, states []*run.State, td run.TmuxDeps) tea.Cmd {
+	if w == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		actions := w.Watch(context.Background(), repo, states, func(s *run.State) bool { return s.IsAgentRunningWith(td) })
+		if len(actions) == 0 {
+			return nil
+		}
+		return pipelineActionMsg{actions: actions}
+	}
+}
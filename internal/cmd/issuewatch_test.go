@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	gh "github.com/patflynn/klaus/internal/github"
+	"github.com/patflynn/klaus/internal/run"
+)
+
+// fakeIssueClient keeps each issue's labels.
+type fakeIssueClient struct {
+	labels map[int]string
+}
+
+func (f *fakeIssueClient) ListLabeledIssues(_ context.Context, label string) ([]gh.Issue, error) {
+	var out []gh.Issue
+	for n := 1; n <= 10; n++ {
+		if f.labels[n] == label {
+			out = append(out, gh.Issue{Number: n, Title: fmt.Sprintf("issue %d", n)})
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeIssueClient) SwapIssueLabel(_ context.Context, number, remove, add string) error {
+	var n int
+	fmt.Sscan(number, &n)
+	if f.labels[n] != remove {
+		return fmt.Errorf("issue %d has no %s label", n, remove)
+	}
+	f.labels[n] = add
+	return nil
+}
+
+func (f *fakeIssueClient) EnsureLabel(context.Context, string, string, string) error { return nil }
+
+func TestIssueWatcher(t *testing.T) {
+	client := &fakeIssueClient{labels: map[int]string{3: "klaus:auto", 5: "klaus:auto"}}
+	var launched []int
+	failNext := false
+	launch := func(w issueWatch, is gh.Issue) (string, error) {
+		if failNext {
+			failNext = false
+			return "", fmt.Errorf("no tmux")
+		}
+		launched = append(launched, is.Number)
+		return fmt.Sprintf("run-%d", is.Number), nil
+	}
+	w := newIssueWatcherWith([]issueWatch{{
+		Project: "proj", Slug: "owner/proj", Label: "klaus:auto", InProgressLabel: "klaus:in-progress",
+		MaxAgents: 1, MaxSpendUSD: 12, Budget: "5",
+	}}, nil, func(string) issueWatchClient { return client }, launch)
+
+	pane, target, three, label := "%1", "proj", "3", "klaus:auto"
+	running := func(s *run.State) bool { return s.TmuxPane != nil }
+	// Someone already launched an agent for #3 by hand.
+	states := []*run.State{{ID: "manual", TargetRepo: &target, Issue: &three, TmuxPane: &pane}}
+	ctx := context.Background()
+
+	actions := w.Watch(ctx, "", states, running)
+	if !reflect.DeepEqual(launched, []int{5}) {
+		t.Fatalf("launched = %v, want [5] (#3 already has an agent)", launched)
+	}
+	if len(actions) != 1 || actions[0].Type != "issue" || !strings.Contains(actions[0].Detail, "owner/proj#5") {
+		t.Errorf("actions = %+v, want one issue action for #5", actions)
+	}
+	if client.labels[5] != "klaus:in-progress" || client.labels[3] != "klaus:auto" {
+		t.Errorf("labels = %v, want #5 in progress", client.labels)
+	}
+
+	// #5's agent isn't in the states yet, but it still fills the repo's one slot.
+	client.labels[7] = "klaus:auto"
+	w.Watch(ctx, "", states, running)
+	if len(launched) != 1 {
+		t.Errorf("launched = %v; max_agents should hold #7 back", launched)
+	}
+
+	// Webhooks for other repos don't touch this one.
+	states = append(states, &run.State{ID: "run-5", TargetRepo: &target, IssueLabel: &label, Budget: stringPtr("5")})
+	if actions := w.Watch(ctx, "owner/other", states, running); actions != nil {
+		t.Errorf("actions for another repo = %+v", actions)
+	}
+
+	// #5's agent finished; a failed launch restores the label and waits.
+	cost := 4.0
+	states[1].CostUSD = &cost
+	failNext = true
+	actions = w.Watch(ctx, "owner/proj", states, running)
+	if len(actions) != 1 || actions[0].Type != "error" || client.labels[7] != "klaus:auto" {
+		t.Errorf("failed launch: actions = %+v, labels = %v", actions, client.labels)
+	}
+	if w.Watch(ctx, "", states, running); len(launched) != 1 {
+		t.Errorf("launched = %v; a failed issue should wait before a retry", launched)
+	}
+
+	// With $8 spent, another $5 agent would pass the $12 cap.
+	delete(w.retryAt, "owner/proj#7")
+	cost = 8
+	actions = w.Watch(ctx, "", states, running)
+	if len(launched) != 1 || len(actions) != 1 || !strings.Contains(actions[0].Detail, "spend cap") {
+		t.Errorf("over the cap: launched = %v, actions = %+v", launched, actions)
+	}
+	if actions := w.Watch(ctx, "", states, running); len(actions) != 0 {
+		t.Errorf("spend cap reported again: %+v", actions)
+	}
+	cost = 4
+	w.Watch(ctx, "", states, running)
+	if !reflect.DeepEqual(launched, []int{5, 7}) {
+		t.Errorf("launched = %v, want #7 once under the cap", launched)
+	}
+}
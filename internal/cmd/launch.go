@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -46,12 +47,56 @@ oversized, sensitive-skipped, or its session UUID is unknown. Use --no-replay
 to force a fresh agent, --replay to force replay (bypassing the size
 threshold), and --replay-threshold-kb to tune the per-launch size cap.
 
+Use --base to stack an agent on another agent's unmerged work: the worktree
+starts from that run's (or PR's) branch and the agent opens its PR against
+it. The pipeline merges stacks bottom-up, and when the parent PR merges it
+retargets the child onto the default branch. --base also takes the name of a
+branch on origin, such as a release branch, which the PR then targets for
+good.
+
+Use --plan to launch a batch of tasks from a YAML or JSON file instead of a
+single prompt. Each task has a prompt and optionally an id, repo, issue,
+budget, profile and depends_on (the ids of tasks it waits for):
+
+  launch_when: merged   # or "completed": launch once dependencies finish
+  tasks:
+    - id: schema
+      prompt: Add the audit_log table
+    - id: api
+      prompt: Expose the audit log over the API
+      depends_on: [schema]
+
+Tasks that depend on nothing launch right away. The rest are held until the
+PRs of the tasks they depend on merge (or, with launch_when: completed,
+until those agents finish), then launched by the pipeline leader — the
+dashboard or klaus pipelined. A task whose dependency fails or whose PR is
+closed is blocked. klaus status and the dashboard show each plan's progress.
+
+Use --candidates N (2-5) for a hard task: N agents work on the same prompt in
+their own worktrees and only commit locally. When the last one finishes, klaus
+runs the build and tests (verify_commands, or the detected ones) and the
+pre-PR review on each, scores them, and opens a PR for the winner from its
+first commit's message. The others' branches are kept. Each run records its
+score (candidate_result in state), candidates:compared is emitted, and
+'klaus compare <group>' shows the result or compares a group by hand.
+
 When sandbox_host is configured in ~/.klaus/config.json, agents run remotely
 via SSH on the sandbox host. The worktree is synced before launch and results
 are synced back after completion. Use --local to force local execution, or
 --host to override the configured sandbox host.`,
-	Args: cobra.ExactArgs(1),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if planFile, _ := cmd.Flags().GetString("plan"); planFile != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if planFile, _ := cmd.Flags().GetString("plan"); planFile != "" {
+			return runLaunchPlan(cmd, planFile)
+		}
+		if n, _ := cmd.Flags().GetInt("candidates"); cmd.Flags().Changed("candidates") {
+			return runLaunchCandidates(cmd, args[0], n)
+		}
 		prompt := args[0]
 		issue, _ := cmd.Flags().GetString("issue")
 		budget, _ := cmd.Flags().GetString("budget")
@@ -63,6 +108,12 @@ are synced back after completion. Use --local to force local execution, or
 		replayFlag, _ := cmd.Flags().GetBool("replay")
 		noReplay, _ := cmd.Flags().GetBool("no-replay")
 		replayThresholdKB, _ := cmd.Flags().GetInt("replay-threshold-kb")
+		baseRef, _ := cmd.Flags().GetString("base")
+		backportOf, _ := cmd.Flags().GetString("backport-of")
+		planTask, _ := cmd.Flags().GetString("plan-task")
+		profileName, _ := cmd.Flags().GetString("profile")
+		issueLabel, _ := cmd.Flags().GetString("issue-label")
+		candidateOf, _ := cmd.Flags().GetString("candidate")
 		ctx := cmd.Context()
 		tmuxClient := tmux.NewExecClient()
 
@@ -73,6 +124,27 @@ are synced back after completion. Use --local to force local execution, or
 		if replayFlag && noReplay {
 			return fmt.Errorf("--replay and --no-replay are mutually exclusive")
 		}
+		if baseRef != "" && prNumber != "" {
+			return fmt.Errorf("--base and --pr are mutually exclusive")
+		}
+		if backportOf != "" && baseRef == "" {
+			return fmt.Errorf("--backport-of requires --base <release-branch>")
+		}
+		planID, planTaskID, _ := strings.Cut(planTask, "/")
+		if planTask != "" && (planID == "" || planTaskID == "") {
+			return fmt.Errorf("--plan-task must be <plan-id>/<task-id>")
+		}
+		var candidate *candidateRef
+		if candidateOf != "" {
+			c, err := parseCandidateRef(candidateOf)
+			if err != nil {
+				return err
+			}
+			if prNumber != "" || baseRef != "" {
+				return fmt.Errorf("--candidate can't be combined with --pr or --base")
+			}
+			candidate = c
+		}
 
 		// Host repo — optional when --repo is specified or session target is set
 		hostRoot, _ := git.RepoRoot()
@@ -101,6 +173,13 @@ are synced back after completion. Use --local to force local execution, or
 			return err
 		}
 
+		profile, err := hostCfg.Profile(profileName)
+		if err != nil {
+			return err
+		}
+		if budget == "" {
+			budget = profileBudget(profile)
+		}
 		if budget == "" {
 			budget = hostCfg.DefaultBudget
 		}
@@ -198,6 +277,7 @@ are synced back after completion. Use --local to force local execution, or
 		var branch string
 		var isPRFix bool
 		var prURL string
+		var stack *stackBase
 
 		if prNumber != "" {
 			ghRepo := resolveGHRepo(repoRef, repoRoot)
@@ -227,13 +307,26 @@ are synced back after completion. Use --local to force local execution, or
 		} else {
 			branch = "agent/" + id
 
+			// Resolve the stack parent before printing the launch banner so a
+			// bad --base fails without a half-started launch.
+			startPoint := "origin/" + defaultBranch
+			if baseRef != "" {
+				stack, err = resolveStackBase(ctx, store, baseRef, repoRoot, resolveGHRepo(repoRef, repoRoot))
+				if err != nil {
+					return err
+				}
+				startPoint = stackStartPoint(repoRoot, stack.Branch)
+			}
+
 			fmt.Printf("Launching agent %s...\n", id)
 			if targetRepo != nil {
 				fmt.Printf("  target:   %s\n", *targetRepo)
 			}
+			if stack != nil {
+				fmt.Printf("  base:     %s (%s)\n", stack.Branch, stack.describe())
+			}
 
 			// Create worktree
-			startPoint := "origin/" + defaultBranch
 			if err := gitClient.WorktreeAdd(ctx, repoRoot, worktree, branch, startPoint); err != nil {
 				return fmt.Errorf("creating worktree: %w", err)
 			}
@@ -265,29 +358,36 @@ are synced back after completion. Use --local to force local execution, or
 		// Set up Nix dev environment if flake.nix exists
 		nix.SetupDevEnvironment(worktree)
 
-		// Build system prompt (from target repo's .klaus/prompt.md if it exists)
+		// Build system prompt (from target repo's .klaus/prompt.md if it exists),
+		// with the --issue thread so the agent doesn't start by fetching it.
+		issueClient := gh.NewGHCLIClient(resolveGHRepo(repoRef, repoRoot))
+		vars := config.PromptVars{
+			RunID:    id,
+			Issue:    issue,
+			Branch:   branch,
+			RepoName: repoName,
+		}
+		fetchIssueVars(ctx, issueClient, &vars)
 		var sysPrompt string
 		if isPRFix {
-			sysPrompt, err = config.RenderPRFixPrompt(repoRoot, config.PromptVars{
-				RunID:    id,
-				Issue:    issue,
-				PR:       prNumber,
-				Branch:   branch,
-				RepoName: repoName,
-			})
+			vars.PR = prNumber
+			sysPrompt, err = config.RenderPRFixPrompt(repoRoot, vars)
 		} else {
-			reviewer := hostCfg.PRReviewerOrDefault()
-			sysPrompt, err = config.RenderPrompt(repoRoot, config.PromptVars{
-				RunID:    id,
-				Issue:    issue,
-				Branch:   branch,
-				RepoName: repoName,
-				Reviewer: reviewer,
-			})
+			vars.Reviewer = hostCfg.PRReviewerOrDefault()
+			if stack != nil {
+				vars.Base = stack.Branch
+			}
+			sysPrompt, err = config.RenderPrompt(repoRoot, vars)
 		}
 		if err != nil {
 			return fmt.Errorf("rendering prompt: %w", err)
 		}
+		if sysPrompt, err = appendProfilePrompt(sysPrompt, profile, hostRoot); err != nil {
+			return err
+		}
+		if candidate != nil {
+			sysPrompt += candidatePrompt(candidate, branch)
+		}
 
 		logFile := filepath.Join(store.LogDir(), id+".jsonl")
 
@@ -362,7 +462,7 @@ are synced back after completion. Use --local to force local execution, or
 		}
 
 		// Build the claude command
-		claudeCmd := buildClaudeCommand(sysPrompt, budget, prompt, id, resolvedResume)
+		claudeCmd := buildClaudeCommand(sysPrompt, budget, prompt, id, resolvedResume, profile)
 
 		// Build the pane command: run claude, pipe through tee and formatter, then finalize.
 		// For cross-repo launches with a host repo, finalize must run from the
@@ -428,6 +528,11 @@ are synced back after completion. Use --local to force local execution, or
 		// pane, then swap it to the last position if needed.
 		pinDashboardToBottom(ctx, currentPane, store, tmuxClient)
 
+		var issueCommentURL string
+		if issue != "" && hostCfg.IssueComments && candidate == nil {
+			issueCommentURL = commentOnIssueLaunch(ctx, issueClient, issue, id, prNumber)
+		}
+
 		// Write state
 		createdAt := time.Now().Format(time.RFC3339)
 		issuePtr := stringPtr(issue)
@@ -465,6 +570,21 @@ are synced back after completion. Use --local to force local execution, or
 		} else if replayedFromRunID != "" {
 			state.OriginalRunID = &replayedFromRunID
 		}
+		if stack != nil {
+			state.BaseBranch = &stack.Branch
+			state.ParentRunID = stringPtr(stack.RunID)
+			state.ParentPR = stringPtr(stack.PR)
+		}
+		state.BackportOf = stringPtr(strings.TrimPrefix(backportOf, "#"))
+		state.PlanID = stringPtr(planID)
+		state.PlanTask = stringPtr(planTaskID)
+		state.Profile = stringPtr(profileName)
+		state.IssueComment = stringPtr(issueCommentURL)
+		state.IssueLabel = stringPtr(issueLabel)
+		if candidate != nil {
+			state.CandidateGroup = &candidate.Group
+			state.CandidateCount = candidate.Count
+		}
 		if isPRFix {
 			state.Type = "pr-fix"
 			if prURL != "" {
@@ -488,6 +608,9 @@ are synced back after completion. Use --local to force local execution, or
 			if normalizedTarget != nil {
 				startedData["target_repo"] = *normalizedTarget
 			}
+			if stack != nil {
+				startedData["base_branch"] = stack.Branch
+			}
 			emitEvent(hds.BaseDir(), id, event.AgentStarted, startedData)
 
 			// If this is a launch against a budget-paused PR, emit
@@ -512,6 +635,9 @@ are synced back after completion. Use --local to force local execution, or
 			fmt.Printf("  host:     local\n")
 		}
 		fmt.Printf("  budget:   $%s\n", budget)
+		if profileName != "" {
+			fmt.Printf("  profile:  %s\n", profileName)
+		}
 		fmt.Printf("  log:      %s\n", logFile)
 		fmt.Println()
 		fmt.Printf("Agent %s is running. Use 'klaus status' to check progress.\n", id)
@@ -534,7 +660,7 @@ func buildPaneCommand(worktree, claudeCmd, logFile, selfBin, finalizePrefix, id
 	)
 }
 
-func buildClaudeCommand(sysPrompt, budget, prompt, runID, resumeSessionName string) string {
+func buildClaudeCommand(sysPrompt, budget, prompt, runID, resumeSessionName string, profile *config.Profile) string {
 	parts := []string{
 		"claude", "-p",
 		"-n", shellQuote(runID),
@@ -548,8 +674,12 @@ func buildClaudeCommand(sysPrompt, budget, prompt, runID, resumeSessionName stri
 		"--output-format", "stream-json",
 		"--max-budget-usd", shellQuote(budget),
 		"--append-system-prompt", shellQuote(sysPrompt),
-		shellQuote(prompt),
 	)
+	args := profileClaudeArgs(profile, true)
+	for i := 0; i < len(args); i += 2 {
+		parts = append(parts, args[i], shellQuote(args[i+1]))
+	}
+	parts = append(parts, shellQuote(prompt))
 	return strings.Join(parts, " ")
 }
 
@@ -600,6 +730,74 @@ func stringPtr(s string) *string {
 	return &s
 }
 
+// stackBase is the branch a stacked launch (--base) builds on.
+type stackBase struct {
+	Branch string
+	RunID  string // run that owns Branch, if klaus launched it
+	PR     string // PR opened from Branch, if any yet
+}
+
+func (b *stackBase) describe() string {
+	if b.RunID == "" && b.PR == "" {
+		return "branch"
+	}
+	var parts []string
+	if b.RunID != "" {
+		parts = append(parts, "run "+b.RunID)
+	}
+	if b.PR != "" {
+		parts = append(parts, "PR #"+b.PR)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// resolveStackBase resolves --base, which names a run in this session, a PR
+// number or a branch on origin (e.g. a release branch), to the branch to
+// start from. Only runs and PRs make the new PR stacked; a plain branch is
+// just its permanent target.
+func resolveStackBase(ctx context.Context, store run.StateStore, ref, repoRoot, ghRepo string) (*stackBase, error) {
+	if s, err := store.Load(ref); err == nil && s != nil && s.Branch != "" {
+		return &stackBase{Branch: s.Branch, RunID: s.ID, PR: s.PRNumber()}, nil
+	}
+
+	pr := strings.TrimPrefix(ref, "#")
+	if _, err := strconv.Atoi(pr); err != nil {
+		if exec.Command("git", "-C", repoRoot, "rev-parse", "--verify", "--quiet", "origin/"+ref).Run() == nil {
+			return &stackBase{Branch: ref}, nil
+		}
+		return nil, fmt.Errorf("--base %q is not a run ID in this session, a PR number or a branch on origin", ref)
+	}
+	branch, err := gh.NewGHCLIClient(ghRepo).GetBranch(ctx, pr)
+	if err != nil {
+		return nil, fmt.Errorf("getting branch of base PR #%s: %w", pr, err)
+	}
+	base := &stackBase{Branch: branch, PR: pr}
+	if states, err := store.List(); err == nil {
+		for _, s := range states {
+			if s.PRNumber() == pr && s.Type != "pr-fix" {
+				base.RunID = s.ID
+				break
+			}
+		}
+	}
+	return base, nil
+}
+
+// stackStartPoint returns the ref a stacked worktree starts from: the
+// parent's pushed branch, or its local branch when the parent agent hasn't
+// pushed yet (worktrees of one repo share branches).
+func stackStartPoint(repoRoot, branch string) string {
+	remote := "origin/" + branch
+	if exec.Command("git", "-C", repoRoot, "rev-parse", "--verify", "--quiet", remote).Run() == nil {
+		return remote
+	}
+	if exec.Command("git", "-C", repoRoot, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch).Run() == nil {
+		fmt.Fprintf(os.Stderr, "warning: %s is not pushed yet; starting from its local head\n", branch)
+		return branch
+	}
+	return remote
+}
+
 // normalizeTargetRepo resolves the target repo to a canonical short name.
 // If targetRepo is set, normalizes it against registered projects.
 // If targetRepo is nil but hostRoot is a git repo matching a registered project,
@@ -769,9 +967,17 @@ func pinDashboardToBottom(ctx context.Context, currentPane string, store run.Sta
 }
 
 func init() {
-	launchCmd.Flags().String("issue", "", "GitHub issue number to reference")
+	launchCmd.Flags().String("issue", "", "GitHub issue to work on; its title, body, labels and recent comments go into the agent's prompt")
 	launchCmd.Flags().String("pr", "", "Push fixes to an existing PR's branch instead of creating a new PR (also the way to resume a budget-paused PR — the agent picks up from the WIP commit)")
-	launchCmd.Flags().String("budget", "", "Max spend in USD (default from config)")
+	launchCmd.Flags().String("base", "", "Stack on another agent's work: start from a run's (run ID) or PR's (number) branch and open the PR against it; or name a branch (e.g. a release branch) to start from and target")
+	launchCmd.Flags().String("backport-of", "", "Record the run as a backport of this merged PR (with --base <release-branch>; used by klaus backport)")
+	launchCmd.Flags().String("plan", "", "Launch the tasks in a YAML or JSON plan file, holding each until its depends_on tasks' PRs merge")
+	launchCmd.Flags().String("plan-task", "", "Record the run as a plan's task, as <plan-id>/<task-id> (used by klaus launch --plan)")
+	launchCmd.Flags().Int("candidates", 0, "Launch this many agents on the prompt, each committing locally; once all finish, klaus builds, tests and scores them and opens a PR for the best")
+	launchCmd.Flags().String("candidate", "", "Record the run as a best-of-N candidate, as <group-id>/<n>/<count> (used by klaus launch --candidates)")
+	launchCmd.Flags().String("issue-label", "", "Record the run as launched by the issue watcher for this label (used by the pipeline's issue_watch)")
+	launchCmd.Flags().String("budget", "", "Max spend in USD (default from the profile, then config)")
+	launchCmd.Flags().String("profile", "", "Agent profile from config \"profiles\": model, budget, extra system prompt, tools and max turns")
 	launchCmd.Flags().String("repo", "", "Target repo: registered project name, owner/repo, or full URL")
 	launchCmd.Flags().Bool("local", false, "Force local execution even when sandbox is configured")
 	launchCmd.Flags().String("host", "", "Override sandbox host (ignores config sandbox_host)")
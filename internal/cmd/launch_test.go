@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -450,7 +451,7 @@ func TestLaunchCmdHasSandboxFlags(t *testing.T) {
 }
 
 func TestBuildClaudeCommand_SessionNaming(t *testing.T) {
-	cmd := buildClaudeCommand("sys prompt", "5", "do stuff", "20260405-1200-abcd", "")
+	cmd := buildClaudeCommand("sys prompt", "5", "do stuff", "20260405-1200-abcd", "", nil)
 	if !strings.Contains(cmd, "-n '20260405-1200-abcd'") {
 		t.Errorf("expected -n flag with run ID, got: %s", cmd)
 	}
@@ -463,7 +464,7 @@ func TestBuildClaudeCommand_SessionNaming(t *testing.T) {
 }
 
 func TestBuildClaudeCommand_WithResume(t *testing.T) {
-	cmd := buildClaudeCommand("sys prompt", "5", "fix CI", "20260405-1200-efgh", "20260405-1100-abcd")
+	cmd := buildClaudeCommand("sys prompt", "5", "fix CI", "20260405-1200-efgh", "20260405-1100-abcd", nil)
 	if !strings.Contains(cmd, "-n '20260405-1200-efgh'") {
 		t.Errorf("expected -n flag with new run ID, got: %s", cmd)
 	}
@@ -475,6 +476,24 @@ func TestBuildClaudeCommand_WithResume(t *testing.T) {
 	}
 }
 
+func TestBuildClaudeCommand_WithProfile(t *testing.T) {
+	profile := &config.Profile{
+		Model:           "opus",
+		Budget:          "20",
+		AllowedTools:    []string{"Read", "Bash(go test:*)"},
+		DisallowedTools: []string{"WebFetch"},
+		MaxTurns:        30,
+	}
+	cmd := buildClaudeCommand("sys", "20", "refactor it", "20261017-1200-abcd", "", profile)
+	want := "--model 'opus' --allowedTools 'Read,Bash(go test:*)' --disallowedTools 'WebFetch' --max-turns '30' 'refactor it'"
+	if !strings.HasSuffix(cmd, want) {
+		t.Errorf("expected profile flags before the prompt, got: %s", cmd)
+	}
+	if got := profileClaudeArgs(profile, false); slices.Contains(got, "--max-turns") {
+		t.Errorf("interactive sessions should not get --max-turns: %v", got)
+	}
+}
+
 func TestLaunchCmdHasResumeFromFlag(t *testing.T) {
 	f := launchCmd.Flags().Lookup("resume-from")
 	if f == nil {
@@ -581,7 +600,7 @@ func TestResumeFallsBackWhenSessionMissing(t *testing.T) {
 	}
 
 	// The downstream claude command should NOT include --resume.
-	cmd := buildClaudeCommand("sys", "5", "do stuff", "20260509-1400-aaaa", resolvedResume)
+	cmd := buildClaudeCommand("sys", "5", "do stuff", "20260509-1400-aaaa", resolvedResume, nil)
 	if strings.Contains(cmd, "--resume") {
 		t.Errorf("expected no --resume flag when session is missing, got: %s", cmd)
 	}
@@ -597,7 +616,7 @@ func TestResumeFallsBackWhenSessionMissing(t *testing.T) {
 	if !claudeSessionExists(orphanUUID) {
 		t.Fatal("claudeSessionExists should now return true after creating the file")
 	}
-	cmdResumed := buildClaudeCommand("sys", "5", "do stuff", "20260509-1400-aaaa", orphanUUID)
+	cmdResumed := buildClaudeCommand("sys", "5", "do stuff", "20260509-1400-aaaa", orphanUUID, nil)
 	if !strings.Contains(cmdResumed, "--resume '"+orphanUUID+"'") {
 		t.Errorf("expected --resume flag when session exists, got: %s", cmdResumed)
 	}
@@ -626,7 +645,7 @@ func TestResumeHandlesNilRunState(t *testing.T) {
 	if resolvedResume != "" {
 		t.Errorf("resolvedResume = %q, want empty for nil run state", resolvedResume)
 	}
-	cmd := buildClaudeCommand("sys", "5", "do stuff", "20260509-1400-aaaa", resolvedResume)
+	cmd := buildClaudeCommand("sys", "5", "do stuff", "20260509-1400-aaaa", resolvedResume, nil)
 	if strings.Contains(cmd, "--resume") {
 		t.Errorf("expected no --resume flag for nil run state, got: %s", cmd)
 	}
@@ -674,7 +693,7 @@ func TestStageResumeTranscript(t *testing.T) {
 		}
 
 		// With staging done, the launched command resumes the session.
-		cmd := buildClaudeCommand("sys", "5", "fix conflicts", "20260628-1001-new", uuid)
+		cmd := buildClaudeCommand("sys", "5", "fix conflicts", "20260628-1001-new", uuid, nil)
 		if !strings.Contains(cmd, "--resume '"+uuid+"'") {
 			t.Errorf("expected --resume after successful staging, got: %s", cmd)
 		}
@@ -697,7 +716,7 @@ func TestStageResumeTranscript(t *testing.T) {
 		}
 
 		// The caller leaves resolvedResume empty, so no --resume flag.
-		cmd := buildClaudeCommand("sys", "5", "fix conflicts", "20260628-1001-new", "")
+		cmd := buildClaudeCommand("sys", "5", "fix conflicts", "20260628-1001-new", "", nil)
 		if strings.Contains(cmd, "--resume") {
 			t.Errorf("expected no --resume when staging failed, got: %s", cmd)
 		}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	tea "github.com/charmbracelet/bubbletea"
+	gh "github.com/patflynn/klaus/internal/github"
+	"github.com/patflynn/klaus/internal/pipeline"
+)
+
+// mainStatusMsg carries default-branch statuses for the pipeline's
+// post-merge watchdog, keyed by owner/repo.
+type mainStatusMsg struct {
+	statuses map[string]*pipeline.MainStatus
+}
+
+func fetchMainStatusCmd(client gh.Client, watches []pipeline.MainWatch, requiredOnly func(targetRepo string) bool) tea.Cmd {
+	return func() tea.Msg {
+		return mainStatusMsg{statuses: fetchMainStatuses(client, watches, requiredOnly)}
+	}
+}
+
+// fetchMainStatuses queries GitHub for the default branch behind each
+// watch: the watched PR's merge commit and base branch, the branch head, and
+// CI on both. requiredOnly applies the watched repo's ci_checks policy to
+// that CI. Watches whose status can't be fetched are left out and retried
+// on the next poll. Shared by the dashboard and `klaus pipelined`.
+func fetchMainStatuses(client gh.Client, watches []pipeline.MainWatch, requiredOnly func(targetRepo string) bool) map[string]*pipeline.MainStatus {
+	ctx := context.Background()
+	statuses := make(map[string]*pipeline.MainStatus, len(watches))
+	for _, w := range watches {
+		st, err := fetchMainStatus(ctx, client, w, requiredOnly(w.TargetRepo))
+		if err != nil {
+			slog.Warn("fetching default branch status", "repo", w.Slug, "err", err)
+			continue
+		}
+		statuses[w.Slug] = st
+	}
+	return statuses
+}
+
+func fetchMainStatus(ctx context.Context, client gh.Client, w pipeline.MainWatch, requiredOnly bool) (*pipeline.MainStatus, error) {
+	data, err := client.APIGet(ctx, fmt.Sprintf("repos/%s/pulls/%s", w.Slug, w.PRNumber))
+	if err != nil {
+		return nil, err
+	}
+	var pr struct {
+		MergeCommitSHA string `json:"merge_commit_sha"`
+		Base           struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	}
+	if err := json.Unmarshal(data, &pr); err != nil {
+		return nil, fmt.Errorf("parsing PR #%s: %w", w.PRNumber, err)
+	}
+	if pr.MergeCommitSHA == "" || pr.Base.Ref == "" {
+		return nil, fmt.Errorf("PR #%s has no merge commit", w.PRNumber)
+	}
+
+	data, err = client.APIGet(ctx, fmt.Sprintf("repos/%s/commits/%s", w.Slug, pr.Base.Ref))
+	if err != nil {
+		return nil, err
+	}
+	var head struct {
+		SHA string `json:"sha"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("parsing %s head: %w", pr.Base.Ref, err)
+	}
+
+	st := &pipeline.MainStatus{
+		Branch:   pr.Base.Ref,
+		MergeSHA: pr.MergeCommitSHA,
+		MergeCI:  commitCI(ctx, client, w.Slug, pr.MergeCommitSHA, pr.Base.Ref, requiredOnly),
+		HeadSHA:  head.SHA,
+	}
+	st.HeadCI = st.MergeCI
+	if st.HeadSHA != st.MergeSHA {
+		st.HeadCI = commitCI(ctx, client, w.Slug, st.HeadSHA, pr.Base.Ref, requiredOnly)
+	}
+	return st, nil
+}
+
+// commitCI summarizes the check runs on a commit as passing, failing,
+// pending or unknown. A commit with no check runs yet is pending. With
+// requiredOnly, only the checks branch requires count, like ci_checks
+// "required" on a PR. Required checks that didn't run aren't waited for:
+// many only run on pull requests.
+func commitCI(ctx context.Context, client gh.Client, slug, sha, branch string, requiredOnly bool) string {
+	data, err := client.APIGet(ctx, fmt.Sprintf("repos/%s/commits/%s/check-runs?per_page=100", slug, sha))
+	if err != nil {
+		return "unknown"
+	}
+	checks, err := gh.ParseCommitCheckRuns(data)
+	if err != nil {
+		return "unknown"
+	}
+	if len(checks) == 0 {
+		return "pending"
+	}
+	if requiredOnly && branch != "" {
+		if required, err := client.RequiredChecks(ctx, slug, branch); err == nil {
+			for i := range checks {
+				checks[i].Required = required[checks[i].Name]
+			}
+		}
+	}
+	return gh.SummarizeChecks(checks, requiredOnly)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gh "github.com/patflynn/klaus/internal/github"
+)
+
+// checkRunsClient answers every API GET with one canned response, and
+// requires the checks in required.
+type checkRunsClient struct {
+	gh.Client
+	data     []byte
+	err      error
+	required map[string]bool
+}
+
+func (c *checkRunsClient) APIGet(context.Context, string) ([]byte, error) {
+	return c.data, c.err
+}
+
+func (c *checkRunsClient) RequiredChecks(context.Context, string, string) (map[string]bool, error) {
+	return c.required, nil
+}
+
+func TestCommitCI(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		err  error
+		want string
+	}{
+		{"all green", `{"check_runs":[{"status":"completed","conclusion":"success"},{"status":"completed","conclusion":"skipped"}]}`, nil, "passing"},
+		{"one failure", `{"check_runs":[{"status":"completed","conclusion":"success"},{"status":"completed","conclusion":"failure"}]}`, nil, "failing"},
+		{"cancelled is not a failure", `{"check_runs":[{"status":"completed","conclusion":"cancelled"}]}`, nil, "passing"},
+		{"no runs yet", `{"check_runs":[]}`, nil, "pending"},
+		{"garbage", `not json`, nil, "unknown"},
+		{"api error", ``, errors.New("HTTP 502"), "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &checkRunsClient{data: []byte(tt.json), err: tt.err}
+			if got := commitCI(context.Background(), client, "owner/repo", "abc123", "main", false); got != tt.want {
+				t.Errorf("commitCI = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommitCIRequiredOnly(t *testing.T) {
+	client := &checkRunsClient{
+		data: []byte(`{"check_runs":[
+			{"name":"test","status":"completed","conclusion":"success"},
+			{"name":"codecov/patch","status":"completed","conclusion":"failure"}
+		]}`),
+		// pr-title only runs on pull requests; it mustn't hold the commit
+		// pending.
+		required: map[string]bool{"test": true, "pr-title": true},
+	}
+	if got := commitCI(context.Background(), client, "owner/repo", "abc123", "main", true); got != "passing" {
+		t.Errorf("required checks only = %q, want passing despite the optional failure", got)
+	}
+	if got := commitCI(context.Background(), client, "owner/repo", "abc123", "main", false); got != "failing" {
+		t.Errorf("all checks = %q, want failing", got)
+	}
+}
@@ -9,14 +9,19 @@ import (
 	"log/slog"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/patflynn/klaus/internal/config"
+	"github.com/patflynn/klaus/internal/conflict"
 	"github.com/patflynn/klaus/internal/git"
 	gh "github.com/patflynn/klaus/internal/github"
+	"github.com/patflynn/klaus/internal/pipeline"
 	"github.com/patflynn/klaus/internal/run"
+	"github.com/patflynn/klaus/internal/verify"
 	"github.com/spf13/cobra"
 )
 
@@ -34,9 +39,23 @@ type mergeRunner struct {
 	pollCI              func(string, string) error
 	markMerged          func(prNumber string)
 	resolveRepo         func(prNumber string) string
+	repoDir             func(prNumber string) string // local checkout of the PR's repo
+	defaultBranch       func(prNumber string) string // nil means "main"
 	checkApproval       func(prNumber string) bool
+	requiredChecksOnly  func(repo string) bool // ci_checks policy; nil means required only
 	forceApproval       bool
 	yesFlag             bool
+
+	// Stacked PRs (klaus launch --base). stackStates returns the run states
+	// recording stacks; nil disables stack handling.
+	stackStates    func() []*run.State
+	getPRBase      func(prNumber, repo string) (string, error)
+	retargetPR     func(prNumber, base, repo string) error
+	markRetargeted func(prNumber string)
+
+	// checkTrain validates a merge train (--train): prNumbers merged
+	// together, in order, onto the default branch.
+	checkTrain func(ctx context.Context, prNumbers []string, repo string) error
 }
 
 func newMergeRunner(out io.Writer, in io.Reader, store run.StateStore, repoFlag string) *mergeRunner {
@@ -47,27 +66,93 @@ func newMergeRunner(out io.Writer, in io.Reader, store run.StateStore, repoFlag
 		getPRTitle: func(pr, repo string) string {
 			return gh.NewGHCLIClient(repo).GetTitle(ctx, pr)
 		},
-		getPRCI: func(pr, repo string) string {
-			return gh.NewGHCLIClient(repo).GetCI(ctx, pr)
-		},
 		getPRConflicts: func(pr, repo string) string {
 			return gh.NewGHCLIClient(repo).GetConflicts(ctx, pr)
 		},
 		getPRReviewDecision: func(pr, repo string) string {
 			return gh.NewGHCLIClient(repo).GetReviewDecision(ctx, pr)
 		},
-		rebaseAndPush: rebaseAndPush,
 		mergePR: func(prNumber, mergeMethod string, deleteBranch bool, repo string) error {
 			return gh.NewGHCLIClient(repo).Merge(ctx, prNumber, mergeMethod, deleteBranch)
 		},
-		pollCI:        defaultPollCI,
 		markMerged:    markRunsMerged(store),
 		checkApproval: buildApprovalChecker(store),
+		getPRBase: func(pr, repo string) (string, error) {
+			return gh.NewGHCLIClient(repo).GetBaseBranch(ctx, pr)
+		},
+		retargetPR: func(pr, base, repo string) error {
+			return gh.NewGHCLIClient(repo).SetBaseBranch(ctx, pr, base)
+		},
+		markRetargeted: markRunsRetargeted(store),
+	}
+	if store != nil {
+		r.stackStates = func() []*run.State {
+			states, _ := store.List()
+			return states
+		}
 	}
 	r.resolveRepo = buildRepoResolver(store, repoFlag)
+	r.repoDir = buildRepoDirResolver(store)
+	r.defaultBranch = func(pr string) string {
+		// A backport (klaus backport) merges into its release branch.
+		if r.stackStates != nil {
+			for _, s := range r.stackStates() {
+				if s.BackportOf != nil && s.BaseBranch != nil && s.PRNumber() == pr {
+					return *s.BaseBranch
+				}
+			}
+		}
+		return repoDefaultBranch(r.repoDir(pr))
+	}
+	r.getPRCI = func(pr, repo string) string {
+		return prCI(ctx, gh.NewGHCLIClient(repo), pr, r.checksRequiredOnly(repo))
+	}
+	r.pollCI = func(pr, repo string) error {
+		return defaultPollCI(pr, repo, r.checksRequiredOnly(repo))
+	}
+	r.rebaseAndPush = func(pr, repo string) error {
+		return rebaseAndPush(r.out, pr, repo, r.repoDir(pr), r.defaultBranch(pr))
+	}
+	r.checkTrain = trainChecker("", r.repoDir, r.defaultBranch, r.checksRequiredOnly)
 	return r
 }
 
+// buildRepoDirResolver returns a function that finds the local checkout of
+// a PR's repo: the clone or repo its run was launched from, or else the
+// current git repo.
+func buildRepoDirResolver(store run.StateStore) func(string) string {
+	var states []*run.State
+	if store != nil {
+		states, _ = store.List()
+	}
+
+	return func(prNumber string) string {
+		for _, s := range states {
+			if extractPRNumber(s) != prNumber {
+				continue
+			}
+			if s.CloneDir != nil && *s.CloneDir != "" {
+				return *s.CloneDir
+			}
+			if s.RepoRoot != nil && *s.RepoRoot != "" {
+				return *s.RepoRoot
+			}
+		}
+		root, _ := git.RepoRoot()
+		return root
+	}
+}
+
+// repoDefaultBranch returns the default_branch configured for the checkout
+// at dir, falling back to "main".
+func repoDefaultBranch(dir string) string {
+	cfg, err := config.Load(dir)
+	if err != nil || cfg.DefaultBranch == "" {
+		return "main"
+	}
+	return cfg.DefaultBranch
+}
+
 // buildRepoResolver returns a function that resolves the target repo for a given PR number.
 // Priority: run state pr_url match > --repo flag > session target > "" (existing behavior).
 func buildRepoResolver(store run.StateStore, repoFlag string) func(string) string {
@@ -174,6 +259,29 @@ func markRunsMerged(store run.StateStore) func(string) {
 	}
 }
 
+// markRunsRetargeted returns a function that records a stacked PR as
+// retargeted onto the default branch, so it is no longer held behind its
+// parent.
+func markRunsRetargeted(store run.StateStore) func(string) {
+	return func(prNumber string) {
+		if store == nil {
+			return
+		}
+		states, err := store.List()
+		if err != nil {
+			return
+		}
+		for _, s := range states {
+			if s.BaseBranch != nil && s.PRNumber() == prNumber {
+				s.BaseBranch = nil
+				if err := store.Save(s); err != nil {
+					slog.Warn("failed to save retargeted state", "id", s.ID, "err", err)
+				}
+			}
+		}
+	}
+}
+
 var mergeCmd = &cobra.Command{
 	Use:   "merge <pr1> <pr2> ...",
 	Short: "Merge PRs sequentially with automatic rebasing",
@@ -181,12 +289,28 @@ var mergeCmd = &cobra.Command{
 
 1. Resolves the target repo (from run state, --repo flag, or session target)
 2. Checks merge readiness (CI, conflicts, review approval)
-3. If conflicts exist, rebases onto main and re-pushes
+3. If conflicts exist, rebases onto the default branch, verifies the result
+   builds and passes tests, and re-pushes
 4. Merges with the specified method (default: squash)
 5. Moves to the next PR
 
 If a rebase fails or CI times out, stops and reports the stuck PR.
 
+With --train, PRs merge as a speculative merge train instead: all queued
+PRs are merged together onto the default branch and the combined result is
+validated once, either by CI on a temporary klaus/train-* branch or, with
+--verify, by running a command in the combined checkout. When the train passes, every PR
+merges. When it fails, the train is bisected to find the PR that breaks it;
+that PR is ejected, the PRs ahead of it merge, and the rest form the next
+train. PRs with failing CI or requested changes don't board. A PR refused
+mid-batch because its branch is no longer up to date with the base is
+rebased, and the rest of the batch is validated again.
+
+Stacked PRs (klaus launch --base) merge bottom-up: the list is reordered so
+each PR follows the PR it is stacked on, and a PR whose parent is still open
+(and not in the list) is refused. Before a parent merges, the PRs stacked on
+it are retargeted onto the branch it merges into.
+
 Use --repo to specify the target repository when running outside a git repo
 (e.g. from a klaus session workspace). If PRs were created by klaus agents,
 the repo is auto-detected from run state.`,
@@ -198,10 +322,15 @@ the repo is auto-detected from run state.`,
 		repoFlag, _ := cmd.Flags().GetString("repo")
 		force, _ := cmd.Flags().GetBool("force")
 		yes, _ := cmd.Flags().GetBool("yes")
+		train, _ := cmd.Flags().GetBool("train")
+		verify, _ := cmd.Flags().GetString("verify")
 
 		if err := validateMergeMethod(mergeMethod); err != nil {
 			return err
 		}
+		if verify != "" && !train {
+			return fmt.Errorf("--verify requires --train")
+		}
 
 		// Best-effort: get the session store so we can update run states
 		// after merge. If not in a session, store will be nil and
@@ -211,6 +340,7 @@ the repo is auto-detected from run state.`,
 		runner := newMergeRunner(os.Stdout, os.Stdin, store, repoFlag)
 		runner.forceApproval = force
 		runner.yesFlag = yes
+		runner.checkTrain = trainChecker(verify, runner.repoDir, runner.defaultBranch, runner.checksRequiredOnly)
 
 		// Load config to check require_approval setting
 		repoRoot, _ := git.RepoRoot()
@@ -221,10 +351,21 @@ the repo is auto-detected from run state.`,
 		if !cfg.RequiresApproval() {
 			runner.forceApproval = true // approval not required by config
 		}
+		policy := pipelinePolicyResolver(cfg, slog.Default())
+		runner.requiredChecksOnly = func(repo string) bool {
+			return policy(repo).CIChecks != pipeline.CIChecksAll
+		}
 
 		if dryRun {
 			return runner.dryRun(args)
 		}
+		if train {
+			// Stop the train cleanly on Ctrl-C: the validation commands
+			// are killed, and the temporary worktree and branch removed.
+			ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			return runner.runTrain(ctx, args, mergeMethod, !noDeleteBranch)
+		}
 		return runner.run(args, mergeMethod, !noDeleteBranch)
 	},
 }
@@ -239,24 +380,27 @@ func validateMergeMethod(method string) error {
 }
 
 
-// rebaseAndPush rebases a PR branch onto origin/main, verifies compilation,
-// and force-pushes using a temporary worktree.
-func rebaseAndPush(prNumber string, repo string) error {
+// rebaseAndPush rebases a PR branch onto origin/<base> in a temporary
+// worktree of the checkout at repoRoot, verifies the result and
+// force-pushes. Conflicts in lockfiles and generated files, and ones git
+// rerere has a recorded resolution for, are resolved automatically.
+// Verification runs the repo's verify_commands, or the commands detected
+// for its ecosystem; each result is reported to out.
+func rebaseAndPush(out io.Writer, prNumber, repo, repoRoot, base string) error {
 	ctx := context.TODO()
 	branch, err := gh.NewGHCLIClient(repo).GetBranch(ctx, prNumber)
 	if err != nil {
 		return fmt.Errorf("getting branch: %w", err)
 	}
 
-	repoRoot, err := git.RepoRoot()
-	if err != nil {
-		return fmt.Errorf("could not determine git repository root: %w", err)
+	if repoRoot == "" {
+		return fmt.Errorf("could not determine git repository root")
 	}
 
 	gitClient := git.NewExecClient()
 
-	if err := gitClient.FetchBranch(ctx, repoRoot, "main"); err != nil {
-		return fmt.Errorf("fetching main: %w", err)
+	if err := gitClient.FetchBranch(ctx, repoRoot, base); err != nil {
+		return fmt.Errorf("fetching %s: %w", base, err)
 	}
 	if err := gitClient.FetchBranch(ctx, repoRoot, branch); err != nil {
 		return fmt.Errorf("fetching %s: %w", branch, err)
@@ -280,25 +424,21 @@ func rebaseAndPush(prNumber string, repo string) error {
 		return fmt.Errorf("creating worktree: %w", err)
 	}
 
-	rebaseCmd := exec.Command("git", "rebase", "origin/main")
-	rebaseCmd.Dir = worktreePath
-	var stderr bytes.Buffer
-	rebaseCmd.Stderr = &stderr
-	if err := rebaseCmd.Run(); err != nil {
-		abortCmd := exec.Command("git", "rebase", "--abort")
-		abortCmd.Dir = worktreePath
-		if abortErr := abortCmd.Run(); abortErr != nil {
-			slog.Warn("failed to abort rebase", "pr", prNumber, "worktree", worktreePath, "err", abortErr)
-		}
-		return fmt.Errorf("rebase conflicts: %s", strings.TrimSpace(stderr.String()))
+	// Lockfile, generated-file and previously seen conflicts are resolved
+	// here; anything else still fails the rebase.
+	res, err := conflict.Rebase(ctx, worktreePath, "origin/"+base, conflict.Options{
+		Generate: generateCommands(repoRoot, worktreePath),
+	})
+	for _, r := range res.Resolved {
+		fmt.Fprintf(out, "  Auto-resolved: %s (%s)\n", r.File, r.How)
+	}
+	if err != nil {
+		return fmt.Errorf("rebase conflicts: %w", err)
 	}
 
-	buildCmd := exec.Command("go", "build", "./...")
-	buildCmd.Dir = worktreePath
-	var buildStderr bytes.Buffer
-	buildCmd.Stderr = &buildStderr
-	if err := buildCmd.Run(); err != nil {
-		return fmt.Errorf("build failed after rebase: %s", strings.TrimSpace(buildStderr.String()))
+	commands := verifyCommands(repoRoot, worktreePath)
+	if err := reportVerify(out, worktreePath, commands); err != nil {
+		return fmt.Errorf("verification failed after rebase: %w", err)
 	}
 
 	pushCmd := exec.Command("git", "push", "--force-with-lease")
@@ -312,8 +452,80 @@ func rebaseAndPush(prNumber string, repo string) error {
 	return nil
 }
 
+// generateCommands returns the generate_commands configured for the
+// checkout at repoRoot, or `go generate ./...` for a Go project checked out
+// in dir.
+func generateCommands(repoRoot, dir string) []string {
+	if cfg, err := config.Load(repoRoot); err == nil && len(cfg.GenerateCommands) > 0 {
+		return cfg.GenerateCommands
+	}
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+		return []string{"go generate ./..."}
+	}
+	return nil
+}
+
+// verifyCommands returns the verify_commands configured for the checkout at
+// repoRoot, or the commands detected for the project checked out in dir.
+func verifyCommands(repoRoot, dir string) []string {
+	if cfg, err := config.Load(repoRoot); err == nil && len(cfg.VerifyCommands) > 0 {
+		return cfg.VerifyCommands
+	}
+	return verify.Detect(dir)
+}
+
+// reportVerify runs the verification commands in dir, reporting each
+// result to out, and returns an error naming the first command that
+// failed.
+func reportVerify(out io.Writer, dir string, commands []string) error {
+	if len(commands) == 0 {
+		fmt.Fprintf(out, "  Verify: no build or test commands detected (set verify_commands to add some)\n")
+		return nil
+	}
+	for _, res := range verify.Run(dir, commands) {
+		status := "passed"
+		if !res.Passed {
+			status = "FAILED"
+		}
+		fmt.Fprintf(out, "  Verify: %s %s (%s)\n", res.Command, status, res.Duration.Round(100*time.Millisecond))
+		if !res.Passed {
+			if res.Output != "" {
+				fmt.Fprintf(out, "%s\n", indentLines(tailLines(res.Output, 20), "    "))
+			}
+			return fmt.Errorf("%s: %s", res.Command, verify.LastLine(res.Output))
+		}
+	}
+	return nil
+}
+
+// tailLines returns at most the last n lines of s.
+func tailLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indentLines prefixes every line of s with indent.
+func indentLines(s, indent string) string {
+	return indent + strings.ReplaceAll(s, "\n", "\n"+indent)
+}
+
+// prCI summarizes a PR's CI as passing, failing or pending. With
+// requiredOnly (ci_checks "required"), a failing or pending optional check
+// doesn't count, as in the pipeline. It falls back to GetCI when the checks
+// can't be read.
+func prCI(ctx context.Context, client gh.Client, prNumber string, requiredOnly bool) string {
+	checks, err := client.GetChecks(ctx, prNumber)
+	if err != nil {
+		return client.GetCI(ctx, prNumber)
+	}
+	return gh.SummarizeChecks(checks, requiredOnly)
+}
+
 // defaultPollCI polls CI checks until they pass or timeout.
-func defaultPollCI(prNumber string, repo string) error {
+func defaultPollCI(prNumber string, repo string, requiredOnly bool) error {
 	timeout := 10 * time.Minute
 	interval := 30 * time.Second
 	deadline := time.Now().Add(timeout)
@@ -321,7 +533,7 @@ func defaultPollCI(prNumber string, repo string) error {
 	ctx := context.TODO()
 
 	for {
-		ci := client.GetCI(ctx, prNumber)
+		ci := prCI(ctx, client, prNumber, requiredOnly)
 		switch ci {
 		case "passing":
 			return nil
@@ -346,6 +558,11 @@ func formatRepoLabel(repo string) string {
 // dryRun prints the merge plan without executing.
 func (r *mergeRunner) dryRun(prNumbers []string) error {
 	fmt.Fprintf(r.out, "Merge plan (dry run):\n\n")
+	var states []*run.State
+	if r.stackStates != nil {
+		states = r.stackStates()
+		prNumbers = run.StackOrder(prNumbers, states)
+	}
 	for i, prNum := range prNumbers {
 		repo := r.resolveRepo(prNum)
 		title := r.getPRTitle(prNum, repo)
@@ -358,18 +575,119 @@ func (r *mergeRunner) dryRun(prNumbers []string) error {
 		fmt.Fprintf(r.out, "  %d. PR #%s [%s]: %s\n", i+1, prNum, repoLabel, title)
 		fmt.Fprintf(r.out, "     CI: %s | Conflicts: %s | Review: %s | Merge: %s\n",
 			ci, conflicts, review, status)
+		if parent := run.StackedOn(prNum, states); parent != "" {
+			fmt.Fprintf(r.out, "     Stacked on #%s\n", parent)
+		}
 	}
 	return nil
 }
 
+// checksRequiredOnly reports whether only required checks count as CI for
+// PRs targeting repo.
+func (r *mergeRunner) checksRequiredOnly(repo string) bool {
+	return r.requiredChecksOnly == nil || r.requiredChecksOnly(repo)
+}
+
+// baseBranch returns the default branch of prNumber's repo.
+func (r *mergeRunner) baseBranch(prNumber string) string {
+	if r.defaultBranch == nil {
+		return "main"
+	}
+	return r.defaultBranch(prNumber)
+}
+
+// stackOrder reorders prNumbers so stacked PRs follow their parents,
+// noting the change.
+func (r *mergeRunner) stackOrder(prNumbers []string) []string {
+	if r.stackStates == nil {
+		return prNumbers
+	}
+	ordered := run.StackOrder(prNumbers, r.stackStates())
+	if strings.Join(ordered, ",") != strings.Join(prNumbers, ",") {
+		fmt.Fprintf(r.out, "Merging stacked PRs after their parents: %s\n", formatPRList(ordered))
+	}
+	return ordered
+}
+
+// stackedOn returns the open PR prNumber is still stacked on, if any.
+func (r *mergeRunner) stackedOn(prNumber string) string {
+	if r.stackStates == nil {
+		return ""
+	}
+	return run.StackedOn(prNumber, r.stackStates())
+}
+
+// stackChild is a PR stacked on a PR about to merge, with the branch it
+// targets until then.
+type stackChild struct {
+	pr   string
+	base string
+}
+
+// retargetChildren moves the PRs stacked on prNumber onto the branch
+// prNumber merges into, before it merges: deleting the merged branch must
+// not close them. On error, children already moved are put back.
+func (r *mergeRunner) retargetChildren(prNumber, repo string) ([]stackChild, string, error) {
+	if r.stackStates == nil {
+		return nil, "", nil
+	}
+	states := r.stackStates()
+	var children []stackChild
+	for _, pr := range run.StackChildren(prNumber, states) {
+		c := stackChild{pr: pr}
+		for _, s := range states {
+			if s.BaseBranch != nil && s.PRNumber() == pr {
+				c.base = *s.BaseBranch
+				break
+			}
+		}
+		children = append(children, c)
+	}
+	if len(children) == 0 {
+		return nil, "", nil
+	}
+	base, err := r.getPRBase(prNumber, repo)
+	if err != nil {
+		return nil, "", fmt.Errorf("getting base branch: %w", err)
+	}
+	for i, c := range children {
+		fmt.Fprintf(r.out, "  Retargeting stacked PR #%s onto %s...\n", c.pr, base)
+		if err := r.retargetPR(c.pr, base, r.resolveRepo(c.pr)); err != nil {
+			r.restoreChildren(children[:i])
+			return nil, "", fmt.Errorf("retargeting PR #%s: %w", c.pr, err)
+		}
+	}
+	return children, base, nil
+}
+
+// restoreChildren points retargeted children back at their parent's branch
+// after the parent failed to merge.
+func (r *mergeRunner) restoreChildren(children []stackChild) {
+	for _, c := range children {
+		if c.base == "" {
+			continue
+		}
+		if err := r.retargetPR(c.pr, c.base, r.resolveRepo(c.pr)); err != nil {
+			fmt.Fprintf(r.out, "  Warning: could not restore PR #%s onto %s: %v\n", c.pr, c.base, err)
+		}
+	}
+}
+
 // run merges PRs sequentially.
 func (r *mergeRunner) run(prNumbers []string, mergeMethod string, deleteBranch bool) error {
 	scanner := bufio.NewScanner(r.in)
+	prNumbers = r.stackOrder(prNumbers)
 	for i, prNum := range prNumbers {
 		repo := r.resolveRepo(prNum)
 		repoLabel := formatRepoLabel(repo)
 		fmt.Fprintf(r.out, "\n[%d/%d] PR #%s [%s]\n", i+1, len(prNumbers), prNum, repoLabel)
 
+		// A stacked PR merges into its parent's branch; only merge it once
+		// the parent has merged and it has been retargeted.
+		if parent := r.stackedOn(prNum); parent != "" {
+			return r.stopQueue(prNum, fmt.Sprintf("stacked on open PR #%s; merge it first", parent), prNumbers[i+1:])
+		}
+
 		title := r.getPRTitle(prNum, repo)
 		fmt.Fprintf(r.out, "  Title: %s\n", title)
 
@@ -381,27 +699,12 @@ func (r *mergeRunner) run(prNumbers []string, mergeMethod string, deleteBranch b
 			ci, conflicts, review)
 
 		// Check approval gate
-		if !r.forceApproval && r.checkApproval != nil && !r.checkApproval(prNum) {
-			if r.yesFlag {
-				fmt.Fprintf(r.out, "  Skipping PR #%s: not approved\n", prNum)
-				continue
-			}
-			// Interactive prompt
-			fmt.Fprintf(r.out, "  PR #%s is not approved. Approve and merge? [y/n/s(kip)] ", prNum)
-			if scanner.Scan() {
-				answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
-				switch answer {
-				case "y", "yes":
-					// Continue with merge
-				case "s", "skip":
-					fmt.Fprintf(r.out, "  Skipped PR #%s\n", prNum)
-					continue
-				default:
-					return r.stopQueue(prNum, "not approved", prNumbers[i+1:])
-				}
-			} else {
-				return r.stopQueue(prNum, "merge not confirmed", prNumbers[i+1:])
-			}
+		skip, stop := r.approvalGate(prNum, scanner)
+		if stop != "" {
+			return r.stopQueue(prNum, stop, prNumbers[i+1:])
+		}
+		if skip {
+			continue
 		}
 
 		// Unfixable blocker: changes requested
@@ -411,7 +714,7 @@ func (r *mergeRunner) run(prNumbers []string, mergeMethod string, deleteBranch b
 
 		// Handle conflicts via rebase
 		if conflicts == "yes" {
-			fmt.Fprintf(r.out, "  Rebasing onto main...\n")
+			fmt.Fprintf(r.out, "  Rebasing onto %s...\n", r.baseBranch(prNum))
 			if err := r.rebaseAndPush(prNum, repo); err != nil {
 				return r.stopQueue(prNum, fmt.Sprintf("rebase failed: %v", err), prNumbers[i+1:])
 			}
@@ -430,13 +733,8 @@ func (r *mergeRunner) run(prNumbers []string, mergeMethod string, deleteBranch b
 			}
 		}
 
-		fmt.Fprintf(r.out, "  Merging (%s)...\n", mergeMethod)
-		if err := r.mergePR(prNum, mergeMethod, deleteBranch, repo); err != nil {
-			return r.stopQueue(prNum, fmt.Sprintf("merge failed: %v", err), prNumbers[i+1:])
-		}
-		fmt.Fprintf(r.out, "  Merged PR #%s.\n", prNum)
-		if r.markMerged != nil {
-			r.markMerged(prNum)
+		if reason := r.mergeOne(prNum, repo, mergeMethod, deleteBranch); reason != "" {
+			return r.stopQueue(prNum, reason, prNumbers[i+1:])
 		}
 	}
 
@@ -444,6 +742,60 @@ func (r *mergeRunner) run(prNumbers []string, mergeMethod string, deleteBranch b
 	return nil
 }
 
+// approvalGate applies the approval check to prNum, prompting unless --yes
+// was given. It reports whether to skip the PR, or a reason to stop the
+// queue.
+func (r *mergeRunner) approvalGate(prNum string, scanner *bufio.Scanner) (skip bool, stop string) {
+	if r.forceApproval || r.checkApproval == nil || r.checkApproval(prNum) {
+		return false, ""
+	}
+	if r.yesFlag {
+		fmt.Fprintf(r.out, "  Skipping PR #%s: not approved\n", prNum)
+		return true, ""
+	}
+	// Interactive prompt
+	fmt.Fprintf(r.out, "  PR #%s is not approved. Approve and merge? [y/n/s(kip)] ", prNum)
+	if !scanner.Scan() {
+		return false, "merge not confirmed"
+	}
+	answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+	switch answer {
+	case "y", "yes":
+		return false, ""
+	case "s", "skip":
+		fmt.Fprintf(r.out, "  Skipped PR #%s\n", prNum)
+		return true, ""
+	default:
+		return false, "not approved"
+	}
+}
+
+// mergeOne merges prNum, first retargeting the PRs stacked on it. It
+// returns a reason to stop the queue on failure.
+func (r *mergeRunner) mergeOne(prNum, repo, mergeMethod string, deleteBranch bool) string {
+	children, base, err := r.retargetChildren(prNum, repo)
+	if err != nil {
+		return fmt.Sprintf("stacked PRs: %v", err)
+	}
+
+	fmt.Fprintf(r.out, "  Merging (%s)...\n", mergeMethod)
+	if err := r.mergePR(prNum, mergeMethod, deleteBranch, repo); err != nil {
+		r.restoreChildren(children)
+		return fmt.Sprintf("merge failed: %v", err)
+	}
+	fmt.Fprintf(r.out, "  Merged PR #%s.\n", prNum)
+	if r.markMerged != nil {
+		r.markMerged(prNum)
+	}
+	for _, c := range children {
+		if r.markRetargeted != nil {
+			r.markRetargeted(c.pr)
+		}
+		fmt.Fprintf(r.out, "  PR #%s now targets %s.\n", c.pr, base)
+	}
+	return ""
+}
+
 // stopQueue reports which PR is stuck and lists remaining unmerged PRs.
 func (r *mergeRunner) stopQueue(stuckPR, reason string, remaining []string) error {
 	fmt.Fprintf(r.out, "\nStopped: PR #%s — %s\n", stuckPR, reason)
@@ -469,5 +821,7 @@ func init() {
 	mergeCmd.Flags().String("repo", "", "Default target repo (owner/repo) for all PRs")
 	mergeCmd.Flags().Bool("force", false, "Bypass approval check")
 	mergeCmd.Flags().BoolP("yes", "y", false, "Skip interactive prompts (skips unapproved PRs with a warning)")
+	mergeCmd.Flags().Bool("train", false, "Validate the PRs together as a speculative merge train and merge them as a batch")
+	mergeCmd.Flags().String("verify", "", "With --train, validate the combined train by running this command locally instead of CI")
 	rootCmd.AddCommand(mergeCmd)
 }
@@ -2,7 +2,11 @@ package cmd
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -821,3 +825,216 @@ func TestMergeEOFStopsQueue(t *testing.T) {
 		t.Errorf("error should mention 'merge not confirmed': %v", err)
 	}
 }
+
+// stackedMergeRunner returns a test runner over a parent PR #1 and PR #2
+// stacked on it, recording merges and retargets in calls.
+func stackedMergeRunner(buf *bytes.Buffer, calls *[]string) (*mergeRunner, []*run.State) {
+	runner := testMergeRunner(buf)
+	parentURL, childURL := "https://github.com/o/r/pull/1", "https://github.com/o/r/pull/2"
+	parentBranch, parentRun := "agent/a", "a"
+	states := []*run.State{
+		{ID: "b", PRURL: &childURL, ParentRunID: &parentRun, BaseBranch: &parentBranch},
+		{ID: "a", Branch: parentBranch, PRURL: &parentURL},
+	}
+	runner.stackStates = func() []*run.State { return states }
+	runner.getPRBase = func(pr, repo string) (string, error) { return "main", nil }
+	runner.retargetPR = func(pr, base, repo string) error {
+		*calls = append(*calls, "retarget "+pr+" "+base)
+		return nil
+	}
+	runner.markRetargeted = func(pr string) {
+		for _, s := range states {
+			if s.PRNumber() == pr {
+				s.BaseBranch = nil
+			}
+		}
+	}
+	runner.mergePR = func(pr, method string, del bool, repo string) error {
+		*calls = append(*calls, "merge "+pr)
+		return nil
+	}
+	return runner, states
+}
+
+func TestMergeStackedPRsInStackOrder(t *testing.T) {
+	var buf bytes.Buffer
+	var calls []string
+	runner, _ := stackedMergeRunner(&buf, &calls)
+
+	if err := runner.run([]string{"2", "1"}, "squash", true); err != nil {
+		t.Fatalf("run() error = %v\n%s", err, buf.String())
+	}
+	// The child is retargeted before its parent's branch is merged and
+	// deleted, then merges itself.
+	want := []string{"retarget 2 main", "merge 1", "merge 2"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if !strings.Contains(buf.String(), "Merging stacked PRs after their parents: #1, #2") {
+		t.Errorf("output should note the reorder:\n%s", buf.String())
+	}
+}
+
+func TestMergeStackedPRRefusedWhileParentOpen(t *testing.T) {
+	var buf bytes.Buffer
+	var calls []string
+	runner, _ := stackedMergeRunner(&buf, &calls)
+
+	err := runner.run([]string{"2"}, "squash", true)
+	if err == nil || !strings.Contains(err.Error(), "stacked on open PR #1") {
+		t.Fatalf("err = %v, want stacked-on refusal", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("calls = %v, want none", calls)
+	}
+}
+
+func TestMergeStackedParentFailureRestoresChild(t *testing.T) {
+	var buf bytes.Buffer
+	var calls []string
+	runner, states := stackedMergeRunner(&buf, &calls)
+	runner.mergePR = func(pr, method string, del bool, repo string) error {
+		calls = append(calls, "merge "+pr)
+		return fmt.Errorf("not mergeable")
+	}
+
+	if err := runner.run([]string{"1"}, "squash", true); err == nil {
+		t.Fatal("expected merge failure")
+	}
+	want := []string{"retarget 2 main", "merge 1", "retarget 2 agent/a"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if states[0].BaseBranch == nil {
+		t.Error("child should still be recorded as stacked")
+	}
+}
+
+func TestRunRebasesOntoConfiguredDefaultBranch(t *testing.T) {
+	var buf bytes.Buffer
+	runner := testMergeRunner(&buf)
+	runner.getPRConflicts = func(string, string) string { return "yes" }
+	runner.defaultBranch = func(string) string { return "develop" }
+
+	if err := runner.run([]string{"1"}, "squash", true); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "Rebasing onto develop") {
+		t.Errorf("should rebase onto the configured default branch:\n%s", buf.String())
+	}
+}
+
+func TestRepoDefaultBranch(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	if got := repoDefaultBranch(dir); got != "main" {
+		t.Errorf("repoDefaultBranch() = %q, want main", got)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, ".klaus"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".klaus", "config.json"), []byte(`{"default_branch": "trunk"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := repoDefaultBranch(dir); got != "trunk" {
+		t.Errorf("repoDefaultBranch() = %q, want trunk", got)
+	}
+}
+
+func TestGenerateCommands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	if got := generateCommands(dir, dir); got != nil {
+		t.Errorf("generateCommands() = %v, want none", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := generateCommands(dir, dir); !reflect.DeepEqual(got, []string{"go generate ./..."}) {
+		t.Errorf("generateCommands() = %v, want go generate", got)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, ".klaus"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".klaus", "config.json"), []byte(`{"generate_commands": ["make gen"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := generateCommands(dir, dir); !reflect.DeepEqual(got, []string{"make gen"}) {
+		t.Errorf("generateCommands() = %v, want configured", got)
+	}
+}
+
+func TestBuildRepoDirResolverPrefersRunClone(t *testing.T) {
+	t.Setenv("KLAUS_SESSION_ID", "")
+	store := run.NewHomeDirStoreFromPath(t.TempDir())
+	if err := store.EnsureDirs(); err != nil {
+		t.Fatalf("EnsureDirs: %v", err)
+	}
+	prURL := "https://github.com/acme/widgets/pull/42"
+	cloneDir := "/tmp/klaus-sessions/.repos/acme/widgets"
+	if err := store.Save(&run.State{ID: "20260101-0000-aaaa", PRURL: &prURL, CloneDir: &cloneDir}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if got := buildRepoDirResolver(store)("42"); got != cloneDir {
+		t.Errorf("repoDir(42) = %q, want %q", got, cloneDir)
+	}
+}
+
+func TestReportVerify(t *testing.T) {
+	var buf bytes.Buffer
+	err := reportVerify(&buf, t.TempDir(), []string{"true", "echo 'undefined: foo' >&2; exit 2", "true"})
+	if err == nil || !strings.Contains(err.Error(), "undefined: foo") {
+		t.Fatalf("err = %v, want failing command output", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Verify: true passed", "FAILED", "    undefined: foo"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := reportVerify(&buf, t.TempDir(), nil); err != nil {
+		t.Fatalf("reportVerify(nil) = %v", err)
+	}
+	if !strings.Contains(buf.String(), "no build or test commands detected") {
+		t.Errorf("should note that nothing was verified:\n%s", buf.String())
+	}
+}
+
+// checksClient reports a PR's checks, or fails to and falls back on ci.
+type checksClient struct {
+	gh.Client
+	checks []gh.CheckRun
+	err    error
+	ci     string
+}
+
+func (c *checksClient) GetChecks(context.Context, string) ([]gh.CheckRun, error) {
+	return c.checks, c.err
+}
+
+func (c *checksClient) GetCI(context.Context, string) string { return c.ci }
+
+func TestPRCIIgnoresOptionalChecks(t *testing.T) {
+	client := &checksClient{checks: []gh.CheckRun{
+		{Name: "test", State: "passing", Required: true},
+		{Name: "codecov/patch", State: "failing"},
+		{Name: "preview", State: "pending"},
+	}}
+	if got := prCI(context.Background(), client, "42", true); got != "passing" {
+		t.Errorf("required only = %q, want passing", got)
+	}
+	if got := prCI(context.Background(), client, "42", false); got != "failing" {
+		t.Errorf("all checks = %q, want failing", got)
+	}
+
+	fallback := &checksClient{err: errors.New("no access"), ci: "pending"}
+	if got := prCI(context.Background(), fallback, "42", true); got != "pending" {
+		t.Errorf("without checks = %q, want GetCI's pending", got)
+	}
+}
@@ -0,0 +1,372 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/patflynn/klaus/internal/git"
+	gh "github.com/patflynn/klaus/internal/github"
+	"github.com/patflynn/klaus/internal/run"
+	"github.com/patflynn/klaus/internal/verify"
+)
+
+// trainConflictError reports a PR whose branch doesn't merge cleanly on top
+// of the default branch and the PRs ahead of it in a merge train.
+type trainConflictError struct {
+	pr     string
+	detail string
+}
+
+func (e *trainConflictError) Error() string {
+	return fmt.Sprintf("PR #%s conflicts with the train: %s", e.pr, e.detail)
+}
+
+// trainEjection is a PR taken out of a merge train, and why.
+type trainEjection struct {
+	pr     string
+	reason string
+}
+
+// runTrain merges PRs as a speculative merge train: the queued PRs are
+// merged together onto the default branch, the combined result is validated
+// once, and the whole batch merges when it passes. When it fails, the train
+// is bisected to find the first PR that breaks it; that PR is ejected, the
+// PRs ahead of it merge, and the rest form the next train. When the
+// repository requires branches to be up to date, a PR refused mid-batch
+// because the PRs ahead of it moved the base is rebased, and the rest of the
+// batch is validated again as the next train.
+func (r *mergeRunner) runTrain(ctx context.Context, prNumbers []string, mergeMethod string, deleteBranch bool) error {
+	scanner := bufio.NewScanner(r.in)
+	prNumbers = r.stackOrder(prNumbers)
+
+	// The train is combined in one clone, so every PR must share a repo.
+	repo := r.resolveRepo(prNumbers[0])
+	for _, prNum := range prNumbers[1:] {
+		if other := r.resolveRepo(prNum); other != repo {
+			return fmt.Errorf("merge train PRs must share a repo: PR #%s is in %s, PR #%s in %s",
+				prNumbers[0], formatRepoLabel(repo), prNum, formatRepoLabel(other))
+		}
+	}
+	fmt.Fprintf(r.out, "Merge train [%s]: %s\n", formatRepoLabel(repo), formatPRList(prNumbers))
+
+	var queue []string
+	var ejected []trainEjection
+	for _, prNum := range prNumbers {
+		fmt.Fprintf(r.out, "\nPR #%s\n", prNum)
+
+		// A stacked PR can ride the train behind its parent, but not
+		// without it.
+		if parent := r.stackedOn(prNum); parent != "" && !slices.Contains(queue, parent) {
+			ejected = append(ejected, trainEjection{prNum, fmt.Sprintf("stacked on open PR #%s", parent)})
+			fmt.Fprintf(r.out, "  Ejected: stacked on open PR #%s\n", parent)
+			continue
+		}
+
+		fmt.Fprintf(r.out, "  Title: %s\n", r.getPRTitle(prNum, repo))
+		ci := r.getPRCI(prNum, repo)
+		review := r.getPRReviewDecision(prNum, repo)
+		fmt.Fprintf(r.out, "  CI: %s | Review: %s\n", ci, review)
+
+		skip, stop := r.approvalGate(prNum, scanner)
+		if stop != "" {
+			return r.stopQueue(prNum, stop, nil)
+		}
+		if skip {
+			continue
+		}
+
+		// CI still running on the PR itself is fine: the train's own
+		// validation covers it. Known failures don't board.
+		var reason string
+		switch {
+		case strings.EqualFold(review, "CHANGES_REQUESTED"):
+			reason = "changes requested in review"
+		case ci == "failing":
+			reason = "CI is failing"
+		}
+		if reason != "" {
+			ejected = append(ejected, trainEjection{prNum, reason})
+			fmt.Fprintf(r.out, "  Ejected: %s\n", reason)
+			continue
+		}
+		queue = append(queue, prNum)
+	}
+
+	merged := 0
+	rebased := make(map[string]bool)
+	for len(queue) > 0 {
+		fmt.Fprintf(r.out, "\nValidating train: %s\n", formatPRList(queue))
+		good := len(queue)
+		if err := r.checkTrain(ctx, queue, repo); err != nil {
+			fmt.Fprintf(r.out, "  Train failed: %v\n", err)
+			if ctx.Err() != nil {
+				r.reportEjected(ejected)
+				return r.stopQueue(queue[0], "interrupted", queue[1:])
+			}
+			var culprit int
+			var reason string
+			culprit, good, reason = r.bisectTrain(ctx, queue, repo, err)
+			if ctx.Err() != nil {
+				r.reportEjected(ejected)
+				return r.stopQueue(queue[0], "interrupted", queue[1:])
+			}
+			var out []trainEjection
+			queue, out = r.ejectFromTrain(queue, queue[culprit], reason)
+			ejected = append(ejected, out...)
+			for _, e := range out {
+				fmt.Fprintf(r.out, "  Ejected PR #%s: %s\n", e.pr, e.reason)
+			}
+		} else {
+			fmt.Fprintf(r.out, "  Train passed.\n")
+		}
+
+		// queue[:good] is validated: merge it.
+		rest := queue[good:]
+		for i, prNum := range queue[:good] {
+			fmt.Fprintf(r.out, "\nPR #%s\n", prNum)
+			reason := r.mergeOne(prNum, repo, mergeMethod, deleteBranch)
+			if reason == "" {
+				merged++
+				continue
+			}
+			if !behindBase(reason) || rebased[prNum] {
+				r.reportEjected(ejected)
+				return r.stopQueue(prNum, reason, queue[i+1:])
+			}
+
+			// The PRs ahead of it moved the base, and the repository
+			// requires branches to be up to date. The validated train no
+			// longer matches what would merge: rebase and validate the
+			// rest again.
+			rebased[prNum] = true
+			rest = queue[i:]
+			fmt.Fprintf(r.out, "  Branch is behind %s; rebasing...\n", r.baseBranch(prNum))
+			if err := r.rebaseAndPush(prNum, repo); err != nil {
+				var out []trainEjection
+				rest, out = r.ejectFromTrain(rest, prNum, fmt.Sprintf("rebase failed: %v", err))
+				ejected = append(ejected, out...)
+				for _, e := range out {
+					fmt.Fprintf(r.out, "  Ejected PR #%s: %s\n", e.pr, e.reason)
+				}
+			}
+			break
+		}
+		queue = rest
+	}
+
+	r.reportEjected(ejected)
+	if len(ejected) > 0 {
+		fmt.Fprintf(r.out, "\nMerged %d of %d PRs.\n", merged, len(prNumbers))
+		return fmt.Errorf("%d PR(s) ejected from the merge train", len(ejected))
+	}
+	fmt.Fprintf(r.out, "\nAll %d PRs merged successfully.\n", len(prNumbers))
+	return nil
+}
+
+// bisectTrain finds the PR that broke a failed train: the last PR of the
+// shortest failing prefix, or the PR named by a conflict. It returns the
+// culprit's index, how many PRs at the front of queue are known to pass
+// together, and why the culprit was ejected.
+func (r *mergeRunner) bisectTrain(ctx context.Context, queue []string, repo string, err error) (culprit, good int, reason string) {
+	var conflict *trainConflictError
+	if errors.As(err, &conflict) {
+		if i := slices.Index(queue, conflict.pr); i >= 0 {
+			return i, 0, "conflicts: " + conflict.detail
+		}
+	}
+
+	// queue[:lo] passes (the default branch alone is assumed to) and
+	// queue[:hi] fails.
+	lo, hi := 0, len(queue)
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		fmt.Fprintf(r.out, "  Bisecting: validating %s\n", formatPRList(queue[:mid]))
+		probeErr := r.checkTrain(ctx, queue[:mid], repo)
+		if probeErr == nil {
+			lo = mid
+			continue
+		}
+		if errors.As(probeErr, &conflict) {
+			if i := slices.Index(queue, conflict.pr); i >= 0 {
+				return i, min(lo, i), "conflicts: " + conflict.detail
+			}
+		}
+		hi, err = mid, probeErr
+	}
+	return hi - 1, lo, fmt.Sprintf("breaks the train: %v", err)
+}
+
+// behindBase reports whether a merge failure reason is GitHub refusing a PR
+// whose branch is behind its base, under branch protection that requires
+// branches to be up to date.
+func behindBase(reason string) bool {
+	return strings.Contains(strings.ToLower(reason), "not up to date")
+}
+
+// ejectFromTrain removes prNum from queue, along with any PRs stacked on
+// it, whose branches carry its commits.
+func (r *mergeRunner) ejectFromTrain(queue []string, prNum, reason string) ([]string, []trainEjection) {
+	out := map[string]string{prNum: reason}
+	if r.stackStates != nil {
+		states := r.stackStates()
+		pending := []string{prNum}
+		for len(pending) > 0 {
+			parent := pending[0]
+			pending = pending[1:]
+			for _, child := range run.StackChildren(parent, states) {
+				if _, ok := out[child]; !ok {
+					out[child] = fmt.Sprintf("stacked on ejected PR #%s", parent)
+					pending = append(pending, child)
+				}
+			}
+		}
+	}
+
+	var kept []string
+	var ejected []trainEjection
+	for _, pr := range queue {
+		if reason, ok := out[pr]; ok {
+			ejected = append(ejected, trainEjection{pr, reason})
+			continue
+		}
+		kept = append(kept, pr)
+	}
+	return kept, ejected
+}
+
+// reportEjected lists the PRs taken out of the train.
+func (r *mergeRunner) reportEjected(ejected []trainEjection) {
+	if len(ejected) == 0 {
+		return
+	}
+	fmt.Fprintf(r.out, "\nEjected from the train:\n")
+	for _, e := range ejected {
+		fmt.Fprintf(r.out, "  PR #%s: %s\n", e.pr, e.reason)
+	}
+}
+
+// trainChecker returns the merge train validator. With a verify command the
+// combined train is checked locally by running it; otherwise the train is
+// pushed to a temporary branch and validated by CI, counting the checks
+// requiredOnly selects for the repo. repoDir and defaultBranch locate the
+// train's checkout and base from its first PR.
+func trainChecker(verifyCmd string, repoDir, defaultBranch func(string) string, requiredOnly func(string) bool) func(context.Context, []string, string) error {
+	return func(ctx context.Context, prNumbers []string, repo string) error {
+		return checkTrain(ctx, prNumbers, repo, repoDir(prNumbers[0]), defaultBranch(prNumbers[0]), verifyCmd, requiredOnly(repo))
+	}
+}
+
+// trainCITimeout bounds how long CI on a train branch may take.
+const trainCITimeout = 20 * time.Minute
+
+// checkTrain merges prNumbers, in order, onto origin/<base> in a temporary
+// worktree of the checkout at repoRoot and validates the result.
+func checkTrain(ctx context.Context, prNumbers []string, repo, repoRoot, base, verifyCmd string, requiredOnly bool) error {
+	if repoRoot == "" {
+		return fmt.Errorf("could not determine git repository root")
+	}
+	gitClient := git.NewExecClient()
+	if err := gitClient.FetchBranch(ctx, repoRoot, base); err != nil {
+		return fmt.Errorf("fetching %s: %w", base, err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "klaus-train-*")
+	if err != nil {
+		return fmt.Errorf("creating temp dir: %w", err)
+	}
+	worktreePath := filepath.Join(tmpDir, "train")
+	defer func() {
+		// Clean up even when interrupted.
+		if err := gitClient.WorktreeRemove(context.WithoutCancel(ctx), repoRoot, worktreePath); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to remove worktree: %v\n", err)
+		}
+		if err := os.RemoveAll(tmpDir); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to remove temp directory: %v\n", err)
+		}
+	}()
+
+	if out, err := verify.Exec(ctx, repoRoot, "git", "worktree", "add", "--detach", worktreePath, "origin/"+base); err != nil {
+		return fmt.Errorf("creating worktree: %s", out)
+	}
+
+	client := gh.NewGHCLIClient(repo)
+	for _, prNum := range prNumbers {
+		branch, err := client.GetBranch(ctx, prNum)
+		if err != nil {
+			return fmt.Errorf("getting branch of PR #%s: %w", prNum, err)
+		}
+		if err := gitClient.FetchBranch(ctx, repoRoot, branch); err != nil {
+			return fmt.Errorf("fetching %s: %w", branch, err)
+		}
+		msg := fmt.Sprintf("Merge train: PR #%s", prNum)
+		if out, err := verify.Exec(ctx, worktreePath, "git", "merge", "--no-ff", "-m", msg, "origin/"+branch); err != nil {
+			if _, abortErr := verify.Exec(context.WithoutCancel(ctx), worktreePath, "git", "merge", "--abort"); abortErr != nil {
+				slog.Warn("failed to abort train merge", "pr", prNum, "worktree", worktreePath, "err", abortErr)
+			}
+			return &trainConflictError{pr: prNum, detail: verify.LastLine(out)}
+		}
+	}
+
+	if verifyCmd != "" {
+		if out, err := verify.Exec(ctx, worktreePath, "sh", "-c", verifyCmd); err != nil {
+			return fmt.Errorf("verify command failed: %s", verify.LastLine(out))
+		}
+		return nil
+	}
+	return trainCI(ctx, worktreePath, repo, base, requiredOnly)
+}
+
+// trainCI pushes the train in dir to a temporary branch and waits for CI on
+// it, judged by the checks base requires when requiredOnly. The branch is
+// deleted afterwards.
+func trainCI(ctx context.Context, dir, repo, base string, requiredOnly bool) error {
+	sha, err := verify.Exec(ctx, dir, "git", "rev-parse", "HEAD")
+	if err != nil {
+		return fmt.Errorf("resolving train head: %s", sha)
+	}
+	client := gh.NewGHCLIClient(repo)
+	slug := repo
+	if slug == "" {
+		owner, name, err := client.GetRepoOwnerAndName(ctx)
+		if err != nil {
+			return fmt.Errorf("resolving repo: %w", err)
+		}
+		slug = owner + "/" + name
+	}
+
+	branch := "klaus/train-" + time.Now().UTC().Format("20060102-150405")
+	if out, err := verify.Exec(ctx, dir, "git", "push", "origin", "HEAD:refs/heads/"+branch); err != nil {
+		return fmt.Errorf("pushing %s: %s", branch, verify.LastLine(out))
+	}
+	defer func() {
+		if out, err := verify.Exec(context.WithoutCancel(ctx), dir, "git", "push", "origin", "--delete", branch); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to delete %s: %s\n", branch, verify.LastLine(out))
+		}
+	}()
+
+	deadline := time.Now().Add(trainCITimeout)
+	for {
+		switch commitCI(ctx, client, slug, sha, base, requiredOnly) {
+		case "passing":
+			return nil
+		case "failing":
+			return fmt.Errorf("CI failed on %s", branch)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("CI on %s timed out after %v (does CI run on branch pushes? try --verify)", branch, trainCITimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(30 * time.Second):
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// trainMergeRunner returns a test runner whose train validation fails
+// whenever a PR in broken rides the train, recording every validated train
+// and merge in calls.
+func trainMergeRunner(buf *bytes.Buffer, calls *[]string, broken ...string) *mergeRunner {
+	runner := testMergeRunner(buf)
+	runner.checkTrain = func(ctx context.Context, prs []string, repo string) error {
+		*calls = append(*calls, "check "+strings.Join(prs, ","))
+		for _, pr := range prs {
+			if slices.Contains(broken, pr) {
+				return fmt.Errorf("tests failed")
+			}
+		}
+		return nil
+	}
+	runner.mergePR = func(pr, method string, del bool, repo string) error {
+		*calls = append(*calls, "merge "+pr)
+		return nil
+	}
+	return runner
+}
+
+func TestMergeTrainMergesPassingBatch(t *testing.T) {
+	var buf bytes.Buffer
+	var calls []string
+	runner := trainMergeRunner(&buf, &calls)
+
+	if err := runner.runTrain(context.Background(), []string{"1", "2", "3"}, "squash", true); err != nil {
+		t.Fatalf("runTrain() error = %v\n%s", err, buf.String())
+	}
+	want := []string{"check 1,2,3", "merge 1", "merge 2", "merge 3"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestMergeTrainBisectsAndEjectsCulprit(t *testing.T) {
+	var buf bytes.Buffer
+	var calls []string
+	runner := trainMergeRunner(&buf, &calls, "3")
+
+	err := runner.runTrain(context.Background(), []string{"1", "2", "3", "4"}, "squash", true)
+	if err == nil || !strings.Contains(err.Error(), "1 PR(s) ejected") {
+		t.Fatalf("err = %v, want one ejection", err)
+	}
+	// #1,#2 pass and #1,#2,#3 fails, so #3 is ejected and #1,#2 merge
+	// before #4 forms the next train.
+	want := []string{
+		"check 1,2,3,4", "check 1,2", "check 1,2,3",
+		"merge 1", "merge 2",
+		"check 4", "merge 4",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if !strings.Contains(buf.String(), "PR #3: breaks the train: tests failed") {
+		t.Errorf("output should report the ejection:\n%s", buf.String())
+	}
+}
+
+func TestMergeTrainConflictEjectsWithoutBisecting(t *testing.T) {
+	var buf bytes.Buffer
+	var calls []string
+	runner := trainMergeRunner(&buf, &calls)
+	runner.checkTrain = func(ctx context.Context, prs []string, repo string) error {
+		calls = append(calls, "check "+strings.Join(prs, ","))
+		if slices.Contains(prs, "2") {
+			return &trainConflictError{pr: "2", detail: "CONFLICT (content): Merge conflict in go.mod"}
+		}
+		return nil
+	}
+
+	if err := runner.runTrain(context.Background(), []string{"1", "2", "3"}, "squash", true); err == nil {
+		t.Fatal("expected an ejection error")
+	}
+	want := []string{"check 1,2,3", "check 1,3", "merge 1", "merge 3"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestMergeTrainEjectsFailingCIBeforeBoarding(t *testing.T) {
+	var buf bytes.Buffer
+	var calls []string
+	runner := trainMergeRunner(&buf, &calls)
+	runner.getPRCI = func(pr, repo string) string {
+		if pr == "2" {
+			return "failing"
+		}
+		return "pending"
+	}
+
+	if err := runner.runTrain(context.Background(), []string{"1", "2", "3"}, "squash", true); err == nil {
+		t.Fatal("expected an ejection error")
+	}
+	want := []string{"check 1,3", "merge 1", "merge 3"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestMergeTrainEjectsChildrenOfCulprit(t *testing.T) {
+	var buf bytes.Buffer
+	var calls []string
+	runner, _ := stackedMergeRunner(&buf, &calls)
+	stacked := runner.mergePR
+	runner.checkTrain = trainMergeRunner(&buf, &calls, "1").checkTrain
+	runner.mergePR = stacked
+
+	if err := runner.runTrain(context.Background(), []string{"2", "1"}, "squash", true); err == nil {
+		t.Fatal("expected an ejection error")
+	}
+	want := []string{"check 1,2", "check 1"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if !strings.Contains(buf.String(), "PR #2: stacked on ejected PR #1") {
+		t.Errorf("output should eject the stacked child:\n%s", buf.String())
+	}
+}
+
+func TestMergeTrainRequiresOneRepo(t *testing.T) {
+	var buf bytes.Buffer
+	runner := testMergeRunner(&buf)
+	runner.resolveRepo = func(pr string) string { return "o/repo" + pr }
+
+	err := runner.runTrain(context.Background(), []string{"1", "2"}, "squash", true)
+	if err == nil || !strings.Contains(err.Error(), "must share a repo") {
+		t.Fatalf("err = %v, want repo mismatch", err)
+	}
+}
+
+func TestMergeTrainRebasesPRBehindBase(t *testing.T) {
+	var buf bytes.Buffer
+	var calls []string
+	runner := trainMergeRunner(&buf, &calls)
+	// The repo requires up-to-date branches: once #1 merges, #2 is
+	// refused until it's rebased.
+	runner.mergePR = func(pr, method string, del bool, repo string) error {
+		calls = append(calls, "merge "+pr)
+		if pr == "2" && !slices.Contains(calls, "rebase 2") {
+			return fmt.Errorf("gh pr merge: exit status 1: the head branch is not up to date with the base branch")
+		}
+		return nil
+	}
+	runner.rebaseAndPush = func(pr, repo string) error {
+		calls = append(calls, "rebase "+pr)
+		return nil
+	}
+
+	if err := runner.runTrain(context.Background(), []string{"1", "2", "3"}, "squash", true); err != nil {
+		t.Fatalf("runTrain() error = %v\n%s", err, buf.String())
+	}
+	want := []string{
+		"check 1,2,3", "merge 1", "merge 2",
+		"rebase 2", "check 2,3", "merge 2", "merge 3",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestMergeTrainStopsWhenStillBehindBase(t *testing.T) {
+	var buf bytes.Buffer
+	var calls []string
+	runner := trainMergeRunner(&buf, &calls)
+	runner.mergePR = func(pr, method string, del bool, repo string) error {
+		calls = append(calls, "merge "+pr)
+		if pr == "2" {
+			return fmt.Errorf("gh pr merge: exit status 1: the head branch is not up to date with the base branch")
+		}
+		return nil
+	}
+
+	err := runner.runTrain(context.Background(), []string{"1", "2", "3"}, "squash", true)
+	if err == nil || !strings.Contains(err.Error(), "PR #2") {
+		t.Fatalf("err = %v, want PR #2 to stop the train", err)
+	}
+	want := []string{"check 1,2,3", "merge 1", "merge 2", "check 2,3", "merge 2"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if !strings.Contains(buf.String(), "Remaining PRs: #3") {
+		t.Errorf("output should list the unmerged PRs:\n%s", buf.String())
+	}
+}
+
+func TestMergeTrainStopsWhenInterrupted(t *testing.T) {
+	var buf bytes.Buffer
+	var calls []string
+	runner := trainMergeRunner(&buf, &calls)
+	ctx, cancel := context.WithCancel(context.Background())
+	runner.checkTrain = func(ctx context.Context, prs []string, repo string) error {
+		calls = append(calls, "check "+strings.Join(prs, ","))
+		cancel()
+		return ctx.Err()
+	}
+
+	err := runner.runTrain(ctx, []string{"1", "2"}, "squash", true)
+	if err == nil || !strings.Contains(err.Error(), "interrupted") {
+		t.Fatalf("err = %v, want interrupted", err)
+	}
+	if want := []string{"check 1,2"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
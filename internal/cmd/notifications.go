@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/patflynn/klaus/internal/event"
+	"github.com/patflynn/klaus/internal/pipeline"
 	"github.com/spf13/cobra"
 )
 
@@ -73,6 +74,14 @@ func printSummary(events []event.Event) {
 		ciFailed      []string
 		ciPassed      []string
 		prMerged      []string
+		transitions   int
+		stalled       []string
+		mainBroken    []string
+		budgetExceeded []string
+		flaky          []string
+		backports      []string
+		prClosed       []string
+		compared       []string
 	)
 
 	for _, evt := range events {
@@ -124,16 +133,68 @@ func printSummary(events []event.Event) {
 				prURL = ""
 			}
 			prMerged = append(prMerged, fmt.Sprintf("#%s", prNumberFromURL(prURL)))
+		case event.MainBroken:
+			repo, _ := evt.Data["repo"].(string)
+			branch, _ := evt.Data["branch"].(string)
+			prNum, _ := evt.Data["pr_number"].(string)
+			mainBroken = append(mainBroken, fmt.Sprintf("%s@%s (by PR #%s)", repo, branch, prNum))
+		case event.PRBudgetExceeded:
+			prNum, _ := evt.Data["pr_number"].(string)
+			spent, _ := evt.Data["spent_usd"].(float64)
+			budgetExceeded = append(budgetExceeded, fmt.Sprintf("#%s ($%.2f)", prNum, spent))
+		case event.CIFlaky:
+			prNum, _ := evt.Data["pr_number"].(string)
+			var checks []string
+			if list, ok := evt.Data["checks"].([]interface{}); ok {
+				for _, c := range list {
+					checks = append(checks, fmt.Sprint(c))
+				}
+			}
+			flaky = append(flaky, fmt.Sprintf("#%s (%s)", prNum, strings.Join(checks, ", ")))
+		case event.PRClosed:
+			prNum, _ := evt.Data["pr_number"].(string)
+			prClosed = append(prClosed, "#"+prNum)
+		case event.CandidatesCompared:
+			group, _ := evt.Data["group"].(string)
+			winner, _ := evt.Data["winner"].(string)
+			if winner == "" {
+				compared = append(compared, shortID(group)+" (no winner)")
+			} else {
+				compared = append(compared, fmt.Sprintf("%s (won by %s)", shortID(group), shortID(winner)))
+			}
+		case event.PRBackportOpened:
+			prURL, _ := evt.Data["pr_url"].(string)
+			of, _ := evt.Data["backport_of"].(string)
+			branch, _ := evt.Data["branch"].(string)
+			backports = append(backports, fmt.Sprintf("#%s (#%s → %s)", prNumberFromURL(prURL), of, branch))
+		case event.PipelineTransition:
+			transitions++
+			prNum, _ := evt.Data["pr_number"].(string)
+			to, _ := evt.Data["to"].(string)
+			from, _ := evt.Data["from"].(string)
+			if to == string(pipeline.StageStalled) && from != to {
+				rule, _ := evt.Data["rule"].(string)
+				stalled = append(stalled, fmt.Sprintf("#%s (%s)", prNum, rule))
+			}
 		}
 	}
 
 	// Print in priority order: attention first, then actionable, then info
+	if len(mainBroken) > 0 {
+		fmt.Printf("%d default branch(es) broken by a merge: %s\n", len(mainBroken), strings.Join(mainBroken, ", "))
+	}
 	if len(needsAttention) > 0 {
 		fmt.Printf("%d agent(s) need attention:\n", len(needsAttention))
 		for _, s := range needsAttention {
 			fmt.Printf("  %s\n", s)
 		}
 	}
+	if len(budgetExceeded) > 0 {
+		fmt.Printf("%d PR(s) hit the spend cap: %s\n", len(budgetExceeded), strings.Join(budgetExceeded, ", "))
+	}
+	if len(stalled) > 0 {
+		fmt.Printf("%d PR(s) stalled in the pipeline: %s\n", len(stalled), strings.Join(stalled, ", "))
+	}
 	if len(prsReady) > 0 {
 		fmt.Printf("%d PR(s) awaiting approval: %s\n", len(prsReady), strings.Join(prsReady, ", "))
 	}
@@ -146,9 +207,21 @@ func printSummary(events []event.Event) {
 	if len(ciFailed) > 0 {
 		fmt.Printf("%d CI failed: %s\n", len(ciFailed), strings.Join(ciFailed, ", "))
 	}
+	if len(flaky) > 0 {
+		fmt.Printf("%d flaky CI failure(s) passed on rerun: %s\n", len(flaky), strings.Join(flaky, ", "))
+	}
+	if len(backports) > 0 {
+		fmt.Printf("%d backport PR(s) opened: %s\n", len(backports), strings.Join(backports, ", "))
+	}
+	if len(compared) > 0 {
+		fmt.Printf("%d best-of-N launch(es) compared: %s\n", len(compared), strings.Join(compared, ", "))
+	}
 	if len(prMerged) > 0 {
 		fmt.Printf("%d PR(s) merged: %s\n", len(prMerged), strings.Join(prMerged, ", "))
 	}
+	if len(prClosed) > 0 {
+		fmt.Printf("%d PR(s) closed without merging: %s\n", len(prClosed), strings.Join(prClosed, ", "))
+	}
 	if len(completed) > 0 {
 		var totalCost float64
 		for _, c := range completed {
@@ -156,6 +229,9 @@ func printSummary(events []event.Event) {
 		}
 		fmt.Printf("%d agent(s) completed since last check ($%.2f total)\n", len(completed), totalCost)
 	}
+	if transitions > 0 {
+		fmt.Printf("%d pipeline transition(s) (klaus watch --since-start --filter %s for details)\n", transitions, event.PipelineTransition)
+	}
 }
 
 type completedInfo struct {
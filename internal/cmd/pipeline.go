@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/patflynn/klaus/internal/config"
+	"github.com/patflynn/klaus/internal/git"
+	gh "github.com/patflynn/klaus/internal/github"
+	"github.com/patflynn/klaus/internal/pipeline"
+	"github.com/spf13/cobra"
+)
+
+var pipelineCmd = &cobra.Command{
+	Use:   "pipeline",
+	Short: "Inspect the PR pipeline state machine",
+}
+
+var pipelineExplainCmd = &cobra.Command{
+	Use:   "explain <pr-number>",
+	Short: "Show which pipeline transition would fire for a PR, and why",
+	Long: `Fetches the PR's current GitHub status and the session's run states, then
+evaluates every transition in the pipeline's ordered rule table against them
+and prints each guard's result, the rule that would fire, the stage it would
+move the PR to, and the actions it would request.
+
+Nothing is executed: no agents are launched, nothing is merged, no events are
+emitted and no state is saved. The PR's pipeline state (attempt counters,
+cooldowns, last agent) comes from the session's checkpoint, so the answer
+matches what the dashboard or 'klaus pipelined' would do on its next poll.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPipelineExplain,
+}
+
+var pipelineSimulateCmd = &cobra.Command{
+	Use:   "simulate <trace>",
+	Short: "Replay a recorded pipeline trace and diff the timeline against a golden file",
+	Long: `Replays a trace recorded with "pipeline": {"record_trace": true} (the
+session's pipeline-trace.jsonl) through a fresh pipeline controller and prints
+the resulting timeline: each transition that fired, with its stages and rule,
+and the actions the controller took.
+
+Nothing is executed: agent launches, merges and review-thread calls are
+stubbed to succeed. The pipeline policy and auto-merge setting come from the
+current config, so a trace can be replayed against a changed state machine or
+policy to see what would have happened differently.
+
+The timeline is compared to the golden file (default <trace>.golden); any
+difference is printed as a diff and the command exits non-zero. Use --update
+to (re)write the golden file from the current timeline.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPipelineSimulate,
+}
+
+func init() {
+	pipelineSimulateCmd.Flags().String("golden", "", "Golden timeline to compare against (default <trace>.golden)")
+	pipelineSimulateCmd.Flags().Bool("update", false, "Write the timeline to the golden file instead of comparing")
+	pipelineCmd.AddCommand(pipelineExplainCmd)
+	pipelineCmd.AddCommand(pipelineSimulateCmd)
+	rootCmd.AddCommand(pipelineCmd)
+}
+
+func runPipelineExplain(cmd *cobra.Command, args []string) error {
+	prNum := strings.TrimPrefix(args[0], "#")
+
+	store, err := sessionStore()
+	if err != nil {
+		return err
+	}
+	states, err := store.List()
+	if err != nil {
+		return err
+	}
+	matched := statesForPR(states, prNum)
+	if len(matched) == 0 {
+		return fmt.Errorf("no run in this session references PR #%s (use 'klaus track' to add it)", prNum)
+	}
+
+	repoRoot, _ := git.RepoRoot()
+	cfg, err := config.Load(repoRoot)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctrl := newPipelineController(store, cfg, logger)
+
+	status := toPipelineStatuses(fetchPRStatuses(gh.NewGHCLIClient(""), matched), states)[prNum]
+	if status == nil {
+		return fmt.Errorf("could not fetch GitHub status for PR #%s", prNum)
+	}
+
+	out := cmd.OutOrStdout()
+	if status.State == "MERGED" || status.State == "CLOSED" {
+		fmt.Fprintf(out, "PR #%s is %s; the pipeline no longer evaluates it.\n", prNum, strings.ToLower(status.State))
+		return nil
+	}
+	renderExplanation(out, status, ctrl.Explain(status, states))
+	return nil
+}
+
+// renderExplanation prints an Explanation as an indented rule-by-rule trace.
+func renderExplanation(w io.Writer, status *pipeline.PRStatus, ex pipeline.Explanation) {
+	repo := status.TargetRepo
+	if repo == "" {
+		repo = "(local)"
+	}
+	stage := string(ex.Stage)
+	if !ex.Known {
+		stage += " (not yet tracked; seeded from GitHub status)"
+	}
+	labels := "-"
+	if len(status.Labels) > 0 {
+		labels = strings.Join(status.Labels, ",")
+	}
+	review := status.ReviewDecision
+	if review == "" {
+		review = "-"
+	}
+	fmt.Fprintf(w, "PR #%s  %s\n", ex.PRNumber, repo)
+	fmt.Fprintf(w, "  stage:  %s\n", stage)
+	fmt.Fprintf(w, "  github: ci=%s conflicts=%s review=%s trusted-comments=%t labels=%s\n\n",
+		status.CI, status.Conflicts, review, status.HasNewTrustedComments, labels)
+
+	fired := false
+	for _, t := range ex.Transitions {
+		var mark, note string
+		switch {
+		case t.Fires:
+			mark, note = "→", "  ← fires"
+			fired = true
+		case t.Disabled:
+			mark, note = "-", "  (disabled by pipeline policy)"
+		case t.Matched && fired:
+			mark, note = "✓", "  (shadowed by an earlier rule)"
+		case t.Matched:
+			mark = "✓"
+		default:
+			mark = "✗"
+		}
+		fmt.Fprintf(w, "%s %s%s\n", mark, t.Name, note)
+		for _, g := range t.Guards {
+			gm := "✗"
+			if g.Pass {
+				gm = "✓"
+			}
+			fmt.Fprintf(w, "    %s %s\n", gm, g.Name)
+		}
+	}
+
+	fmt.Fprintln(w)
+	if ex.Fired == "" {
+		fmt.Fprintln(w, "No transition matches; the PR stays where it is.")
+		return
+	}
+	fmt.Fprintf(w, "Would fire: %s (stage %s → %s)\n", ex.Fired, ex.Stage, ex.NextStage)
+	if len(ex.Actions) == 0 && len(ex.Descriptors) == 0 {
+		fmt.Fprintln(w, "Actions: none")
+		return
+	}
+	fmt.Fprintln(w, "Actions:")
+	for _, a := range ex.Actions {
+		fmt.Fprintf(w, "  %s: %s\n", a.Type, a.Detail)
+	}
+	for _, d := range ex.Descriptors {
+		switch d.Type {
+		case pipeline.ActionLaunchAgent:
+			fmt.Fprintf(w, "  %s (repo %q", d.Type, d.Repo)
+			if d.ResumeFrom != "" {
+				fmt.Fprintf(w, ", resume from %s", d.ResumeFrom)
+			}
+			fmt.Fprintf(w, ")\n      prompt: %s\n", strings.ReplaceAll(d.Prompt, "\n", "\n              "))
+		case pipeline.ActionMergePR:
+			fmt.Fprintf(w, "  %s %s (repo %s)\n", d.Type, strings.Join(d.PRNumbers, ","), d.Repo)
+		default:
+			fmt.Fprintf(w, "  %s\n", d.Type)
+		}
+	}
+}
+
+func runPipelineSimulate(cmd *cobra.Command, args []string) error {
+	tracePath := args[0]
+	trace, err := pipeline.ReadTrace(tracePath)
+	if err != nil {
+		return fmt.Errorf("reading trace: %w", err)
+	}
+	golden, _ := cmd.Flags().GetString("golden")
+	if golden == "" {
+		golden = tracePath + ".golden"
+	}
+	update, _ := cmd.Flags().GetBool("update")
+
+	repoRoot, _ := git.RepoRoot()
+	cfg, err := config.Load(repoRoot)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	steps := pipeline.Simulate(trace, pipeline.SimOptions{
+		AutoMergeOnApproval: cfg.AutoMergesOnApproval(),
+		PolicyFor:           pipelinePolicyResolver(cfg, logger),
+	})
+	var timeline bytes.Buffer
+	pipeline.FormatTimeline(&timeline, steps)
+
+	out := cmd.OutOrStdout()
+	out.Write(timeline.Bytes()) //nolint:errcheck
+
+	if update {
+		if err := os.WriteFile(golden, timeline.Bytes(), 0o644); err != nil {
+			return fmt.Errorf("writing golden file: %w", err)
+		}
+		fmt.Fprintf(out, "\nWrote %s (%d trace entries)\n", golden, len(trace))
+		return nil
+	}
+
+	want, err := os.ReadFile(golden)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintf(out, "\nNo golden file at %s (run with --update to create it)\n", golden)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading golden file: %w", err)
+	}
+	if bytes.Equal(want, timeline.Bytes()) {
+		fmt.Fprintf(out, "\nTimeline matches %s\n", golden)
+		return nil
+	}
+	fmt.Fprintf(out, "\n--- %s\n+++ simulated\n", golden)
+	for _, line := range diffLines(splitLines(string(want)), splitLines(timeline.String())) {
+		fmt.Fprintln(out, line)
+	}
+	return fmt.Errorf("simulated timeline differs from %s", golden)
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// diffLines returns a line diff of want and got: lines only in want are
+// prefixed "-", lines only in got "+", and shared lines "  ". Runs of more
+// than two shared lines away from a change are collapsed to "  ...".
+func diffLines(want, got []string) []string {
+	// lcs[i][j] is the longest common subsequence of want[i:] and got[j:].
+	lcs := make([][]int, len(want)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(got)+1)
+	}
+	for i := len(want) - 1; i >= 0; i-- {
+		for j := len(got) - 1; j >= 0; j-- {
+			if want[i] == got[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var all []string
+	i, j := 0, 0
+	for i < len(want) || j < len(got) {
+		switch {
+		case i < len(want) && j < len(got) && want[i] == got[j]:
+			all = append(all, "  "+want[i])
+			i++
+			j++
+		case i < len(want) && (j == len(got) || lcs[i+1][j] >= lcs[i][j+1]):
+			all = append(all, "- "+want[i])
+			i++
+		default:
+			all = append(all, "+ "+got[j])
+			j++
+		}
+	}
+
+	const context = 2
+	near := func(k int) bool {
+		for d := -context; d <= context; d++ {
+			if n := k + d; n >= 0 && n < len(all) && !strings.HasPrefix(all[n], "  ") {
+				return true
+			}
+		}
+		return false
+	}
+	var out []string
+	for k, line := range all {
+		if !strings.HasPrefix(line, "  ") || near(k) {
+			out = append(out, line)
+		} else if len(out) == 0 || out[len(out)-1] != "  ..." {
+			out = append(out, "  ...")
+		}
+	}
+	return out
+}
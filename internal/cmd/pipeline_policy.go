@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/patflynn/klaus/internal/config"
+	"github.com/patflynn/klaus/internal/pipeline"
+	"github.com/patflynn/klaus/internal/project"
+)
+
+// pipelinePolicy converts a repo's "pipeline" config block into a pipeline
+// policy. Unset fields keep the built-in defaults. An invalid block (unknown
+// transition names or profiles, unparseable prompt templates) is logged and
+// ignored in full, so a typo can't silently disable half of a repo's pipeline.
+func pipelinePolicy(cfg config.Config, repo string, logger *slog.Logger) pipeline.Policy {
+	p := pipeline.DefaultPolicy()
+	// Dispatched agents run with the default budget (klaus launch --pr).
+	if budget, err := strconv.ParseFloat(cfg.DefaultBudget, 64); err == nil && budget > 0 {
+		p.AgentBudgetUSD = budget
+	}
+	pc := cfg.Pipeline
+	if pc == nil {
+		return p
+	}
+
+	if len(pc.Transitions) > 0 {
+		p.Disabled = make(map[string]bool)
+		for name, enabled := range pc.Transitions {
+			if !enabled {
+				p.Disabled[name] = true
+			}
+		}
+	}
+	if pc.MaxFixAttempts > 0 {
+		p.MaxFixAttempts = pc.MaxFixAttempts
+	}
+	if pc.MaxRebaseAttempts > 0 {
+		p.MaxRebaseAttempts = pc.MaxRebaseAttempts
+	}
+	if pc.MaxReviewFixAttempts > 0 {
+		p.MaxReviewFixAttempts = pc.MaxReviewFixAttempts
+	}
+	if pc.DispatchCooldownSeconds > 0 {
+		p.DispatchCooldown = time.Duration(pc.DispatchCooldownSeconds) * time.Second
+	}
+	if pc.RetryBackoffSeconds > 0 {
+		p.RetryBackoff = time.Duration(pc.RetryBackoffSeconds) * time.Second
+	}
+	if pc.MaxLaunchRetries > 0 {
+		p.MaxLaunchRetries = pc.MaxLaunchRetries
+	} else if pc.MaxLaunchRetries < 0 {
+		p.MaxLaunchRetries = 0
+	}
+	if pc.MaxPRSpendUSD > 0 {
+		p.MaxPRSpendUSD = pc.MaxPRSpendUSD
+	}
+	if pc.MainWatchdog != "" {
+		p.MainWatchdog = pc.MainWatchdog
+	}
+	if pc.MaxMainAgents > 0 {
+		p.MaxMainAgents = pc.MaxMainAgents
+	}
+	if pc.RerunFailedChecks != "" {
+		p.RerunFailedChecks = pc.RerunFailedChecks
+	}
+	if pc.FlakyThreshold > 0 {
+		p.FlakyThreshold = pc.FlakyThreshold
+	}
+	if pc.CIChecks != "" {
+		p.CIChecks = pc.CIChecks
+	}
+	if pc.AutoResolveConflicts != nil {
+		p.AutoResolveConflicts = *pc.AutoResolveConflicts
+	}
+	if pc.ClosedCleanup != nil {
+		p.ClosedCleanup = pc.ClosedCleanup
+	}
+	p.Prompts = pipeline.PromptTemplates{
+		CIFix:            pc.Prompts["ci_fix"],
+		Rebase:           pc.Prompts["rebase"],
+		ChangesRequested: pc.Prompts["changes_requested"],
+		TrustedComments:  pc.Prompts["trusted_comments"],
+	}
+	p.Profiles = pipeline.DispatchProfiles{
+		CIFix:            pc.Profiles["ci_fix"],
+		Rebase:           pc.Profiles["rebase"],
+		ChangesRequested: pc.Profiles["changes_requested"],
+		TrustedComments:  pc.Profiles["trusted_comments"],
+		MainWatchdog:     pc.Profiles["main_watchdog"],
+	}
+
+	// A profile's budget applies to the agents dispatched with it, so the
+	// spend cap must allow for the priciest one.
+	err := p.Validate()
+	for _, name := range pc.Profiles {
+		prof, perr := cfg.Profile(name)
+		if perr != nil {
+			err = perr
+			break
+		}
+		if budget, perr := strconv.ParseFloat(profileBudget(prof), 64); perr == nil && budget > p.AgentBudgetUSD {
+			p.AgentBudgetUSD = budget
+		}
+	}
+	if err != nil {
+		logger.Error("ignoring invalid pipeline config", "repo", repo, "err", err)
+		def := pipeline.DefaultPolicy()
+		def.AgentBudgetUSD = p.AgentBudgetUSD
+		return def
+	}
+	return p
+}
+
+// pipelinePolicyResolver returns the controller's per-repo policy lookup.
+// PRs targeting a registered project use that project's .klaus/config.json
+// (layered over the global config, as config.Load does); any other repo uses
+// base, the config klaus was started with. Policies are resolved once per
+// repo and cached for the life of the process.
+func pipelinePolicyResolver(base config.Config, logger *slog.Logger) func(repo string) pipeline.Policy {
+	reg, _ := project.Load()
+	var mu sync.Mutex
+	cache := make(map[string]pipeline.Policy)
+
+	return func(repo string) pipeline.Policy {
+		mu.Lock()
+		defer mu.Unlock()
+		if p, ok := cache[repo]; ok {
+			return p
+		}
+		cfg := base
+		if reg != nil {
+			if root, ok := reg.Get(project.NormalizeRepoName(repo, reg)); ok {
+				repoCfg, err := config.Load(root)
+				if err != nil {
+					logger.Warn("loading project config for pipeline policy", "repo", repo, "err", err)
+				} else {
+					cfg = repoCfg
+				}
+			}
+		}
+		p := pipelinePolicy(cfg, repo, logger)
+		cache[repo] = p
+		return p
+	}
+}
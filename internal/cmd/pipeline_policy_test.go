@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/patflynn/klaus/internal/config"
+	"github.com/patflynn/klaus/internal/pipeline"
+)
+
+func TestPipelinePolicy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("no block keeps defaults", func(t *testing.T) {
+		got := pipelinePolicy(config.Config{}, "r", logger)
+		if got.MaxFixAttempts != pipeline.DefaultPolicy().MaxFixAttempts || got.Disabled != nil {
+			t.Errorf("got %+v, want defaults", got)
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		cfg := config.Config{Pipeline: &config.PipelineConfig{
+			Transitions: map[string]bool{
+				"ci-passing/conflicts-dispatch-rebase": false,
+				"ci-failing/dispatch-fix-agent":        true,
+			},
+			MaxFixAttempts:          6,
+			DispatchCooldownSeconds: 120,
+			MaxLaunchRetries:        -1,
+			Prompts:                 map[string]string{"ci_fix": "fix {{.PR}}"},
+		}}
+		got := pipelinePolicy(cfg, "r", logger)
+		if !got.Disabled["ci-passing/conflicts-dispatch-rebase"] || got.Disabled["ci-failing/dispatch-fix-agent"] {
+			t.Errorf("Disabled = %v", got.Disabled)
+		}
+		if got.MaxFixAttempts != 6 || got.DispatchCooldown != 2*time.Minute || got.MaxLaunchRetries != 0 {
+			t.Errorf("limits = %+v", got)
+		}
+		if got.MaxRebaseAttempts != pipeline.DefaultPolicy().MaxRebaseAttempts {
+			t.Errorf("unset MaxRebaseAttempts = %d, want default", got.MaxRebaseAttempts)
+		}
+		if got.Prompts.CIFix != "fix {{.PR}}" {
+			t.Errorf("Prompts.CIFix = %q", got.Prompts.CIFix)
+		}
+	})
+
+	t.Run("invalid block falls back to defaults", func(t *testing.T) {
+		cfg := config.Config{Pipeline: &config.PipelineConfig{
+			Transitions:    map[string]bool{"no-such-transition": false},
+			MaxFixAttempts: 9,
+		}}
+		got := pipelinePolicy(cfg, "r", logger)
+		if got.MaxFixAttempts != pipeline.DefaultPolicy().MaxFixAttempts {
+			t.Errorf("invalid config applied: %+v", got)
+		}
+	})
+
+	t.Run("main watchdog", func(t *testing.T) {
+		cfg := config.Config{Pipeline: &config.PipelineConfig{MainWatchdog: "revert", MaxMainAgents: 2}}
+		got := pipelinePolicy(cfg, "r", logger)
+		if got.MainWatchdog != pipeline.MainWatchdogRevert || got.MaxMainAgents != 2 {
+			t.Errorf("main watchdog = %q/%d, want revert/2", got.MainWatchdog, got.MaxMainAgents)
+		}
+		cfg.Pipeline.MainWatchdog = "rollback"
+		if got := pipelinePolicy(cfg, "r", logger); got.MainWatchdog != pipeline.MainWatchdogNotify {
+			t.Errorf("unknown main_watchdog applied: %q", got.MainWatchdog)
+		}
+	})
+
+	t.Run("failed check reruns", func(t *testing.T) {
+		cfg := config.Config{Pipeline: &config.PipelineConfig{RerunFailedChecks: "flaky", FlakyThreshold: 3}}
+		got := pipelinePolicy(cfg, "r", logger)
+		if got.RerunFailedChecks != pipeline.RerunFlaky || got.FlakyThreshold != 3 {
+			t.Errorf("reruns = %q/%d, want flaky/3", got.RerunFailedChecks, got.FlakyThreshold)
+		}
+		cfg.Pipeline.RerunFailedChecks = "sometimes"
+		if got := pipelinePolicy(cfg, "r", logger); got.RerunFailedChecks != pipeline.RerunOff {
+			t.Errorf("unknown rerun_failed_checks applied: %q", got.RerunFailedChecks)
+		}
+	})
+
+	t.Run("ci checks", func(t *testing.T) {
+		if got := pipelinePolicy(config.Config{}, "r", logger); got.CIChecks != pipeline.CIChecksRequired {
+			t.Errorf("default ci_checks = %q, want required", got.CIChecks)
+		}
+		cfg := config.Config{Pipeline: &config.PipelineConfig{CIChecks: "all"}}
+		if got := pipelinePolicy(cfg, "r", logger); got.CIChecks != pipeline.CIChecksAll {
+			t.Errorf("ci_checks = %q, want all", got.CIChecks)
+		}
+	})
+
+	t.Run("auto-resolve conflicts", func(t *testing.T) {
+		if got := pipelinePolicy(config.Config{}, "r", logger); !got.AutoResolveConflicts {
+			t.Error("auto_resolve_conflicts should default to true")
+		}
+		off := false
+		cfg := config.Config{Pipeline: &config.PipelineConfig{AutoResolveConflicts: &off}}
+		if got := pipelinePolicy(cfg, "r", logger); got.AutoResolveConflicts {
+			t.Error("auto_resolve_conflicts: false not applied")
+		}
+	})
+
+	t.Run("closed cleanup", func(t *testing.T) {
+		if got := pipelinePolicy(config.Config{}, "r", logger).ClosedCleanup; !reflect.DeepEqual(got, []string{"worktree", "branch"}) {
+			t.Errorf("default closed_cleanup = %v, want [worktree branch]", got)
+		}
+		cfg := config.Config{Pipeline: &config.PipelineConfig{ClosedCleanup: []string{}}}
+		if got := pipelinePolicy(cfg, "r", logger).ClosedCleanup; len(got) != 0 {
+			t.Errorf("empty closed_cleanup = %v, want nothing removed", got)
+		}
+		cfg = config.Config{Pipeline: &config.PipelineConfig{ClosedCleanup: []string{"remote_branch", "state"}}}
+		if got := pipelinePolicy(cfg, "r", logger).ClosedCleanup; !reflect.DeepEqual(got, []string{"remote_branch", "state"}) {
+			t.Errorf("closed_cleanup = %v, want [remote_branch state]", got)
+		}
+		cfg = config.Config{Pipeline: &config.PipelineConfig{ClosedCleanup: []string{"everything"}}}
+		if got := pipelinePolicy(cfg, "r", logger).ClosedCleanup; !reflect.DeepEqual(got, []string{"worktree", "branch"}) {
+			t.Errorf("unknown closed_cleanup option applied: %v", got)
+		}
+	})
+
+	t.Run("spend cap", func(t *testing.T) {
+		cfg := config.Config{DefaultBudget: "5.00", Pipeline: &config.PipelineConfig{MaxPRSpendUSD: 20}}
+		got := pipelinePolicy(cfg, "r", logger)
+		if got.MaxPRSpendUSD != 20 || got.AgentBudgetUSD != 5 {
+			t.Errorf("spend cap = %v/%v, want 20/5", got.MaxPRSpendUSD, got.AgentBudgetUSD)
+		}
+		if got := pipelinePolicy(config.Config{}, "r", logger); got.MaxPRSpendUSD != 0 {
+			t.Errorf("unset max_pr_spend_usd = %v, want uncapped", got.MaxPRSpendUSD)
+		}
+	})
+
+	t.Run("dispatch profiles", func(t *testing.T) {
+		cfg := config.Config{
+			DefaultBudget: "5.00",
+			Profiles:      map[string]config.Profile{"quick-fix": {Model: "haiku", Budget: "2"}, "refactor": {Budget: "15"}},
+			Pipeline:      &config.PipelineConfig{Profiles: map[string]string{"ci_fix": "quick-fix", "rebase": "refactor"}},
+		}
+		got := pipelinePolicy(cfg, "r", logger)
+		if want := (pipeline.DispatchProfiles{CIFix: "quick-fix", Rebase: "refactor"}); got.Profiles != want {
+			t.Errorf("Profiles = %+v, want %+v", got.Profiles, want)
+		}
+		if got.AgentBudgetUSD != 15 {
+			t.Errorf("AgentBudgetUSD = %v, want the priciest profile's 15", got.AgentBudgetUSD)
+		}
+		cfg.Pipeline.Profiles["main_watchdog"] = "docs"
+		if got := pipelinePolicy(cfg, "r", logger); got.Profiles != (pipeline.DispatchProfiles{}) {
+			t.Errorf("unknown profile applied: %+v", got.Profiles)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/patflynn/klaus/internal/pipeline"
+)
+
+func TestRenderExplanation(t *testing.T) {
+	status := &pipeline.PRStatus{PRNumber: "42", State: "OPEN", CI: "failing", Conflicts: "none", TargetRepo: "klaus"}
+	ex := pipeline.Explanation{
+		PRNumber:  "42",
+		Stage:     pipeline.StageCIFailed,
+		NextStage: pipeline.StageCIFailed,
+		Known:     true,
+		Transitions: []pipeline.TransitionResult{
+			{Name: "ci-failing/dispatch-fix-agent", Guards: []pipeline.GuardResult{
+				{Name: "ciFailing", Pass: true},
+				{Name: "cooldownExpired", Pass: false},
+			}},
+			{Name: "ci-failing/update-stage", Matched: true, Fires: true, Guards: []pipeline.GuardResult{
+				{Name: "ciFailing", Pass: true},
+			}},
+			{Name: "ci-failing/noop-already-handling", Matched: true, Guards: []pipeline.GuardResult{
+				{Name: "ciFailing", Pass: true},
+			}},
+		},
+		Fired: "ci-failing/update-stage",
+	}
+
+	var b strings.Builder
+	renderExplanation(&b, status, ex)
+	out := b.String()
+
+	for _, want := range []string{
+		"PR #42  klaus",
+		"✗ ci-failing/dispatch-fix-agent\n",
+		"    ✗ cooldownExpired",
+		"→ ci-failing/update-stage  ← fires",
+		"✓ ci-failing/noop-already-handling  (shadowed by an earlier rule)",
+		"Would fire: ci-failing/update-stage (stage ci_failed → ci_failed)",
+		"Actions: none",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	want := []string{"[1] t1", "  a", "  b", "  c", "  d", "  e", "  f", "[2] t2", "  g"}
+	got := []string{"[1] t1", "  a", "  b", "  c", "  d", "  e", "  f", "[2] t2", "  h"}
+	diff := strings.Join(diffLines(want, got), "\n")
+	wantDiff := strings.Join([]string{
+		"  ...",
+		"    f",
+		"  [2] t2",
+		"-   g",
+		"+   h",
+	}, "\n")
+	if diff != wantDiff {
+		t.Errorf("diff:\n%s\nwant:\n%s", diff, wantDiff)
+	}
+	if d := diffLines(want, want); len(d) != 1 || d[0] != "  ..." {
+		t.Errorf("identical input diff = %q", d)
+	}
+}
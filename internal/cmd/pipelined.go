@@ -0,0 +1,644 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/patflynn/klaus/internal/config"
+	"github.com/patflynn/klaus/internal/event"
+	"github.com/patflynn/klaus/internal/git"
+	gh "github.com/patflynn/klaus/internal/github"
+	"github.com/patflynn/klaus/internal/pipeline"
+	"github.com/patflynn/klaus/internal/run"
+	"github.com/patflynn/klaus/internal/webhook"
+	"github.com/spf13/cobra"
+)
+
+// defaultPollInterval matches the dashboard's GitHub polling cadence.
+const defaultPollInterval = 30 * time.Second
+
+// fsDebounce coalesces bursts of run-state writes (e.g. the controller
+// persisting approvals) into a single re-evaluation.
+const fsDebounce = 500 * time.Millisecond
+
+var pipelinedCmd = &cobra.Command{
+	Use:   "pipelined",
+	Short: "Run the PR pipeline headlessly, without the dashboard TUI",
+	Long: `Runs the PR pipeline controller as a long-lived background process for the
+current session. It drives the same state machine as 'klaus dashboard' —
+dispatching CI-fix, rebase and review-fix agents and auto-merging approved
+PRs — but needs no terminal, so the pipeline keeps working when nobody has a
+dashboard open.
+
+It owns the webhook server (when configured), the 30s GitHub poll or slow
+reconcile heartbeat, and the run-state watcher. Logs are written as JSON
+lines to stderr (or --log-file). SIGINT/SIGTERM finish the in-flight
+evaluation, checkpoint pipeline state, release the leader lease and shut
+down the webhook server.
+
+Only one process per session executes the pipeline: the holder of the
+session's leader lease. If a dashboard already leads, pipelined waits as a
+standby and takes over when the lease is released or goes stale. Dashboards
+opened while pipelined leads are read-only views of its pipeline state.`,
+	Args: cobra.NoArgs,
+	RunE: runPipelined,
+}
+
+func init() {
+	pipelinedCmd.Flags().String("log-file", "", "Append JSON logs to this file instead of stderr")
+	rootCmd.AddCommand(pipelinedCmd)
+}
+
+func runPipelined(cmd *cobra.Command, args []string) error {
+	store, err := sessionStore()
+	if err != nil {
+		return err
+	}
+	hds, ok := store.(*run.HomeDirStore)
+	if !ok {
+		return fmt.Errorf("klaus pipelined requires a session store")
+	}
+
+	var logOut io.Writer = os.Stderr
+	if path, _ := cmd.Flags().GetString("log-file"); path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("opening log file: %w", err)
+		}
+		defer f.Close()
+		logOut = f
+	}
+	logger := slog.New(slog.NewJSONHandler(logOut, nil))
+
+	repoRoot, _ := git.RepoRoot()
+	cfg, err := config.Load(repoRoot)
+	if err != nil {
+		logger.Warn("loading config", "err", err)
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	d := &pipelineDaemon{
+		store:     store,
+		ghClient:  gh.NewGHCLIClient(""),
+		ctrl:      newPipelineController(store, cfg, logger),
+		issues:    newIssueWatcher(logger),
+		logger:    logger,
+		tmuxDeps:  run.DefaultTmuxDeps(),
+		pollEvery: defaultPollInterval,
+	}
+	d.leader = newLeadership(
+		pipeline.NewLease(filepath.Join(hds.BaseDir(), pipeline.LeaseFileName), "pipelined"),
+		d.ctrl,
+		filepath.Join(hds.BaseDir(), pipeline.StateFileName),
+	)
+
+	eventsPath := event.NewLog(hds.BaseDir()).Path()
+	internalCh := make(chan event.Event, 64)
+	d.internalCh = internalCh
+	go func() {
+		defer close(internalCh)
+		if err := event.Tail(ctx, eventsPath, internalCh); err != nil {
+			logger.Warn("event tail stopped", "err", err)
+		}
+	}()
+
+	var webhookSrv *webhook.Server
+	if cfg.Webhook != nil {
+		secret, err := cfg.Webhook.ReadSecret(os.ReadFile)
+		if err != nil {
+			return fmt.Errorf("webhook server: %w", err)
+		}
+		if secret == "" {
+			logger.Warn("webhook.secret_file not configured; webhook signatures will not be verified")
+		}
+		port := cfg.Webhook.Port
+		if port == 0 {
+			port = 9800
+		}
+		ch := make(chan webhook.Event, 64)
+		webhookSrv = webhook.NewServer(port, cfg.Webhook.Path, ch)
+		webhookSrv.SetSecret(secret)
+		if err := webhookSrv.Listen(); err != nil {
+			// The current leader (e.g. a dashboard) most likely holds the
+			// port. Poll instead; webhooks are only invalidation signals.
+			logger.Warn("webhook server unavailable; polling instead", "err", err)
+			webhookSrv = nil
+		} else {
+			go func() {
+				if err := webhookSrv.Serve(); err != nil && err != http.ErrServerClosed {
+					logger.Error("webhook server error", "err", err)
+				}
+			}()
+			d.webhookCh = ch
+			if !cfg.Webhook.PollFallback {
+				d.pollEvery = 0
+				d.reconcileEvery = reconcileInterval(cfg.Webhook)
+			}
+		}
+	}
+
+	var webhookAddr string
+	if webhookSrv != nil {
+		webhookAddr = webhookSrv.Addr()
+	}
+	logger.Info("pipelined started",
+		"session", filepath.Base(hds.BaseDir()),
+		"pid", os.Getpid(),
+		"webhook", webhookAddr,
+		"poll", d.pollEvery.String(),
+		"reconcile", d.reconcileEvery.String(),
+	)
+
+	runErr := d.run(ctx)
+	if err := d.leader.lease.Release(); err != nil {
+		logger.Error("releasing leader lease", "err", err)
+	}
+
+	if webhookSrv != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := webhookSrv.Shutdown(shutdownCtx); err != nil {
+			logger.Error("webhook server shutdown", "err", err)
+		}
+	}
+	logger.Info("pipelined stopped")
+	return runErr
+}
+
+// pipelineDaemon is the headless equivalent of the dashboard's Update loop:
+// every trigger (poll tick, reconcile heartbeat, webhook, internal event,
+// run-state change) re-fetches GitHub status and feeds it to the controller.
+type pipelineDaemon struct {
+	store    run.StateStore
+	ghClient gh.Client
+	ctrl     *pipeline.Controller
+	logger   *slog.Logger
+	tmuxDeps run.TmuxDeps
+	issues   *issueWatcher // nil unless a registered project sets pipeline.issue_watch
+
+	pollEvery      time.Duration // 0 disables polling
+	reconcileEvery time.Duration // 0 disables the reconcile heartbeat
+
+	webhookCh  <-chan webhook.Event
+	internalCh <-chan event.Event
+
+	// leader gates execution: only the session's leader evaluates the
+	// pipeline. Nil means always lead (tests).
+	leader *leadership
+
+	states []*run.State
+
+	// Jobs that launch agents (plan advancement, the issue watcher) or
+	// compare candidates run off the loop, since they take a while and
+	// would hold up evaluation and lease renewal. busy names the jobs running, pending
+	// those requested meanwhile, and done carries their results back to
+	// the loop.
+	busy    map[string]bool
+	pending map[string]func() []pipeline.Action
+	done    chan jobResult
+}
+
+// jobResult is what an off-loop job did.
+type jobResult struct {
+	job     string
+	actions []pipeline.Action
+}
+
+// run blocks until ctx is cancelled. Evaluations are run with a background
+// context so a shutdown signal never interrupts an agent launch or merge
+// halfway through; the loop exits once the in-flight evaluation and any
+// off-loop jobs finish.
+func (d *pipelineDaemon) run(ctx context.Context) error {
+	fsCh, closeWatcher, err := watchStateDir(d.store.StateDir())
+	if err != nil {
+		return err
+	}
+	defer closeWatcher()
+
+	var pollC, reconcileC <-chan time.Time
+	if d.pollEvery > 0 {
+		t := time.NewTicker(d.pollEvery)
+		defer t.Stop()
+		pollC = t.C
+	}
+	if d.reconcileEvery > 0 {
+		t := time.NewTicker(d.reconcileEvery)
+		defer t.Stop()
+		reconcileC = t.C
+	}
+
+	var leaseC <-chan time.Time
+	if d.leader != nil {
+		// The lease is renewed in the background; the loop's own tick
+		// adopts (or drops) leadership between evaluations.
+		go d.leader.keep(ctx, func(err error) { d.logger.Error("renewing leader lease", "err", err) })
+		t := time.NewTicker(pipeline.LeaseHeartbeat)
+		defer t.Stop()
+		leaseC = t.C
+	}
+
+	var debounce *time.Timer
+	var debounceC <-chan time.Time
+
+	d.heartbeat()
+	d.reload()
+	d.evaluate(d.states)
+	d.watchIssues("")
+
+	for {
+		select {
+		case <-ctx.Done():
+			if debounce != nil {
+				debounce.Stop()
+			}
+			d.drain()
+			return nil
+
+		case r := <-d.jobDone():
+			d.finish(r)
+
+		case <-leaseC:
+			if d.heartbeat() {
+				d.reload()
+				d.evaluate(d.states)
+				d.watchIssues("")
+			}
+
+		case <-pollC:
+			d.reload()
+			d.evaluate(d.states)
+			d.watchIssues("")
+
+		case <-reconcileC:
+			d.logger.Debug("reconcile heartbeat")
+			d.reload()
+			d.evaluate(d.states)
+			d.watchIssues("")
+
+		case <-fsCh:
+			if debounce == nil {
+				debounce = time.NewTimer(fsDebounce)
+			} else {
+				debounce.Reset(fsDebounce)
+			}
+			debounceC = debounce.C
+
+		case <-debounceC:
+			debounceC = nil
+			d.reload()
+			d.evaluate(d.states)
+
+		case ev, ok := <-d.webhookCh:
+			if !ok {
+				d.logger.Error("webhook event channel closed; live webhook updates stopped")
+				d.webhookCh = nil
+				continue
+			}
+			d.logger.Info("webhook", "type", ev.EventType, "repo", ev.Repo, "pr", ev.PRNumber)
+			if ev.PRNumber != "" {
+				if matched := statesForPR(d.states, ev.PRNumber); len(matched) > 0 {
+					d.evaluate(matched)
+				}
+			} else if ev.EventType == "push" && ev.Repo != "" {
+				d.evaluate(d.states)
+			} else if ev.EventType == "issues" && ev.Repo != "" {
+				d.watchIssues(ev.Repo)
+			}
+
+		case ev, ok := <-d.internalCh:
+			if !ok {
+				d.internalCh = nil
+				continue
+			}
+			prNum := internalEventPRNumber(ev)
+			if shouldInvalidate(ev.Type) && prNum != "" {
+				d.reload()
+				if matched := statesForPR(d.states, prNum); len(matched) > 0 {
+					d.evaluate(matched)
+				}
+			}
+		}
+	}
+}
+
+// heartbeat renews (or tries to acquire) the leader lease and logs
+// leadership changes. It reports whether this call made us the leader.
+func (d *pipelineDaemon) heartbeat() bool {
+	if d.leader == nil {
+		return false
+	}
+	wasLeader := d.leader.leading()
+	acquired, err := d.leader.renew()
+	if err != nil {
+		d.logger.Error("renewing leader lease", "err", err)
+	}
+	switch {
+	case acquired:
+		d.logger.Info("acquired pipeline leadership")
+	case wasLeader && !d.leader.leading():
+		d.logger.Warn("lost pipeline leadership", "leader", d.leader.lease.Holder().String())
+	case !wasLeader && !d.leader.leading() && err == nil:
+		d.logger.Debug("standing by", "leader", d.leader.lease.Holder().String())
+	}
+	return acquired
+}
+
+func (d *pipelineDaemon) leading() bool {
+	return d.leader.leading()
+}
+
+// reload refreshes run states from the store and, when leading, finalizes
+// stale runs, starts comparing the candidate groups that completed, and
+// starts launching ready plan tasks.
+func (d *pipelineDaemon) reload() {
+	states, err := d.store.List()
+	if err != nil {
+		d.logger.Error("listing run states", "err", err)
+		return
+	}
+	if d.leading() {
+		for _, group := range finalizeStaleRuns(d.store, states, d.tmuxDeps) {
+			d.background("compare "+group, func() []pipeline.Action {
+				return []pipeline.Action{compareStaleGroup(context.Background(), d.store, group)}
+			})
+		}
+		d.advancePlans(states)
+	}
+	d.states = states
+}
+
+// advancePlans launches the plan tasks whose dependencies are done, off the
+// loop.
+func (d *pipelineDaemon) advancePlans(states []*run.State) {
+	hds, ok := d.store.(*run.HomeDirStore)
+	if !ok {
+		return
+	}
+	running := func(s *run.State) bool { return s.IsAgentRunningWith(d.tmuxDeps) }
+	d.background("plan", func() []pipeline.Action {
+		return advancePlans(hds.BaseDir(), states, running, launchPlanTask)
+	})
+}
+
+// background runs fn on its own goroutine; the loop logs its actions when
+// it finishes (see finish). A job requested while it is already running is
+// run once more afterwards, with the latest fn.
+func (d *pipelineDaemon) background(job string, fn func() []pipeline.Action) {
+	if d.busy == nil {
+		d.busy = make(map[string]bool)
+		d.pending = make(map[string]func() []pipeline.Action)
+	}
+	if d.busy[job] {
+		d.pending[job] = fn
+		return
+	}
+	d.busy[job] = true
+	done := d.jobDone()
+	go func() { done <- jobResult{job: job, actions: fn()} }()
+}
+
+// jobDone returns the channel off-loop jobs report to. It is buffered for
+// one result per recurring job, so those never wait on a busy loop; a
+// candidate comparison or conflict resolution, rare and long, may wait its
+// turn.
+func (d *pipelineDaemon) jobDone() chan jobResult {
+	if d.done == nil {
+		d.done = make(chan jobResult, 2) // plan, issue watch
+	}
+	return d.done
+}
+
+// finish logs what an off-loop job did and starts its pending rerun, if
+// any. Launched agents change run state, so it is reloaded at once, and a
+// finished conflict resolution's PR is evaluated again so one the resolver
+// couldn't handle gets its rebase agent without waiting for the next poll.
+func (d *pipelineDaemon) finish(r jobResult) {
+	d.logJob(r)
+	fn := d.pending[r.job]
+	delete(d.pending, r.job)
+	if fn != nil && d.leading() {
+		d.background(r.job, fn)
+	}
+	if len(r.actions) > 0 {
+		d.reload()
+	}
+	if pr, ok := strings.CutPrefix(r.job, "resolve "); ok {
+		if matched := statesForPR(d.states, pr); len(matched) > 0 {
+			d.evaluate(matched)
+		}
+	}
+}
+
+// drain waits for running off-loop jobs at shutdown, so a launch isn't cut
+// off halfway. Pending reruns are dropped.
+func (d *pipelineDaemon) drain() {
+	clear(d.pending)
+	for {
+		running := false
+		for _, busy := range d.busy {
+			running = running || busy
+		}
+		if !running {
+			return
+		}
+		d.logJob(<-d.jobDone())
+	}
+}
+
+func (d *pipelineDaemon) logJob(r jobResult) {
+	d.busy[r.job] = false
+	for _, a := range r.actions {
+		if a.Error != "" {
+			d.logger.Error(r.job+" action failed", "detail", a.Detail, "err", a.Error)
+			continue
+		}
+		d.logger.Info(r.job+" action", "detail", a.Detail)
+	}
+}
+
+// watchIssues launches agents for labelled issues in the watched repos, or
+// in repo alone when it is set, off the loop. Followers leave this to the
+// leader.
+func (d *pipelineDaemon) watchIssues(repo string) {
+	if d.issues == nil || !d.leading() {
+		return
+	}
+	// A pass queued behind a running one covers every repo, so it also
+	// covers whatever an earlier queued request was for.
+	if d.busy["issue watch"] {
+		repo = ""
+	}
+	states := d.states
+	running := func(s *run.State) bool { return s.IsAgentRunningWith(d.tmuxDeps) }
+	d.background("issue watch", func() []pipeline.Action {
+		return d.issues.Watch(context.Background(), repo, states, running)
+	})
+}
+
+// evaluate fetches GitHub status for the PRs referenced by subset and runs the
+// pipeline controller over them, then checks any default branches the
+// post-merge watchdog is watching. Followers skip evaluation entirely.
+func (d *pipelineDaemon) evaluate(subset []*run.State) {
+	if !d.leading() {
+		return
+	}
+	var actions []pipeline.Action
+	if statuses := fetchPRStatuses(d.ghClient, subset); len(statuses) > 0 {
+		actions = d.ctrl.HandleGHStatus(context.Background(), toPipelineStatuses(statuses, d.states), d.states)
+	}
+	for _, r := range d.ctrl.PendingResolutions() {
+		d.background("resolve "+r.PRNumber, func() []pipeline.Action {
+			return d.ctrl.ResolveConflicts(context.Background(), r)
+		})
+	}
+	// The post-merge watchdog keeps polling the default branch after the
+	// merged PR's runs are gone.
+	if watches := d.ctrl.MainWatches(); len(watches) > 0 {
+		mainActions := d.ctrl.HandleMainStatus(context.Background(), fetchMainStatuses(d.ghClient, watches, d.ctrl.RequiredChecksOnly), d.states)
+		actions = append(actions, mainActions...)
+	}
+	for _, a := range actions {
+		if a.Error != "" {
+			d.logger.Error("pipeline action failed", "type", a.Type, "detail", a.Detail, "err", a.Error)
+			continue
+		}
+		d.logger.Info("pipeline action", "type", a.Type, "detail", a.Detail)
+	}
+	if len(actions) > 0 {
+		// Dispatched agents and merges change run state; pick it up now
+		// rather than waiting for the watcher.
+		d.reload()
+	}
+}
+
+// watchStateDir forwards a signal for every .json change in dir. The returned
+// func stops the watcher.
+func watchStateDir(dir string) (<-chan struct{}, func(), error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, fmt.Errorf("creating state dir: %w", err)
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating file watcher: %w", err)
+	}
+	if err := w.Add(dir); err != nil {
+		w.Close()
+		return nil, nil, fmt.Errorf("watching state dir: %w", err)
+	}
+	out := make(chan struct{}, 1)
+	go func() {
+		for {
+			select {
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if filepath.Ext(ev.Name) != ".json" {
+					continue
+				}
+				select {
+				case out <- struct{}{}:
+				default:
+				}
+			case _, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return out, func() { w.Close() }, nil
+}
+
+// leadership is a process's claim on the session's pipeline leader lease.
+// The lease is renewed on its own goroutine (keep), since the event loop
+// blocks for as long as a merge or agent launch takes, far longer than
+// LeaseTTL. A lease acquired there is only acted on once the loop adopts
+// it (renew), which first reloads the controller from the checkpoint so it
+// resumes from the previous leader's state rather than whatever it held
+// when it last led.
+type leadership struct {
+	lease     *pipeline.Lease
+	ctrl      *pipeline.Controller
+	statePath string
+
+	mu      sync.Mutex
+	adopted time.Time // AcquiredAt of the adopted lease; zero if none
+}
+
+func newLeadership(lease *pipeline.Lease, ctrl *pipeline.Controller, statePath string) *leadership {
+	l := &leadership{lease: lease, ctrl: ctrl, statePath: statePath}
+	ctrl.SetLeader(l.leading)
+	return l
+}
+
+// leading reports whether this process executes pipeline actions: it holds
+// the lease and has adopted this acquisition of it. Nil always leads.
+func (l *leadership) leading() bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lease.IsLeader() && !l.adopted.IsZero() && l.lease.Holder().AcquiredAt.Equal(l.adopted)
+}
+
+// renew heartbeats the lease and adopts it if it was acquired since the
+// last call (here or by keep). It reports whether leadership was just
+// adopted.
+func (l *leadership) renew() (bool, error) {
+	_, _, err := l.lease.TryAcquire()
+	var acquiredAt time.Time
+	if l.lease.IsLeader() {
+		acquiredAt = l.lease.Holder().AcquiredAt
+	}
+	l.mu.Lock()
+	adopt := !acquiredAt.IsZero() && !acquiredAt.Equal(l.adopted)
+	if !adopt {
+		l.adopted = acquiredAt
+	}
+	l.mu.Unlock()
+	if !adopt {
+		return false, err
+	}
+
+	if l.statePath != "" {
+		if lerr := l.ctrl.LoadState(l.statePath); lerr != nil {
+			err = fmt.Errorf("restoring pipeline state: %w", lerr)
+		}
+	}
+	l.mu.Lock()
+	l.adopted = acquiredAt
+	l.mu.Unlock()
+	return true, err
+}
+
+// keep renews the lease every LeaseHeartbeat until ctx is done, however
+// long the event loop is busy. Renewal errors are passed to onErr, if set.
+func (l *leadership) keep(ctx context.Context, onErr func(error)) {
+	t := time.NewTicker(pipeline.LeaseHeartbeat)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if _, _, err := l.lease.TryAcquire(); err != nil && onErr != nil {
+				onErr(err)
+			}
+		}
+	}
+}
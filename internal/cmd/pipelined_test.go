@@ -0,0 +1,408 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patflynn/klaus/internal/config"
+	gh "github.com/patflynn/klaus/internal/github"
+	"github.com/patflynn/klaus/internal/pipeline"
+	"github.com/patflynn/klaus/internal/run"
+)
+
+// statusGHClient is a gh.Client that answers the PR status queries used by
+// fetchPRStatus with fixed values. Other methods are unimplemented.
+type statusGHClient struct {
+	gh.Client
+	ci        string
+	conflicts string // "none" when empty
+}
+
+func (c *statusGHClient) GetState(context.Context, string) string { return "OPEN" }
+func (c *statusGHClient) GetCI(context.Context, string) string    { return c.ci }
+func (c *statusGHClient) GetConflicts(context.Context, string) string {
+	if c.conflicts == "" {
+		return "none"
+	}
+	return c.conflicts
+}
+func (c *statusGHClient) GetReviewDecision(context.Context, string) string { return "APPROVED" }
+func (c *statusGHClient) GetLabels(context.Context, string) []string       { return nil }
+
+// GetChecks fails so fetchPRStatus falls back to GetCI.
+func (c *statusGHClient) GetChecks(context.Context, string) ([]gh.CheckRun, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestPipelineDaemonDispatchesAndShutsDown(t *testing.T) {
+	baseDir := t.TempDir()
+	store := run.NewHomeDirStoreFromPath(baseDir)
+	if err := store.EnsureDirs(); err != nil {
+		t.Fatal(err)
+	}
+	prURL := "https://github.com/o/r/pull/42"
+	if err := store.Save(&run.State{ID: "run-1", PRURL: &prURL, CreatedAt: time.Now().UTC().Format(time.RFC3339)}); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	ctrl := newPipelineController(store, config.Defaults(), logger)
+	ctrl.SetTmuxDeps(testDashboardTmuxDeps())
+	launched := make(chan string, 4)
+	ctrl.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launched <- prNumber
+		return "agent-1", nil
+	})
+
+	d := &pipelineDaemon{
+		store:     store,
+		ghClient:  &statusGHClient{ci: "failing"},
+		ctrl:      ctrl,
+		logger:    logger,
+		tmuxDeps:  testDashboardTmuxDeps(),
+		pollEvery: time.Hour,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- d.run(ctx) }()
+
+	select {
+	case pr := <-launched:
+		if pr != "42" {
+			t.Errorf("launched for PR %q, want 42", pr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon did not dispatch a CI-fix agent")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("run returned %v on shutdown", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon did not shut down after cancel")
+	}
+
+	// The controller checkpointed its state for read-only viewers.
+	states, err := pipeline.ReadStateFile(filepath.Join(baseDir, pipeline.StateFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if states["42"] == nil || states["42"].Stage != pipeline.StageCIFailed {
+		t.Errorf("checkpointed state = %+v, want PR 42 in ci_failed", states["42"])
+	}
+}
+
+// holdLease records another live process on this host (our parent) as the
+// session's pipeline leader.
+func holdLease(t *testing.T, path, kind string) pipeline.LeaseHolder {
+	t.Helper()
+	host, _ := os.Hostname()
+	h := pipeline.LeaseHolder{
+		PID:         os.Getppid(),
+		Host:        host,
+		Kind:        kind,
+		AcquiredAt:  time.Now().UTC(),
+		HeartbeatAt: time.Now().UTC(),
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestPipelineDaemonStandsByUntilLeader(t *testing.T) {
+	baseDir := t.TempDir()
+	store := run.NewHomeDirStoreFromPath(baseDir)
+	if err := store.EnsureDirs(); err != nil {
+		t.Fatal(err)
+	}
+	prURL := "https://github.com/o/r/pull/42"
+	if err := store.Save(&run.State{ID: "run-1", PRURL: &prURL, CreatedAt: time.Now().UTC().Format(time.RFC3339)}); err != nil {
+		t.Fatal(err)
+	}
+	leasePath := filepath.Join(baseDir, pipeline.LeaseFileName)
+	holdLease(t, leasePath, "dashboard")
+
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	ctrl := newPipelineController(store, config.Defaults(), logger)
+	ctrl.SetTmuxDeps(testDashboardTmuxDeps())
+	launches := 0
+	ctrl.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launches++
+		return "agent-1", nil
+	})
+	d := &pipelineDaemon{
+		store:    store,
+		ghClient: &statusGHClient{ci: "failing"},
+		ctrl:     ctrl,
+		logger:   logger,
+		tmuxDeps: testDashboardTmuxDeps(),
+	}
+	d.leader = newLeadership(pipeline.NewLease(leasePath, "pipelined"), ctrl, filepath.Join(baseDir, pipeline.StateFileName))
+
+	if d.heartbeat() {
+		t.Fatal("acquired leadership while a dashboard holds the lease")
+	}
+	d.reload()
+	d.evaluate(d.states)
+	if launches != 0 {
+		t.Fatalf("standby daemon dispatched %d agents", launches)
+	}
+
+	// The dashboard quits and releases the lease.
+	if err := os.Remove(leasePath); err != nil {
+		t.Fatal(err)
+	}
+	if !d.heartbeat() {
+		t.Fatal("did not take over a released lease")
+	}
+	d.evaluate(d.states)
+	if launches != 1 {
+		t.Errorf("leader dispatched %d agents, want 1", launches)
+	}
+}
+
+// TestLeadershipAdoptsBackgroundRenewal checks that a lease taken over by
+// the background heartbeat is only acted on once the event loop adopts it,
+// restoring the previous leader's checkpoint first.
+func TestLeadershipAdoptsBackgroundRenewal(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, pipeline.StateFileName)
+	leasePath := filepath.Join(dir, pipeline.LeaseFileName)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// The previous leader checkpointed PR 7.
+	owner := pipeline.New(nil, nil, logger)
+	if err := owner.LoadState(statePath); err != nil {
+		t.Fatal(err)
+	}
+	owner.SetTmuxDeps(testDashboardTmuxDeps())
+	owner.HandleGHStatus(context.Background(), map[string]*pipeline.PRStatus{
+		"7": {PRNumber: "7", State: "OPEN", CI: "pending"},
+	}, nil)
+	holdLease(t, leasePath, "dashboard")
+
+	ctrl := pipeline.New(nil, nil, logger)
+	l := newLeadership(pipeline.NewLease(leasePath, "pipelined"), ctrl, statePath)
+	if acquired, err := l.renew(); err != nil || acquired || l.leading() {
+		t.Fatalf("renew = %v, %v while another process leads", acquired, err)
+	}
+
+	// The leader goes away and the background heartbeat takes over.
+	if err := os.Remove(leasePath); err != nil {
+		t.Fatal(err)
+	}
+	if held, _, err := l.lease.TryAcquire(); err != nil || !held {
+		t.Fatalf("TryAcquire = %v, %v", held, err)
+	}
+	if l.leading() {
+		t.Fatal("leading before the loop adopted the lease")
+	}
+	if acquired, err := l.renew(); err != nil || !acquired || !l.leading() {
+		t.Fatalf("renew = %v, %v; want leadership adopted", acquired, err)
+	}
+	if ctrl.PipelineStates()["7"] == nil {
+		t.Error("adopting leadership didn't restore the checkpoint")
+	}
+	if acquired, _ := l.renew(); acquired {
+		t.Error("renewing a held lease reported a new acquisition")
+	}
+}
+
+func TestReadOnlyDashboardRendersDaemonState(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, pipeline.StateFileName)
+
+	// The leader's controller checkpoints PR 7 as pending.
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	owner := pipeline.New(nil, nil, logger)
+	if err := owner.LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+	owner.SetTmuxDeps(testDashboardTmuxDeps())
+	owner.HandleGHStatus(context.Background(), map[string]*pipeline.PRStatus{
+		"7": {PRNumber: "7", State: "OPEN", CI: "pending"},
+	}, nil)
+
+	leasePath := filepath.Join(dir, pipeline.LeaseFileName)
+	leader := holdLease(t, leasePath, "pipelined")
+	lease := pipeline.NewLease(leasePath, "dashboard")
+	if leading, _, err := lease.TryAcquire(); err != nil || leading {
+		t.Fatalf("TryAcquire = %v, %v; want follower", leading, err)
+	}
+
+	launches := 0
+	viewerCtrl := pipeline.New(nil, nil, logger)
+	viewerCtrl.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launches++
+		return "x", nil
+	})
+	m := dashboardModel{
+		leader:            newLeadership(lease, viewerCtrl, path),
+		pipelineStatePath: path,
+		pipelineCtrl:      viewerCtrl,
+		ghStatus:          map[string]*prStatus{},
+		tmuxDeps:          testDashboardTmuxDeps(),
+	}
+	updated, _ := m.Update(ghStatusMsg{statuses: map[string]*prStatus{
+		"7": {PRNumber: "7", State: "OPEN", CI: "failing"},
+	}})
+	got := updated.(dashboardModel)
+
+	if launches != 0 {
+		t.Errorf("read-only dashboard dispatched %d agents", launches)
+	}
+	if got.pipelineStates["7"] == nil || got.pipelineStates["7"].Stage != pipeline.StageCIPending {
+		t.Errorf("pipelineStates = %+v, want daemon's ci_pending stage for PR 7", got.pipelineStates["7"])
+	}
+
+	got.states = []*run.State{}
+	view := got.View()
+	if !strings.Contains(view, "Leader: "+leader.String()) {
+		t.Errorf("header does not show the leader %q:\n%s", leader, view)
+	}
+	if !strings.Contains(view, "read-only view") {
+		t.Errorf("follower view not marked read-only:\n%s", view)
+	}
+}
+
+func TestPipelineDaemonRunsJobsOffLoop(t *testing.T) {
+	d := &pipelineDaemon{logger: slog.New(slog.NewJSONHandler(io.Discard, nil))}
+	release := make(chan struct{})
+	runs := 0
+	job := func() []pipeline.Action {
+		runs++
+		<-release
+		return nil
+	}
+
+	// Neither call waits for the job; the second is queued behind the first.
+	d.background("plan", job)
+	d.background("plan", job)
+	close(release)
+	d.finish(<-d.jobDone())
+	d.finish(<-d.jobDone())
+	if runs != 2 || d.busy["plan"] {
+		t.Fatalf("runs = %d, busy = %v; want the queued job run once more", runs, d.busy["plan"])
+	}
+
+	// Shutdown waits for a running job.
+	release = make(chan struct{})
+	d.background("plan", job)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	d.drain()
+	if runs != 3 || d.busy["plan"] {
+		t.Errorf("runs = %d, busy = %v after drain", runs, d.busy["plan"])
+	}
+}
+
+func TestPipelineDaemonWatchesIssuesOffLoop(t *testing.T) {
+	store := run.NewHomeDirStoreFromPath(t.TempDir())
+	if err := store.EnsureDirs(); err != nil {
+		t.Fatal(err)
+	}
+	client := &fakeIssueClient{labels: map[int]string{3: "klaus:auto", 4: "klaus:auto"}}
+	release := make(chan struct{})
+	var launched []int
+	launch := func(w issueWatch, is gh.Issue) (string, error) {
+		<-release
+		launched = append(launched, is.Number)
+		return "run-" + strconv.Itoa(is.Number), nil
+	}
+	d := &pipelineDaemon{
+		store:    store,
+		logger:   slog.New(slog.NewJSONHandler(io.Discard, nil)),
+		tmuxDeps: testDashboardTmuxDeps(),
+		issues: newIssueWatcherWith([]issueWatch{{
+			Project: "proj", Slug: "owner/proj", Label: "klaus:auto", InProgressLabel: "klaus:in-progress",
+			MaxAgents: 2, Budget: "5",
+		}}, nil, func(string) issueWatchClient { return client }, launch),
+	}
+
+	// The launches are blocked, so watchIssues must not wait for them; a
+	// webhook meanwhile queues another pass.
+	d.watchIssues("")
+	d.watchIssues("owner/proj")
+	close(release)
+	for d.busy["issue watch"] {
+		d.finish(<-d.jobDone())
+	}
+	d.drain()
+	if !reflect.DeepEqual(launched, []int{3, 4}) {
+		t.Errorf("launched = %v, want [3 4]", launched)
+	}
+}
+
+func TestPipelineDaemonResolvesConflictsOffLoop(t *testing.T) {
+	store := run.NewHomeDirStoreFromPath(t.TempDir())
+	if err := store.EnsureDirs(); err != nil {
+		t.Fatal(err)
+	}
+	prURL := "https://github.com/o/r/pull/42"
+	if err := store.Save(&run.State{ID: "run-1", PRURL: &prURL, CreatedAt: time.Now().UTC().Format(time.RFC3339)}); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	ctrl := newPipelineController(store, config.Defaults(), logger)
+	ctrl.SetTmuxDeps(testDashboardTmuxDeps())
+	ctrl.SetCleanupWorktree(func(context.Context, string) error { return nil })
+	release := make(chan struct{})
+	ctrl.SetResolveConflicts(func(context.Context, string, string) error {
+		<-release
+		return errors.New("conflicts need manual resolution: main.go")
+	})
+	var launched []string
+	ctrl.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launched = append(launched, prNumber)
+		return "agent-1", nil
+	})
+
+	d := &pipelineDaemon{
+		store:    store,
+		ghClient: &statusGHClient{ci: "passing", conflicts: "yes"},
+		ctrl:     ctrl,
+		logger:   logger,
+		tmuxDeps: testDashboardTmuxDeps(),
+	}
+
+	// The resolver is blocked, so evaluation must not wait for it, and
+	// nothing else is dispatched for the PR meanwhile.
+	d.reload()
+	d.evaluate(d.states)
+	d.evaluate(d.states)
+	if !d.busy["resolve 42"] || len(launched) != 0 {
+		t.Fatalf("busy = %v, launched = %v; want the resolution running and no agent", d.busy, launched)
+	}
+
+	// Its failure hands the conflicts to the rebase agent straight away.
+	close(release)
+	for d.busy["resolve 42"] {
+		d.finish(<-d.jobDone())
+	}
+	d.drain()
+	if !reflect.DeepEqual(launched, []string{"42"}) {
+		t.Errorf("launched = %v, want the rebase agent for 42", launched)
+	}
+}
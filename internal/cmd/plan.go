@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/patflynn/klaus/internal/pipeline"
+	"github.com/patflynn/klaus/internal/plan"
+	"github.com/patflynn/klaus/internal/run"
+	"github.com/patflynn/klaus/internal/tmux"
+	"github.com/patflynn/klaus/internal/verify"
+	"github.com/spf13/cobra"
+)
+
+// planMu serializes plan advancement within a process so two passes never
+// launch the same task.
+var planMu sync.Mutex
+
+// planTaskLauncher launches one plan task and returns its run ID.
+type planTaskLauncher func(p *plan.Plan, t *plan.Task) (string, error)
+
+// launchPlanTask launches a task with 'klaus launch', recording the run as
+// the task's with --plan-task.
+func launchPlanTask(p *plan.Plan, t *plan.Task) (string, error) {
+	args := []string{"launch", "--plan-task", p.ID + "/" + t.ID}
+	if t.Repo != "" {
+		args = append(args, "--repo", t.Repo)
+	}
+	if t.Issue != "" {
+		args = append(args, "--issue", t.Issue)
+	}
+	if t.Budget != "" {
+		args = append(args, "--budget", t.Budget)
+	}
+	if t.Profile != "" {
+		args = append(args, "--profile", t.Profile)
+	}
+	args = append(args, t.Prompt)
+	out, err := exec.Command("klaus", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("klaus launch: %w: %s", err, verify.LastLine(strings.TrimSpace(string(out))))
+	}
+	return pipeline.ExtractAgentID(string(out)), nil
+}
+
+// runLaunchPlan is 'klaus launch --plan'.
+func runLaunchPlan(cmd *cobra.Command, path string) error {
+	if !tmux.InSession() {
+		return fmt.Errorf("klaus launch must be run inside a tmux session")
+	}
+	for _, name := range []string{"issue", "pr", "base", "backport-of", "budget", "profile", "repo", "resume-from", "plan-task", "issue-label", "candidates", "candidate"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s can't be combined with --plan; set it per task in the plan file", name)
+		}
+	}
+	store, err := sessionStore()
+	if err != nil {
+		return err
+	}
+	if err := store.EnsureDirs(); err != nil {
+		return err
+	}
+	hds, ok := store.(*run.HomeDirStore)
+	if !ok {
+		return fmt.Errorf("--plan needs a session directory")
+	}
+	states, err := store.List()
+	if err != nil {
+		return err
+	}
+	return launchPlan(cmd.OutOrStdout(), hds.BaseDir(), path, states, (*run.State).IsAgentRunning, launchPlanTask)
+}
+
+// launchPlan reads a plan file, saves it to the session and launches the
+// tasks that depend on nothing. The rest are launched by the pipeline
+// leader (the dashboard or klaus pipelined) as their dependencies finish.
+func launchPlan(out io.Writer, baseDir, path string, states []*run.State, running func(*run.State) bool, launch planTaskLauncher) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	p, err := plan.Parse(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	id, err := run.GenID()
+	if err != nil {
+		return err
+	}
+	p.ID = id
+	p.Source, _ = filepath.Abs(path)
+	p.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	fmt.Fprintf(out, "Plan %s: %d task(s); held tasks launch once their dependencies %s\n", p.ID, len(p.Tasks), launchWhenVerb(p))
+	planMu.Lock()
+	defer planMu.Unlock()
+	actions, err := advancePlan(p, baseDir, states, running, launch)
+	for _, a := range actions {
+		if a.Error != "" {
+			fmt.Fprintf(out, "  %s: %s\n", a.Detail, a.Error)
+			continue
+		}
+		fmt.Fprintf(out, "  %s\n", a.Detail)
+	}
+	if err != nil {
+		return err
+	}
+	held := 0
+	for _, t := range p.Tasks {
+		if !t.Launched() {
+			held++
+		}
+	}
+	if held > 0 {
+		fmt.Fprintf(out, "  %d task(s) held; the dashboard or klaus pipelined launches them as their dependencies finish\n", held)
+	}
+	return nil
+}
+
+func launchWhenVerb(p *plan.Plan) string {
+	if p.LaunchWhen == plan.LaunchWhenCompleted {
+		return "complete"
+	}
+	return "merge"
+}
+
+// advancePlans launches every ready task in the session's plans. The
+// pipeline leader calls it whenever run states change.
+func advancePlans(baseDir string, states []*run.State, running func(*run.State) bool, launch planTaskLauncher) []pipeline.Action {
+	planMu.Lock()
+	defer planMu.Unlock()
+	plans, err := plan.List(baseDir)
+	if err != nil {
+		return []pipeline.Action{{Type: "error", Detail: "Loading plans failed", Error: err.Error()}}
+	}
+	var actions []pipeline.Action
+	for _, p := range plans {
+		a, err := advancePlan(p, baseDir, states, running, launch)
+		actions = append(actions, a...)
+		if err != nil {
+			actions = append(actions, pipeline.Action{Type: "error", Detail: fmt.Sprintf("Plan %s: saving failed", shortID(p.ID)), Error: err.Error()})
+		}
+	}
+	return actions
+}
+
+// advancePlan records the plan's task statuses and launches its ready
+// tasks. Tasks are marked launched and saved before any launch starts, so a
+// pass that overlaps a slow launch doesn't start the task again. Callers
+// must hold planMu.
+func advancePlan(p *plan.Plan, baseDir string, states []*run.State, running func(*run.State) bool, launch planTaskLauncher) ([]pipeline.Action, error) {
+	changed := p.Record(states, running)
+	ready := p.Ready(states, running)
+	if len(ready) == 0 {
+		if changed {
+			return nil, plan.Save(baseDir, p)
+		}
+		return nil, nil
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, t := range ready {
+		t.LaunchedAt = now
+		t.Status = plan.StatusRunning
+	}
+	if err := plan.Save(baseDir, p); err != nil {
+		return nil, err
+	}
+
+	var actions []pipeline.Action
+	for _, t := range ready {
+		runID, err := launch(p, t)
+		if err != nil {
+			t.LaunchError = err.Error()
+			t.Status = plan.StatusFailed
+			actions = append(actions, pipeline.Action{Type: "error", Detail: fmt.Sprintf("Plan %s: launching %s failed", shortID(p.ID), t.ID), Error: err.Error()})
+			continue
+		}
+		t.RunID = runID
+		actions = append(actions, pipeline.Action{Type: "plan", Detail: fmt.Sprintf("Plan %s: launched %s (run %s)", shortID(p.ID), t.ID, shortID(runID))})
+	}
+	return actions, plan.Save(baseDir, p)
+}
+
+// advancePlansCmd advances the session's plans off the dashboard's update
+// loop, since launching a task takes a while. It reports what it launched
+// as pipeline actions, which also reloads run states.
+func advancePlansCmd(store run.StateStore, states []*run.State, td run.TmuxDeps) tea.Cmd {
+	hds, ok := store.(*run.HomeDirStore)
+	if !ok {
+		return nil
+	}
+	return func() tea.Msg {
+		actions := advancePlans(hds.BaseDir(), states, func(s *run.State) bool { return s.IsAgentRunningWith(td) }, launchPlanTask)
+		if len(actions) == 0 {
+			return nil
+		}
+		return pipelineActionMsg{actions: actions}
+	}
+}
+
+// activePlans returns the session's unfinished plans, or nil when there
+// are none or the store has no session directory.
+func activePlans(store run.StateStore, states []*run.State, running func(*run.State) bool) []*plan.Plan {
+	hds, ok := store.(*run.HomeDirStore)
+	if !ok {
+		return nil
+	}
+	plans, _ := plan.List(hds.BaseDir())
+	var active []*plan.Plan
+	for _, p := range plans {
+		if !p.Finished(states, running) {
+			active = append(active, p)
+		}
+	}
+	return active
+}
+
+// planProgressLines summarizes each unfinished plan for the dashboard.
+func planProgressLines(store run.StateStore, states []*run.State, td run.TmuxDeps) []string {
+	running := func(s *run.State) bool { return s.IsAgentRunningWith(td) }
+	var lines []string
+	for _, p := range activePlans(store, states, running) {
+		lines = append(lines, fmt.Sprintf("  plan %s (%s): %s", shortID(p.ID), filepath.Base(p.Source), p.Summary(states, running)))
+	}
+	return lines
+}
+
+// renderPlans writes each plan's progress and its tasks' statuses.
+func renderPlans(w io.Writer, plans []*plan.Plan, states []*run.State, running func(*run.State) bool) {
+	for _, p := range plans {
+		fmt.Fprintf(w, "\nPlan %s (%s): %s\n", shortID(p.ID), filepath.Base(p.Source), p.Summary(states, running))
+		for _, t := range p.Tasks {
+			line := fmt.Sprintf("  %-12s %-10s", t.ID, p.Status(t, states, running))
+			if s := p.Run(t, states); s != nil {
+				line += " run " + shortID(s.ID)
+				if s.PRURL != nil {
+					line += " · " + *s.PRURL
+				}
+			} else if t.RunID != "" {
+				line += " run " + shortID(t.RunID)
+			}
+			if len(t.DependsOn) > 0 {
+				line += " · after " + strings.Join(t.DependsOn, ", ")
+			}
+			if t.LaunchError != "" {
+				line += " · " + t.LaunchError
+			}
+			fmt.Fprintln(w, strings.TrimRight(line, " "))
+		}
+	}
+}
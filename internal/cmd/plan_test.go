@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/patflynn/klaus/internal/plan"
+	"github.com/patflynn/klaus/internal/run"
+)
+
+func TestLaunchPlanHoldsDependentTasks(t *testing.T) {
+	baseDir := t.TempDir()
+	planFile := filepath.Join(t.TempDir(), "tasks.yaml")
+	if err := os.WriteFile(planFile, []byte(`
+tasks:
+  - {id: schema, prompt: Add the table, repo: owner/repo}
+  - {id: api, prompt: Expose it, depends_on: [schema]}
+  - {id: docs, prompt: Document it, issue: 7}
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var launched []string
+	launch := func(p *plan.Plan, task *plan.Task) (string, error) {
+		launched = append(launched, task.ID)
+		if task.ID == "docs" {
+			return "", fmt.Errorf("no tmux")
+		}
+		return "run-" + task.ID, nil
+	}
+	notRunning := func(*run.State) bool { return false }
+
+	var out bytes.Buffer
+	if err := launchPlan(&out, baseDir, planFile, nil, notRunning, launch); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(launched, []string{"schema", "docs"}) {
+		t.Errorf("launched = %v, want [schema docs]", launched)
+	}
+	for _, want := range []string{"launched schema (run schema)", "launching docs failed: no tmux", "1 task(s) held"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	plans, err := plan.List(baseDir)
+	if err != nil || len(plans) != 1 {
+		t.Fatalf("List() = %v, %v; want the saved plan", plans, err)
+	}
+	p := plans[0]
+	if p.Task("schema").RunID != "run-schema" || p.Task("docs").LaunchError == "" || p.Task("api").Launched() {
+		t.Errorf("saved tasks = %+v %+v %+v", p.Task("schema"), p.Task("api"), p.Task("docs"))
+	}
+
+	// Nothing more launches until schema's PR merges, and only once after.
+	planTask := "schema"
+	schemaRun := &run.State{ID: "run-schema", PlanID: &p.ID, PlanTask: &planTask}
+	launched = nil
+	if actions := advancePlans(baseDir, []*run.State{schemaRun}, notRunning, launch); len(actions) != 0 {
+		t.Errorf("actions before merge = %+v, want none", actions)
+	}
+	merged := "2026-10-17T00:00:00Z"
+	schemaRun.MergedAt = &merged
+	actions := advancePlans(baseDir, []*run.State{schemaRun}, notRunning, launch)
+	advancePlans(baseDir, []*run.State{schemaRun}, notRunning, launch)
+	if !reflect.DeepEqual(launched, []string{"api"}) {
+		t.Errorf("launched after merge = %v, want [api] once", launched)
+	}
+	if len(actions) != 1 || actions[0].Type != "plan" {
+		t.Errorf("actions = %+v, want one plan action", actions)
+	}
+
+	var rendered bytes.Buffer
+	p, _ = plan.Load(baseDir, p.ID)
+	renderPlans(&rendered, []*plan.Plan{p}, []*run.State{schemaRun}, notRunning)
+	for _, want := range []string{"tasks.yaml): 1/3 merged, 1 running, 1 failed", "schema       merged     run schema", "api          running    run api · after schema"} {
+		if !strings.Contains(rendered.String(), want) {
+			t.Errorf("rendered plan missing %q:\n%s", want, rendered.String())
+		}
+	}
+}
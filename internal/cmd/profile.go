@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/patflynn/klaus/internal/config"
+)
+
+// profileBudget returns the profile's budget, or "" for none.
+func profileBudget(p *config.Profile) string {
+	if p == nil {
+		return ""
+	}
+	return p.Budget
+}
+
+// profileClaudeArgs returns the claude flags a profile sets, unquoted. Tool
+// lists are passed comma-joined as one argument so the variadic flags can't
+// swallow the prompt that follows them. maxTurns is false for interactive
+// sessions, where claude ignores --max-turns.
+func profileClaudeArgs(p *config.Profile, maxTurns bool) []string {
+	if p == nil {
+		return nil
+	}
+	var args []string
+	if p.Model != "" {
+		args = append(args, "--model", p.Model)
+	}
+	if len(p.AllowedTools) > 0 {
+		args = append(args, "--allowedTools", strings.Join(p.AllowedTools, ","))
+	}
+	if len(p.DisallowedTools) > 0 {
+		args = append(args, "--disallowedTools", strings.Join(p.DisallowedTools, ","))
+	}
+	if maxTurns && p.MaxTurns > 0 {
+		args = append(args, "--max-turns", strconv.Itoa(p.MaxTurns))
+	}
+	return args
+}
+
+// appendProfilePrompt appends the profile's system prompt file, if any, to
+// sysPrompt. Its relative path is looked up in repoRoot's .klaus/.
+func appendProfilePrompt(sysPrompt string, p *config.Profile, repoRoot string) (string, error) {
+	extra, err := p.SystemPrompt(repoRoot)
+	if err != nil || extra == "" {
+		return sysPrompt, err
+	}
+	return sysPrompt + "\n\n" + extra, nil
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	gh "github.com/patflynn/klaus/internal/github"
+	"github.com/spf13/cobra"
+)
+
+var rebaseCmd = &cobra.Command{
+	Use:   "rebase <pr-number>",
+	Short: "Rebase a PR onto its base branch, resolving trivial conflicts",
+	Long: `Rebases a PR onto its base branch in a temporary worktree and force-pushes
+it, without an agent.
+
+Conflicts that don't need judgment are resolved automatically:
+
+  - files git rerere has a recorded resolution for
+  - lockfiles (go.sum, package-lock.json, pnpm-lock.yaml, yarn.lock,
+    Cargo.lock, flake.lock, uv.lock, poetry.lock), regenerated with their
+    package manager from the base branch's version
+  - generated files, regenerated with generate_commands (default:
+    go generate ./... for Go projects)
+
+The result must pass verify_commands (or the detected build and test
+commands) before it is pushed. Any other conflict aborts the rebase and
+leaves the PR untouched. The pipeline runs this before dispatching a
+rebase agent.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		prNum := strings.TrimPrefix(args[0], "#")
+		repoFlag, _ := cmd.Flags().GetString("repo")
+
+		store, _ := sessionStore()
+		repo := buildRepoResolver(store, repoFlag)(prNum)
+		dir := buildRepoDirResolver(store)(prNum)
+
+		// A stacked PR rebases onto its parent's branch, not the default
+		// branch.
+		base, err := gh.NewGHCLIClient(repo).GetBaseBranch(cmd.Context(), prNum)
+		if err != nil || base == "" {
+			base = repoDefaultBranch(dir)
+		}
+
+		fmt.Printf("Rebasing PR #%s onto %s...\n", prNum, base)
+		if err := rebaseAndPush(os.Stdout, prNum, repo, dir, base); err != nil {
+			return err
+		}
+		fmt.Printf("Rebased and pushed PR #%s.\n", prNum)
+		return nil
+	},
+}
+
+func init() {
+	rebaseCmd.Flags().String("repo", "", "Target repo (owner/repo)")
+	rootCmd.AddCommand(rebaseCmd)
+}
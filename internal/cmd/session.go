@@ -62,6 +62,7 @@ func runSession(cmd *cobra.Command, forceNew bool) error {
 
 	continueFlag, _ := cmd.Flags().GetBool("continue")
 	resumeFlag, _ := cmd.Flags().GetString("resume")
+	profileName, _ := cmd.Flags().GetString("profile")
 
 	// Git repo is optional — session can run without one
 	root, _ := git.RepoRoot()
@@ -72,6 +73,9 @@ func runSession(cmd *cobra.Command, forceNew bool) error {
 	if err != nil {
 		return err
 	}
+	if _, err := cfg.Profile(profileName); err != nil {
+		return err
+	}
 
 	sessionsDir, err := run.SessionsDir()
 	if err != nil {
@@ -218,6 +222,10 @@ func runSession(cmd *cobra.Command, forceNew bool) error {
 			CreatedAt:       prevState.CreatedAt,
 			RepoRoot:        prevState.RepoRoot,
 			ClaudeSessionID: prevState.ClaudeSessionID,
+			Profile:         prevState.Profile,
+		}
+		if profileName != "" {
+			state.Profile = &profileName
 		}
 		if err := store.Save(state); err != nil {
 			return fmt.Errorf("saving refreshed session state: %w", err)
@@ -291,6 +299,7 @@ func runSession(cmd *cobra.Command, forceNew bool) error {
 			Worktree:  worktree,
 			CreatedAt: createdAt,
 			RepoRoot:  repoRoot,
+			Profile:   stringPtr(profileName),
 		}
 		if err := store.Save(state); err != nil {
 			return fmt.Errorf("saving state: %w", err)
@@ -313,6 +322,17 @@ func runSession(cmd *cobra.Command, forceNew bool) error {
 	if err != nil {
 		return fmt.Errorf("rendering session prompt: %w", err)
 	}
+	// A resumed session keeps the profile it started with.
+	var profile *config.Profile
+	if state.Profile != nil {
+		if profile, err = cfg.Profile(*state.Profile); err != nil {
+			return err
+		}
+		if sessionPrompt, err = appendProfilePrompt(sessionPrompt, profile, root); err != nil {
+			return err
+		}
+		fmt.Printf("  profile:  %s\n", *state.Profile)
+	}
 
 	// Configure tmux window for better situational awareness
 	currentPane := os.Getenv("TMUX_PANE")
@@ -362,6 +382,7 @@ func runSession(cmd *cobra.Command, forceNew bool) error {
 		"-n", id,
 		"--append-system-prompt", sessionPrompt,
 	}
+	claudeArgs = append(claudeArgs, profileClaudeArgs(profile, false)...)
 	if resuming && state.ClaudeSessionID != nil && *state.ClaudeSessionID != "" {
 		claudeArgs = append(claudeArgs, "--resume", *state.ClaudeSessionID)
 	} else if resuming {
@@ -486,6 +507,8 @@ func genUUIDv4() string {
 func init() {
 	sessionCmd.Flags().Bool("continue", false, "Resume the most recent coordinator session")
 	sessionCmd.Flags().String("resume", "", "Resume a specific session by ID")
+	sessionCmd.Flags().String("profile", "", "Agent profile from config \"profiles\" (model, extra system prompt, tools); kept when the session is resumed")
+	newSessionCmd.Flags().String("profile", "", "Agent profile from config \"profiles\" (model, extra system prompt, tools)")
 	rootCmd.AddCommand(sessionCmd)
 	rootCmd.AddCommand(newSessionCmd)
 }
@@ -81,6 +81,8 @@ var statusCmd = &cobra.Command{
 				s.ID, status, cost, issue, repo, host, pr, ci, conflicts, merge, prompt)
 		}
 
+		running := (*run.State).IsAgentRunning
+		renderPlans(os.Stdout, activePlans(store, states, running), states, running)
 		return nil
 	},
 }
@@ -97,6 +99,9 @@ func determineStatus(ctx context.Context, s *run.State, tc tmux.Client) string {
 		if s.MergedAt != nil {
 			return "merged"
 		}
+		if s.ClosedAt != nil {
+			return "closed"
+		}
 		return "tracking"
 	}
 
@@ -107,6 +112,9 @@ func determineStatus(ctx context.Context, s *run.State, tc tmux.Client) string {
 	if s.MergedAt != nil {
 		return "merged"
 	}
+	if s.ClosedAt != nil {
+		return "closed"
+	}
 	if s.PRURL != nil {
 		return "pr-created"
 	}
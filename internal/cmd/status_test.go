@@ -104,6 +104,14 @@ func TestDetermineStatus(t *testing.T) {
 			s:    &run.State{PRURL: strPtr("https://github.com/owner/repo/pull/1")},
 			want: "pr-created",
 		},
+		{
+			name: "closed PR returns closed",
+			s: &run.State{
+				PRURL:    strPtr("https://github.com/owner/repo/pull/1"),
+				ClosedAt: strPtr("2026-04-01T00:00:00Z"),
+			},
+			want: "closed",
+		},
 		{
 			name: "session type with missing worktree returns ended",
 			s:    &run.State{Type: "session", Worktree: "/nonexistent/path/that/does/not/exist"},
@@ -26,13 +26,18 @@ import (
 // types that aren't emitted yet (reserved entries) so the filter remains
 // forward-compatible as the pipeline grows.
 var defaultWatchFilter = []string{
-	event.AgentPRCreated, // live
-	"agent:error",        // reserved
-	event.PRApproved,     // live
-	event.PRMerged,       // live
-	"ci:failed",          // reserved (closest live equivalent: agent:ci-failed)
-	"ci:passed",          // reserved (closest live equivalent: agent:ci-passed)
-	"pr:comment",         // reserved
+	event.AgentPRCreated,     // live
+	"agent:error",            // reserved
+	event.PRApproved,         // live
+	event.PRMerged,           // live
+	event.PRClosed,           // live
+	event.MainBroken,         // live
+	event.PRBudgetExceeded,   // live
+	event.PRBackportOpened,   // live
+	event.CandidatesCompared, // live
+	"ci:failed",              // reserved (closest live equivalent: agent:ci-failed)
+	"ci:passed",              // reserved (closest live equivalent: agent:ci-passed)
+	"pr:comment",             // reserved
 }
 
 // knownEventTypes maps event types to a one-line description and whether the
@@ -48,7 +53,14 @@ var knownEventTypes = []eventTypeInfo{
 	{event.PRAwaitingApproval, "live", "A PR is ready for human approval"},
 	{event.PRApproved, "live", "A PR was approved"},
 	{event.PRMerged, "live", "A PR merged"},
+	{event.PRClosed, "live", "A PR klaus was tracking was closed without merging; its runs are cleaned up per pipeline.closed_cleanup"},
 	{event.PRApprovalChanged, "live", "Klaus-internal approval state for a PR changed (e.g. via klaus approve)"},
+	{event.MainBroken, "live", "CI failed on a merged PR's merge commit: the merge broke the default branch"},
+	{event.PRBudgetExceeded, "live", "A PR hit its cumulative agent spend cap; the pipeline stopped dispatching agents for it"},
+	{event.PRBackportOpened, "live", "A backport of a merged PR onto a release branch opened (klaus backport)"},
+	{event.CIFlaky, "live", "A PR's failed checks passed when the pipeline re-ran them (a flake)"},
+	{event.CandidatesCompared, "live", "A best-of-N launch's candidates were built, tested and scored; the winner got a PR (klaus launch --candidates)"},
+	{event.PipelineTransition, "live", "A pipeline rule fired for a PR (audit trail; not in the default filter)"},
 	{"agent:error", "reserved", "Reserved for unrecoverable agent failures (not currently emitted; use agent:needs-attention)"},
 	{"ci:failed", "reserved", "Reserved short name (currently emitted as agent:ci-failed)"},
 	{"ci:passed", "reserved", "Reserved short name (currently emitted as agent:ci-passed)"},
@@ -446,6 +458,48 @@ func eventSummary(evt event.Event) string {
 			return fmt.Sprintf("PR #%s merged", prNum)
 		}
 		return "PR merged"
+	case event.PRClosed:
+		if prNum != "" {
+			return fmt.Sprintf("PR #%s closed without merging", prNum)
+		}
+		return "PR closed without merging"
+	case event.MainBroken:
+		sha := get("sha")
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		return fmt.Sprintf("%s@%s broken by PR #%s (%s)", get("repo"), get("branch"), prNum, sha)
+	case event.PRBudgetExceeded:
+		return fmt.Sprintf("PR #%s spent $%s of its $%s cap", prNum, get("spent_usd"), get("cap_usd"))
+	case event.CIFlaky:
+		var checks []string
+		if list, ok := d["checks"].([]interface{}); ok {
+			for _, c := range list {
+				checks = append(checks, fmt.Sprint(c))
+			}
+		}
+		return fmt.Sprintf("PR #%s: flaky %s passed on rerun", prNum, strings.Join(checks, ", "))
+	case event.PRBackportOpened:
+		return fmt.Sprintf("PR #%s backports #%s to %s: %s", prNum, get("backport_of"), get("branch"), prURL)
+	case event.CandidatesCompared:
+		n := 0
+		if list, ok := d["candidates"].([]interface{}); ok {
+			n = len(list)
+		}
+		winner := get("winner")
+		if winner == "" {
+			return fmt.Sprintf("best of %d (%s): no candidate could win", n, get("group"))
+		}
+		if prURL != "" {
+			return fmt.Sprintf("best of %d (%s): %s won, PR #%s: %s", n, get("group"), winner, prNum, prURL)
+		}
+		return fmt.Sprintf("best of %d (%s): %s won, opening its PR failed", n, get("group"), winner)
+	case event.PipelineTransition:
+		line := fmt.Sprintf("PR #%s %s → %s (%s)", prNum, get("from"), get("to"), get("rule"))
+		if runID := get("dispatched_run_id"); runID != "" {
+			line += " dispatched " + runID
+		}
+		return line
 	}
 
 	// Generic fallback: list known fields in a stable order.
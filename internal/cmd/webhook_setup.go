@@ -20,6 +20,7 @@ var webhookEvents = []string{
 	"check_suite",
 	"pull_request",
 	"pull_request_review",
+	"issues",
 }
 
 // ghHook represents a GitHub repository webhook from the API.
@@ -152,13 +153,9 @@ func runWebhookSetup(cmd *cobra.Command, args []string, deps WebhookDeps) error
 		return fmt.Errorf("webhook.secret_file is not configured; add it to your klaus config")
 	}
 
-	secretBytes, err := deps.ReadFile(cfg.Webhook.SecretFile)
+	secret, err := cfg.Webhook.ReadSecret(deps.ReadFile)
 	if err != nil {
-		return fmt.Errorf("reading webhook secret: %w", err)
-	}
-	secret := strings.TrimSpace(string(secretBytes))
-	if secret == "" {
-		return fmt.Errorf("webhook secret file is empty: %s", cfg.Webhook.SecretFile)
+		return err
 	}
 
 	reg, err := deps.LoadRegistry()
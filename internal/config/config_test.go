@@ -127,6 +127,26 @@ func TestRenderPromptNoIssue(t *testing.T) {
 	}
 }
 
+func TestRenderPromptStackedBase(t *testing.T) {
+	dir := t.TempDir()
+
+	prompt, err := RenderPrompt(dir, PromptVars{RunID: "r2", Base: "agent/r1"})
+	if err != nil {
+		t.Fatalf("RenderPrompt() error: %v", err)
+	}
+	if !strings.Contains(prompt, "`--base agent/r1`") {
+		t.Errorf("stacked prompt should target the parent branch:\n%s", prompt)
+	}
+
+	prompt, err = RenderPrompt(dir, PromptVars{RunID: "r3"})
+	if err != nil {
+		t.Fatalf("RenderPrompt() error: %v", err)
+	}
+	if strings.Contains(prompt, "--base") {
+		t.Error("unstacked prompt should not mention --base")
+	}
+}
+
 func TestRenderPromptCustomTemplate(t *testing.T) {
 	dir := t.TempDir()
 	klausDir := filepath.Join(dir, ".klaus")
@@ -1042,3 +1062,135 @@ func TestLinkSharedMemoryDottedPath(t *testing.T) {
 		t.Fatalf("memory path for dotted worktree is not a symlink: %v", err)
 	}
 }
+
+func TestLoadPipelineConfigLayers(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	if err := os.MkdirAll(filepath.Join(homeDir, ".klaus"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	global := `{"pipeline": {"max_fix_attempts": 5, "transitions": {"ci-failing/dispatch-fix-agent": false}, "prompts": {"ci_fix": "global"}}}`
+	if err := os.WriteFile(filepath.Join(homeDir, ".klaus", "config.json"), []byte(global), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repoRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoRoot, ".klaus"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	local := `{"pipeline": {"dispatch_cooldown_seconds": 300, "transitions": {"ci-passing/conflicts-dispatch-rebase": false}, "prompts": {"rebase": "local"}}}`
+	if err := os.WriteFile(filepath.Join(repoRoot, ".klaus", "config.json"), []byte(local), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc := cfg.Pipeline
+	if pc == nil {
+		t.Fatal("pipeline block not loaded")
+	}
+	if pc.MaxFixAttempts != 5 || pc.DispatchCooldownSeconds != 300 {
+		t.Errorf("limits = %d attempts, %ds cooldown; want global 5 and local 300", pc.MaxFixAttempts, pc.DispatchCooldownSeconds)
+	}
+	if len(pc.Transitions) != 2 {
+		t.Errorf("transitions = %v, want global and local entries merged", pc.Transitions)
+	}
+	if pc.Prompts["ci_fix"] != "global" || pc.Prompts["rebase"] != "local" {
+		t.Errorf("prompts = %v, want global and local entries merged", pc.Prompts)
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	if err := os.MkdirAll(filepath.Join(homeDir, ".klaus"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	global := `{"profiles": {"docs": {"model": "haiku", "budget": "1"}, "refactor": {"model": "sonnet", "max_turns": 10}}}`
+	if err := os.WriteFile(filepath.Join(homeDir, ".klaus", "config.json"), []byte(global), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(homeDir, ".klaus", "docs.md"), []byte("Write in plain English.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repoRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoRoot, ".klaus"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	local := `{"profiles": {"refactor": {"model": "opus", "system_prompt_file": "refactor.md"}}}`
+	if err := os.WriteFile(filepath.Join(repoRoot, ".klaus", "config.json"), []byte(local), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoRoot, ".klaus", "refactor.md"), []byte("Keep behavior identical."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refactor, err := cfg.Profile("refactor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refactor.Model != "opus" || refactor.MaxTurns != 0 {
+		t.Errorf("refactor = %+v, want the repo-local profile replacing the global one", refactor)
+	}
+	if got, err := refactor.SystemPrompt(repoRoot); err != nil || got != "Keep behavior identical." {
+		t.Errorf("refactor.SystemPrompt() = %q, %v", got, err)
+	}
+
+	docs, err := cfg.Profile("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs.SystemPromptFile = "docs.md"
+	if got, err := docs.SystemPrompt(repoRoot); err != nil || got != "Write in plain English." {
+		t.Errorf("docs.SystemPrompt() = %q, %v; want the file from ~/.klaus", got, err)
+	}
+	docs.SystemPromptFile = "missing.md"
+	if _, err := docs.SystemPrompt(repoRoot); err == nil {
+		t.Error("expected an error for a missing system prompt file")
+	}
+
+	if p, err := cfg.Profile(""); p != nil || err != nil {
+		t.Errorf("Profile(\"\") = %v, %v; want the default setup", p, err)
+	}
+	if _, err := cfg.Profile("quick-fix"); err == nil || !strings.Contains(err.Error(), "have docs, refactor") {
+		t.Errorf("Profile(unknown) error = %v, want the configured names", err)
+	}
+}
+
+func TestWebhookReadSecret(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret")
+	if err := os.WriteFile(path, []byte("  hunter2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := (&WebhookConfig{SecretFile: path}).ReadSecret(os.ReadFile)
+	if err != nil || got != "hunter2" {
+		t.Errorf("ReadSecret = %q, %v; want hunter2", got, err)
+	}
+
+	if got, err := (&WebhookConfig{}).ReadSecret(os.ReadFile); err != nil || got != "" {
+		t.Errorf("unconfigured secret = %q, %v; want empty, nil", got, err)
+	}
+	var unset *WebhookConfig
+	if got, err := unset.ReadSecret(os.ReadFile); err != nil || got != "" {
+		t.Errorf("secret without webhook config = %q, %v; want empty, nil", got, err)
+	}
+
+	if _, err := (&WebhookConfig{SecretFile: filepath.Join(dir, "missing")}).ReadSecret(os.ReadFile); err == nil {
+		t.Error("expected error for unreadable secret file")
+	}
+
+	empty := filepath.Join(dir, "empty")
+	os.WriteFile(empty, []byte("\n"), 0o600)
+	if _, err := (&WebhookConfig{SecretFile: empty}).ReadSecret(os.ReadFile); err == nil {
+		t.Error("expected error for empty secret file")
+	}
+}
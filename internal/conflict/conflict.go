@@ -0,0 +1,268 @@
+// Package conflict rebases a branch and resolves the conflicts that don't
+// need judgment: ones git rerere has seen resolved before, lockfiles, which
+// are regenerated with their package manager, and generated files, which
+// are regenerated from source. Anything else is left to a person or an
+// agent.
+package conflict
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/patflynn/klaus/internal/verify"
+)
+
+// lockfiles maps lockfile names to the command that reconciles them with
+// their manifest. Each runs in the lockfile's directory after the base
+// branch's version is checked out, so only the entries the branch adds are
+// resolved; nothing is upgraded.
+var lockfiles = map[string]string{
+	"go.sum":            "go mod tidy",
+	"package-lock.json": "npm install --package-lock-only --ignore-scripts",
+	"pnpm-lock.yaml":    "pnpm install --lockfile-only --ignore-scripts",
+	"yarn.lock":         "yarn install --ignore-scripts",
+	"Cargo.lock":        "cargo metadata --format-version 1 >/dev/null",
+	"flake.lock":        "nix flake lock",
+	"uv.lock":           "uv lock",
+	"poetry.lock":       "poetry lock --no-update",
+}
+
+// maxSteps bounds the commits one rebase stops at.
+const maxSteps = 200
+
+// Options configure Rebase.
+type Options struct {
+	// Generate are the commands that regenerate generated files. Without
+	// any, a conflict in a generated file is unresolvable.
+	Generate []string
+}
+
+// Resolution is one conflict Rebase resolved.
+type Resolution struct {
+	File string
+	How  string // the command that regenerated it, or "rerere"
+}
+
+// Result describes a successful Rebase.
+type Result struct {
+	Resolved []Resolution
+}
+
+// UnresolvedError reports conflicts Rebase couldn't resolve. The rebase has
+// been aborted.
+type UnresolvedError struct {
+	Files []string
+}
+
+func (e *UnresolvedError) Error() string {
+	return fmt.Sprintf("conflicts need manual resolution: %s", strings.Join(e.Files, ", "))
+}
+
+// Rebase rebases the branch checked out in dir onto upstream with git rerere
+// enabled, resolving lockfile and generated-file conflicts at each commit
+// it stops at. On failure, including cancellation of ctx, the rebase is
+// aborted and dir is left as it was.
+func Rebase(ctx context.Context, dir, upstream string, opts Options) (Result, error) {
+	var res Result
+	out, err := gitRerere(ctx, dir, "rebase", upstream)
+	res.Resolved = append(res.Resolved, rerereResolved(out)...)
+	if err == nil {
+		return res, nil
+	}
+
+	for step := 0; ; step++ {
+		if !rebaseInProgress(ctx, dir) {
+			return res, fmt.Errorf("rebase failed: %s", verify.LastLine(out))
+		}
+		if step == maxSteps {
+			abort(ctx, dir)
+			return res, fmt.Errorf("rebase stopped more than %d times", maxSteps)
+		}
+
+		resolved, err := resolveStep(ctx, dir, opts)
+		if err != nil {
+			abort(ctx, dir)
+			return res, err
+		}
+		res.Resolved = append(res.Resolved, resolved...)
+
+		// A commit whose changes upstream already has is dropped.
+		if _, err := git(ctx, dir, "diff", "--cached", "--quiet"); err == nil {
+			out, err = gitRerere(ctx, dir, "rebase", "--skip")
+		} else {
+			out, err = gitRerere(ctx, dir, "-c", "core.editor=true", "rebase", "--continue")
+		}
+		res.Resolved = append(res.Resolved, rerereResolved(out)...)
+		if err == nil {
+			return res, nil
+		}
+	}
+}
+
+// resolveStep resolves the conflicts of the commit a rebase stopped at and
+// stages the result.
+func resolveStep(ctx context.Context, dir string, opts Options) ([]Resolution, error) {
+	out, err := git(ctx, dir, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, fmt.Errorf("listing conflicts: %s", verify.LastLine(out))
+	}
+	files := splitLines(out)
+
+	var unresolved, generated []string
+	var resolved []Resolution
+	cmds := make(map[string][]string) // command → directories to run it in
+	for _, f := range files {
+		if cmd, ok := lockfiles[path.Base(f)]; ok {
+			cmds[cmd] = appendUnique(cmds[cmd], path.Dir(f))
+			resolved = append(resolved, Resolution{File: f, How: cmd})
+			continue
+		}
+		if len(opts.Generate) > 0 && isGenerated(ctx, dir, f) {
+			generated = append(generated, f)
+			continue
+		}
+		unresolved = append(unresolved, f)
+	}
+	if len(unresolved) > 0 {
+		return nil, &UnresolvedError{Files: unresolved}
+	}
+
+	// Start every resolved file from the base branch's side ("ours" while
+	// rebasing) and rebuild the branch's changes with its tool.
+	for _, f := range append(resolvedFiles(resolved), generated...) {
+		if out, err := git(ctx, dir, "checkout", "--ours", "--", f); err != nil {
+			return nil, fmt.Errorf("checking out %s: %s", f, verify.LastLine(out))
+		}
+	}
+	names := make([]string, 0, len(cmds))
+	for cmd := range cmds {
+		names = append(names, cmd)
+	}
+	sort.Strings(names)
+	for _, cmd := range names {
+		for _, sub := range cmds[cmd] {
+			if out, err := verify.Exec(ctx, filepath.Join(dir, sub), "sh", "-c", cmd); err != nil {
+				return nil, fmt.Errorf("%s: %s", cmd, verify.LastLine(out))
+			}
+		}
+	}
+	if len(generated) > 0 {
+		for _, cmd := range opts.Generate {
+			if out, err := verify.Exec(ctx, dir, "sh", "-c", cmd); err != nil {
+				return nil, fmt.Errorf("%s: %s", cmd, verify.LastLine(out))
+			}
+		}
+		for _, f := range generated {
+			resolved = append(resolved, Resolution{File: f, How: strings.Join(opts.Generate, " && ")})
+		}
+	}
+
+	if out, err := git(ctx, dir, "add", "-A"); err != nil {
+		return nil, fmt.Errorf("staging resolution: %s", verify.LastLine(out))
+	}
+	return resolved, nil
+}
+
+// isGenerated reports whether f is a generated file: marked
+// linguist-generated in .gitattributes, or carrying Go's
+// "Code generated ... DO NOT EDIT." header on either side of the conflict.
+func isGenerated(ctx context.Context, dir, f string) bool {
+	if out, err := git(ctx, dir, "check-attr", "linguist-generated", "--", f); err == nil {
+		if v := strings.TrimSpace(out[strings.LastIndex(out, ":")+1:]); v == "true" || v == "set" {
+			return true
+		}
+	}
+	for _, stage := range []string{":2:", ":3:"} {
+		out, err := git(ctx, dir, "show", stage+f)
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(strings.NewReader(out))
+		for i := 0; i < 10 && sc.Scan(); i++ {
+			if line := sc.Text(); strings.Contains(line, "Code generated") && strings.Contains(line, "DO NOT EDIT") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rerereResolved lists the files git rerere resolved from a recorded
+// resolution, from rebase output.
+func rerereResolved(out string) []Resolution {
+	var res []Resolution
+	for _, line := range splitLines(out) {
+		// "Resolved 'go.mod' using previous resolution." (or "Staged ...").
+		if !strings.HasSuffix(line, "using previous resolution.") {
+			continue
+		}
+		if i, j := strings.IndexByte(line, '\''), strings.LastIndexByte(line, '\''); i >= 0 && j > i {
+			res = append(res, Resolution{File: line[i+1 : j], How: "rerere"})
+		}
+	}
+	return res
+}
+
+func rebaseInProgress(ctx context.Context, dir string) bool {
+	for _, name := range []string{"rebase-merge", "rebase-apply"} {
+		p, err := git(ctx, dir, "rev-parse", "--git-path", name)
+		if err != nil {
+			continue
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		if _, err := os.Stat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func abort(ctx context.Context, dir string) {
+	_, _ = git(context.WithoutCancel(ctx), dir, "rebase", "--abort")
+}
+
+// gitRerere runs git with rerere recording and reusing resolutions, staging
+// the files it resolves. The resolutions live in the repository's shared
+// rr-cache, so ones recorded in any worktree of the clone are reused.
+func gitRerere(ctx context.Context, dir string, args ...string) (string, error) {
+	return git(ctx, dir, append([]string{"-c", "rerere.enabled=true", "-c", "rerere.autoUpdate=true"}, args...)...)
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	return verify.Exec(ctx, dir, "git", args...)
+}
+
+func resolvedFiles(rs []Resolution) []string {
+	files := make([]string, len(rs))
+	for i, r := range rs {
+		files[i] = r.File
+	}
+	return files
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+func splitLines(s string) []string {
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
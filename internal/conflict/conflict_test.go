@@ -0,0 +1,158 @@
+package conflict
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRepo creates a repo whose main branch and feature branch both change
+// files, returning the repo dir with feature checked out. base and feature
+// are the files each branch writes on top of the shared initial commit.
+func testRepo(t *testing.T, initial, base, feature map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	gitT(t, dir, "init", "-q", "-b", "main")
+	gitT(t, dir, "config", "user.email", "test@example.com")
+	gitT(t, dir, "config", "user.name", "Test")
+	commitFiles(t, dir, initial, "initial")
+	gitT(t, dir, "checkout", "-q", "-b", "feature")
+	commitFiles(t, dir, feature, "feature")
+	gitT(t, dir, "checkout", "-q", "main")
+	commitFiles(t, dir, base, "base")
+	gitT(t, dir, "checkout", "-q", "feature")
+	return dir
+}
+
+func commitFiles(t *testing.T, dir string, files map[string]string, msg string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitT(t, dir, "add", "-A")
+	gitT(t, dir, "commit", "-q", "-m", msg)
+}
+
+func gitT(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRebaseRegeneratesLockfile(t *testing.T) {
+	// A fake lockfile tool: the lockfile lists the manifest's deps, sorted.
+	lockfiles["deps.lock"] = "sort deps > deps.lock"
+	defer delete(lockfiles, "deps.lock")
+
+	dir := testRepo(t,
+		map[string]string{"sub/deps": "a\n", "sub/deps.lock": "a\n", "main.txt": "x\n"},
+		map[string]string{"sub/deps.lock": "a\nb\n", "sub/deps": "a\nb\n", "main.txt": "y\n"},
+		map[string]string{"sub/deps.lock": "a\nc\n", "feature.txt": "z\n"},
+	)
+
+	res, err := Rebase(context.Background(), dir, "main", Options{})
+	if err != nil {
+		t.Fatalf("Rebase() error = %v", err)
+	}
+	want := []Resolution{{File: "sub/deps.lock", How: "sort deps > deps.lock"}}
+	if !reflect.DeepEqual(res.Resolved, want) {
+		t.Errorf("Resolved = %+v, want %+v", res.Resolved, want)
+	}
+	// Regenerated in sub/ from the base branch's manifest.
+	if got := readFile(t, dir, "sub/deps.lock"); got != "a\nb\n" {
+		t.Errorf("deps.lock = %q", got)
+	}
+	if got := gitT(t, dir, "log", "--format=%s", "-3"); got != "feature\nbase\ninitial" {
+		t.Errorf("history = %q", got)
+	}
+}
+
+func TestRebaseRegeneratesGeneratedFile(t *testing.T) {
+	header := "// Code generated by gen. DO NOT EDIT.\n"
+	dir := testRepo(t,
+		map[string]string{"src": "1\n", "gen.go": header + "1\n"},
+		map[string]string{"src": "1\n2\n", "gen.go": header + "1\n2\n"},
+		map[string]string{"gen.go": header + "1\n3\n", "other": "x\n"},
+	)
+	gen := `{ printf '// Code generated by gen. DO NOT EDIT.\n'; cat src; } > gen.go`
+
+	res, err := Rebase(context.Background(), dir, "main", Options{Generate: []string{gen}})
+	if err != nil {
+		t.Fatalf("Rebase() error = %v", err)
+	}
+	if len(res.Resolved) != 1 || res.Resolved[0].File != "gen.go" {
+		t.Errorf("Resolved = %+v, want gen.go", res.Resolved)
+	}
+	if got := readFile(t, dir, "gen.go"); got != header+"1\n2\n" {
+		t.Errorf("gen.go = %q", got)
+	}
+}
+
+func TestRebaseAbortsOnSourceConflict(t *testing.T) {
+	dir := testRepo(t,
+		map[string]string{"main.go": "package main\n"},
+		map[string]string{"main.go": "package main\n// base\n"},
+		map[string]string{"main.go": "package main\n// feature\n"},
+	)
+	head := gitT(t, dir, "rev-parse", "HEAD")
+
+	_, err := Rebase(context.Background(), dir, "main", Options{Generate: []string{"true"}})
+	var unresolved *UnresolvedError
+	if !errors.As(err, &unresolved) || !reflect.DeepEqual(unresolved.Files, []string{"main.go"}) {
+		t.Fatalf("err = %v, want main.go unresolved", err)
+	}
+	if rebaseInProgress(context.Background(), dir) {
+		t.Error("rebase should have been aborted")
+	}
+	if got := gitT(t, dir, "rev-parse", "HEAD"); got != head {
+		t.Errorf("HEAD = %s, want %s", got, head)
+	}
+}
+
+func TestRebaseWithoutConflicts(t *testing.T) {
+	dir := testRepo(t,
+		map[string]string{"a": "1\n"},
+		map[string]string{"b": "2\n"},
+		map[string]string{"c": "3\n"},
+	)
+	res, err := Rebase(context.Background(), dir, "main", Options{})
+	if err != nil {
+		t.Fatalf("Rebase() error = %v", err)
+	}
+	if len(res.Resolved) != 0 {
+		t.Errorf("Resolved = %+v, want none", res.Resolved)
+	}
+}
+
+func TestRerereResolved(t *testing.T) {
+	out := "Auto-merging go.mod\nCONFLICT (content): Merge conflict in go.mod\nStaged 'go.mod' using previous resolution.\nResolved 'api/types.go' using previous resolution.\n"
+	want := []Resolution{{File: "go.mod", How: "rerere"}, {File: "api/types.go", How: "rerere"}}
+	if got := rerereResolved(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("rerereResolved() = %+v, want %+v", got, want)
+	}
+}
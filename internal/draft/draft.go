@@ -71,6 +71,7 @@ type PauseInput struct {
 	CostUSD    float64 // observed spend
 	BudgetUSD  float64 // budget cap (0 if unknown)
 	ExistingPR string  // PR number if known; empty means "discover or create"
+	BaseBranch string  // branch a created PR targets; empty means the repo's default branch
 }
 
 // PauseOutput reports what HandleBudgetPause observed/created.
@@ -238,6 +239,9 @@ func ensurePR(ctx context.Context, r Runner, in PauseInput) (prNumber, prURL str
 	if in.Repo != "" {
 		args = []string{"pr", "create", "--repo", in.Repo, "--draft", "--title", title, "--body", body, "--head", in.Branch}
 	}
+	if in.BaseBranch != "" {
+		args = append(args, "--base", in.BaseBranch)
+	}
 	stdout, err := r.GH(ctx, in.Worktree, args...)
 	if err != nil {
 		return "", "", false, err
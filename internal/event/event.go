@@ -31,6 +31,31 @@ const (
 	// so the same signal can be reused once klaus supports non-GitHub
 	// merge-readiness sources.
 	PRApprovalChanged = "pr:approval-changed"
+	// PipelineTransition records a pipeline FSM rule firing for a PR: the
+	// stage it moved from and to, the rule name, the PR's attempt counters
+	// and the run ID of any agent the rule dispatched.
+	PipelineTransition = "pipeline:transition"
+	// MainBroken signals that CI failed on the merge commit of a PR the
+	// pipeline saw merge, i.e. the merge broke the repo's default branch.
+	MainBroken = "main:broken"
+	// PRBudgetExceeded signals that the agents dispatched against a PR have
+	// spent its per-PR cap, so the pipeline stopped dispatching for it.
+	PRBudgetExceeded = "pr:budget-exceeded"
+	// CIFlaky signals that a PR's failed checks passed when the pipeline
+	// re-ran them on the same commit, i.e. the failure was a flake.
+	CIFlaky = "ci:flaky"
+	// PRBackportOpened signals that a backport of a merged PR was opened
+	// against a release branch, by klaus directly (a clean cherry-pick) or
+	// by the agent it dispatched to resolve the pick's conflicts.
+	PRBackportOpened = "pr:backport-opened"
+	// PRClosed signals that a PR klaus was tracking was closed without
+	// merging. The pipeline stops tracking it and cleans up its runs as
+	// configured (pipeline.closed_cleanup); reopening the PR resumes tracking.
+	PRClosed = "pr:closed"
+	// CandidatesCompared records the comparison of a best-of-N launch's
+	// candidates (klaus launch --candidates): each candidate's score and
+	// rank, the winner and the PR opened for it.
+	CandidatesCompared = "candidates:compared"
 )
 
 // BudgetPausedLabel is the GitHub label applied to PRs whose agents have
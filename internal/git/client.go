@@ -32,6 +32,9 @@ type Client interface {
 	// BranchDelete deletes a local branch.
 	BranchDelete(ctx context.Context, repoDir, branch string) error
 
+	// DeleteRemoteBranch deletes a branch from origin.
+	DeleteRemoteBranch(ctx context.Context, repoDir, branch string) error
+
 	// IsClean reports whether the working tree at repoDir has no uncommitted changes.
 	IsClean(ctx context.Context, repoDir string) (bool, error)
 
@@ -71,6 +74,10 @@ type Client interface {
 	// ReadDataRefFile returns the raw bytes of a file stored in the data ref tree.
 	ReadDataRefFile(ctx context.Context, repoDir, dataRef, treePath string) ([]byte, error)
 
+	// ListDataRefFiles returns the paths of the files under dir in the data
+	// ref tree, or none when the ref or dir doesn't exist.
+	ListDataRefFiles(ctx context.Context, repoDir, dataRef, dir string) ([]string, error)
+
 	// InstallCommitMsgHook installs a commit-msg hook in the given worktree that
 	// strips Claude/Anthropic attribution from commit messages.
 	InstallCommitMsgHook(ctx context.Context, worktreeDir string) error
@@ -42,6 +42,10 @@ func (c *ExecClient) BranchDelete(ctx context.Context, repoDir, branch string) e
 	return BranchDelete(ctx, repoDir, branch)
 }
 
+func (c *ExecClient) DeleteRemoteBranch(ctx context.Context, repoDir, branch string) error {
+	return DeleteRemoteBranch(ctx, repoDir, branch)
+}
+
 func (c *ExecClient) IsClean(ctx context.Context, repoDir string) (bool, error) {
 	return IsClean(ctx, repoDir)
 }
@@ -82,6 +86,10 @@ func (c *ExecClient) FetchDataRef(ctx context.Context, repoDir, dataRef string)
 	return FetchDataRef(ctx, repoDir, dataRef)
 }
 
+func (c *ExecClient) ListDataRefFiles(ctx context.Context, repoDir, dataRef, dir string) ([]string, error) {
+	return ListDataRefFiles(ctx, repoDir, dataRef, dir)
+}
+
 func (c *ExecClient) ReadDataRefFile(ctx context.Context, repoDir, dataRef, treePath string) ([]byte, error) {
 	return ReadDataRefFile(ctx, repoDir, dataRef, treePath)
 }
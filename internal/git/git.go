@@ -86,6 +86,22 @@ func ReadDataRefFile(ctx context.Context, repoDir, dataRef, treePath string) ([]
 	return stdout.Bytes(), nil
 }
 
+// ListDataRefFiles returns the paths of the files stored under dir (e.g.
+// "runs") in the data ref tree, or none when the ref or dir doesn't exist.
+func ListDataRefFiles(ctx context.Context, repoDir, dataRef, dir string) ([]string, error) {
+	if _, err := runGit(ctx, repoDir, "rev-parse", "--verify", "--quiet", dataRef); err != nil {
+		return nil, nil
+	}
+	out, err := runGit(ctx, repoDir, "ls-tree", "-r", "--name-only", dataRef, "--", strings.TrimSuffix(dir, "/")+"/")
+	if err != nil {
+		return nil, fmt.Errorf("listing %s in %s: %w", dir, dataRef, err)
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
 // WorktreeAdd creates a new worktree at path on a new branch based on startPoint.
 // If the branch is already checked out in a stale worktree, it prunes and retries once.
 func WorktreeAdd(ctx context.Context, repoDir, path, branch, startPoint string) error {
@@ -188,6 +204,12 @@ func BranchDelete(ctx context.Context, repoDir, branch string) error {
 	return err
 }
 
+// DeleteRemoteBranch deletes a branch from origin.
+func DeleteRemoteBranch(ctx context.Context, repoDir, branch string) error {
+	_, err := runGit(ctx, repoDir, "push", "origin", "--delete", branch)
+	return err
+}
+
 // IsClean reports whether the working tree at repoDir has no uncommitted changes.
 // Returns true only when git status --porcelain produces empty output.
 func IsClean(ctx context.Context, repoDir string) (bool, error) {
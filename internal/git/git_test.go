@@ -212,6 +212,35 @@ func TestSyncToDataRefMultipleFiles(t *testing.T) {
 	}
 }
 
+func TestListDataRefFiles(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+	ref := "refs/klaus/data"
+
+	// No ref yet: nothing to list, not an error.
+	if got, err := ListDataRefFiles(ctx, repo, ref, "runs"); err != nil || len(got) != 0 {
+		t.Fatalf("ListDataRefFiles (no ref) = %v, %v", got, err)
+	}
+
+	f := filepath.Join(t.TempDir(), "x")
+	os.WriteFile(f, []byte("x"), 0o644)
+	files := map[string]string{"runs/a.json": f, "runs/b.json": f, "logs/a.jsonl": f}
+	if err := SyncToDataRef(ctx, repo, ref, "Runs", files); err != nil {
+		t.Fatalf("SyncToDataRef: %v", err)
+	}
+
+	got, err := ListDataRefFiles(ctx, repo, ref, "runs")
+	if err != nil {
+		t.Fatalf("ListDataRefFiles: %v", err)
+	}
+	if len(got) != 2 || got[0] != "runs/a.json" || got[1] != "runs/b.json" {
+		t.Errorf("ListDataRefFiles = %v, want runs/a.json and runs/b.json", got)
+	}
+	if got, _ := ListDataRefFiles(ctx, repo, ref, "sessions"); len(got) != 0 {
+		t.Errorf("ListDataRefFiles (missing dir) = %v, want none", got)
+	}
+}
+
 // resetProtocolCache resets the sync.Once so protocol detection runs again.
 func resetProtocolCache() {
 	ghProtocolOnce = sync.Once{}
@@ -748,6 +777,32 @@ func TestWorktreeAdd_LiveWorktreeReturnsError(t *testing.T) {
 	}
 }
 
+func TestDeleteRemoteBranch(t *testing.T) {
+	ctx := context.Background()
+	repo := initTestRepo(t)
+
+	bareDir := filepath.Join(t.TempDir(), "bare.git")
+	if out, err := exec.Command("git", "clone", "--bare", repo, bareDir).CombinedOutput(); err != nil {
+		t.Fatalf("bare clone: %v\n%s", err, out)
+	}
+	if _, err := runGit(ctx, repo, "remote", "add", "origin", bareDir); err != nil {
+		t.Fatalf("remote add: %v", err)
+	}
+	if _, err := runGit(ctx, repo, "push", "origin", "main:agent/abc"); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	if err := DeleteRemoteBranch(ctx, repo, "agent/abc"); err != nil {
+		t.Fatalf("DeleteRemoteBranch: %v", err)
+	}
+	if out, _ := runGit(ctx, bareDir, "branch", "--list", "agent/abc"); out != "" {
+		t.Errorf("remote branch should be deleted, got %q", out)
+	}
+	if err := DeleteRemoteBranch(ctx, repo, "agent/abc"); err == nil {
+		t.Error("expected an error deleting a branch that no longer exists")
+	}
+}
+
 func TestWorktreePrune(t *testing.T) {
 	ctx := context.Background()
 	repo := initTestRepo(t)
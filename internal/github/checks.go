@@ -0,0 +1,306 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CheckRun is one CI check (a check run or a commit status) on a PR's head
+// commit.
+type CheckRun struct {
+	Name     string
+	State    string // passing, failing, pending, skipped
+	Link     string
+	Required bool // required by the base branch's protection or rulesets
+}
+
+// requiredChecksTTL is how long a branch's required checks are cached.
+// Protection rules change rarely and every PR on a branch shares them.
+const requiredChecksTTL = 10 * time.Minute
+
+var requiredChecksCache = struct {
+	sync.Mutex
+	entries map[string]requiredChecksEntry
+}{entries: make(map[string]requiredChecksEntry)}
+
+type requiredChecksEntry struct {
+	names   map[string]bool
+	fetched time.Time
+}
+
+// GetChecks returns every check on a PR's head commit, each marked
+// required or not per the base branch's protection and rulesets. Required
+// checks that haven't reported yet are included as pending. When the
+// required set can't be read (e.g. no access to the rules API) no check is
+// marked required.
+func (c *GHCLIClient) GetChecks(ctx context.Context, prRef string) ([]CheckRun, error) {
+	ctx, cancel := ensureTimeout(ctx)
+	defer cancel()
+
+	args := c.ghArgs([]string{"pr", "view", "--json", "url,baseRefName,statusCheckRollup"}, prRef)
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("gh pr view: %w", wrapTimeoutErr(ctx, "gh pr view", err))
+	}
+	prURL, base, checks, err := ParseStatusCheckRollup(stdout.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	slug := OwnerRepoFromPRURL(prURL)
+	if slug == "" || base == "" {
+		return checks, nil
+	}
+	required, err := c.RequiredChecks(ctx, slug, base)
+	if err != nil {
+		return checks, nil
+	}
+	return MarkRequired(checks, required), nil
+}
+
+// RequiredChecks returns the check names a branch requires, from both
+// classic branch protection and repository rulesets. Results are cached
+// for requiredChecksTTL.
+func (c *GHCLIClient) RequiredChecks(ctx context.Context, slug, branch string) (map[string]bool, error) {
+	key := slug + "@" + branch
+	requiredChecksCache.Lock()
+	e, ok := requiredChecksCache.entries[key]
+	requiredChecksCache.Unlock()
+	if ok && time.Since(e.fetched) < requiredChecksTTL {
+		return e.names, nil
+	}
+
+	// The branch endpoint (unlike .../protection) is readable without admin
+	// rights and includes the protection's required status checks.
+	data, err := c.APIGet(ctx, fmt.Sprintf("repos/%s/branches/%s", slug, url.PathEscape(branch)))
+	if err != nil {
+		return nil, err
+	}
+	names, err := ParseBranchRequiredChecks(data)
+	if err != nil {
+		return nil, err
+	}
+	// Rulesets are newer and absent on older GitHub Enterprise servers;
+	// classic protection alone is still a usable answer.
+	if data, err := c.APIGet(ctx, fmt.Sprintf("repos/%s/rules/branches/%s", slug, url.PathEscape(branch))); err == nil {
+		rules, err := ParseRulesetRequiredChecks(data)
+		if err != nil {
+			return nil, err
+		}
+		for n := range rules {
+			names[n] = true
+		}
+	}
+
+	requiredChecksCache.Lock()
+	requiredChecksCache.entries[key] = requiredChecksEntry{names: names, fetched: time.Now()}
+	requiredChecksCache.Unlock()
+	return names, nil
+}
+
+// ParseStatusCheckRollup parses `gh pr view --json url,baseRefName,statusCheckRollup`
+// output into the PR URL, its base branch and its checks.
+func ParseStatusCheckRollup(data []byte) (prURL, base string, checks []CheckRun, err error) {
+	var resp struct {
+		URL         string `json:"url"`
+		BaseRefName string `json:"baseRefName"`
+		Rollup      []struct {
+			Typename   string `json:"__typename"`
+			Name       string `json:"name"`       // CheckRun
+			Status     string `json:"status"`     // CheckRun
+			Conclusion string `json:"conclusion"` // CheckRun
+			DetailsURL string `json:"detailsUrl"` // CheckRun
+			Context    string `json:"context"`    // StatusContext
+			State      string `json:"state"`      // StatusContext
+			TargetURL  string `json:"targetUrl"`  // StatusContext
+		} `json:"statusCheckRollup"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", "", nil, fmt.Errorf("parsing status checks: %w", err)
+	}
+	for _, r := range resp.Rollup {
+		if r.Typename == "StatusContext" {
+			checks = append(checks, CheckRun{Name: r.Context, State: statusContextState(r.State), Link: r.TargetURL})
+			continue
+		}
+		checks = append(checks, CheckRun{Name: r.Name, State: checkRunState(r.Status, r.Conclusion), Link: r.DetailsURL})
+	}
+	return resp.URL, resp.BaseRefName, checks, nil
+}
+
+func checkRunState(status, conclusion string) string {
+	if status != "COMPLETED" {
+		return "pending"
+	}
+	switch conclusion {
+	case "SUCCESS", "NEUTRAL":
+		return "passing"
+	case "SKIPPED":
+		return "skipped"
+	case "STALE":
+		return "pending"
+	default: // FAILURE, TIMED_OUT, CANCELLED, ACTION_REQUIRED, STARTUP_FAILURE
+		return "failing"
+	}
+}
+
+// ParseCommitCheckRuns parses a GET /repos/{owner}/{repo}/commits/{ref}/check-runs
+// response into the commit's checks, with states from CommitCheckState.
+func ParseCommitCheckRuns(data []byte) ([]CheckRun, error) {
+	var resp struct {
+		CheckRuns []struct {
+			Name       string `json:"name"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+			HTMLURL    string `json:"html_url"`
+		} `json:"check_runs"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parsing check runs: %w", err)
+	}
+	checks := make([]CheckRun, 0, len(resp.CheckRuns))
+	for _, r := range resp.CheckRuns {
+		checks = append(checks, CheckRun{Name: r.Name, State: CommitCheckState(r.Status, r.Conclusion), Link: r.HTMLURL})
+	}
+	return checks, nil
+}
+
+// CommitCheckState maps a check run's status and conclusion, as the REST
+// API reports them for a commit, to a CheckRun state. Unlike on a PR, a
+// cancelled run is skipped rather than failing: default-branch workflows are
+// routinely cancelled when a newer push supersedes them.
+func CommitCheckState(status, conclusion string) string {
+	if strings.EqualFold(conclusion, "cancelled") {
+		return "skipped"
+	}
+	return checkRunState(strings.ToUpper(status), strings.ToUpper(conclusion))
+}
+
+func statusContextState(state string) string {
+	switch state {
+	case "SUCCESS":
+		return "passing"
+	case "FAILURE", "ERROR":
+		return "failing"
+	default: // PENDING, EXPECTED
+		return "pending"
+	}
+}
+
+// ParseBranchRequiredChecks returns the required status checks from a
+// GET /repos/{owner}/{repo}/branches/{branch} response.
+func ParseBranchRequiredChecks(data []byte) (map[string]bool, error) {
+	var resp struct {
+		Protection struct {
+			RequiredStatusChecks struct {
+				Contexts []string `json:"contexts"`
+				Checks   []struct {
+					Context string `json:"context"`
+				} `json:"checks"`
+			} `json:"required_status_checks"`
+		} `json:"protection"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parsing branch protection: %w", err)
+	}
+	names := make(map[string]bool)
+	for _, n := range resp.Protection.RequiredStatusChecks.Contexts {
+		names[n] = true
+	}
+	for _, ch := range resp.Protection.RequiredStatusChecks.Checks {
+		names[ch.Context] = true
+	}
+	return names, nil
+}
+
+// ParseRulesetRequiredChecks returns the required status checks from a
+// GET /repos/{owner}/{repo}/rules/branches/{branch} response.
+func ParseRulesetRequiredChecks(data []byte) (map[string]bool, error) {
+	var rules []struct {
+		Type       string `json:"type"`
+		Parameters struct {
+			RequiredStatusChecks []struct {
+				Context string `json:"context"`
+			} `json:"required_status_checks"`
+		} `json:"parameters"`
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parsing branch rules: %w", err)
+	}
+	names := make(map[string]bool)
+	for _, r := range rules {
+		if r.Type != "required_status_checks" {
+			continue
+		}
+		for _, ch := range r.Parameters.RequiredStatusChecks {
+			names[ch.Context] = true
+		}
+	}
+	return names, nil
+}
+
+// MarkRequired flags the checks named in required, and adds a pending entry
+// for each required check that hasn't reported yet (GitHub blocks merging
+// on those too).
+func MarkRequired(checks []CheckRun, required map[string]bool) []CheckRun {
+	seen := make(map[string]bool, len(checks))
+	out := make([]CheckRun, 0, len(checks))
+	for _, ch := range checks {
+		ch.Required = required[ch.Name]
+		seen[ch.Name] = true
+		out = append(out, ch)
+	}
+	var missing []string
+	for n := range required {
+		if !seen[n] {
+			missing = append(missing, n)
+		}
+	}
+	sort.Strings(missing)
+	for _, n := range missing {
+		out = append(out, CheckRun{Name: n, State: "pending", Required: true})
+	}
+	return out
+}
+
+// SummarizeChecks collapses checks into passing, failing or pending, like
+// ParseCIStatus does for `gh pr checks` output. With requiredOnly, only
+// required checks count, unless none is required. No checks at all is
+// passing, matching GetCI's "no checks reported".
+func SummarizeChecks(checks []CheckRun, requiredOnly bool) string {
+	if requiredOnly {
+		var req []CheckRun
+		for _, ch := range checks {
+			if ch.Required {
+				req = append(req, ch)
+			}
+		}
+		if len(req) > 0 {
+			checks = req
+		}
+	}
+	pending := false
+	for _, ch := range checks {
+		switch ch.State {
+		case "failing":
+			return "failing"
+		case "pending":
+			pending = true
+		}
+	}
+	if pending {
+		return "pending"
+	}
+	return "passing"
+}
@@ -0,0 +1,125 @@
+package github
+
+import (
+	"testing"
+)
+
+func TestParseStatusCheckRollup(t *testing.T) {
+	data := []byte(`{
+		"url": "https://github.com/o/r/pull/7",
+		"baseRefName": "main",
+		"statusCheckRollup": [
+			{"__typename": "CheckRun", "name": "test", "status": "COMPLETED", "conclusion": "SUCCESS", "detailsUrl": "https://github.com/o/r/runs/1"},
+			{"__typename": "CheckRun", "name": "lint", "status": "COMPLETED", "conclusion": "FAILURE"},
+			{"__typename": "CheckRun", "name": "e2e", "status": "IN_PROGRESS", "conclusion": ""},
+			{"__typename": "CheckRun", "name": "docs", "status": "COMPLETED", "conclusion": "SKIPPED"},
+			{"__typename": "StatusContext", "context": "codecov/patch", "state": "FAILURE", "targetUrl": "https://codecov.io/x"}
+		]
+	}`)
+	prURL, base, checks, err := ParseStatusCheckRollup(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prURL != "https://github.com/o/r/pull/7" || base != "main" {
+		t.Errorf("prURL=%q base=%q", prURL, base)
+	}
+	want := map[string]string{"test": "passing", "lint": "failing", "e2e": "pending", "docs": "skipped", "codecov/patch": "failing"}
+	if len(checks) != len(want) {
+		t.Fatalf("got %d checks, want %d", len(checks), len(want))
+	}
+	for _, ch := range checks {
+		if want[ch.Name] != ch.State {
+			t.Errorf("%s: state %q, want %q", ch.Name, ch.State, want[ch.Name])
+		}
+	}
+	if checks[4].Link != "https://codecov.io/x" {
+		t.Errorf("status context link = %q", checks[4].Link)
+	}
+}
+
+func TestParseRequiredChecks(t *testing.T) {
+	branch := []byte(`{"name": "main", "protection": {"enabled": true, "required_status_checks": {
+		"contexts": ["test"], "checks": [{"context": "test", "app_id": 15368}, {"context": "build"}]}}}`)
+	got, err := ParseBranchRequiredChecks(branch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !got["test"] || !got["build"] {
+		t.Errorf("branch protection = %v", got)
+	}
+
+	rules := []byte(`[
+		{"type": "pull_request", "parameters": {"required_approving_review_count": 1}},
+		{"type": "required_status_checks", "parameters": {"required_status_checks": [{"context": "lint"}]}}
+	]`)
+	got, err = ParseRulesetRequiredChecks(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !got["lint"] {
+		t.Errorf("ruleset = %v", got)
+	}
+
+	got, err = ParseBranchRequiredChecks([]byte(`{"name": "main", "protected": false}`))
+	if err != nil || len(got) != 0 {
+		t.Errorf("unprotected branch = %v, %v", got, err)
+	}
+}
+
+func TestSummarizeChecks(t *testing.T) {
+	checks := MarkRequired([]CheckRun{
+		{Name: "test", State: "passing"},
+		{Name: "codecov/patch", State: "failing"},
+		{Name: "preview", State: "pending"},
+	}, map[string]bool{"test": true, "build": true})
+
+	if len(checks) != 4 || checks[3].Name != "build" || checks[3].State != "pending" || !checks[3].Required {
+		t.Fatalf("unreported required check should be pending: %+v", checks)
+	}
+	if got := SummarizeChecks(checks, false); got != "failing" {
+		t.Errorf("all checks = %q, want failing", got)
+	}
+	if got := SummarizeChecks(checks, true); got != "pending" {
+		t.Errorf("required checks = %q, want pending (build not reported)", got)
+	}
+	checks[3].State = "passing"
+	if got := SummarizeChecks(checks, true); got != "passing" {
+		t.Errorf("required checks = %q, want passing despite optional failures", got)
+	}
+
+	optionalOnly := []CheckRun{{Name: "lint", State: "failing"}}
+	if got := SummarizeChecks(optionalOnly, true); got != "failing" {
+		t.Errorf("with nothing required every check counts: got %q", got)
+	}
+	if got := SummarizeChecks(nil, true); got != "passing" {
+		t.Errorf("no checks = %q, want passing", got)
+	}
+}
+
+func TestParseCommitCheckRuns(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{"all green", `{"check_runs":[{"status":"completed","conclusion":"success"},{"status":"completed","conclusion":"skipped"}]}`, "passing"},
+		{"one failure", `{"check_runs":[{"status":"completed","conclusion":"success"},{"status":"completed","conclusion":"failure"}]}`, "failing"},
+		{"failure beats in-progress", `{"check_runs":[{"status":"in_progress"},{"status":"completed","conclusion":"timed_out"}]}`, "failing"},
+		{"in progress", `{"check_runs":[{"status":"queued"},{"status":"completed","conclusion":"success"}]}`, "pending"},
+		{"cancelled is not a failure", `{"check_runs":[{"status":"completed","conclusion":"cancelled"}]}`, "passing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks, err := ParseCommitCheckRuns([]byte(tt.json))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := SummarizeChecks(checks, false); got != tt.want {
+				t.Errorf("SummarizeChecks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if _, err := ParseCommitCheckRuns([]byte("not json")); err == nil {
+		t.Error("expected an error for garbage")
+	}
+}
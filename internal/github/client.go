@@ -10,16 +10,27 @@ type Client interface {
 
 	// PR queries
 	GetCI(ctx context.Context, prRef string) string
+	GetChecks(ctx context.Context, prRef string) ([]CheckRun, error)
+	RequiredChecks(ctx context.Context, slug, branch string) (map[string]bool, error)
 	GetConflicts(ctx context.Context, prRef string) string
 	GetReviewDecision(ctx context.Context, prRef string) string
 	GetState(ctx context.Context, prRef string) string
 	GetLabels(ctx context.Context, prRef string) []string
 	GetBranch(ctx context.Context, prRef string) (string, error)
+	GetBaseBranch(ctx context.Context, prRef string) (string, error)
 	GetURL(ctx context.Context, prRef string) (string, error)
 	GetTitle(ctx context.Context, prRef string) string
 
 	// PR mutations
 	Merge(ctx context.Context, prNumber, mergeMethod string, deleteBranch bool) error
+	SetBaseBranch(ctx context.Context, prRef, base string) error
+
+	// Issues
+	FetchIssue(ctx context.Context, number string) (*Issue, error)
+	CommentOnIssue(ctx context.Context, number, body string) (commentURL string, err error)
+	ListLabeledIssues(ctx context.Context, label string) ([]Issue, error)
+	SwapIssueLabel(ctx context.Context, number, remove, add string) error
+	EnsureLabel(ctx context.Context, name, description, color string) error
 
 	// Review operations
 	FetchPRReviewComments(ctx context.Context, owner, repo, prNumber string) ([]PRReviewComment, error)
@@ -226,6 +226,26 @@ func (c *GHCLIClient) GetBranch(ctx context.Context, prRef string) (string, erro
 	return branch, nil
 }
 
+// GetBaseBranch returns the branch a PR targets.
+func (c *GHCLIClient) GetBaseBranch(ctx context.Context, prRef string) (string, error) {
+	ctx, cancel := ensureTimeout(ctx)
+	defer cancel()
+
+	args := c.ghArgs([]string{"pr", "view", "--json", "baseRefName", "-q", ".baseRefName"}, prRef)
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("gh pr view: %w", wrapTimeoutErr(ctx, "gh pr view", err))
+	}
+	base := strings.TrimSpace(stdout.String())
+	if base == "" {
+		return "", fmt.Errorf("could not determine base branch for PR %s", prRef)
+	}
+	return base, nil
+}
+
 // GetURL returns the HTML URL for a PR.
 func (c *GHCLIClient) GetURL(ctx context.Context, prRef string) (string, error) {
 	ctx, cancel := ensureTimeout(ctx)
@@ -271,7 +291,22 @@ func (c *GHCLIClient) Merge(ctx context.Context, prNumber, mergeMethod string, d
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("gh pr merge: %w", wrapTimeoutErr(ctx, "gh pr merge", err))
+		return fmt.Errorf("gh pr merge: %w: %s", wrapTimeoutErr(ctx, "gh pr merge", err), strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// SetBaseBranch retargets a PR onto another base branch.
+func (c *GHCLIClient) SetBaseBranch(ctx context.Context, prRef, base string) error {
+	ctx, cancel := ensureTimeout(ctx)
+	defer cancel()
+
+	args := c.ghArgs([]string{"pr", "edit", "--base", base}, prRef)
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("gh pr edit: %w: %s", wrapTimeoutErr(ctx, "gh pr edit", err), strings.TrimSpace(stderr.String()))
 	}
 	return nil
 }
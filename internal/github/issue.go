@@ -0,0 +1,153 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Issue is a GitHub issue and its comment thread.
+type Issue struct {
+	Number   int
+	Title    string
+	URL      string
+	Body     string
+	Labels   []string
+	Comments []IssueComment // oldest first
+}
+
+// IssueComment is one comment on an issue.
+type IssueComment struct {
+	Author    string
+	Body      string
+	CreatedAt string
+}
+
+// issueViewFields are the fields FetchIssue asks 'gh issue view' for.
+const issueViewFields = "number,title,url,body,labels,comments"
+
+// ParseIssue parses the output of 'gh issue view --json number,title,url,body,labels,comments'.
+func ParseIssue(data []byte) (*Issue, error) {
+	var raw struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+		URL    string `json:"url"`
+		Body   string `json:"body"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+		Comments []struct {
+			Author struct {
+				Login string `json:"login"`
+			} `json:"author"`
+			Body      string `json:"body"`
+			CreatedAt string `json:"createdAt"`
+		} `json:"comments"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing issue: %w", err)
+	}
+	is := &Issue{Number: raw.Number, Title: raw.Title, URL: raw.URL, Body: raw.Body}
+	for _, l := range raw.Labels {
+		is.Labels = append(is.Labels, l.Name)
+	}
+	for _, c := range raw.Comments {
+		is.Comments = append(is.Comments, IssueComment{Author: c.Author.Login, Body: c.Body, CreatedAt: c.CreatedAt})
+	}
+	return is, nil
+}
+
+// FetchIssue fetches an issue with its labels and comments.
+func (c *GHCLIClient) FetchIssue(ctx context.Context, number string) (*Issue, error) {
+	ctx, cancel := ensureTimeout(ctx)
+	defer cancel()
+
+	args := c.ghArgs([]string{"issue", "view", "--json", issueViewFields}, number)
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("gh issue view: %w: %s", wrapTimeoutErr(ctx, "gh issue view", err), strings.TrimSpace(stderr.String()))
+	}
+	return ParseIssue(stdout.Bytes())
+}
+
+// CommentOnIssue posts a comment on an issue and returns the comment's URL.
+func (c *GHCLIClient) CommentOnIssue(ctx context.Context, number, body string) (string, error) {
+	ctx, cancel := ensureTimeout(ctx)
+	defer cancel()
+
+	args := c.ghArgs([]string{"issue", "comment", "--body", body}, number)
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("gh issue comment: %w: %s", wrapTimeoutErr(ctx, "gh issue comment", err), strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// ListLabeledIssues returns the open issues carrying label, oldest first.
+// Only Number and Title are set.
+func (c *GHCLIClient) ListLabeledIssues(ctx context.Context, label string) ([]Issue, error) {
+	ctx, cancel := ensureTimeout(ctx)
+	defer cancel()
+
+	args := []string{"issue", "list", "--state", "open", "--label", label, "--json", "number,title", "--limit", "100"}
+	if c.repo != "" {
+		args = append(args, "--repo", c.repo)
+	}
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("gh issue list: %w: %s", wrapTimeoutErr(ctx, "gh issue list", err), strings.TrimSpace(stderr.String()))
+	}
+	var issues []Issue
+	if err := json.Unmarshal(stdout.Bytes(), &issues); err != nil {
+		return nil, fmt.Errorf("parsing issue list: %w", err)
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Number < issues[j].Number })
+	return issues, nil
+}
+
+// SwapIssueLabel removes one label from an issue and adds another.
+func (c *GHCLIClient) SwapIssueLabel(ctx context.Context, number, remove, add string) error {
+	ctx, cancel := ensureTimeout(ctx)
+	defer cancel()
+
+	args := c.ghArgs([]string{"issue", "edit", "--remove-label", remove, "--add-label", add}, number)
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("gh issue edit: %w: %s", wrapTimeoutErr(ctx, "gh issue edit", err), strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// EnsureLabel creates a label in the repo, or updates its description and
+// color if it exists.
+func (c *GHCLIClient) EnsureLabel(ctx context.Context, name, description, color string) error {
+	ctx, cancel := ensureTimeout(ctx)
+	defer cancel()
+
+	args := []string{"label", "create", name, "--force", "--description", description, "--color", color}
+	if c.repo != "" {
+		args = append(args, "--repo", c.repo)
+	}
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("gh label create: %w: %s", wrapTimeoutErr(ctx, "gh label create", err), strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package github
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIssue(t *testing.T) {
+	data := []byte(`{
+		"number": 12,
+		"title": "Login fails on Safari",
+		"url": "https://github.com/owner/repo/issues/12",
+		"body": "Steps to reproduce...",
+		"labels": [{"id": "L1", "name": "bug"}, {"id": "L2", "name": "auth"}],
+		"comments": [{"author": {"login": "alice"}, "body": "Same here", "createdAt": "2026-10-01T12:00:00Z"}]
+	}`)
+	is, err := ParseIssue(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Issue{
+		Number:   12,
+		Title:    "Login fails on Safari",
+		URL:      "https://github.com/owner/repo/issues/12",
+		Body:     "Steps to reproduce...",
+		Labels:   []string{"bug", "auth"},
+		Comments: []IssueComment{{Author: "alice", Body: "Same here", CreatedAt: "2026-10-01T12:00:00Z"}},
+	}
+	if !reflect.DeepEqual(is, want) {
+		t.Errorf("ParseIssue() = %+v, want %+v", is, want)
+	}
+	if _, err := ParseIssue([]byte("not json")); err == nil {
+		t.Error("ParseIssue() should fail on bad JSON")
+	}
+}
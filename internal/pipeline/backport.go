@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	ghutil "github.com/patflynn/klaus/internal/github"
+)
+
+// BackportLabelPrefix starts the PR labels that request a backport:
+// "backport release-1.4" (or "backport:release-1.4", "backport/release-1.4")
+// asks for one onto release-1.4 once the PR merges.
+const BackportLabelPrefix = "backport"
+
+// BackportBranches returns the release branches a PR's labels request
+// backports onto, in label order and without duplicates.
+func BackportBranches(labels []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, l := range labels {
+		rest, ok := strings.CutPrefix(l, BackportLabelPrefix)
+		if !ok || rest == "" || !strings.ContainsRune(" :/", rune(rest[0])) {
+			continue
+		}
+		if b := strings.TrimSpace(strings.TrimLeft(rest, " :/")); b != "" && !seen[b] {
+			seen[b] = true
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// mergedBackportBranches returns the branches to backport a merged PR onto:
+// those its labels named while it was open (ps may be nil for a PR never
+// polled open) and those on its merged status, since backport labels are
+// often added as the PR merges.
+func mergedBackportBranches(ps *PRPipelineState, status *PRStatus) []string {
+	var branches []string
+	if ps != nil {
+		branches = slices.Clone(ps.BackportBranches)
+	}
+	for _, b := range BackportBranches(status.Labels) {
+		if !slices.Contains(branches, b) {
+			branches = append(branches, b)
+		}
+	}
+	return branches
+}
+
+// backportResult is the outcome of an ActionBackport descriptor.
+type backportResult struct {
+	prNumber string
+	branches []string
+	err      error
+}
+
+// backportMerged backports a merged PR onto the branches its labels named.
+// It runs without c.mu held.
+func (c *Controller) backportMerged(ctx context.Context, desc ActionDescriptor) backportResult {
+	slug := ghutil.OwnerRepoFromPRURL(desc.PRURL)
+	if slug == "" {
+		slug = desc.Repo
+	}
+	return backportResult{
+		prNumber: desc.PRNumber,
+		branches: desc.Branches,
+		err:      c.backport(ctx, slug, desc.PRNumber, desc.Branches),
+	}
+}
+
+// applyBackportResult reports a backport. A failed one isn't retried: the
+// PR has left the pipeline, and 'klaus backport' can be rerun by hand.
+// Callers must hold c.mu.
+func (c *Controller) applyBackportResult(r backportResult) []Action {
+	branches := strings.Join(r.branches, ", ")
+	if r.err != nil {
+		c.logger.Warn("backport failed", "pr", r.prNumber, "branches", branches, "err", r.err)
+		return []Action{{Type: "error", Detail: fmt.Sprintf("PR #%s: backport to %s failed", r.prNumber, branches), Error: truncateError(r.err.Error(), 120)}}
+	}
+	return []Action{{Type: "backport", Detail: fmt.Sprintf("Backporting PR #%s to %s", r.prNumber, branches)}}
+}
+
+// defaultBackport runs 'klaus backport', which cherry-picks the PR onto
+// each branch and opens the backport PRs, or dispatches an agent for a
+// pick that conflicts.
+func (c *Controller) defaultBackport(ctx context.Context, slug, prNumber string, branches []string) error {
+	args := []string{"backport"}
+	if slug != "" {
+		args = append(args, "--repo", slug)
+	}
+	args = append(args, prNumber)
+	args = append(args, branches...)
+	cmd := exec.CommandContext(ctx, "klaus", args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("klaus backport: %w: %s", err, string(out))
+	}
+	return nil
+}
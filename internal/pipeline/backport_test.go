@@ -0,0 +1,160 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/patflynn/klaus/internal/run"
+)
+
+func TestBackportBranches(t *testing.T) {
+	labels := []string{"bug", "backport release-1.4", "backport:release-1.3", "backport/release-1.4", "backports", "backport", "backport-candidate"}
+	want := []string{"release-1.4", "release-1.3"}
+	if got := BackportBranches(labels); !reflect.DeepEqual(got, want) {
+		t.Errorf("BackportBranches() = %v, want %v", got, want)
+	}
+}
+
+func TestMergedPRWithBackportLabelsIsBackported(t *testing.T) {
+	c, _ := newTestController(t)
+	var calls []string
+	c.SetBackport(func(_ context.Context, slug, prNumber string, branches []string) error {
+		if slug != "owner/repo" {
+			t.Errorf("backport slug = %q, want owner/repo", slug)
+		}
+		calls = append(calls, prNumber)
+		if !reflect.DeepEqual(branches, []string{"release-1.4"}) {
+			t.Errorf("branches = %v, want [release-1.4]", branches)
+		}
+		return nil
+	})
+
+	prURL := "https://github.com/owner/repo/pull/42"
+	status := func(state string) map[string]*PRStatus {
+		return map[string]*PRStatus{"42": {
+			PRNumber: "42", PRURL: prURL, State: state, CI: "passing", Conflicts: "none",
+			TargetRepo: "repo", Labels: []string{"backport release-1.4"},
+		}}
+	}
+	ctx := context.Background()
+
+	c.HandleGHStatus(ctx, status("OPEN"), nil)
+	if len(calls) != 0 {
+		t.Fatalf("backported before merging: %v", calls)
+	}
+	// Merged PRs are polled without labels; the ones seen while open count.
+	merged := status("MERGED")
+	merged["42"].Labels = nil
+	actions := c.HandleGHStatus(ctx, merged, nil)
+	if !reflect.DeepEqual(calls, []string{"42"}) {
+		t.Fatalf("backport calls = %v, want [42]", calls)
+	}
+	if len(actions) != 1 || actions[0].Type != "backport" {
+		t.Errorf("actions = %+v, want one backport action", actions)
+	}
+
+	// The PR has left the pipeline; later polls don't backport again.
+	c.HandleGHStatus(ctx, merged, nil)
+	if len(calls) != 1 {
+		t.Errorf("backported %d times, want once", len(calls))
+	}
+}
+
+func TestBackportLabelAddedAtMerge(t *testing.T) {
+	c, _ := newTestController(t)
+	var calls [][]string
+	c.SetBackport(func(_ context.Context, _, _ string, branches []string) error {
+		calls = append(calls, branches)
+		return nil
+	})
+
+	prURL := "https://github.com/owner/repo/pull/42"
+	status := func(state string, labels ...string) map[string]*PRStatus {
+		return map[string]*PRStatus{"42": {
+			PRNumber: "42", PRURL: prURL, State: state, CI: "passing", Conflicts: "none",
+			TargetRepo: "repo", Labels: labels,
+		}}
+	}
+	ctx := context.Background()
+
+	c.HandleGHStatus(ctx, status("OPEN", "backport release-1.3"), nil)
+	c.HandleGHStatus(ctx, status("MERGED", "backport release-1.3", "backport release-1.4"), nil)
+	want := [][]string{{"release-1.3", "release-1.4"}}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("backport calls = %v, want %v", calls, want)
+	}
+}
+
+func TestBackportPRNeverPolledOpen(t *testing.T) {
+	c, _ := newTestController(t)
+	var calls []string
+	c.SetBackport(func(_ context.Context, _, prNumber string, branches []string) error {
+		calls = append(calls, prNumber+":"+strings.Join(branches, ","))
+		return nil
+	})
+
+	prURL := "https://github.com/owner/repo/pull/42"
+	runStates := []*run.State{{ID: "run-1", PRURL: &prURL}}
+	merged := map[string]*PRStatus{"42": {
+		PRNumber: "42", PRURL: prURL, State: "MERGED", TargetRepo: "repo",
+		Labels: []string{"backport release-1.4"},
+	}}
+	ctx := context.Background()
+
+	// The first sighting of the merge backports it; the runs it stamped
+	// mark it as seen on later polls.
+	c.HandleGHStatus(ctx, merged, runStates)
+	c.HandleGHStatus(ctx, merged, runStates)
+	if want := []string{"42:release-1.4"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("backport calls = %v, want %v", calls, want)
+	}
+}
+
+func TestMergedPRWithoutBackportLabels(t *testing.T) {
+	c, _ := newTestController(t)
+	c.SetBackport(func(context.Context, string, string, []string) error {
+		t.Error("backport should not run without backport labels")
+		return nil
+	})
+	ctx := context.Background()
+	st := &PRStatus{PRNumber: "7", PRURL: "https://github.com/owner/repo/pull/7", State: "OPEN", CI: "passing", Conflicts: "none"}
+	c.HandleGHStatus(ctx, map[string]*PRStatus{"7": st}, nil)
+	merged := *st
+	merged.State = "MERGED"
+	c.HandleGHStatus(ctx, map[string]*PRStatus{"7": &merged}, nil)
+}
+
+func TestBackportFailureIsReported(t *testing.T) {
+	c, _ := newTestController(t)
+	c.SetBackport(func(context.Context, string, string, []string) error {
+		return errors.New("cherry-pick failed")
+	})
+	ctx := context.Background()
+	st := &PRStatus{PRNumber: "8", PRURL: "https://github.com/owner/repo/pull/8", State: "OPEN", CI: "pending", Conflicts: "none", Labels: []string{"backport release-2"}}
+	c.HandleGHStatus(ctx, map[string]*PRStatus{"8": st}, nil)
+	merged := *st
+	merged.State = "MERGED"
+	actions := c.HandleGHStatus(ctx, map[string]*PRStatus{"8": &merged}, nil)
+	if len(actions) != 1 || actions[0].Type != "error" || actions[0].Error == "" {
+		t.Errorf("actions = %+v, want one backport error", actions)
+	}
+}
+
+func TestBackportRebaseTargetsReleaseBranch(t *testing.T) {
+	c, _ := newTestController(t)
+	var prompt string
+	c.SetLaunchAgent(func(_ context.Context, prNumber, repo, p, resumeFrom, profile string) (string, error) {
+		prompt = p
+		return "agent-rebase", nil
+	})
+	c.HandleGHStatus(context.Background(), map[string]*PRStatus{"50": {
+		PRNumber: "50", PRURL: "https://github.com/owner/repo/pull/50", State: "OPEN", CI: "passing",
+		Conflicts: "yes", TargetRepo: "repo", BaseBranch: "release-1.4",
+	}}, nil)
+	if !strings.Contains(prompt, "Rebase onto origin/release-1.4") || strings.Contains(prompt, "main") {
+		t.Errorf("rebase prompt = %q, want a rebase onto the release branch", prompt)
+	}
+}
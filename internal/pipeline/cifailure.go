@@ -0,0 +1,278 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	ghutil "github.com/patflynn/klaus/internal/github"
+)
+
+// CIFailureFileSuffix names the file, next to a fix agent's log, that holds
+// the CI failure excerpts embedded in its prompt.
+const CIFailureFileSuffix = "-ci-failure.log"
+
+// maxCIFailurePromptBytes bounds the excerpt embedded in a fix prompt. The
+// full excerpt is stored beside the agent's logs.
+const maxCIFailurePromptBytes = 6000
+
+// maxCheckExcerptLines bounds the excerpt kept per failing check.
+const maxCheckExcerptLines = 80
+
+// CheckFailure is a failed check run and its log (or, for checks that
+// aren't GitHub Actions jobs, the check's output text).
+type CheckFailure struct {
+	Name string
+	URL  string
+	Log  string
+}
+
+var (
+	// Actions prefixes every log line with an RFC 3339 timestamp.
+	logTimestampRE = regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(\.\d+)?Z ?`)
+	ansiRE         = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+	// failureLineRE matches the lines worth showing a fix agent: failing
+	// test names, compiler and linter diagnostics, panics and tracebacks,
+	// assertion failures and the runner's own error annotations.
+	failureLineRE = regexp.MustCompile(strings.Join([]string{
+		`^\s*--- FAIL`,                         // go test
+		`^FAIL\s`,                              // go test package summary
+		`^\s*panic: `,                          // go panic
+		`^fatal error: `,                       // go runtime
+		`^\S+\.\w+:\d+(:\d+)?: `,               // file:line[:col]: diagnostics (go, gcc, eslint -f unix, ...)
+		`^error(\[E\d+\])?: `,                  // rustc, cargo
+		`^##\[error\]`,                         // Actions annotations
+		`^Traceback \(most recent call last\)`, // python
+		`^(FAILED|ERROR) \S`,                   // pytest summary
+		`^\s*(●|✕|✗) `,                         // jest, mocha
+		`(?i)\bassert(ion)?error\b`,            // assertion failures
+	}, "|"))
+
+	// Panics, failed tests and tracebacks keep more trailing context so the
+	// stack or the test's message comes along.
+	deepContextRE = regexp.MustCompile(`^\s*(panic: |--- FAIL|Traceback|●)`)
+)
+
+// failureContext is how many lines after a matching line are kept.
+func failureContext(line string) int {
+	if deepContextRE.MatchString(line) {
+		return 12
+	}
+	return 3
+}
+
+// cleanLogLine strips the Actions timestamp and terminal colors.
+func cleanLogLine(line string) string {
+	line = logTimestampRE.ReplaceAllString(line, "")
+	return strings.TrimRight(ansiRE.ReplaceAllString(line, ""), " \r")
+}
+
+// ExtractCIFailure cuts the failure-relevant sections out of a CI log:
+// matching lines plus a few lines of context, with elided stretches marked
+// "...". When nothing matches it falls back to the log's tail, where runners
+// usually print the error. The result is at most maxCheckExcerptLines lines.
+func ExtractCIFailure(log string) string {
+	raw := strings.Split(strings.TrimRight(log, "\n"), "\n")
+	lines := make([]string, len(raw))
+	for i, l := range raw {
+		lines[i] = cleanLogLine(l)
+	}
+
+	keep := make([]bool, len(lines))
+	found := false
+	for i, l := range lines {
+		if !failureLineRE.MatchString(l) {
+			continue
+		}
+		found = true
+		end := i + failureContext(l)
+		for j := i; j <= end && j < len(lines); j++ {
+			keep[j] = true
+		}
+	}
+	if !found {
+		start := len(lines) - 30
+		if start < 0 {
+			start = 0
+		}
+		for j := start; j < len(lines); j++ {
+			keep[j] = true
+		}
+	}
+
+	var out []string
+	for i, l := range lines {
+		if !keep[i] {
+			continue
+		}
+		if i > 0 && !keep[i-1] && len(out) > 0 {
+			out = append(out, "...")
+		}
+		out = append(out, l)
+		if len(out) >= maxCheckExcerptLines {
+			out = append(out, "... (excerpt truncated)")
+			break
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// formatCIFailures renders the excerpts of each failing check. maxBytes > 0
+// cuts the result at a line boundary.
+func formatCIFailures(failures []CheckFailure, maxBytes int) string {
+	var b strings.Builder
+	for _, f := range failures {
+		fmt.Fprintf(&b, "### %s", f.Name)
+		if f.URL != "" {
+			fmt.Fprintf(&b, " (%s)", f.URL)
+		}
+		b.WriteString("\n")
+		if ex := ExtractCIFailure(f.Log); ex != "" {
+			b.WriteString(ex)
+			b.WriteString("\n")
+		} else {
+			b.WriteString("(no log output)\n")
+		}
+		b.WriteString("\n")
+	}
+	s := strings.TrimRight(b.String(), "\n")
+	if maxBytes > 0 && len(s) > maxBytes {
+		cut := strings.LastIndexByte(s[:maxBytes], '\n')
+		if cut < 0 {
+			cut = maxBytes
+		}
+		s = s[:cut] + "\n... (truncated; see the full logs with `gh run view --log-failed`)"
+	}
+	return s
+}
+
+// ciFailurePrompt appends the failure excerpts to a fix agent's prompt.
+func ciFailurePrompt(prompt string, failures []CheckFailure) string {
+	return fmt.Sprintf("%s\n\nThe failing checks' logs were fetched for you; the relevant sections are below. Start from these instead of re-running `gh run view`.\n\n```\n%s\n```",
+		prompt, formatCIFailures(failures, maxCIFailurePromptBytes))
+}
+
+// storeCIFailure writes the full (unbounded) excerpts next to the agent's
+// run log. Controllers without a store skip it.
+func (c *Controller) storeCIFailure(agentID string, failures []CheckFailure) {
+	if c.store == nil || agentID == "" || len(failures) == 0 {
+		return
+	}
+	dir := c.store.LogDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		c.logger.Warn("failed to store CI failure excerpt", "run", agentID, "err", err)
+		return
+	}
+	path := filepath.Join(dir, agentID+CIFailureFileSuffix)
+	if err := os.WriteFile(path, []byte(formatCIFailures(failures, 0)+"\n"), 0o644); err != nil {
+		c.logger.Warn("failed to store CI failure excerpt", "run", agentID, "err", err)
+	}
+}
+
+// FailedCheck is a failed check run on a PR's head commit.
+type FailedCheck struct {
+	ID      int64
+	Name    string
+	URL     string
+	Actions bool   // run by GitHub Actions; ID is also the job ID
+	Output  string // the check's output title, summary and text
+}
+
+// defaultFailedChecks lists the failed check runs on a PR's head commit.
+func (c *Controller) defaultFailedChecks(ctx context.Context, prURL, prNumber string) ([]FailedCheck, error) {
+	slug := ghutil.OwnerRepoFromPRURL(prURL)
+	if slug == "" {
+		return nil, fmt.Errorf("not a GitHub PR URL: %q", prURL)
+	}
+	client := ghutil.NewGHCLIClient("")
+
+	data, err := client.APIGet(ctx, fmt.Sprintf("repos/%s/pulls/%s", slug, prNumber))
+	if err != nil {
+		return nil, err
+	}
+	var pr struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if err := json.Unmarshal(data, &pr); err != nil {
+		return nil, fmt.Errorf("parsing PR #%s: %w", prNumber, err)
+	}
+
+	data, err = client.APIGet(ctx, fmt.Sprintf("repos/%s/commits/%s/check-runs?per_page=100", slug, pr.Head.SHA))
+	if err != nil {
+		return nil, err
+	}
+	return parseFailedCheckRuns(data)
+}
+
+// defaultFetchCIFailures fetches the failed check runs on a PR's head commit
+// and their job logs.
+func (c *Controller) defaultFetchCIFailures(ctx context.Context, prURL, prNumber string) ([]CheckFailure, error) {
+	checks, err := c.failedChecks(ctx, prURL, prNumber)
+	if err != nil {
+		return nil, err
+	}
+	slug := ghutil.OwnerRepoFromPRURL(prURL)
+	client := ghutil.NewGHCLIClient("")
+
+	var failures []CheckFailure
+	for _, fc := range checks {
+		f := CheckFailure{Name: fc.Name, URL: fc.URL, Log: fc.Output}
+		// Actions jobs' logs are the useful part; other apps only have the
+		// check's output.
+		if fc.Actions {
+			if log, err := client.APIGet(ctx, fmt.Sprintf("repos/%s/actions/jobs/%d/logs", slug, fc.ID)); err == nil {
+				f.Log = string(log)
+			} else {
+				c.logger.Warn("failed to fetch job log", "pr", prNumber, "check", fc.Name, "err", err)
+			}
+		}
+		failures = append(failures, f)
+	}
+	return failures, nil
+}
+
+// parseFailedCheckRuns returns the failed runs from a check-runs API
+// response, judged by ghutil.CommitCheckState like the main watchdog.
+func parseFailedCheckRuns(data []byte) ([]FailedCheck, error) {
+	var resp struct {
+		CheckRuns []struct {
+			ID         int64  `json:"id"`
+			Name       string `json:"name"`
+			HTMLURL    string `json:"html_url"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+			App        struct {
+				Slug string `json:"slug"`
+			} `json:"app"`
+			Output struct {
+				Title   string `json:"title"`
+				Summary string `json:"summary"`
+				Text    string `json:"text"`
+			} `json:"output"`
+		} `json:"check_runs"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parsing check runs: %w", err)
+	}
+	var out []FailedCheck
+	for _, r := range resp.CheckRuns {
+		if ghutil.CommitCheckState(r.Status, r.Conclusion) != "failing" {
+			continue
+		}
+		out = append(out, FailedCheck{
+			ID:      r.ID,
+			Name:    r.Name,
+			URL:     r.HTMLURL,
+			Actions: r.App.Slug == "github-actions",
+			Output:  strings.TrimSpace(r.Output.Title + "\n" + r.Output.Summary + "\n" + r.Output.Text),
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,138 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/patflynn/klaus/internal/run"
+)
+
+func TestExtractCIFailure(t *testing.T) {
+	log := strings.Join([]string{
+		"2026-10-17T10:00:00.0000000Z ##[group]Run go test ./...",
+		"2026-10-17T10:00:01.0000000Z go: downloading github.com/spf13/cobra v1.8.0",
+		"2026-10-17T10:00:02.0000000Z ok  \tgithub.com/o/r/internal/a\t0.01s",
+		"2026-10-17T10:00:03.0000000Z --- FAIL: TestParse (0.00s)",
+		"2026-10-17T10:00:03.0000000Z     parse_test.go:42: got \"a\", want \"b\"",
+		"2026-10-17T10:00:03.0000000Z FAIL",
+		"2026-10-17T10:00:03.0000000Z FAIL\tgithub.com/o/r/internal/b\t0.02s",
+		"2026-10-17T10:00:04.0000000Z ok  \tgithub.com/o/r/internal/c\t0.01s",
+		"2026-10-17T10:00:04.0000000Z ok  \tgithub.com/o/r/internal/d\t0.01s",
+		"2026-10-17T10:00:04.0000000Z ok  \tgithub.com/o/r/internal/e\t0.01s",
+		"2026-10-17T10:00:04.0000000Z ok  \tgithub.com/o/r/internal/f\t0.01s",
+		"2026-10-17T10:00:04.0000000Z ok  \tgithub.com/o/r/internal/g\t0.01s",
+		"2026-10-17T10:00:04.0000000Z ok  \tgithub.com/o/r/internal/h\t0.01s",
+		"2026-10-17T10:00:04.0000000Z ok  \tgithub.com/o/r/internal/i\t0.01s",
+		"2026-10-17T10:00:04.0000000Z ok  \tgithub.com/o/r/internal/j\t0.01s",
+		"2026-10-17T10:00:04.0000000Z ok  \tgithub.com/o/r/internal/k\t0.01s",
+		"2026-10-17T10:00:04.0000000Z ok  \tgithub.com/o/r/internal/l\t0.01s",
+		"2026-10-17T10:00:04.0000000Z ok  \tgithub.com/o/r/internal/m\t0.01s",
+		"2026-10-17T10:00:04.0000000Z ok  \tgithub.com/o/r/internal/n\t0.01s",
+		"2026-10-17T10:00:05.0000000Z \x1b[31m##[error]Process completed with exit code 1.\x1b[0m",
+	}, "\n")
+
+	got := ExtractCIFailure(log)
+	for _, want := range []string{"--- FAIL: TestParse", "parse_test.go:42: got", "FAIL\tgithub.com/o/r/internal/b", "##[error]Process completed with exit code 1."} {
+		if !strings.Contains(got, want) {
+			t.Errorf("excerpt missing %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"downloading", "2026-10-17T", "\x1b[", "internal/n\t"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("excerpt should not contain %q:\n%s", unwanted, got)
+		}
+	}
+	if !strings.Contains(got, "\n...\n") {
+		t.Errorf("elided lines should be marked:\n%s", got)
+	}
+}
+
+func TestExtractCIFailureFallsBackToTail(t *testing.T) {
+	var lines []string
+	for i := 0; i < 100; i++ {
+		lines = append(lines, "line "+strings.Repeat("x", i%3))
+	}
+	lines = append(lines, "make: *** [Makefile:12: lint] Error 2")
+	got := ExtractCIFailure(strings.Join(lines, "\n"))
+	if n := strings.Count(got, "\n") + 1; n != 30 {
+		t.Errorf("fallback kept %d lines, want the last 30", n)
+	}
+	if !strings.HasSuffix(got, "Error 2") {
+		t.Errorf("fallback should end with the log's last line:\n%s", got)
+	}
+}
+
+func TestParseFailedCheckRuns(t *testing.T) {
+	data := []byte(`{"check_runs": [
+		{"id": 1, "name": "test", "status": "completed", "conclusion": "failure", "html_url": "https://github.com/o/r/runs/1", "app": {"slug": "github-actions"}},
+		{"id": 2, "name": "lint", "status": "completed", "conclusion": "success", "app": {"slug": "github-actions"}},
+		{"id": 3, "name": "external", "status": "completed", "conclusion": "timed_out", "app": {"slug": "ci-app"}, "output": {"title": "Build timed out"}},
+		{"id": 4, "name": "flaky", "status": "completed", "conclusion": "cancelled"}
+	]}`)
+	runs, err := parseFailedCheckRuns(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].ID != 1 || !runs[0].Actions || runs[1].Name != "external" || runs[1].Actions || runs[1].Output != "Build timed out" {
+		t.Errorf("runs = %+v", runs)
+	}
+}
+
+func TestCIFixPromptEmbedsFailureExcerpt(t *testing.T) {
+	c, dir := newTestController(t)
+	var fetchedURL, prompt string
+	c.SetFetchCIFailures(func(ctx context.Context, prURL, prNumber string) ([]CheckFailure, error) {
+		fetchedURL = prURL
+		return []CheckFailure{{
+			Name: "test",
+			URL:  "https://github.com/owner/repo/runs/1",
+			Log:  "ok  \tpkg/a\n--- FAIL: TestThing (0.00s)\n    thing_test.go:9: boom\nFAIL\tpkg/b",
+		}}, nil
+	})
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, p, resumeFrom, profile string) (string, error) {
+		prompt = p
+		return "agent-fix", nil
+	})
+
+	c.HandleGHStatus(context.Background(), map[string]*PRStatus{
+		"42": {PRNumber: "42", PRURL: "https://github.com/owner/repo/pull/42", State: "OPEN", CI: "failing", TargetRepo: "repo"},
+	}, nil)
+
+	if fetchedURL != "https://github.com/owner/repo/pull/42" {
+		t.Errorf("fetched CI failures for %q", fetchedURL)
+	}
+	for _, want := range []string{"CI is failing on PR #42", "### test (https://github.com/owner/repo/runs/1)", "--- FAIL: TestThing", "thing_test.go:9: boom"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+
+	stored, err := os.ReadFile(filepath.Join(run.NewGitDirStore(filepath.Join(dir, "klaus")).LogDir(), "agent-fix"+CIFailureFileSuffix))
+	if err != nil {
+		t.Fatalf("excerpt not stored beside the run logs: %v", err)
+	}
+	if !strings.Contains(string(stored), "thing_test.go:9: boom") {
+		t.Errorf("stored excerpt = %q", stored)
+	}
+}
+
+func TestCIFailurePromptIsBounded(t *testing.T) {
+	var failures []CheckFailure
+	for i := 0; i < 20; i++ {
+		var log []string
+		for j := 0; j < 60; j++ {
+			log = append(log, "pkg/file.go:10:2: undefined: somethingQuiteLongToFillTheBudget")
+		}
+		failures = append(failures, CheckFailure{Name: "build", Log: strings.Join(log, "\n")})
+	}
+	got := ciFailurePrompt("fix it", failures)
+	if len(got) > maxCIFailurePromptBytes+500 {
+		t.Errorf("prompt is %d bytes, want about %d", len(got), maxCIFailurePromptBytes)
+	}
+	if !strings.Contains(got, "truncated") {
+		t.Error("truncated prompt should say so")
+	}
+}
@@ -0,0 +1,162 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/patflynn/klaus/internal/event"
+	"github.com/patflynn/klaus/internal/run"
+)
+
+// What Policy.ClosedCleanup can remove for the runs on a closed PR.
+const (
+	CleanupWorktree     = "worktree"
+	CleanupBranch       = "branch"
+	CleanupRemoteBranch = "remote_branch" // only branches klaus pushed; see 'klaus cleanup --remote-branch'
+	CleanupState        = "state"
+)
+
+// closePR handles a PR closed without merging. The first poll that sees it
+// closed records its move to StageClosed as a transition and drops it from
+// the pipeline, stamps ClosedAt on its runs, emits pr:closed and returns a
+// descriptor cleaning the runs up as the policy says. Runs already stamped
+// are left alone, so the close is handled once even across restarts; the
+// stamps are also what reopenPR resumes from. Callers must hold c.mu.
+func (c *Controller) closePR(prNum string, status *PRStatus, runStates []*run.State) []ActionDescriptor {
+	ps, tracked := c.prStates[prNum]
+	var runs []*run.State
+	for _, s := range runStates {
+		if runStateMatchesPR(s, prNum) && s.ClosedAt == nil && s.MergedAt == nil {
+			runs = append(runs, s)
+		}
+	}
+	if tracked {
+		from := ps.Stage
+		ps.Stage = StageClosed
+		c.emitTransition(firedTransition{prNumber: prNum, prURL: status.PRURL, from: from, rule: "status/closed"}, "")
+		delete(c.prStates, prNum)
+	}
+	if !tracked && len(runs) == 0 {
+		return nil
+	}
+
+	now := c.now().UTC().Format(time.RFC3339)
+	for _, s := range runs {
+		s.ClosedAt = &now
+		if c.store != nil {
+			if err := c.store.Save(s); err != nil {
+				c.logger.Error("failed to persist PR close to run state", "pr", prNum, "run", s.ID, "err", err)
+			}
+		}
+	}
+	c.logger.Info("PR closed without merging", "pr", prNum, "runs", len(runs))
+	c.emitEvent(prNum, event.PRClosed, map[string]interface{}{
+		"pr_number": prNum,
+		"pr_url":    status.PRURL,
+	})
+
+	cleanup := c.policy(status.TargetRepo).ClosedCleanup
+	if len(cleanup) == 0 || len(runs) == 0 {
+		return nil
+	}
+	return []ActionDescriptor{{
+		Type:      ActionCleanupClosed,
+		PRNumber:  prNum,
+		Repo:      status.TargetRepo,
+		PRURL:     status.PRURL,
+		RunStates: runs,
+		Cleanup:   cleanup,
+	}}
+}
+
+// reopenPR resumes tracking a PR that was closed and has been reopened,
+// which its runs' ClosedAt stamps tell: it clears them and reseeds the PR's
+// stage from its status. Callers must hold c.mu.
+func (c *Controller) reopenPR(ps *PRPipelineState, status *PRStatus, runStates []*run.State) []Action {
+	reopened := false
+	for _, s := range runStates {
+		if s.ClosedAt == nil || !runStateMatchesPR(s, ps.PRNumber) {
+			continue
+		}
+		reopened = true
+		s.ClosedAt = nil
+		if c.store != nil {
+			if err := c.store.Save(s); err != nil {
+				c.logger.Error("failed to persist PR reopen to run state", "pr", ps.PRNumber, "run", s.ID, "err", err)
+			}
+		}
+	}
+	if !reopened {
+		return nil
+	}
+	ps.Stage = seedStageFromStatus(status)
+	c.logger.Info("PR reopened; tracking resumed", "pr", ps.PRNumber, "stage", string(ps.Stage))
+	return []Action{{Type: "reopen", Detail: fmt.Sprintf("PR #%s reopened; tracking resumed", ps.PRNumber)}}
+}
+
+// closedCleanupResult is the outcome of an ActionCleanupClosed descriptor.
+type closedCleanupResult struct {
+	prNumber string
+	cleaned  []string // run IDs cleaned up
+	cleanup  []string
+	err      error
+}
+
+// cleanupClosedPR cleans up the idle runs on a closed PR. Runs whose agent
+// is still working are left for 'klaus cleanup'. It runs without c.mu held.
+func (c *Controller) cleanupClosedPR(ctx context.Context, desc ActionDescriptor) closedCleanupResult {
+	r := closedCleanupResult{prNumber: desc.PRNumber, cleanup: desc.Cleanup}
+	for _, s := range desc.RunStates {
+		if c.isRunning(s) {
+			continue
+		}
+		if err := c.cleanupRun(ctx, s.ID, desc.Cleanup); err != nil {
+			r.err = err
+			continue
+		}
+		r.cleaned = append(r.cleaned, s.ID)
+	}
+	return r
+}
+
+// applyClosedCleanupResult reports a closed PR's cleanup. Callers must hold
+// c.mu.
+func (c *Controller) applyClosedCleanupResult(r closedCleanupResult) []Action {
+	var actions []Action
+	if len(r.cleaned) > 0 {
+		actions = append(actions, Action{Type: "cleanup", Detail: fmt.Sprintf("Cleaned up %d run(s) on closed PR #%s (%s)", len(r.cleaned), r.prNumber, strings.Join(r.cleanup, ", "))})
+	}
+	if r.err != nil {
+		c.logger.Warn("closed PR cleanup failed", "pr", r.prNumber, "err", r.err)
+		actions = append(actions, Action{Type: "error", Detail: fmt.Sprintf("PR #%s: cleanup after close failed", r.prNumber), Error: truncateError(r.err.Error(), 120)})
+	}
+	return actions
+}
+
+// defaultCleanupRun runs 'klaus cleanup', keeping whatever cleanup doesn't
+// name.
+func (c *Controller) defaultCleanupRun(ctx context.Context, runID string, cleanup []string) error {
+	args := []string{"cleanup", runID}
+	if !slices.Contains(cleanup, CleanupWorktree) {
+		args = append(args, "--keep-worktree")
+	}
+	if !slices.Contains(cleanup, CleanupBranch) {
+		args = append(args, "--keep-branch")
+	}
+	if !slices.Contains(cleanup, CleanupState) {
+		args = append(args, "--keep-state")
+	}
+	if slices.Contains(cleanup, CleanupRemoteBranch) {
+		args = append(args, "--remote-branch")
+	}
+	cmd := exec.CommandContext(ctx, "klaus", args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("klaus cleanup %s: %w: %s", runID, err, string(out))
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package pipeline
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/patflynn/klaus/internal/event"
+	"github.com/patflynn/klaus/internal/run"
+)
+
+func TestClosedPRIsCleanedUpOnce(t *testing.T) {
+	c, dir := newTestController(t)
+	var cleaned []string
+	c.SetCleanupRun(func(_ context.Context, runID string, cleanup []string) error {
+		cleaned = append(cleaned, runID)
+		if !reflect.DeepEqual(cleanup, []string{CleanupWorktree, CleanupBranch}) {
+			t.Errorf("cleanup = %v, want the default [worktree branch]", cleanup)
+		}
+		return nil
+	})
+
+	prURL := "https://github.com/owner/repo/pull/42"
+	pane, seven := "%1", "7"
+	runStates := []*run.State{
+		{ID: "run-1", PRURL: &prURL},
+		{ID: "run-2", PRURL: &prURL, Type: "pr-fix", TmuxPane: &pane}, // still running
+		{ID: "other", PR: &seven},
+	}
+	status := func(state string) map[string]*PRStatus {
+		return map[string]*PRStatus{"42": {PRNumber: "42", PRURL: prURL, State: state, CI: "pending", TargetRepo: "repo"}}
+	}
+	ctx := context.Background()
+
+	c.HandleGHStatus(ctx, status("OPEN"), runStates)
+	actions := c.HandleGHStatus(ctx, status("CLOSED"), runStates)
+	if !reflect.DeepEqual(cleaned, []string{"run-1"}) {
+		t.Errorf("cleaned = %v, want [run-1] (running agents are left alone)", cleaned)
+	}
+	if len(actions) != 1 || actions[0].Type != "cleanup" {
+		t.Errorf("actions = %+v, want one cleanup action", actions)
+	}
+	if _, ok := c.PipelineStates()["42"]; ok {
+		t.Error("closed PR should leave the pipeline")
+	}
+	if runStates[0].ClosedAt == nil || runStates[1].ClosedAt == nil || runStates[2].ClosedAt != nil {
+		t.Errorf("ClosedAt = %v, %v, %v; want set on the PR's runs only", runStates[0].ClosedAt, runStates[1].ClosedAt, runStates[2].ClosedAt)
+	}
+
+	// Later polls, including after a restart, don't close it again.
+	c.HandleGHStatus(ctx, status("CLOSED"), runStates)
+	c2, _ := newTestController(t)
+	c2.SetCleanupRun(func(context.Context, string, []string) error {
+		t.Error("restarted controller cleaned up an already-closed PR")
+		return nil
+	})
+	c2.HandleGHStatus(ctx, status("CLOSED"), runStates)
+	if len(cleaned) != 1 {
+		t.Errorf("cleaned up %d times, want once", len(cleaned))
+	}
+
+	events, err := event.NewLog(filepath.Join(dir, "session")).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed, transitions := 0, 0
+	for _, e := range events {
+		switch e.Type {
+		case event.PRClosed:
+			closed++
+		case event.PipelineTransition:
+			if e.Data["to"] == string(StageClosed) {
+				transitions++
+				if e.Data["from"] != string(StageCIPending) || e.Data["rule"] != "status/closed" {
+					t.Errorf("close transition = %v", e.Data)
+				}
+			}
+		}
+	}
+	if closed != 1 || transitions != 1 {
+		t.Errorf("pr:closed emitted %d times and the transition to closed %d times, want 1 each", closed, transitions)
+	}
+}
+
+func TestClosedPRCleanupDisabled(t *testing.T) {
+	c, _ := newTestController(t)
+	c.SetPolicyResolver(func(string) Policy {
+		p := DefaultPolicy()
+		p.ClosedCleanup = nil
+		return p
+	})
+	c.SetCleanupRun(func(context.Context, string, []string) error {
+		t.Error("cleanup should not run with closed_cleanup empty")
+		return nil
+	})
+	prURL := "https://github.com/owner/repo/pull/9"
+	runStates := []*run.State{{ID: "run-1", PRURL: &prURL}}
+	actions := c.HandleGHStatus(context.Background(), map[string]*PRStatus{
+		"9": {PRNumber: "9", PRURL: prURL, State: "CLOSED"},
+	}, runStates)
+	if len(actions) != 0 {
+		t.Errorf("actions = %+v, want none", actions)
+	}
+	if runStates[0].ClosedAt == nil {
+		t.Error("ClosedAt should be set even when nothing is cleaned up")
+	}
+}
+
+func TestReopenedPRResumesTracking(t *testing.T) {
+	c, _ := newTestController(t)
+	var launched int
+	c.SetLaunchAgent(func(context.Context, string, string, string, string, string) (string, error) {
+		launched++
+		return "agent-1", nil
+	})
+
+	prURL := "https://github.com/owner/repo/pull/42"
+	closedAt := "2026-10-01T00:00:00Z"
+	runStates := []*run.State{{ID: "run-1", PRURL: &prURL, ClosedAt: &closedAt}}
+
+	actions := c.HandleGHStatus(context.Background(), map[string]*PRStatus{
+		"42": {PRNumber: "42", PRURL: prURL, State: "OPEN", CI: "failing", Conflicts: "none", TargetRepo: "repo"},
+	}, runStates)
+	if runStates[0].ClosedAt != nil {
+		t.Error("ClosedAt should be cleared on reopen")
+	}
+	if actions[0].Type != "reopen" {
+		t.Errorf("actions = %+v, want reopen first", actions)
+	}
+	if launched != 1 {
+		t.Errorf("launched %d agents, want the reopened PR's CI fix", launched)
+	}
+	if got := c.PipelineStates()["42"].Stage; got != StageCIFailed {
+		t.Errorf("stage = %s, want ci_failed", got)
+	}
+}
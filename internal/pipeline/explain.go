@@ -0,0 +1,158 @@
+package pipeline
+
+import (
+	"io"
+	"log/slog"
+
+	"github.com/patflynn/klaus/internal/run"
+)
+
+// GuardResult is the outcome of one guard of a transition.
+type GuardResult struct {
+	Name string
+	Pass bool
+}
+
+// TransitionResult is the outcome of evaluating one transition's guards.
+type TransitionResult struct {
+	Name     string
+	Disabled bool          // disabled by the repo's pipeline policy
+	Guards   []GuardResult // each guard of the transition, all evaluated
+	Matched  bool          // every guard passed
+	Fires    bool          // the first enabled, matching transition
+}
+
+// Explanation describes how the controller would evaluate a PR right now.
+type Explanation struct {
+	PRNumber    string
+	Stage       Stage // current stage (a new PR's stage is seeded from its status)
+	NextStage   Stage // stage after the firing transition applies
+	Known       bool  // the controller already tracks this PR
+	Transitions []TransitionResult
+	Fired       string // name of the firing transition; "" if none matched
+	Actions     []Action
+	Descriptors []ActionDescriptor
+}
+
+// Explain evaluates every transition for the PR described by status without
+// executing anything: guards are all evaluated (not short-circuited) so the
+// result shows which ones blocked each rule, and the firing rule is applied to
+// a copy of the PR's state on a scratch controller that emits no events, saves
+// no run states and launches nothing.
+func (c *Controller) Explain(status *PRStatus, runStates []*run.State) Explanation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ex := Explanation{PRNumber: status.PRNumber}
+	status = c.gateStatus(status)
+
+	var ps PRPipelineState
+	if cur, ok := c.prStates[status.PRNumber]; ok {
+		ps = clonePRState(cur)
+		ex.Known = true
+	} else {
+		ps = PRPipelineState{
+			PRNumber:       status.PRNumber,
+			Stage:          seedStageFromStatus(status),
+			SeenCommentIDs: make(map[int64]bool),
+		}
+	}
+
+	// Mirror HandleGHStatus's agent-liveness refresh.
+	if ps.LastAgentID != "" {
+		ps.AgentRunning = false
+		for _, s := range runStates {
+			if s != nil && s.ID == ps.LastAgentID && c.isRunning(s) {
+				ps.AgentRunning = true
+			}
+		}
+	}
+	ex.Stage = ps.Stage
+
+	// Guards and Apply functions may update run states in memory (e.g.
+	// markRunStatesApproved); give them copies.
+	scratchRuns := make([]*run.State, len(runStates))
+	for i, s := range runStates {
+		if s != nil {
+			cp := *s
+			scratchRuns[i] = &cp
+		}
+	}
+	scratch := &Controller{
+		logger:              slog.New(slog.NewTextHandler(io.Discard, nil)),
+		prStates:            map[string]*PRPipelineState{ps.PRNumber: &ps},
+		mainWatches:         cloneMainWatches(c.mainWatches),
+		checkHistory:        cloneCheckHistory(c.checkHistory),
+		autoMergeOnApproval: c.autoMergeOnApproval,
+		policyFor:           c.policyFor,
+		tmuxDeps:            c.tmuxDeps,
+		now:                 c.now,
+	}
+
+	disabled := scratch.policy(status.TargetRepo).Disabled
+	var fire *transition
+	for i := range transitions {
+		t := &transitions[i]
+		tr := TransitionResult{Name: t.Name, Disabled: disabled[t.Name]}
+		parts := t.Guard.parts
+		if parts == nil {
+			parts = []guard{t.Guard}
+		}
+		tr.Matched = true
+		for _, g := range parts {
+			pass := g.check(scratch, &ps, status, scratchRuns)
+			tr.Guards = append(tr.Guards, GuardResult{Name: g.name, Pass: pass})
+			tr.Matched = tr.Matched && pass
+		}
+		if tr.Matched && !tr.Disabled && fire == nil {
+			tr.Fires = true
+			fire = t
+		}
+		ex.Transitions = append(ex.Transitions, tr)
+	}
+
+	ex.NextStage = ps.Stage
+	if fire != nil {
+		ex.Fired = fire.Name
+		ex.Actions, ex.Descriptors = fire.Apply(scratch, &ps, status, scratchRuns)
+		ex.NextStage = ps.Stage
+	}
+	return ex
+}
+
+// clonePRState deep-copies a PR's pipeline state.
+func clonePRState(ps *PRPipelineState) PRPipelineState {
+	cp := *ps
+	cp.SeenCommentIDs = make(map[int64]bool, len(ps.SeenCommentIDs))
+	for id, seen := range ps.SeenCommentIDs {
+		cp.SeenCommentIDs[id] = seen
+	}
+	cp.PendingResolveThreadIDs = append([]string(nil), ps.PendingResolveThreadIDs...)
+	return cp
+}
+
+// cloneMainWatches copies the controller's watches so a scratch controller
+// sees them without being able to change them.
+func cloneMainWatches(watches map[string]*MainWatch) map[string]*MainWatch {
+	out := make(map[string]*MainWatch, len(watches))
+	for slug, w := range watches {
+		cp := *w
+		out[slug] = &cp
+	}
+	return out
+}
+
+// cloneCheckHistory copies the controller's check histories, for the same
+// reason.
+func cloneCheckHistory(history map[string]map[string]*CheckHistory) map[string]map[string]*CheckHistory {
+	out := make(map[string]map[string]*CheckHistory, len(history))
+	for slug, checks := range history {
+		m := make(map[string]*CheckHistory, len(checks))
+		for name, h := range checks {
+			cp := *h
+			m[name] = &cp
+		}
+		out[slug] = m
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExplain_ShowsBlockingGuard(t *testing.T) {
+	c, _ := newTestController(t)
+	c.mu.Lock()
+	c.prStates["42"] = &PRPipelineState{
+		PRNumber:       "42",
+		Stage:          StageCIFailed,
+		LastAgentID:    "agent-old",
+		LastDispatchAt: time.Now(), // cooldown active
+	}
+	c.mu.Unlock()
+
+	ex := c.Explain(&PRStatus{PRNumber: "42", State: "OPEN", CI: "failing", TargetRepo: "owner/repo"}, nil)
+
+	if !ex.Known || ex.Stage != StageCIFailed {
+		t.Errorf("Known=%v Stage=%s, want tracked ci_failed", ex.Known, ex.Stage)
+	}
+	if ex.Fired != "ci-failing/update-stage" {
+		t.Errorf("Fired = %q, want ci-failing/update-stage", ex.Fired)
+	}
+	var dispatch *TransitionResult
+	for i := range ex.Transitions {
+		if ex.Transitions[i].Name == "ci-failing/dispatch-fix-agent" {
+			dispatch = &ex.Transitions[i]
+		}
+	}
+	if dispatch == nil || dispatch.Matched {
+		t.Fatalf("dispatch-fix-agent = %+v, want present and blocked", dispatch)
+	}
+	for _, g := range dispatch.Guards {
+		if want := g.Name != "cooldownExpired"; g.Pass != want {
+			t.Errorf("guard %s pass=%v, want %v", g.Name, g.Pass, want)
+		}
+	}
+	if len(ex.Transitions) != len(transitions) {
+		t.Errorf("explained %d transitions, want all %d", len(ex.Transitions), len(transitions))
+	}
+}
+
+func TestExplain_DoesNotExecute(t *testing.T) {
+	c, dir := newTestController(t)
+	launched := false
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launched = true
+		return "agent-1", nil
+	})
+
+	ex := c.Explain(&PRStatus{PRNumber: "42", State: "OPEN", CI: "failing", TargetRepo: "owner/repo"}, nil)
+
+	if ex.Fired != "ci-failing/dispatch-fix-agent" {
+		t.Fatalf("Fired = %q, want ci-failing/dispatch-fix-agent", ex.Fired)
+	}
+	var sawLaunch bool
+	for _, d := range ex.Descriptors {
+		if d.Type == ActionLaunchAgent {
+			sawLaunch = true
+		}
+	}
+	if !sawLaunch {
+		t.Errorf("descriptors %v missing launch-agent", ex.Descriptors)
+	}
+	if launched {
+		t.Error("Explain launched an agent")
+	}
+	if len(c.PipelineStates()) != 0 {
+		t.Errorf("Explain created controller state: %v", c.PipelineStates())
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "session", "events.jsonl")); err == nil && len(data) > 0 {
+		t.Errorf("Explain emitted events: %s", data)
+	}
+
+	// A real evaluation afterwards still dispatches (Explain didn't consume
+	// the cooldown).
+	c.HandleGHStatus(context.Background(), map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "failing", TargetRepo: "owner/repo"},
+	}, nil)
+	if !launched {
+		t.Error("HandleGHStatus after Explain did not dispatch")
+	}
+}
+
+func TestExplain_DisabledTransition(t *testing.T) {
+	c, _ := newTestController(t)
+	c.SetPolicyResolver(func(string) Policy {
+		p := DefaultPolicy()
+		p.Disabled = map[string]bool{"ci-failing/dispatch-fix-agent": true}
+		return p
+	})
+
+	ex := c.Explain(&PRStatus{PRNumber: "42", State: "OPEN", CI: "failing"}, nil)
+	if ex.Fired != "ci-failing/update-stage" || len(ex.Descriptors) != 0 {
+		t.Errorf("Fired=%q descriptors=%v, want update-stage with no actions", ex.Fired, ex.Descriptors)
+	}
+	for _, tr := range ex.Transitions {
+		if tr.Name == "ci-failing/dispatch-fix-agent" && (!tr.Disabled || !tr.Matched || tr.Fires) {
+			t.Errorf("disabled rule reported as %+v", tr)
+		}
+	}
+}
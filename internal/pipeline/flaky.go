@@ -0,0 +1,233 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/patflynn/klaus/internal/event"
+	ghutil "github.com/patflynn/klaus/internal/github"
+)
+
+// Failed-check rerun modes (Policy.RerunFailedChecks).
+const (
+	RerunOff    = "off"    // dispatch a fix agent on every failure (default)
+	RerunFlaky  = "flaky"  // re-run once when every failed check is known-flaky or still unproven
+	RerunAlways = "always" // re-run every failure once before dispatching
+)
+
+// Rerun progress for a PR's current CI failure (PRPipelineState.Rerun).
+const (
+	rerunRequested = "requested" // failed checks re-requested; waiting for CI to restart
+	rerunRunning   = "running"   // CI went pending after the rerun
+	rerunDone      = "done"      // rerun failed again, or was skipped; dispatch a fix agent
+)
+
+// rerunStartTimeout bounds how long a re-requested failure may stay red
+// before the pipeline gives up on the rerun and dispatches a fix agent.
+const rerunStartTimeout = 10 * time.Minute
+
+// defaultFlakyThreshold is how many flakes make a check known-flaky.
+const defaultFlakyThreshold = 2
+
+// CheckHistory is the pass/fail record of one CI check in one repo, built
+// from the failures the pipeline has seen and re-run.
+type CheckHistory struct {
+	Failures    int       `json:"failures"` // failures seen on PRs
+	Flakes      int       `json:"flakes"`   // failures that passed when re-run on the same commit
+	Repeats     int       `json:"repeats"`  // failures that failed again when re-run
+	LastFlakeAt time.Time `json:"last_flake_at,omitempty"`
+}
+
+// CheckHistories returns a snapshot of the per-repo check histories, keyed
+// by owner/repo and then check name.
+func (c *Controller) CheckHistories() map[string]map[string]CheckHistory {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]map[string]CheckHistory, len(c.checkHistory))
+	for slug, checks := range c.checkHistory {
+		m := make(map[string]CheckHistory, len(checks))
+		for name, h := range checks {
+			m[name] = *h
+		}
+		out[slug] = m
+	}
+	return out
+}
+
+// checkRecord returns the history of a check, creating it. Callers must hold
+// c.mu.
+func (c *Controller) checkRecord(slug, name string) *CheckHistory {
+	checks := c.checkHistory[slug]
+	if checks == nil {
+		checks = make(map[string]*CheckHistory)
+		c.checkHistory[slug] = checks
+	}
+	h := checks[name]
+	if h == nil {
+		h = &CheckHistory{}
+		checks[name] = h
+	}
+	return h
+}
+
+// knownFlaky returns the checks among names whose history has at least
+// threshold flakes. Callers must hold c.mu.
+func (c *Controller) knownFlaky(slug string, names []string, threshold int) []string {
+	var out []string
+	for _, name := range names {
+		if h := c.checkHistory[slug][name]; h != nil && h.Flakes >= threshold {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// unproven returns the checks among names with fewer than threshold reruns
+// on record, flaked or repeated. Flaky mode re-runs them too: flakes are
+// only learned from reruns, so without this no check could ever become
+// known-flaky. Callers must hold c.mu.
+func (c *Controller) unproven(slug string, names []string, threshold int) []string {
+	var out []string
+	for _, name := range names {
+		h := c.checkHistory[slug][name]
+		if h == nil || h.Flakes+h.Repeats < threshold {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// observeRerun follows a PR's CI through a requested rerun. A rerun that
+// goes green was a flake: it's recorded against each re-run check and
+// ci:flaky is emitted. A rerun that fails again is recorded as a repeat and
+// leaves the failure to the fix-agent rules. Callers must hold c.mu.
+func (c *Controller) observeRerun(ps *PRPipelineState, status *PRStatus) {
+	if ps.Rerun == "" {
+		return
+	}
+	slug := ghutil.OwnerRepoFromPRURL(status.PRURL)
+	switch status.CI {
+	case "pending":
+		if ps.Rerun == rerunRequested {
+			ps.Rerun = rerunRunning
+		}
+	case "passing":
+		if ps.Rerun == rerunRequested || ps.Rerun == rerunRunning {
+			known := c.knownFlaky(slug, ps.RerunChecks, c.policy(status.TargetRepo).FlakyThreshold)
+			for _, name := range ps.RerunChecks {
+				h := c.checkRecord(slug, name)
+				h.Flakes++
+				h.LastFlakeAt = c.now()
+			}
+			ps.FlakyChecks = mergeNames(ps.FlakyChecks, ps.RerunChecks)
+			c.logger.Info("failed checks passed on rerun", "pr", ps.PRNumber, "checks", ps.RerunChecks)
+			c.emitEvent(ps.PRNumber, event.CIFlaky, map[string]interface{}{
+				"pr_number":   ps.PRNumber,
+				"pr_url":      status.PRURL,
+				"repo":        slug,
+				"checks":      ps.RerunChecks,
+				"known_flaky": known,
+			})
+		}
+		ps.Rerun = ""
+		ps.RerunChecks = nil
+	case "failing":
+		if ps.Rerun == rerunRunning {
+			for _, name := range ps.RerunChecks {
+				c.checkRecord(slug, name).Repeats++
+			}
+			ps.Rerun = rerunDone
+		}
+	}
+}
+
+// rerunResult is the outcome of an ActionRerunChecks descriptor.
+type rerunResult struct {
+	prNumber string
+	slug     string
+	checks   []string // failed checks seen
+	known    []string // those already known-flaky
+	rerun    bool     // the checks were re-requested
+	err      error
+}
+
+// rerunFailedChecks lists a PR's failed checks and, per policy, re-requests
+// them. It runs without c.mu held.
+func (c *Controller) rerunFailedChecks(ctx context.Context, desc ActionDescriptor) rerunResult {
+	res := rerunResult{prNumber: desc.PRNumber, slug: ghutil.OwnerRepoFromPRURL(desc.PRURL)}
+	failed, err := c.failedChecks(ctx, desc.PRURL, desc.PRNumber)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	for _, fc := range failed {
+		res.checks = append(res.checks, fc.Name)
+	}
+	sort.Strings(res.checks)
+	if len(failed) == 0 {
+		return res
+	}
+
+	c.mu.Lock()
+	pol := c.policy(desc.Repo)
+	res.known = c.knownFlaky(res.slug, res.checks, pol.FlakyThreshold)
+	unproven := c.unproven(res.slug, res.checks, pol.FlakyThreshold)
+	c.mu.Unlock()
+	// Known-flaky checks have threshold flakes and unproven ones fewer, so
+	// the two never overlap.
+	if pol.RerunFailedChecks == RerunFlaky && len(res.known)+len(unproven) < len(res.checks) {
+		return res
+	}
+
+	for _, fc := range failed {
+		if err := c.rerunCheck(ctx, res.slug, fc.ID); err != nil {
+			res.err = fmt.Errorf("re-running %s: %w", fc.Name, err)
+			return res
+		}
+	}
+	res.rerun = true
+	return res
+}
+
+// applyRerunResult records a rerun's outcome. Without a rerun the failure
+// falls through to the fix-agent rules on the next poll. Callers must hold
+// c.mu.
+func (c *Controller) applyRerunResult(r rerunResult) []Action {
+	ps := c.prStates[r.prNumber]
+	if ps == nil {
+		return nil
+	}
+	for _, name := range r.checks {
+		c.checkRecord(r.slug, name).Failures++
+	}
+	if len(r.known) > 0 {
+		ps.FlakyChecks = mergeNames(ps.FlakyChecks, r.known)
+	}
+	if r.err != nil {
+		ps.Rerun = rerunDone
+		c.logger.Warn("failed to re-run failed checks", "pr", r.prNumber, "err", r.err)
+		return []Action{{Type: "error", Detail: fmt.Sprintf("PR #%s: re-running failed checks failed", r.prNumber), Error: truncateError(r.err.Error(), 120)}}
+	}
+	if !r.rerun {
+		ps.Rerun = rerunDone
+		return nil
+	}
+	ps.RerunChecks = r.checks
+	return []Action{{Type: "rerun", Detail: fmt.Sprintf("Re-ran %d failed check(s) on PR #%s", len(r.checks), r.prNumber)}}
+}
+
+// mergeNames returns the sorted union of two name lists.
+func mergeNames(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var out []string
+	for _, n := range append(append([]string(nil), a...), b...) {
+		if !seen[n] {
+			seen[n] = true
+			out = append(out, n)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,180 @@
+package pipeline
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/patflynn/klaus/internal/event"
+)
+
+// newRerunController returns a test controller with the given rerun mode,
+// whose failed checks are failed and whose launches and reruns are counted.
+func newRerunController(t *testing.T, mode string, failed []FailedCheck) (c *Controller, dir string, launched, reruns *int) {
+	t.Helper()
+	c, dir = newTestController(t)
+	p := DefaultPolicy()
+	p.RerunFailedChecks = mode
+	c.SetPolicyResolver(func(string) Policy { return p })
+	launched, reruns = new(int), new(int)
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		*launched++
+		return "agent-fix", nil
+	})
+	c.failedChecks = func(ctx context.Context, prURL, prNumber string) ([]FailedCheck, error) {
+		return failed, nil
+	}
+	c.rerunCheck = func(ctx context.Context, slug string, checkID int64) error {
+		if slug != "owner/repo" {
+			t.Errorf("rerun slug = %q, want owner/repo", slug)
+		}
+		*reruns++
+		return nil
+	}
+	return c, dir, launched, reruns
+}
+
+func ciStatus(ci string) map[string]*PRStatus {
+	return map[string]*PRStatus{
+		"42": {PRNumber: "42", PRURL: "https://github.com/owner/repo/pull/42", State: "OPEN", CI: ci, TargetRepo: "repo"},
+	}
+}
+
+func TestRerunFailedChecks_FlakePasses(t *testing.T) {
+	c, dir, launched, reruns := newRerunController(t, RerunAlways, []FailedCheck{{ID: 7, Name: "test"}, {ID: 8, Name: "lint"}})
+	statePath := filepath.Join(dir, "session", StateFileName)
+	if err := c.LoadState(statePath); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	c.HandleGHStatus(ctx, ciStatus("failing"), nil)
+	if *reruns != 2 || *launched != 0 {
+		t.Fatalf("reruns=%d launched=%d, want 2 reruns and no fix agent", *reruns, *launched)
+	}
+	if ps := c.PipelineStates()["42"]; ps.Stage != StageCIRerun || ps.Rerun != rerunRequested {
+		t.Fatalf("stage=%s rerun=%q, want ci_rerun/requested", ps.Stage, ps.Rerun)
+	}
+
+	// GitHub still reports the old failure until the rerun starts.
+	c.HandleGHStatus(ctx, ciStatus("failing"), nil)
+	c.HandleGHStatus(ctx, ciStatus("pending"), nil)
+	c.HandleGHStatus(ctx, ciStatus("passing"), nil)
+	if *launched != 0 || *reruns != 2 {
+		t.Errorf("reruns=%d launched=%d after a flake, want 2/0", *reruns, *launched)
+	}
+
+	ps := c.PipelineStates()["42"]
+	if ps.Rerun != "" || len(ps.FlakyChecks) != 2 || ps.FlakyChecks[0] != "lint" {
+		t.Errorf("rerun=%q flaky=%v, want cleared and [lint test]", ps.Rerun, ps.FlakyChecks)
+	}
+	h := c.CheckHistories()["owner/repo"]["test"]
+	if h.Failures != 1 || h.Flakes != 1 || h.Repeats != 0 {
+		t.Errorf("history = %+v", h)
+	}
+
+	events, err := event.NewLog(filepath.Join(dir, "session")).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	flaky := 0
+	for _, e := range events {
+		if e.Type == event.CIFlaky {
+			flaky++
+		}
+	}
+	if flaky != 1 {
+		t.Errorf("ci:flaky emitted %d times, want 1", flaky)
+	}
+
+	// The history survives a restart.
+	c2, _ := newTestController(t)
+	if err := c2.LoadState(statePath); err != nil {
+		t.Fatal(err)
+	}
+	if got := c2.CheckHistories()["owner/repo"]["lint"].Flakes; got != 1 {
+		t.Errorf("restored lint flakes = %d, want 1", got)
+	}
+}
+
+func TestRerunFailedChecks_RepeatDispatchesFix(t *testing.T) {
+	c, _, launched, reruns := newRerunController(t, RerunAlways, []FailedCheck{{ID: 7, Name: "test"}})
+	ctx := context.Background()
+
+	c.HandleGHStatus(ctx, ciStatus("failing"), nil)
+	c.HandleGHStatus(ctx, ciStatus("pending"), nil)
+	c.HandleGHStatus(ctx, ciStatus("failing"), nil)
+	if *reruns != 1 || *launched != 1 {
+		t.Fatalf("reruns=%d launched=%d, want one rerun then a fix agent", *reruns, *launched)
+	}
+	ps := c.PipelineStates()["42"]
+	if ps.Stage != StageCIFailed || ps.Rerun != "" {
+		t.Errorf("stage=%s rerun=%q, want ci_failed with the rerun cleared", ps.Stage, ps.Rerun)
+	}
+	if h := c.CheckHistories()["owner/repo"]["test"]; h.Repeats != 1 || h.Flakes != 0 {
+		t.Errorf("history = %+v", h)
+	}
+}
+
+func TestRerunFailedChecks_RerunThatNeverStarts(t *testing.T) {
+	c, _, launched, _ := newRerunController(t, RerunAlways, []FailedCheck{{ID: 7, Name: "test"}})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	c.HandleGHStatus(ctx, ciStatus("failing"), nil)
+	c.HandleGHStatus(ctx, ciStatus("failing"), nil)
+	if *launched != 0 {
+		t.Fatalf("fix agent dispatched while the rerun is starting")
+	}
+	now = now.Add(rerunStartTimeout + time.Minute)
+	c.HandleGHStatus(ctx, ciStatus("failing"), nil)
+	if *launched != 1 {
+		t.Errorf("launched=%d, want a fix agent once the rerun timed out", *launched)
+	}
+}
+
+func TestRerunFailedChecks_FlakyModeLearnsFlakes(t *testing.T) {
+	c, _, launched, reruns := newRerunController(t, RerunFlaky, []FailedCheck{{ID: 7, Name: "test"}})
+	ctx := context.Background()
+
+	// A check with no history is re-run until it has flaky_threshold
+	// reruns on record; each flake counts towards known-flaky.
+	for i := 1; i <= defaultFlakyThreshold; i++ {
+		c.HandleGHStatus(ctx, ciStatus("failing"), nil)
+		if *reruns != i || *launched != 0 {
+			t.Fatalf("failure %d: reruns=%d launched=%d, want a rerun of an unproven check", i, *reruns, *launched)
+		}
+		c.HandleGHStatus(ctx, ciStatus("pending"), nil)
+		c.HandleGHStatus(ctx, ciStatus("passing"), nil)
+	}
+	if h := c.CheckHistories()["owner/repo"]["test"]; h.Flakes != defaultFlakyThreshold {
+		t.Fatalf("history = %+v, want %d flakes", h, defaultFlakyThreshold)
+	}
+
+	// Now known-flaky: rerun first, and the PR is marked flaky.
+	c.HandleGHStatus(ctx, ciStatus("failing"), nil)
+	if *reruns != defaultFlakyThreshold+1 || *launched != 0 {
+		t.Errorf("reruns=%d launched=%d, want a rerun of the known-flaky check", *reruns, *launched)
+	}
+	if got := c.PipelineStates()["42"].FlakyChecks; len(got) != 1 || got[0] != "test" {
+		t.Errorf("FlakyChecks = %v, want [test]", got)
+	}
+}
+
+func TestRerunFailedChecks_FlakyModeSkipsProvenFailures(t *testing.T) {
+	c, _, launched, reruns := newRerunController(t, RerunFlaky, []FailedCheck{{ID: 7, Name: "test"}})
+	// The check has failed again on each of its reruns: not flaky.
+	c.checkRecord("owner/repo", "test").Repeats = defaultFlakyThreshold
+	ctx := context.Background()
+
+	c.HandleGHStatus(ctx, ciStatus("failing"), nil)
+	if *reruns != 0 {
+		t.Fatalf("reran a check that repeats its failures")
+	}
+	c.HandleGHStatus(ctx, ciStatus("failing"), nil)
+	if *launched != 1 {
+		t.Fatalf("launched=%d, want a fix agent for a check that isn't flaky", *launched)
+	}
+}
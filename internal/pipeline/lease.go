@@ -0,0 +1,247 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// LeaseFileName is the name of the leader lease file inside a session
+// directory (e.g. ~/.klaus/sessions/<id>/pipeline-leader.json).
+const LeaseFileName = "pipeline-leader.json"
+
+// LeaseHeartbeat is how often the leader should renew its lease (and how
+// often followers should retry acquiring it).
+const LeaseHeartbeat = 15 * time.Second
+
+// LeaseTTL is how long a lease stays valid without a heartbeat. After that a
+// follower may take over, so a leader that hangs or is SIGKILLed on another
+// host does not block the pipeline forever.
+const LeaseTTL = 3 * LeaseHeartbeat
+
+// LeaseHolder identifies the process holding (or recorded as holding) the
+// leader lease.
+type LeaseHolder struct {
+	PID         int       `json:"pid"`
+	Host        string    `json:"host"`
+	Kind        string    `json:"kind"` // "dashboard" or "pipelined"
+	AcquiredAt  time.Time `json:"acquired_at"`
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+}
+
+// String formats the holder for display, e.g. "klaus pipelined (pid 4242 on devbox)".
+func (h LeaseHolder) String() string {
+	if h.PID == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("klaus %s (pid %d on %s)", h.Kind, h.PID, h.Host)
+}
+
+// Lease is a per-session leader lease. Only the process holding it may
+// execute pipeline actions; everyone else renders the leader's checkpoint.
+//
+// The lease file is read and rewritten under an exclusive flock on a sidecar
+// lock file, so two processes racing for an expired lease can't both win.
+// The flock is held only for that read-modify-write, not for the leader's
+// lifetime; liveness comes from the heartbeat, so a hung leader loses the
+// lease after LeaseTTL.
+type Lease struct {
+	path string
+	self LeaseHolder
+
+	// Injectable for testing.
+	now   func() time.Time
+	alive func(pid int) bool
+
+	mu     sync.Mutex
+	held   bool
+	holder LeaseHolder
+}
+
+// NewLease returns a lease on the file at path for the current process.
+// kind names the kind of process ("dashboard" or "pipelined") for display.
+func NewLease(path, kind string) *Lease {
+	host, _ := os.Hostname()
+	return &Lease{
+		path:  path,
+		self:  LeaseHolder{PID: os.Getpid(), Host: host, Kind: kind},
+		now:   time.Now,
+		alive: processAlive,
+	}
+}
+
+// TryAcquire acquires the lease if it is free, expired, or already ours, and
+// renews the heartbeat if we already hold it. It returns whether we are the
+// leader and who the leader is. Call it every LeaseHeartbeat.
+func (l *Lease) TryAcquire() (bool, LeaseHolder, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	held, holder, err := l.tryAcquire()
+	if err != nil {
+		// Can't prove we still hold the lease — step down rather than risk
+		// two leaders.
+		l.held = false
+		return false, l.holder, err
+	}
+	l.held = held
+	l.holder = holder
+	return held, holder, nil
+}
+
+func (l *Lease) tryAcquire() (bool, LeaseHolder, error) {
+	unlock, err := lockFile(l.path + ".lock")
+	if err != nil {
+		return false, LeaseHolder{}, err
+	}
+	defer unlock()
+
+	cur, err := readLease(l.path)
+	if err != nil {
+		return false, LeaseHolder{}, err
+	}
+	now := l.now().UTC()
+	if cur != nil && !l.isSelf(*cur) && !l.expired(*cur, now) {
+		return false, *cur, nil
+	}
+
+	next := l.self
+	next.AcquiredAt = now
+	if cur != nil && l.isSelf(*cur) {
+		next.AcquiredAt = cur.AcquiredAt
+	}
+	next.HeartbeatAt = now
+	if err := writeLease(l.path, next); err != nil {
+		return false, LeaseHolder{}, err
+	}
+	return true, next, nil
+}
+
+// Release gives up the lease if we hold it, so a follower can take over
+// immediately instead of waiting for LeaseTTL.
+func (l *Lease) Release() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.held {
+		return nil
+	}
+	l.held = false
+
+	unlock, err := lockFile(l.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cur, err := readLease(l.path)
+	if err != nil || cur == nil || !l.isSelf(*cur) {
+		return err
+	}
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing leader lease: %w", err)
+	}
+	return nil
+}
+
+// IsLeader reports whether the last TryAcquire made us the leader.
+func (l *Lease) IsLeader() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.held
+}
+
+// Holder returns the leader observed by the last TryAcquire.
+func (l *Lease) Holder() LeaseHolder {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.holder
+}
+
+func (l *Lease) isSelf(h LeaseHolder) bool {
+	return h.PID == l.self.PID && h.Host == l.self.Host
+}
+
+// expired reports whether h may be taken over: its heartbeat is older than
+// LeaseTTL, or it names a process on this host that no longer exists.
+func (l *Lease) expired(h LeaseHolder, now time.Time) bool {
+	if now.Sub(h.HeartbeatAt) > LeaseTTL {
+		return true
+	}
+	return h.Host == l.self.Host && !l.alive(h.PID)
+}
+
+// readLease returns the lease recorded at path, or nil if there is none. An
+// unparseable file is treated as no lease so a corrupt write can't wedge the
+// session without a leader.
+func readLease(path string) (*LeaseHolder, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading leader lease: %w", err)
+	}
+	var h LeaseHolder
+	if err := json.Unmarshal(data, &h); err != nil || h.PID <= 0 {
+		return nil, nil
+	}
+	return &h, nil
+}
+
+func writeLease(path string, h LeaseHolder) error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling leader lease: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temp lease file: %w", err)
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("writing leader lease: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("closing leader lease: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("renaming leader lease: %w", err)
+	}
+	return nil
+}
+
+// lockFile takes an exclusive flock on path, creating it (and its directory)
+// if needed. The returned func releases the lock.
+func lockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating lock dir: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", filepath.Base(path), err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("locking %s: %w", filepath.Base(path), err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN) //nolint:errcheck
+		f.Close()
+	}, nil
+}
+
+// processAlive reports whether a process with the given PID exists on this
+// host.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
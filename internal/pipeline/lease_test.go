@@ -0,0 +1,140 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestLease returns a lease for a fake process pid on host "h" with an
+// injectable clock and process table.
+func newTestLease(path string, pid int, now *time.Time, alive map[int]bool) *Lease {
+	l := NewLease(path, "dashboard")
+	l.self.PID = pid
+	l.self.Host = "h"
+	l.now = func() time.Time { return *now }
+	l.alive = func(pid int) bool { return alive[pid] }
+	return l
+}
+
+func TestLeaseSingleLeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LeaseFileName)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	alive := map[int]bool{1: true, 2: true}
+	a := newTestLease(path, 1, &now, alive)
+	b := newTestLease(path, 2, &now, alive)
+
+	if leading, _, err := a.TryAcquire(); err != nil || !leading {
+		t.Fatalf("a.TryAcquire = %v, %v; want leader", leading, err)
+	}
+	leading, holder, err := b.TryAcquire()
+	if err != nil || leading {
+		t.Fatalf("b.TryAcquire = %v, %v; want follower", leading, err)
+	}
+	if holder.PID != 1 {
+		t.Errorf("b sees leader pid %d, want 1", holder.PID)
+	}
+
+	// Heartbeats keep a's lease fresh well past one TTL.
+	for i := 0; i < 5; i++ {
+		now = now.Add(LeaseHeartbeat)
+		if leading, _, _ := a.TryAcquire(); !leading {
+			t.Fatalf("a lost the lease on heartbeat %d", i)
+		}
+		if leading, _, _ := b.TryAcquire(); leading {
+			t.Fatalf("b took over a live lease on heartbeat %d", i)
+		}
+	}
+}
+
+func TestLeaseTakeoverOnStaleHeartbeat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LeaseFileName)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	alive := map[int]bool{1: true, 2: true}
+	a := newTestLease(path, 1, &now, alive)
+	b := newTestLease(path, 2, &now, alive)
+
+	a.TryAcquire()
+	now = now.Add(LeaseTTL + time.Second)
+	if leading, _, _ := b.TryAcquire(); !leading {
+		t.Fatal("b did not take over a stale lease")
+	}
+	// a wakes up and must notice it is no longer leader.
+	if leading, holder, _ := a.TryAcquire(); leading || holder.PID != 2 {
+		t.Errorf("a.TryAcquire = %v, leader %d; want follower of 2", leading, holder.PID)
+	}
+}
+
+func TestLeaseTakeoverFromDeadProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LeaseFileName)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	alive := map[int]bool{1: true, 2: true}
+	a := newTestLease(path, 1, &now, alive)
+	b := newTestLease(path, 2, &now, alive)
+
+	a.TryAcquire()
+	alive[1] = false // SIGKILLed without releasing
+	if leading, _, _ := b.TryAcquire(); !leading {
+		t.Fatal("b did not take over from a dead leader on the same host")
+	}
+}
+
+func TestLeaseRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LeaseFileName)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	alive := map[int]bool{1: true, 2: true}
+	a := newTestLease(path, 1, &now, alive)
+	b := newTestLease(path, 2, &now, alive)
+
+	a.TryAcquire()
+	b.TryAcquire()
+
+	// A follower's Release must not remove the leader's lease.
+	if err := b.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("follower release removed the lease: %v", err)
+	}
+
+	if err := a.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if a.IsLeader() {
+		t.Error("a still leader after Release")
+	}
+	if leading, _, _ := b.TryAcquire(); !leading {
+		t.Error("b did not acquire a released lease")
+	}
+}
+
+func TestLeaseConcurrentAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LeaseFileName)
+	now := time.Now()
+	alive := map[int]bool{}
+	for pid := 1; pid <= 8; pid++ {
+		alive[pid] = true
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	leaders := 0
+	for pid := 1; pid <= 8; pid++ {
+		l := newTestLease(path, pid, &now, alive)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if leading, _, err := l.TryAcquire(); err == nil && leading {
+				mu.Lock()
+				leaders++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if leaders != 1 {
+		t.Errorf("%d processes acquired the lease, want exactly 1", leaders)
+	}
+}
@@ -0,0 +1,203 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// StateFileName is the name of the pipeline checkpoint file inside a session
+// directory (e.g. ~/.klaus/sessions/<id>/pipeline-state.json).
+const StateFileName = "pipeline-state.json"
+
+// stateFileVersion is the on-disk schema version of the checkpoint file.
+// Bump it whenever PRPipelineState changes in a way older readers can't
+// interpret, and teach LoadState how to migrate (or discard) older files.
+const stateFileVersion = 1
+
+// stateFile is the serialized form of the controller's per-PR state.
+type stateFile struct {
+	Version  int                         `json:"version"`
+	SavedAt  time.Time                   `json:"saved_at"`
+	PRStates map[string]*PRPipelineState `json:"pr_states"`
+
+	// MainWatches are the post-merge default-branch watches, keyed by
+	// owner/repo. Absent in files written before the watchdog existed.
+	MainWatches map[string]*MainWatch `json:"main_watches,omitempty"`
+
+	// CheckHistory is the per-repo record of failed and re-run CI checks,
+	// keyed by owner/repo and then check name.
+	CheckHistory map[string]map[string]*CheckHistory `json:"check_history,omitempty"`
+}
+
+// LoadState restores per-PR pipeline state from the checkpoint file at path
+// and enables checkpointing to that path after every HandleGHStatus call.
+// Any in-memory per-PR state is replaced, so a follower that becomes leader
+// can call it again to pick up the previous leader's checkpoint. A missing
+// file is not an error: the controller starts empty and the file is created
+// on the first checkpoint.
+//
+// If the file was written by a newer klaus (higher version), LoadState returns
+// an error and leaves persistence disabled so the newer file is not clobbered.
+// Files with an older version are discarded and the controller starts fresh.
+func (c *Controller) LoadState(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sf, err := readStateFile(path)
+	if err != nil {
+		return err
+	}
+	if sf != nil && sf.Version > stateFileVersion {
+		return fmt.Errorf("pipeline state %s has version %d, newer than supported version %d", path, sf.Version, stateFileVersion)
+	}
+	c.statePath = path
+	c.prStates = make(map[string]*PRPipelineState)
+	c.mainWatches = make(map[string]*MainWatch)
+	c.checkHistory = make(map[string]map[string]*CheckHistory)
+	if sf == nil {
+		return nil
+	}
+	if sf.Version < stateFileVersion {
+		c.logger.Warn("discarding pipeline state with old schema version",
+			"path", path,
+			"version", sf.Version,
+			"want", stateFileVersion,
+		)
+		return nil
+	}
+
+	for prNum, ps := range sf.PRStates {
+		if ps == nil {
+			continue
+		}
+		if ps.PRNumber == "" {
+			ps.PRNumber = prNum
+		}
+		if ps.SeenCommentIDs == nil {
+			ps.SeenCommentIDs = make(map[int64]bool)
+		}
+		c.prStates[prNum] = ps
+	}
+	for slug, w := range sf.MainWatches {
+		if w != nil {
+			c.mainWatches[slug] = w
+		}
+	}
+	for slug, checks := range sf.CheckHistory {
+		if checks != nil {
+			c.checkHistory[slug] = checks
+		}
+	}
+	c.logger.Info("restored pipeline state", "path", path, "prs", len(sf.PRStates))
+	return nil
+}
+
+// ReadStateFile returns the per-PR state recorded in the checkpoint file at
+// path without taking ownership of it. It is used by read-only views (e.g. a
+// dashboard attached to `klaus pipelined`) to render what another process's
+// controller is doing. A missing file yields an empty map.
+func ReadStateFile(path string) (map[string]*PRPipelineState, error) {
+	sf, err := readStateFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if sf == nil || sf.Version != stateFileVersion {
+		return map[string]*PRPipelineState{}, nil
+	}
+	if sf.PRStates == nil {
+		sf.PRStates = map[string]*PRPipelineState{}
+	}
+	return sf.PRStates, nil
+}
+
+// readStateFile parses the checkpoint at path. It returns nil, nil if the
+// file does not exist.
+func readStateFile(path string) (*stateFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading pipeline state: %w", err)
+	}
+	var sf stateFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		return nil, fmt.Errorf("parsing pipeline state: %w", err)
+	}
+	return &sf, nil
+}
+
+// checkpoint writes the current per-PR state to the checkpoint file, if
+// persistence is enabled and this process still leads. Callers must hold
+// c.mu.
+//
+// Concurrent writers (e.g. two dashboards on the same session) are serialized
+// with an exclusive flock on a sidecar lock file, and the checkpoint itself is
+// written to a temp file and renamed into place so readers never observe a
+// partially written file.
+func (c *Controller) checkpoint() {
+	if c.statePath == "" || !c.leading() {
+		return
+	}
+	if err := writeStateFile(c.statePath, stateFile{
+		PRStates:     c.prStates,
+		MainWatches:  c.mainWatches,
+		CheckHistory: c.checkHistory,
+	}); err != nil {
+		c.logger.Error("failed to checkpoint pipeline state", "path", c.statePath, "err", err)
+	}
+}
+
+// writeStateFile writes sf, stamped with the current version and time.
+func writeStateFile(path string, sf stateFile) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating state dir: %w", err)
+	}
+
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening state lock: %w", err)
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("locking pipeline state: %w", err)
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN) //nolint:errcheck
+
+	sf.Version = stateFileVersion
+	sf.SavedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(sf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling pipeline state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temp state file: %w", err)
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("writing pipeline state: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("syncing pipeline state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("closing pipeline state: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("renaming pipeline state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPipelineStateSurvivesRestart(t *testing.T) {
+	c, dir := newTestController(t)
+	path := filepath.Join(dir, "session", StateFileName)
+	if err := c.LoadState(path); err != nil {
+		t.Fatalf("LoadState on missing file: %v", err)
+	}
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		return "agent-001", nil
+	})
+
+	statuses := map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "failing", TargetRepo: "owner/repo"},
+	}
+	c.HandleGHStatus(context.Background(), statuses, nil)
+
+	// Simulate accumulated breaker state that must not reset on restart.
+	c.mu.Lock()
+	c.prStates["42"].FixAttempts = 2
+	c.prStates["42"].PendingResolveThreadIDs = []string{"T_1", "T_2"}
+	c.prStates["42"].SeenCommentIDs[99] = true
+	c.checkpoint()
+	c.mu.Unlock()
+
+	restarted, _ := newTestController(t)
+	if err := restarted.LoadState(path); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	ps := restarted.PipelineStates()["42"]
+	if ps == nil {
+		t.Fatal("expected PR #42 state to be restored")
+	}
+	if ps.Stage != StageCIFailed {
+		t.Errorf("stage = %s, want %s", ps.Stage, StageCIFailed)
+	}
+	if ps.LastAgentID != "agent-001" {
+		t.Errorf("LastAgentID = %q, want agent-001", ps.LastAgentID)
+	}
+	if ps.FixAttempts != 2 {
+		t.Errorf("FixAttempts = %d, want 2", ps.FixAttempts)
+	}
+	if len(ps.PendingResolveThreadIDs) != 2 {
+		t.Errorf("PendingResolveThreadIDs = %v, want 2 entries", ps.PendingResolveThreadIDs)
+	}
+	if !ps.SeenCommentIDs[99] {
+		t.Error("expected SeenCommentIDs to be restored")
+	}
+	if time.Since(ps.LastDispatchAt) > time.Minute {
+		t.Errorf("LastDispatchAt not restored: %v", ps.LastDispatchAt)
+	}
+}
+
+func TestPipelineStateRestoredCooldownBlocksRedispatch(t *testing.T) {
+	c, dir := newTestController(t)
+	path := filepath.Join(dir, "session", StateFileName)
+	if err := c.LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		return "agent-001", nil
+	})
+	statuses := map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "failing", TargetRepo: "owner/repo"},
+	}
+	c.HandleGHStatus(context.Background(), statuses, nil)
+
+	restarted, _ := newTestController(t)
+	if err := restarted.LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+	launches := 0
+	restarted.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launches++
+		return "agent-002", nil
+	})
+	// The agent from before the restart has finished (no run states), but the
+	// restored dispatch cooldown must still hold.
+	restarted.HandleGHStatus(context.Background(), statuses, nil)
+	if launches != 0 {
+		t.Errorf("expected restored cooldown to block re-dispatch, got %d launches", launches)
+	}
+}
+
+func TestPipelineStateMergedPRDroppedFromCheckpoint(t *testing.T) {
+	c, dir := newTestController(t)
+	path := filepath.Join(dir, "session", StateFileName)
+	if err := c.LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "pending"},
+	}
+	c.HandleGHStatus(context.Background(), statuses, nil)
+	statuses["42"] = &PRStatus{PRNumber: "42", State: "MERGED"}
+	c.HandleGHStatus(context.Background(), statuses, nil)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sf stateFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		t.Fatal(err)
+	}
+	if sf.Version != stateFileVersion {
+		t.Errorf("version = %d, want %d", sf.Version, stateFileVersion)
+	}
+	if _, ok := sf.PRStates["42"]; ok {
+		t.Error("merged PR should not remain in the checkpoint")
+	}
+}
+
+func TestPipelineStateRejectsNewerVersion(t *testing.T) {
+	c, dir := newTestController(t)
+	path := filepath.Join(dir, StateFileName)
+	newer := `{"version": 99, "pr_states": {"42": {"pr_number": "42", "stage": "ci_failed"}}}`
+	if err := os.WriteFile(path, []byte(newer), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := c.LoadState(path)
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("expected newer-version error, got %v", err)
+	}
+
+	// Persistence stays disabled so the newer file is not overwritten.
+	c.HandleGHStatus(context.Background(), map[string]*PRStatus{
+		"7": {PRNumber: "7", State: "OPEN", CI: "pending"},
+	}, nil)
+	data, _ := os.ReadFile(path)
+	if string(data) != newer {
+		t.Errorf("newer checkpoint was overwritten: %s", data)
+	}
+}
+
+func TestPipelineStateDiscardsOlderVersion(t *testing.T) {
+	c, dir := newTestController(t)
+	path := filepath.Join(dir, StateFileName)
+	old := `{"pr_states": {"42": {"pr_number": "42", "stage": "stalled", "fix_attempts": 3}}}`
+	if err := os.WriteFile(path, []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.LoadState(path); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if len(c.PipelineStates()) != 0 {
+		t.Errorf("expected old-version state to be discarded, got %v", c.PipelineStates())
+	}
+}
+
+func TestPipelineStateConcurrentCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, StateFileName)
+
+	done := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		go func() {
+			states := map[string]*PRPipelineState{
+				"42": {PRNumber: "42", Stage: StageCIPending, FixAttempts: i},
+			}
+			done <- writeStateFile(path, stateFile{PRStates: states})
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("writeStateFile: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sf stateFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		t.Fatalf("checkpoint corrupted by concurrent writers: %v", err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, StateFileName+".tmp-*"))
+	if len(matches) != 0 {
+		t.Errorf("temp files left behind: %v", matches)
+	}
+}
+
+func TestLostLeadershipDropsActionsAndCheckpoint(t *testing.T) {
+	c, dir := newTestController(t)
+	path := filepath.Join(dir, "session", StateFileName)
+	if err := c.LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+	leading := true
+	c.SetLeader(func() bool { return leading })
+	launches := 0
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launches++
+		// The lease lapses while the first launch runs.
+		leading = false
+		return "agent-001", nil
+	})
+
+	c.HandleGHStatus(context.Background(), map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "failing", TargetRepo: "owner/repo"},
+		"43": {PRNumber: "43", State: "OPEN", CI: "failing", TargetRepo: "owner/repo"},
+	}, nil)
+	if launches != 1 {
+		t.Errorf("launched %d agents after losing leadership, want 1", launches)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("a former leader wrote the checkpoint (stat err = %v)", err)
+	}
+}
@@ -30,6 +30,15 @@ const (
 	StageMerged        Stage = "merged"
 	StageStalled       Stage = "stalled"
 	StageBudgetPaused  Stage = "budget_paused"
+	// StageBudgetExceeded parks a PR whose agents have spent its per-PR cap
+	// (Policy.MaxPRSpendUSD); no more agents are dispatched for it.
+	StageBudgetExceeded Stage = "budget_exceeded"
+	// StageCIRerun holds a failing PR while its failed checks are re-run
+	// (Policy.RerunFailedChecks) before a fix agent is considered.
+	StageCIRerun Stage = "ci_rerun"
+	// StageClosed is a PR closed without merging; like StageMerged it leaves
+	// the pipeline, but it is picked up again if the PR is reopened.
+	StageClosed Stage = "closed"
 )
 
 // PRStatus holds the GitHub-fetched status for a single PR, passed from the dashboard.
@@ -43,29 +52,64 @@ type PRStatus struct {
 	TargetRepo            string // canonical project short name (e.g. "klaus") for registered projects, else an owner/repo slug; NOT guaranteed to be a GitHub owner/repo slug — do not use in gh api paths
 	HasNewTrustedComments bool   // unaddressed comments from trusted reviewers
 	Labels                []string // GitHub label names; klaus uses "klaus:budget-paused" as a pause signal
+
+	// BaseBranch is the branch the PR targets when it isn't the default
+	// branch (a backport's release branch, a stacked PR's parent), from
+	// its run state. Empty means the default branch.
+	BaseBranch string `json:",omitempty"`
+
+	// Checks are the individual checks behind CI, each marked required or
+	// not by the base branch's protection. Empty when they couldn't be
+	// fetched, in which case CI is used as is.
+	Checks []ghutil.CheckRun `json:",omitempty"`
 }
 
-// PRPipelineState tracks per-PR pipeline state.
+// PRPipelineState tracks per-PR pipeline state. It is checkpointed to the
+// session directory (see LoadState) so circuit breakers, cooldowns and pending
+// thread resolutions survive a dashboard restart.
 type PRPipelineState struct {
-	PRNumber                string
-	Stage                   Stage
-	LastAgentID             string // run ID of last dispatched agent
-	AgentRunning            bool   // whether the dispatched agent is still active
-	SeenCommentIDs          map[int64]bool
-	PendingResolveThreadIDs []string  // GraphQL thread IDs to resolve after agent completes
-	RetryCount              int       // number of launch retries after failure
-	LastFailedAt            time.Time // when the last launch failure occurred
-	LastDispatchAt          time.Time // when the last agent was dispatched (cooldown guard)
-	FixAttempts             int       // number of CI-fix agents dispatched that completed without fixing CI
-	RebaseAttempts          int       // number of rebase agents dispatched that completed without resolving conflicts
-	ReviewFixAttempts       int       // number of review-fix agents dispatched that completed without addressing trusted comments
+	PRNumber                string         `json:"pr_number"`
+	Stage                   Stage          `json:"stage"`
+	LastAgentID             string         `json:"last_agent_id,omitempty"` // run ID of last dispatched agent
+	AgentRunning            bool           `json:"agent_running"`           // whether the dispatched agent is still active
+	SeenCommentIDs          map[int64]bool `json:"seen_comment_ids,omitempty"`
+	PendingResolveThreadIDs []string       `json:"pending_resolve_thread_ids,omitempty"` // GraphQL thread IDs to resolve after agent completes
+	RetryCount              int            `json:"retry_count"`                          // number of launch retries after failure
+	LastFailedAt            time.Time      `json:"last_failed_at"`                       // when the last launch failure occurred
+	LastDispatchAt          time.Time      `json:"last_dispatch_at"`                     // when the last agent was dispatched (cooldown guard)
+	FixAttempts             int            `json:"fix_attempts"`                         // number of CI-fix agents dispatched that completed without fixing CI
+	RebaseAttempts          int            `json:"rebase_attempts"`                      // number of rebase agents dispatched that completed without resolving conflicts
+	ReviewFixAttempts       int            `json:"review_fix_attempts"`                  // number of review-fix agents dispatched that completed without addressing trusted comments
+
+	// Rerun tracks re-running the current CI failure's checks (see
+	// Policy.RerunFailedChecks); empty when no rerun is in progress.
+	Rerun       string    `json:"rerun,omitempty"`
+	RerunAt     time.Time `json:"rerun_at,omitempty"`
+	RerunFrom   Stage     `json:"rerun_from,omitempty"`    // stage before the rerun
+	RerunChecks []string  `json:"rerun_checks,omitempty"`  // checks re-requested
+	FlakyChecks []string  `json:"flaky_checks,omitempty"`  // known-flaky checks seen failing on this PR
+
+	// RetargetedFrom is the merged stack parent's branch this PR was moved
+	// off; set once the PR targets the default branch instead.
+	RetargetedFrom string `json:"retargeted_from,omitempty"`
+
+	// AutoResolveTried records that 'klaus rebase' already pushed a
+	// resolution for the current conflicts, so if they persist the next
+	// dispatch goes to a rebase agent. Cleared once the PR is mergeable.
+	AutoResolveTried bool `json:"auto_resolve_tried,omitempty"`
+
+	// BackportBranches are the release branches the PR's "backport <branch>"
+	// labels name, as of the last poll while it was open. They are
+	// backported onto once it merges (see BackportBranches).
+	BackportBranches []string `json:"backport_branches,omitempty"`
 
 	pendingLaunchDetail string // transient: detail text for pending launch action
+	resolving           bool   // transient: a queued conflict resolution hasn't finished
 }
 
 // Action describes a side-effect the controller wants the dashboard to perform.
 type Action struct {
-	Type   string // "launch", "merge", or "error"
+	Type   string // "launch", "resolve", "merge", "rerun", "retarget", "backport", "cleanup", "reopen", "plan", "compare", or "error"
 	Detail string // human-readable description
 	Error  string // non-empty if action represents a failure
 }
@@ -78,8 +122,35 @@ const (
 	ActionMergePR
 	ActionCleanupWorktrees
 	ActionSnapshotThreads
+	ActionRerunChecks
+	ActionRetargetPR
+	ActionBackport
+	ActionCleanupClosed
 )
 
+func (t ActionType) String() string {
+	switch t {
+	case ActionLaunchAgent:
+		return "launch-agent"
+	case ActionMergePR:
+		return "merge-pr"
+	case ActionCleanupWorktrees:
+		return "cleanup-worktrees"
+	case ActionSnapshotThreads:
+		return "snapshot-threads"
+	case ActionRerunChecks:
+		return "rerun-checks"
+	case ActionRetargetPR:
+		return "retarget-pr"
+	case ActionBackport:
+		return "backport"
+	case ActionCleanupClosed:
+		return "cleanup-closed"
+	default:
+		return fmt.Sprintf("ActionType(%d)", int(t))
+	}
+}
+
 // ActionDescriptor is a pure data description of a side-effect to perform.
 // evaluate() returns these instead of executing I/O directly.
 type ActionDescriptor struct {
@@ -88,8 +159,14 @@ type ActionDescriptor struct {
 	Repo       string
 	Prompt     string
 	ResumeFrom string
-	PRNumbers  []string // for merge
-	RunStates  []*run.State // for worktree cleanup
+	PRNumbers  []string // for merge; for retarget, the merged stack parent
+	RunStates  []*run.State // for worktree and closed-PR cleanup
+	PRURL      string
+	CIFailure  bool // fetch the failing checks' logs and embed an excerpt in Prompt
+	Conflicts  bool // try resolving the conflicts without an agent first; launch only if that fails
+	Branches   []string // for backport, the release branches to cherry-pick onto
+	Cleanup    []string // for closed-PR cleanup, what to remove (Policy.ClosedCleanup)
+	Profile    string   // for launches, the agent profile to run with (Policy.Profiles)
 }
 
 // Controller manages the PR pipeline lifecycle.
@@ -100,15 +177,44 @@ type Controller struct {
 	prStates map[string]*PRPipelineState // keyed by PR number
 	mu       sync.Mutex
 
+	mainWatches map[string]*MainWatch // post-merge default-branch watches, keyed by owner/repo
+
+	checkHistory map[string]map[string]*CheckHistory // failed-check history, keyed by owner/repo and check name
+
+	resolutions []ConflictResolution // queued for the caller; see PendingResolutions
+
+	statePath string // checkpoint file for prStates; empty disables persistence
+
+	// leader reports whether this process still leads the session's
+	// pipeline. Nil means always lead.
+	leader func() bool
+
 	autoMergeOnApproval bool // whether to auto-merge approved PRs
 
+	policyFor func(repo string) Policy // per-repo policy; nil means DefaultPolicy
+
 	tmuxDeps run.TmuxDeps // tmux operations for checking pane state
 
+	now   func() time.Time // clock for cooldowns and backoff; replaced by the simulator
+	trace *TraceRecorder   // records HandleGHStatus inputs; nil disables
+
+	// onTransition observes audited transitions (see emitTransition); used by
+	// the simulator.
+	onTransition func(ft firedTransition, ps *PRPipelineState, runID string)
+
 	// Injectable runners for testing.
-	launchAgent     func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error)
+	launchAgent     func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error)
 	mergePRs        func(ctx context.Context, repo string, prNumbers []string) error
 	snapshotThreads func(repo, prNumber string) ([]string, error)
 	resolveThread   func(threadID string) error
+	fetchCIFailures func(ctx context.Context, prURL, prNumber string) ([]CheckFailure, error)
+	failedChecks    func(ctx context.Context, prURL, prNumber string) ([]FailedCheck, error)
+	rerunCheck      func(ctx context.Context, slug string, checkID int64) error
+	retargetPR      func(ctx context.Context, slug, prNumber, parentPR string) (string, error)
+	resolveConflicts func(ctx context.Context, slug, prNumber string) error
+	backport         func(ctx context.Context, slug, prNumber string, branches []string) error
+	cleanupRun       func(ctx context.Context, runID string, cleanup []string) error
+	cleanupWorktree  func(ctx context.Context, runID string) error
 }
 
 // New creates a new pipeline controller.
@@ -119,16 +225,44 @@ func New(store run.StateStore, eventLog *event.Log, logger *slog.Logger) *Contro
 		logger:   logger,
 		prStates: make(map[string]*PRPipelineState),
 		tmuxDeps: run.DefaultTmuxDeps(),
+
+		mainWatches: make(map[string]*MainWatch),
+		checkHistory: make(map[string]map[string]*CheckHistory),
+		now:      time.Now,
 	}
 	c.launchAgent = c.defaultLaunchAgent
 	c.mergePRs = c.defaultMergePRs
 	c.snapshotThreads = c.defaultSnapshotThreads
+	c.fetchCIFailures = c.defaultFetchCIFailures
+	c.failedChecks = c.defaultFailedChecks
+	c.retargetPR = c.defaultRetargetPR
+	c.resolveConflicts = c.defaultResolveConflicts
+	c.backport = c.defaultBackport
+	c.cleanupRun = c.defaultCleanupRun
+	c.cleanupWorktree = c.defaultCleanupWorktree
+	c.rerunCheck = func(ctx context.Context, slug string, checkID int64) error {
+		return ghutil.NewGHCLIClient("").APIPost(ctx, fmt.Sprintf("repos/%s/check-runs/%d/rerequest", slug, checkID), nil)
+	}
 	c.resolveThread = func(threadID string) error {
 		return ghutil.NewGHCLIClient("").ResolveReviewThread(context.TODO(), threadID)
 	}
 	return c
 }
 
+// SetLeader gates execution on leadership. Evaluation can outlast the
+// leader lease (a merge polls CI for minutes), so leader is checked again
+// before each action is executed and before checkpointing; once it reports
+// false the remaining actions are dropped and the checkpoint is left to the
+// new leader.
+func (c *Controller) SetLeader(leader func() bool) {
+	c.leader = leader
+}
+
+// leading reports whether this process may execute actions.
+func (c *Controller) leading() bool {
+	return c.leader == nil || c.leader()
+}
+
 // SetTmuxDeps overrides the tmux dependencies used for pane state checks.
 func (c *Controller) SetTmuxDeps(td run.TmuxDeps) {
 	c.tmuxDeps = td
@@ -142,7 +276,7 @@ func (c *Controller) SetAutoMergeOnApproval(enabled bool) {
 }
 
 // SetLaunchAgent overrides the agent launcher (for testing).
-func (c *Controller) SetLaunchAgent(fn func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error)) {
+func (c *Controller) SetLaunchAgent(fn func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error)) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.launchAgent = fn
@@ -162,6 +296,49 @@ func (c *Controller) SetSnapshotThreads(fn func(repo, prNumber string) ([]string
 	c.snapshotThreads = fn
 }
 
+// SetFetchCIFailures overrides CI failure log fetching (for testing).
+func (c *Controller) SetFetchCIFailures(fn func(ctx context.Context, prURL, prNumber string) ([]CheckFailure, error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetchCIFailures = fn
+}
+
+// SetRetargetPR overrides retargeting stacked PRs (for testing).
+func (c *Controller) SetRetargetPR(fn func(ctx context.Context, slug, prNumber, parentPR string) (string, error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retargetPR = fn
+}
+
+// SetResolveConflicts overrides the agentless conflict resolver (for testing).
+func (c *Controller) SetResolveConflicts(fn func(ctx context.Context, slug, prNumber string) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resolveConflicts = fn
+}
+
+// SetBackport overrides backporting merged PRs (for testing).
+func (c *Controller) SetBackport(fn func(ctx context.Context, slug, prNumber string, branches []string) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.backport = fn
+}
+
+// SetCleanupRun overrides cleaning up the runs on closed PRs (for testing).
+func (c *Controller) SetCleanupRun(fn func(ctx context.Context, runID string, cleanup []string) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cleanupRun = fn
+}
+
+// SetCleanupWorktree overrides removing a finished run's worktree before a
+// PR is re-dispatched (for testing).
+func (c *Controller) SetCleanupWorktree(fn func(ctx context.Context, runID string) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cleanupWorktree = fn
+}
+
 // SetResolveThread overrides thread resolution (for testing).
 func (c *Controller) SetResolveThread(fn func(threadID string) error) {
 	c.mu.Lock()
@@ -184,6 +361,8 @@ func (c *Controller) HandleGHStatus(ctx context.Context, statuses map[string]*PR
 	var descriptors []ActionDescriptor
 	// Track which PRs need thread resolution (agent just completed).
 	var threadResolvePRs []*PRPipelineState
+	// Transitions to audit once dispatch results are known (phase 3).
+	var fired []firedTransition
 
 	// Build a set of running agent run IDs from current run states.
 	runningAgents := make(map[string]bool)
@@ -192,24 +371,49 @@ func (c *Controller) HandleGHStatus(ctx context.Context, statuses map[string]*PR
 			runningAgents[s.ID] = true
 		}
 	}
+	c.recordTrace(statuses, runStates, runningAgents)
 
 	for prNum, status := range statuses {
-		if status.State == "MERGED" || status.State == "CLOSED" {
-			// Clean up tracking for merged/closed PRs.
-			if ps, ok := c.prStates[prNum]; ok {
-				if status.State == "MERGED" && ps.Stage != StageMerged {
-					ps.Stage = StageMerged
-					c.emitEvent(prNum, event.PRMerged, map[string]interface{}{
-						"pr_number": prNum,
-						"pr_url":    status.PRURL,
+		if status.State == "CLOSED" {
+			descriptors = append(descriptors, c.closePR(prNum, status, runStates)...)
+			continue
+		}
+		if status.State == "MERGED" {
+			// A PR never polled open (it merged before klaus saw it)
+			// has no state; its runs being stamped now marks its merge
+			// as new.
+			stamped := c.stampMerged(prNum, runStates)
+			ps, tracked := c.prStates[prNum]
+			if tracked && ps.Stage != StageMerged {
+				from := ps.Stage
+				ps.Stage = StageMerged
+				c.emitTransition(firedTransition{prNumber: prNum, prURL: status.PRURL, from: from, rule: "status/merged"}, "")
+				c.emitEvent(prNum, event.PRMerged, map[string]interface{}{
+					"pr_number": prNum,
+					"pr_url":    status.PRURL,
+				})
+				c.watchMain(prNum, status)
+			}
+			if tracked || stamped {
+				if branches := mergedBackportBranches(ps, status); len(branches) > 0 {
+					descriptors = append(descriptors, ActionDescriptor{
+						Type:     ActionBackport,
+						PRNumber: prNum,
+						Repo:     status.TargetRepo,
+						PRURL:    status.PRURL,
+						Branches: branches,
 					})
 				}
-				delete(c.prStates, prNum)
 			}
+			// Clean up tracking for merged PRs.
+			delete(c.prStates, prNum)
+			descriptors = append(descriptors, c.stackRetargets(prNum, runStates)...)
 			continue
 		}
 
+		status = c.gateStatus(status)
 		ps := c.getOrCreateState(prNum, status)
+		actions = append(actions, c.reopenPR(ps, status, runStates)...)
 
 		// Update agent running status.
 		wasRunning := ps.AgentRunning
@@ -222,8 +426,14 @@ func (c *Controller) HandleGHStatus(ctx context.Context, statuses map[string]*PR
 			threadResolvePRs = append(threadResolvePRs, ps)
 		}
 
+		c.observeRerun(ps, status)
+		if status.Conflicts != "yes" {
+			ps.AutoResolveTried = false
+		}
+		ps.BackportBranches = BackportBranches(status.Labels)
+
 		prevStage := ps.Stage
-		evalActions, evalDescs := c.evaluate(ps, status, runStates)
+		rule, evalActions, evalDescs := c.evaluate(ps, status, runStates)
 		actions = append(actions, evalActions...)
 		descriptors = append(descriptors, evalDescs...)
 
@@ -232,8 +442,14 @@ func (c *Controller) HandleGHStatus(ctx context.Context, statuses map[string]*PR
 				"pr", prNum,
 				"from", string(prevStage),
 				"to", string(ps.Stage),
+				"rule", rule,
 			)
 		}
+		// Wait rules fire on every poll without doing anything; only
+		// transitions that move the PR or request work are audited.
+		if rule != "" && (ps.Stage != prevStage || len(evalActions) > 0 || len(evalDescs) > 0) {
+			fired = append(fired, firedTransition{prNumber: prNum, prURL: status.PRURL, from: prevStage, rule: rule})
+		}
 	}
 
 	c.mu.Unlock()
@@ -248,6 +464,7 @@ func (c *Controller) HandleGHStatus(ctx context.Context, statuses map[string]*PR
 	// Execute descriptors and collect results to apply under the lock.
 	type launchResult struct {
 		prNumber string
+		repo     string
 		agentID  string
 		err      error
 	}
@@ -257,12 +474,21 @@ func (c *Controller) HandleGHStatus(ctx context.Context, statuses map[string]*PR
 		err      error
 	}
 	var launchResults []launchResult
+	var resolutions []ConflictResolution
 	var mergeResults []mergeResult
-
-	for _, desc := range descriptors {
+	var rerunResults []rerunResult
+	var retargetResults []retargetResult
+	var backportResults []backportResult
+	var closedCleanupResults []closedCleanupResult
+
+	for i, desc := range descriptors {
+		if !c.leading() {
+			c.logger.Warn("lost pipeline leadership; dropping actions", "dropped", len(descriptors)-i)
+			break
+		}
 		switch desc.Type {
 		case ActionCleanupWorktrees:
-			c.cleanupStaleWorktrees(desc.PRNumber, desc.RunStates)
+			c.cleanupStaleWorktrees(ctx, desc.PRNumber, desc.RunStates)
 
 		case ActionSnapshotThreads:
 			c.mu.Lock()
@@ -273,13 +499,51 @@ func (c *Controller) HandleGHStatus(ctx context.Context, statuses map[string]*PR
 			}
 
 		case ActionLaunchAgent:
-			agentID, err := c.launchAgent(ctx, desc.PRNumber, desc.Repo, desc.Prompt, desc.ResumeFrom)
+			if desc.Conflicts {
+				slug := ghutil.OwnerRepoFromPRURL(desc.PRURL)
+				if slug == "" {
+					slug = desc.Repo
+				}
+				// Rebasing and verifying can take minutes, so the
+				// caller runs the resolver off its loop; a failure is
+				// left to the agent on a later evaluation.
+				resolutions = append(resolutions, ConflictResolution{PRNumber: desc.PRNumber, Slug: slug})
+				continue
+			}
+			prompt := desc.Prompt
+			var failures []CheckFailure
+			if desc.CIFailure {
+				var err error
+				failures, err = c.fetchCIFailures(ctx, desc.PRURL, desc.PRNumber)
+				if err != nil {
+					c.logger.Warn("failed to fetch CI failure logs; fix agent will fetch them itself", "pr", desc.PRNumber, "err", err)
+				} else if len(failures) > 0 {
+					prompt = ciFailurePrompt(prompt, failures)
+				}
+			}
+			agentID, err := c.launchAgent(ctx, desc.PRNumber, desc.Repo, prompt, desc.ResumeFrom, desc.Profile)
+			if err == nil {
+				c.storeCIFailure(agentID, failures)
+			}
 			launchResults = append(launchResults, launchResult{
 				prNumber: desc.PRNumber,
+				repo:     desc.Repo,
 				agentID:  agentID,
 				err:      err,
 			})
 
+		case ActionRerunChecks:
+			rerunResults = append(rerunResults, c.rerunFailedChecks(ctx, desc))
+
+		case ActionRetargetPR:
+			retargetResults = append(retargetResults, c.retargetStackedPR(ctx, desc))
+
+		case ActionBackport:
+			backportResults = append(backportResults, c.backportMerged(ctx, desc))
+
+		case ActionCleanupClosed:
+			closedCleanupResults = append(closedCleanupResults, c.cleanupClosedPR(ctx, desc))
+
 		case ActionMergePR:
 			err := c.mergePRs(ctx, desc.Repo, desc.PRNumbers)
 			mergeResults = append(mergeResults, mergeResult{
@@ -294,6 +558,17 @@ func (c *Controller) HandleGHStatus(ctx context.Context, statuses map[string]*PR
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	for _, r := range resolutions {
+		ps := c.prStates[r.PRNumber]
+		if ps == nil {
+			continue
+		}
+		ps.resolving = true
+		ps.pendingLaunchDetail = ""
+		c.resolutions = append(c.resolutions, r)
+	}
+
+	dispatched := make(map[string]string)
 	for _, lr := range launchResults {
 		ps := c.prStates[lr.prNumber]
 		if ps == nil {
@@ -301,38 +576,70 @@ func (c *Controller) HandleGHStatus(ctx context.Context, statuses map[string]*PR
 		}
 		if lr.err != nil {
 			c.logger.Error("failed to dispatch agent", "pr", lr.prNumber, "err", lr.err)
-			if !c.handleLaunchRetry(ps) {
+			if !c.handleLaunchRetry(ps, c.policy(lr.repo)) {
 				ps.Stage = StageStalled
 				actions = append(actions, Action{Type: "error", Detail: fmt.Sprintf("PR #%s: dispatch failed", lr.prNumber), Error: truncateError(lr.err.Error(), 120)})
 			}
 		} else {
+			dispatched[lr.prNumber] = lr.agentID
 			ps.RetryCount = 0
 			ps.LastAgentID = lr.agentID
 			ps.AgentRunning = true
-			ps.LastDispatchAt = time.Now()
+			ps.LastDispatchAt = c.now()
 			actions = append(actions, Action{Type: "launch", Detail: ps.pendingLaunchDetail})
 			ps.pendingLaunchDetail = ""
 		}
 	}
 
+	for _, rr := range rerunResults {
+		actions = append(actions, c.applyRerunResult(rr)...)
+	}
+
+	for _, rt := range retargetResults {
+		actions = append(actions, c.applyRetargetResult(rt)...)
+	}
+
+	for _, br := range backportResults {
+		actions = append(actions, c.applyBackportResult(br)...)
+	}
+
+	for _, cr := range closedCleanupResults {
+		actions = append(actions, c.applyClosedCleanupResult(cr)...)
+	}
+
+	for _, ft := range fired {
+		c.emitTransition(ft, dispatched[ft.prNumber])
+	}
+
 	for _, mr := range mergeResults {
 		ps := c.prStates[mr.prNumber]
 		if ps == nil {
 			continue
 		}
+		ft := firedTransition{prNumber: mr.prNumber, from: ps.Stage, rule: "auto-merge/merged"}
+		if st := statuses[mr.prNumber]; st != nil {
+			ft.prURL = st.PRURL
+		}
 		if mr.err != nil {
 			c.logger.Error("auto-merge failed", "pr", mr.prNumber, "err", mr.err)
 			ps.Stage = StageStalled
+			ft.rule = "auto-merge/failed"
+			c.emitTransition(ft, "")
 			actions = append(actions, Action{Type: "error", Detail: fmt.Sprintf("PR #%s: auto-merge failed", mr.prNumber), Error: truncateError(mr.err.Error(), 120)})
 		} else {
 			ps.Stage = StageMerged
+			c.emitTransition(ft, "")
 			c.emitEvent(mr.prNumber, event.PRMerged, map[string]interface{}{
 				"pr_number": mr.prNumber,
 			})
+			if st := statuses[mr.prNumber]; st != nil {
+				c.watchMain(mr.prNumber, st)
+			}
 			actions = append(actions, Action{Type: "merge", Detail: fmt.Sprintf("Merged PR #%s", mr.prNumber)})
 		}
 	}
 
+	c.checkpoint()
 	return actions
 }
 
@@ -378,58 +685,66 @@ func seedStageFromStatus(status *PRStatus) Stage {
 	}
 }
 
-// maxLaunchRetries is the maximum number of agent launch retries before going to StageStalled.
+// Default policy limits; repos can override them (see Policy).
+
+// maxLaunchRetries is the default number of agent launch retries before going to StageStalled.
 const maxLaunchRetries = 2
 
-// maxFixAttempts is the maximum number of fix agents dispatched for a single PR
+// maxFixAttempts is the default number of fix agents dispatched for a single PR
 // before the pipeline gives up. Reset when the branch is updated or CI passes.
 const maxFixAttempts = 3
 
-// retryBackoff is the minimum time between launch retries.
+// retryBackoff is the default minimum time between launch retries.
 const retryBackoff = 60 * time.Second
 
-// dispatchCooldown is the minimum time between agent dispatches for the same PR.
+// dispatchCooldown is the default minimum time between agent dispatches for the same PR.
 const dispatchCooldown = 60 * time.Second
 
 // evaluate checks the current GH status and determines transitions + action
-// descriptors. It performs NO I/O — all side-effects are described as
+// descriptors, returning the name of the transition that fired ("" if none). It performs NO I/O — all side-effects are described as
 // ActionDescriptor values for the caller to execute outside the lock.
 //
 // The method iterates over the transition table defined in transitions.go.
 // The first transition whose Guard matches is applied; subsequent transitions
-// are skipped. This makes the state machine explicit and testable.
-func (c *Controller) evaluate(ps *PRPipelineState, status *PRStatus, runStates []*run.State) ([]Action, []ActionDescriptor) {
+// are skipped. This makes the state machine explicit and testable. Transitions
+// disabled by the PR's repo policy are skipped as if their guard failed.
+func (c *Controller) evaluate(ps *PRPipelineState, status *PRStatus, runStates []*run.State) (string, []Action, []ActionDescriptor) {
+	disabled := c.policy(status.TargetRepo).Disabled
 	for _, t := range transitions {
-		if t.Guard(c, ps, status, runStates) {
-			return t.Apply(c, ps, status, runStates)
+		if disabled[t.Name] {
+			continue
+		}
+		if t.Guard.check(c, ps, status, runStates) {
+			actions, descs := t.Apply(c, ps, status, runStates)
+			return t.Name, actions, descs
 		}
 	}
-	return nil, nil
+	return "", nil, nil
 }
 
 // handleLaunchRetry checks whether the pipeline state is eligible for retry.
 // Returns true if the retry was accepted (caller should NOT go to StageStalled).
-func (c *Controller) handleLaunchRetry(ps *PRPipelineState) bool {
-	if ps.RetryCount >= maxLaunchRetries {
+func (c *Controller) handleLaunchRetry(ps *PRPipelineState, pol Policy) bool {
+	if ps.RetryCount >= pol.MaxLaunchRetries {
 		return false
 	}
-	if !ps.LastFailedAt.IsZero() && time.Since(ps.LastFailedAt) < retryBackoff {
+	if !ps.LastFailedAt.IsZero() && c.now().Sub(ps.LastFailedAt) < pol.RetryBackoff {
 		// Too soon to retry — stay in current stage but don't stall yet.
 		return true
 	}
 	ps.RetryCount++
-	ps.LastFailedAt = time.Now()
+	ps.LastFailedAt = c.now()
 	c.logger.Info("agent launch failed, will retry",
 		"pr", ps.PRNumber,
 		"retry", ps.RetryCount,
-		"max", maxLaunchRetries,
+		"max", pol.MaxLaunchRetries,
 	)
 	return true
 }
 
 // cleanupStaleWorktrees removes worktrees from completed runs that match the given PR number.
 // This prevents "worktree already exists" errors when re-dispatching agents.
-func (c *Controller) cleanupStaleWorktrees(prNumber string, runStates []*run.State) {
+func (c *Controller) cleanupStaleWorktrees(ctx context.Context, prNumber string, runStates []*run.State) {
 	for _, s := range runStates {
 		if s.PR == nil || *s.PR != prNumber {
 			continue
@@ -450,17 +765,24 @@ func (c *Controller) cleanupStaleWorktrees(prNumber string, runStates []*run.Sta
 			"run", s.ID,
 			"worktree", s.Worktree,
 		)
-		cmd := exec.Command("klaus", "cleanup", s.ID)
-		if out, err := cmd.CombinedOutput(); err != nil {
+		if err := c.cleanupWorktree(ctx, s.ID); err != nil {
 			c.logger.Error("stale worktree cleanup failed",
 				"run", s.ID,
 				"err", err,
-				"output", string(out),
 			)
 		}
 	}
 }
 
+// defaultCleanupWorktree runs 'klaus cleanup' for a finished run.
+func (c *Controller) defaultCleanupWorktree(ctx context.Context, runID string) error {
+	cmd := exec.CommandContext(ctx, "klaus", "cleanup", runID)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("klaus cleanup %s: %w: %s", runID, err, string(out))
+	}
+	return nil
+}
+
 // markRunStatesApproved sets Approved=true on run states matching the given PR number
 // that are not already approved, and persists the change via the store.
 func (c *Controller) markRunStatesApproved(prNumber string, runStates []*run.State) {
@@ -495,6 +817,42 @@ func runStateMatchesPR(s *run.State, prNumber string) bool {
 	return false
 }
 
+// stampMerged sets MergedAt on the PR's runs that lack it, so a PR merged
+// on GitHub rather than by 'klaus merge' still reads as merged (e.g. for
+// plan tasks waiting on it). It reports whether any run was stamped.
+// Callers must hold c.mu.
+func (c *Controller) stampMerged(prNum string, runStates []*run.State) bool {
+	var now string
+	for _, s := range runStates {
+		if s.MergedAt != nil || !runStateMatchesPR(s, prNum) {
+			continue
+		}
+		if now == "" {
+			now = c.now().UTC().Format(time.RFC3339)
+		}
+		s.MergedAt = &now
+		if c.store != nil {
+			if err := c.store.Save(s); err != nil {
+				c.logger.Error("failed to persist PR merge to run state", "pr", prNum, "run", s.ID, "err", err)
+			}
+		}
+	}
+	return now != ""
+}
+
+// PRSpend returns the cumulative cost of the finalized runs on a PR: the run
+// that opened it and every agent dispatched against it. Runs still in
+// progress have no cost yet and count as zero.
+func PRSpend(prNumber string, runStates []*run.State) float64 {
+	var total float64
+	for _, s := range runStates {
+		if s != nil && s.CostUSD != nil && runStateMatchesPR(s, prNumber) {
+			total += *s.CostUSD
+		}
+	}
+	return total
+}
+
 // hasKlausApproval returns true if any run state for the given PR has been
 // approved via `klaus approve`.
 func (c *Controller) hasKlausApproval(prNumber string, runStates []*run.State) bool {
@@ -509,6 +867,46 @@ func (c *Controller) hasKlausApproval(prNumber string, runStates []*run.State) b
 	return false
 }
 
+// firedTransition records a transition applied in HandleGHStatus's first
+// phase, for the audit event emitted once dispatch results are known.
+type firedTransition struct {
+	prNumber string
+	prURL    string
+	from     Stage
+	rule     string
+}
+
+// emitTransition emits a PipelineTransition event for ft. The stage and
+// attempt counters are read after dispatch, so a failed launch that stalls
+// the PR is recorded with its final stage. runID is the agent dispatched by
+// the transition, if any.
+func (c *Controller) emitTransition(ft firedTransition, runID string) {
+	ps := c.prStates[ft.prNumber]
+	if ps == nil {
+		return
+	}
+	data := map[string]interface{}{
+		"pr_number":           ft.prNumber,
+		"from":                string(ft.from),
+		"to":                  string(ps.Stage),
+		"rule":                ft.rule,
+		"fix_attempts":        ps.FixAttempts,
+		"rebase_attempts":     ps.RebaseAttempts,
+		"review_fix_attempts": ps.ReviewFixAttempts,
+		"retry_count":         ps.RetryCount,
+	}
+	if ft.prURL != "" {
+		data["pr_url"] = ft.prURL
+	}
+	if runID != "" {
+		data["dispatched_run_id"] = runID
+	}
+	if c.onTransition != nil {
+		c.onTransition(ft, ps, runID)
+	}
+	c.emitEvent(ft.prNumber, event.PipelineTransition, data)
+}
+
 func (c *Controller) emitEvent(prNumber, eventType string, data map[string]interface{}) {
 	if c.eventLog == nil {
 		return
@@ -519,14 +917,20 @@ func (c *Controller) emitEvent(prNumber, eventType string, data map[string]inter
 	}
 }
 
-func (c *Controller) defaultLaunchAgent(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
-	args := []string{"launch", "--pr", prNumber}
+func (c *Controller) defaultLaunchAgent(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+	args := []string{"launch"}
+	if prNumber != "" {
+		args = append(args, "--pr", prNumber)
+	}
 	if repo != "" {
 		args = append(args, "--repo", repo)
 	}
 	if resumeFrom != "" {
 		args = append(args, "--resume-from", resumeFrom)
 	}
+	if profile != "" {
+		args = append(args, "--profile", profile)
+	}
 	args = append(args, prompt)
 	cmd := exec.CommandContext(ctx, "klaus", args...)
 	out, err := cmd.CombinedOutput()
@@ -536,7 +940,7 @@ func (c *Controller) defaultLaunchAgent(ctx context.Context, prNumber, repo, pro
 	// Extract run ID from output (first line typically: "Launching agent <id>...")
 	// Best-effort extraction.
 	output := string(out)
-	if id := extractAgentID(output); id != "" {
+	if id := ExtractAgentID(output); id != "" {
 		return id, nil
 	}
 	return "unknown", nil
@@ -556,8 +960,26 @@ func (c *Controller) defaultMergePRs(ctx context.Context, repo string, prNumbers
 	return nil
 }
 
-// extractAgentID attempts to pull the run ID from "Launching agent <id>..." output.
-func extractAgentID(output string) string {
+// defaultResolveConflicts rebases a PR with 'klaus rebase', which resolves
+// lockfile, generated-file and rerere-known conflicts, verifies the result
+// and pushes it. It fails, leaving the PR untouched, when any conflict
+// needs judgment.
+func (c *Controller) defaultResolveConflicts(ctx context.Context, slug, prNumber string) error {
+	args := []string{"rebase"}
+	if slug != "" {
+		args = append(args, "--repo", slug)
+	}
+	args = append(args, prNumber)
+	cmd := exec.CommandContext(ctx, "klaus", args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("klaus rebase: %w: %s", err, string(out))
+	}
+	return nil
+}
+
+// ExtractAgentID attempts to pull the run ID from "Launching agent <id>..." output.
+func ExtractAgentID(output string) string {
 	for _, line := range strings.Split(output, "\n") {
 		if strings.HasPrefix(line, "Launching agent ") {
 			parts := strings.Fields(line)
@@ -700,6 +1122,12 @@ func StageLabel(stage Stage) string {
 		return "stalled"
 	case StageBudgetPaused:
 		return "budget paused, awaiting decision"
+	case StageBudgetExceeded:
+		return "spend cap reached"
+	case StageCIRerun:
+		return "CI failed, re-running checks"
+	case StageClosed:
+		return "closed"
 	default:
 		return string(stage)
 	}
@@ -2,10 +2,12 @@ package pipeline
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -38,6 +40,16 @@ func newTestController(t *testing.T) (*Controller, string) {
 
 	c := New(store, eventLog, logger)
 	c.SetTmuxDeps(testTmuxDeps())
+	c.SetFetchCIFailures(func(context.Context, string, string) ([]CheckFailure, error) { return nil, nil })
+	// Conflicts go straight to the rebase agent; the auto-resolve tests
+	// turn the resolver back on.
+	c.SetPolicyResolver(func(string) Policy {
+		p := DefaultPolicy()
+		p.AutoResolveConflicts = false
+		return p
+	})
+	c.SetBackport(func(context.Context, string, string, []string) error { return nil })
+	c.SetCleanupRun(func(context.Context, string, []string) error { return nil })
 	return c, dir
 }
 
@@ -45,7 +57,7 @@ func TestStateTransition_CIPendingToFailed(t *testing.T) {
 	c, _ := newTestController(t)
 
 	var launchedPR string
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchedPR = prNumber
 		return "agent-001", nil
 	})
@@ -74,7 +86,7 @@ func TestStateTransition_CIFailedToPassedToApproved(t *testing.T) {
 	c.SetAutoMergeOnApproval(true)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "agent-001", nil
 	})
@@ -123,7 +135,7 @@ func TestNoDuplicateAgentDispatch(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "agent-001", nil
 	})
@@ -152,7 +164,7 @@ func TestAgentReDispatchAfterCompletion(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "agent-002", nil
 	})
@@ -189,7 +201,7 @@ func TestReviewCommentsDispatchAgent(t *testing.T) {
 	c, _ := newTestController(t)
 
 	var launchedPrompt string
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchedPrompt = prompt
 		return "agent-review", nil
 	})
@@ -246,7 +258,7 @@ func TestReviewFixPromptsInstructAgentToReplyToComments(t *testing.T) {
 			c, _ := newTestController(t)
 
 			var launchedPrompt string
-			c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+			c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 				launchedPrompt = prompt
 				return "agent-x", nil
 			})
@@ -308,7 +320,7 @@ func TestReviewFixPromptsInstructAgentToReplyToComments(t *testing.T) {
 
 func TestMergedPRCleanedUp(t *testing.T) {
 	c, _ := newTestController(t)
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		return "agent-001", nil
 	})
 
@@ -323,12 +335,17 @@ func TestMergedPRCleanedUp(t *testing.T) {
 	}
 
 	// PR gets merged externally.
+	pr, other := "42", "7"
+	runStates := []*run.State{{ID: "run-1", PR: &pr}, {ID: "run-2", PR: &other}}
 	statuses["42"] = &PRStatus{PRNumber: "42", State: "MERGED"}
-	c.HandleGHStatus(context.Background(), statuses, nil)
+	c.HandleGHStatus(context.Background(), statuses, runStates)
 
 	if len(c.PipelineStates()) != 0 {
 		t.Error("expected merged PR to be cleaned up from tracking")
 	}
+	if runStates[0].MergedAt == nil || runStates[1].MergedAt != nil {
+		t.Errorf("MergedAt = %v, %v; want set on the merged PR's run only", runStates[0].MergedAt, runStates[1].MergedAt)
+	}
 }
 
 func TestClosedPRCleanedUp(t *testing.T) {
@@ -359,7 +376,7 @@ func TestAutoMergeBlockedByConflicts(t *testing.T) {
 	})
 
 	var launchedPrompt string
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchedPrompt = prompt
 		return "agent-rebase", nil
 	})
@@ -414,6 +431,7 @@ func TestStageLabelCoverage(t *testing.T) {
 		{StageMerging, "merging"},
 		{StageMerged, "merged"},
 		{StageStalled, "stalled"},
+		{StageClosed, "closed"},
 		{Stage("unknown"), "unknown"},
 	}
 	for _, tt := range tests {
@@ -435,9 +453,9 @@ func TestExtractAgentID(t *testing.T) {
 		{"", ""},
 	}
 	for _, tt := range tests {
-		got := extractAgentID(tt.input)
+		got := ExtractAgentID(tt.input)
 		if got != tt.want {
-			t.Errorf("extractAgentID(%q) = %q, want %q", tt.input, got, tt.want)
+			t.Errorf("ExtractAgentID(%q) = %q, want %q", tt.input, got, tt.want)
 		}
 	}
 }
@@ -467,7 +485,7 @@ func TestLaunchFailureRetriesBeforeStalling(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "", fmt.Errorf("worktree already exists")
 	})
@@ -537,7 +555,7 @@ func TestWorktreeCleanupBeforeDispatch(t *testing.T) {
 
 	var cleanedUpID string
 	// Override launchAgent to track that cleanup happened before launch.
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		// By the time launch is called, the stale worktree should have
 		// had cleanup attempted. We can't easily verify the cleanup command
 		// ran (it would fail since the run ID doesn't exist in store), but
@@ -574,7 +592,7 @@ func TestReviewFixLaunchRetry(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "", fmt.Errorf("worktree already exists")
 	})
@@ -598,7 +616,7 @@ func TestTrustedCommentDispatch(t *testing.T) {
 	c, _ := newTestController(t)
 
 	var launchedPrompt string
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchedPrompt = prompt
 		return "agent-trusted", nil
 	})
@@ -633,7 +651,7 @@ func TestNoDispatchWithoutTrustedComments(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "agent-001", nil
 	})
@@ -665,7 +683,7 @@ func TestNoDoubleDispatchOnTrustedComments(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "agent-trusted", nil
 	})
@@ -702,7 +720,7 @@ func TestNeedsRebaseTransitionsToMergeAfterConflictsResolved(t *testing.T) {
 	c.SetAutoMergeOnApproval(true)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return fmt.Sprintf("agent-%03d", launchCount), nil
 	})
@@ -757,7 +775,7 @@ func TestNeedsRebaseTransitionsToCIFailedIfCIFails(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return fmt.Sprintf("agent-%03d", launchCount), nil
 	})
@@ -804,7 +822,7 @@ func TestNeedsRebaseNoDoubleDispatch(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "agent-rebase", nil
 	})
@@ -836,7 +854,7 @@ func TestRebaseDispatchRetryOnFailure(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "", fmt.Errorf("worktree already exists")
 	})
@@ -900,7 +918,7 @@ func TestUnapprovedConflictsDispatchesRebase(t *testing.T) {
 
 	var launchedPrompt string
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchedPrompt = prompt
 		launchCount++
 		return "agent-rebase", nil
@@ -946,11 +964,152 @@ func TestUnapprovedConflictsDispatchesRebase(t *testing.T) {
 	}
 }
 
+func TestAutoResolveConflictsSkipsRebaseAgent(t *testing.T) {
+	c, _ := newTestController(t)
+	c.SetPolicyResolver(func(string) Policy { return DefaultPolicy() })
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	var resolved []string
+	c.SetResolveConflicts(func(_ context.Context, slug, prNumber string) error {
+		resolved = append(resolved, slug+"#"+prNumber)
+		return nil
+	})
+	launchCount := 0
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launchCount++
+		return "agent-rebase", nil
+	})
+
+	statuses := map[string]*PRStatus{
+		"42": {
+			PRNumber: "42", State: "OPEN", CI: "passing", Conflicts: "yes",
+			TargetRepo: "klaus", PRURL: "https://github.com/owner/repo/pull/42",
+		},
+	}
+
+	// The resolution is queued for the caller rather than run inline.
+	if actions := c.HandleGHStatus(context.Background(), statuses, nil); len(actions) != 0 {
+		t.Errorf("expected no actions while the resolution is queued, got %v", actions)
+	}
+	if len(resolved) != 0 {
+		t.Errorf("resolver ran during evaluation: %v", resolved)
+	}
+	pending := c.PendingResolutions()
+	want := []ConflictResolution{{PRNumber: "42", Slug: "owner/repo"}}
+	if !reflect.DeepEqual(pending, want) {
+		t.Fatalf("pending = %v, want %v", pending, want)
+	}
+	if again := c.PendingResolutions(); len(again) != 0 {
+		t.Errorf("PendingResolutions should clear the queue, got %v", again)
+	}
+
+	// Nothing else is dispatched while it runs.
+	c.HandleGHStatus(context.Background(), statuses, nil)
+	if launchCount != 0 || len(c.PendingResolutions()) != 0 {
+		t.Errorf("dispatched during the resolution: %d launches", launchCount)
+	}
+
+	actions := c.ResolveConflicts(context.Background(), pending[0])
+	if !reflect.DeepEqual(resolved, []string{"owner/repo#42"}) {
+		t.Errorf("resolved = %v, want owner/repo#42", resolved)
+	}
+	if len(actions) != 1 || actions[0].Type != "resolve" {
+		t.Errorf("expected a resolve action, got %v", actions)
+	}
+
+	// Within the cooldown nothing is dispatched while GitHub catches up.
+	c.HandleGHStatus(context.Background(), statuses, nil)
+	if launchCount != 0 || len(c.PendingResolutions()) != 0 {
+		t.Errorf("dispatched within cooldown: %d launches", launchCount)
+	}
+
+	// Conflicts that persist past the cooldown go to the agent.
+	now = now.Add(2 * dispatchCooldown)
+	c.HandleGHStatus(context.Background(), statuses, nil)
+	if pending := c.PendingResolutions(); len(pending) != 0 {
+		t.Errorf("resolver should run once per conflict, queued %v", pending)
+	}
+	if launchCount != 1 {
+		t.Errorf("expected the rebase agent once auto-resolve was tried, got %d launches", launchCount)
+	}
+
+	// Once mergeable, the next conflict is tried without an agent again.
+	statuses["42"].Conflicts = "no"
+	c.HandleGHStatus(context.Background(), statuses, nil)
+	if c.PipelineStates()["42"].AutoResolveTried {
+		t.Error("AutoResolveTried should reset once the conflicts are gone")
+	}
+}
+
+func TestAutoResolveConflictsFallsBackToAgent(t *testing.T) {
+	c, _ := newTestController(t)
+	c.SetPolicyResolver(func(string) Policy { return DefaultPolicy() })
+
+	c.SetResolveConflicts(func(context.Context, string, string) error {
+		return errors.New("conflicts need manual resolution: main.go")
+	})
+	launchCount := 0
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launchCount++
+		return "agent-rebase", nil
+	})
+
+	statuses := map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "passing", Conflicts: "yes", TargetRepo: "owner/repo"},
+	}
+	c.HandleGHStatus(context.Background(), statuses, nil)
+	for _, r := range c.PendingResolutions() {
+		c.ResolveConflicts(context.Background(), r)
+	}
+	if launchCount != 0 {
+		t.Fatalf("launched %d agents before the next evaluation", launchCount)
+	}
+
+	// The next evaluation hands the conflicts to the agent at once.
+	actions := c.HandleGHStatus(context.Background(), statuses, nil)
+	if launchCount != 1 {
+		t.Errorf("expected the rebase agent after the resolver failed, got %d launches", launchCount)
+	}
+	if len(actions) != 1 || actions[0].Type != "launch" {
+		t.Errorf("expected a launch action, got %v", actions)
+	}
+}
+
+func TestAutoResolveConflictsDisabledByPolicy(t *testing.T) {
+	c, _ := newTestController(t)
+	c.SetPolicyResolver(func(string) Policy {
+		p := DefaultPolicy()
+		p.AutoResolveConflicts = false
+		return p
+	})
+
+	resolveCount := 0
+	c.SetResolveConflicts(func(context.Context, string, string) error {
+		resolveCount++
+		return nil
+	})
+	launchCount := 0
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launchCount++
+		return "agent-rebase", nil
+	})
+
+	statuses := map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "passing", Conflicts: "yes", TargetRepo: "owner/repo"},
+	}
+	c.HandleGHStatus(context.Background(), statuses, nil)
+
+	if resolveCount != 0 || launchCount != 1 {
+		t.Errorf("got %d resolves, %d launches; want 0, 1", resolveCount, launchCount)
+	}
+}
+
 func TestRebaseCircuitBreaker(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return fmt.Sprintf("agent-%03d", launchCount), nil
 	})
@@ -1040,7 +1199,7 @@ func TestStalledFromCIRecoversWithConflicts(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return fmt.Sprintf("agent-%03d", launchCount), nil
 	})
@@ -1091,7 +1250,7 @@ func TestRebaseDispatchDoesNotConsumeCIFixBudget(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return fmt.Sprintf("agent-%03d", launchCount), nil
 	})
@@ -1149,7 +1308,7 @@ func TestConflictsWaitDoesNotSpamCIPassedEvent(t *testing.T) {
 	c, baseDir := newTestController(t)
 	eventLog := event.NewLog(filepath.Join(baseDir, "session"))
 
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		return "agent-rebase", nil
 	})
 
@@ -1202,7 +1361,7 @@ func TestApprovedNoConflictsMerges(t *testing.T) {
 	c.SetAutoMergeOnApproval(true)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "agent-001", nil
 	})
@@ -1280,7 +1439,7 @@ func TestReviewThreadsResolvedAfterAgentCompletion(t *testing.T) {
 		resolvedThreads = append(resolvedThreads, threadID)
 		return nil
 	})
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		return "agent-fix", nil
 	})
 
@@ -1350,7 +1509,7 @@ func TestSnapshotThreadsUsesOwnerRepoSlugFromPRURL(t *testing.T) {
 				gotRepo = repo
 				return nil, nil
 			})
-			c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+			c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 				return "agent-fix", nil
 			})
 
@@ -1447,7 +1606,7 @@ func TestReviewThreadResolutionFailureDoesNotBlock(t *testing.T) {
 		}
 		return nil
 	})
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		return "agent-fix", nil
 	})
 	c.SetMergePRs(func(ctx context.Context, repo string, prNumbers []string) error {
@@ -1502,7 +1661,7 @@ func TestTrustedCommentSnapshotsThreads(t *testing.T) {
 	c.SetResolveThread(func(threadID string) error {
 		return nil
 	})
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		return "agent-trusted", nil
 	})
 
@@ -1540,7 +1699,7 @@ func TestNoThreadResolutionWhileAgentRunning(t *testing.T) {
 		resolveCount++
 		return nil
 	})
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		return "agent-fix", nil
 	})
 
@@ -1569,7 +1728,7 @@ func TestDispatchCooldownPreventsRapidRedispatch(t *testing.T) {
 		c, _ := newTestController(t)
 
 		launchCount := 0
-		c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+		c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 			launchCount++
 			return fmt.Sprintf("agent-%03d", launchCount), nil
 		})
@@ -1612,7 +1771,7 @@ func TestDispatchCooldownPreventsRapidRedispatch(t *testing.T) {
 		c, _ := newTestController(t)
 
 		launchCount := 0
-		c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+		c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 			launchCount++
 			return fmt.Sprintf("agent-%03d", launchCount), nil
 		})
@@ -1658,7 +1817,7 @@ func TestDispatchCooldownPreventsRapidRedispatch(t *testing.T) {
 		c, _ := newTestController(t)
 
 		launchCount := 0
-		c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+		c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 			launchCount++
 			return fmt.Sprintf("agent-%03d", launchCount), nil
 		})
@@ -1709,7 +1868,7 @@ func TestCIFixAgentResumesFromLastAgent(t *testing.T) {
 	c, _ := newTestController(t)
 
 	var capturedResume string
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		capturedResume = resumeFrom
 		return "agent-fix", nil
 	})
@@ -1738,7 +1897,7 @@ func TestReviewFixAgentResumesFromLastAgent(t *testing.T) {
 	c, _ := newTestController(t)
 
 	var capturedResume string
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		capturedResume = resumeFrom
 		return "agent-review-fix", nil
 	})
@@ -1769,7 +1928,7 @@ func TestFirstAgentDispatchHasEmptyResume(t *testing.T) {
 	c, _ := newTestController(t)
 
 	var capturedResume string
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		capturedResume = resumeFrom
 		return "agent-first", nil
 	})
@@ -1789,7 +1948,7 @@ func TestFixAgentCircuitBreaker(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return fmt.Sprintf("agent-%03d", launchCount), nil
 	})
@@ -1861,7 +2020,7 @@ func TestFixAgentCircuitBreakerResetOnCIPass(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return fmt.Sprintf("agent-%03d", launchCount), nil
 	})
@@ -1899,7 +2058,7 @@ func TestFixAgentCircuitBreakerResetOnNewPush(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return fmt.Sprintf("agent-%03d", launchCount), nil
 	})
@@ -1961,7 +2120,7 @@ func TestKlausApprovalTriggersAutoMerge(t *testing.T) {
 	c.SetAutoMergeOnApproval(true)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "agent-001", nil
 	})
@@ -2016,7 +2175,7 @@ func TestChangesRequestedOverridesKlausApproval(t *testing.T) {
 	})
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "agent-fix", nil
 	})
@@ -2089,7 +2248,7 @@ func TestKlausApprovalWithConflictsDispatchesRebase(t *testing.T) {
 
 	var launchedPrompt string
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchedPrompt = prompt
 		launchCount++
 		return "agent-rebase", nil
@@ -2179,7 +2338,7 @@ func TestCooldownRespectsLastFailedAt(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "", fmt.Errorf("worktree conflict")
 	})
@@ -2218,7 +2377,7 @@ func TestMergedStageIsTerminal(t *testing.T) {
 
 	launchCount := 0
 	mergeCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "agent-001", nil
 	})
@@ -2262,7 +2421,7 @@ func TestSessionResumeSeedsFromGitHubStatus(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "agent-001", nil
 	})
@@ -2292,7 +2451,7 @@ func TestSessionResumeFailingCISeedsCorrectly(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "agent-fix", nil
 	})
@@ -2326,7 +2485,7 @@ func TestCoordinatorLaunchedPRFixSuppresses(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "pipeline-agent", nil
 	})
@@ -2360,7 +2519,7 @@ func TestCoordinatorLaunchedPRFixSuppressesViaPRField(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "pipeline-agent", nil
 	})
@@ -2392,7 +2551,7 @@ func TestCoordinatorLaunchedPRFixFinishedAllowsDispatch(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "pipeline-agent", nil
 	})
@@ -2427,7 +2586,7 @@ func TestCoordinatorLaunchedNonPRFixDoesNotSuppress(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "pipeline-agent", nil
 	})
@@ -2460,7 +2619,7 @@ func TestBudgetPausedLabelDrivesStage(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "agent-x", nil
 	})
@@ -2493,7 +2652,7 @@ func TestBudgetPausedLabelDrivesStage(t *testing.T) {
 func TestBudgetPausedTransitionsToCIFailedOnceLabelCleared(t *testing.T) {
 	c, _ := newTestController(t)
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return "agent-fix", nil
 	})
@@ -2534,7 +2693,7 @@ func TestBudgetPausedTransitionsToCIFailedOnceLabelCleared(t *testing.T) {
 
 func TestBudgetPausedEmitsEventOnTransition(t *testing.T) {
 	c, _ := newTestController(t)
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		return "agent-x", nil
 	})
 
@@ -2573,7 +2732,7 @@ func TestTrustedCommentsCircuitBreaker(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return fmt.Sprintf("agent-%03d", launchCount), nil
 	})
@@ -2673,7 +2832,7 @@ func TestTrustedCommentsBreakerResetsWhenAddressed(t *testing.T) {
 	c, _ := newTestController(t)
 
 	launchCount := 0
-	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom string) (string, error) {
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
 		launchCount++
 		return fmt.Sprintf("agent-%03d", launchCount), nil
 	})
@@ -2715,3 +2874,83 @@ func TestTrustedCommentsBreakerResetsWhenAddressed(t *testing.T) {
 		t.Errorf("expected dispatch after breaker reset, got %d launches", launchCount)
 	}
 }
+
+func TestTransitionEmitsAuditEvent(t *testing.T) {
+	c, baseDir := newTestController(t)
+	eventLog := event.NewLog(filepath.Join(baseDir, "session"))
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		return "agent-fix", nil
+	})
+
+	statuses := map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "failing", TargetRepo: "owner/repo", PRURL: "https://github.com/owner/repo/pull/42"},
+	}
+	c.HandleGHStatus(context.Background(), statuses, nil)
+
+	// The fix agent is still running: the wait rule fires again but changes
+	// nothing, so it must not be audited.
+	runStates := []*run.State{{ID: "agent-fix", TmuxPane: strPtr("%1")}}
+	c.HandleGHStatus(context.Background(), statuses, runStates)
+
+	events, err := eventLog.Read()
+	if err != nil {
+		t.Fatalf("reading event log: %v", err)
+	}
+	var transitions []event.Event
+	for _, e := range events {
+		if e.Type == event.PipelineTransition {
+			transitions = append(transitions, e)
+		}
+	}
+	if len(transitions) != 1 {
+		t.Fatalf("expected 1 pipeline:transition event, got %d (events: %v)", len(transitions), events)
+	}
+	d := transitions[0].Data
+	if d["pr_number"] != "42" || d["from"] != "ci_failed" || d["to"] != "ci_failed" {
+		t.Errorf("unexpected pr/from/to: %v", d)
+	}
+	if d["rule"] != "ci-failing/dispatch-fix-agent" {
+		t.Errorf("rule = %v, want ci-failing/dispatch-fix-agent", d["rule"])
+	}
+	if d["dispatched_run_id"] != "agent-fix" {
+		t.Errorf("dispatched_run_id = %v, want agent-fix", d["dispatched_run_id"])
+	}
+	// Attempts count agents that completed without fixing CI; none has yet.
+	if d["fix_attempts"] != float64(0) || d["retry_count"] != float64(0) {
+		t.Errorf("attempt counters = %v/%v, want 0/0", d["fix_attempts"], d["retry_count"])
+	}
+}
+
+func TestMergeEmitsAuditEvent(t *testing.T) {
+	c, baseDir := newTestController(t)
+	eventLog := event.NewLog(filepath.Join(baseDir, "session"))
+	c.SetAutoMergeOnApproval(true)
+	c.SetMergePRs(func(ctx context.Context, repo string, prNumbers []string) error { return nil })
+
+	// #42 is auto-merged; #43 is merged by hand while CI is pending.
+	statuses := map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "passing", ReviewDecision: "APPROVED", Conflicts: "none", TargetRepo: "owner/repo"},
+		"43": {PRNumber: "43", State: "OPEN", CI: "pending", TargetRepo: "owner/repo"},
+	}
+	c.HandleGHStatus(context.Background(), statuses, nil)
+	statuses["43"] = &PRStatus{PRNumber: "43", State: "MERGED", TargetRepo: "owner/repo"}
+	c.HandleGHStatus(context.Background(), map[string]*PRStatus{"43": statuses["43"]}, nil)
+
+	events, err := eventLog.Read()
+	if err != nil {
+		t.Fatalf("reading event log: %v", err)
+	}
+	got := map[string]string{}
+	for _, e := range events {
+		if e.Type == event.PipelineTransition && e.Data["to"] == string(StageMerged) {
+			got[e.Data["pr_number"].(string)] = fmt.Sprintf("%v/%v", e.Data["from"], e.Data["rule"])
+		}
+	}
+	want := map[string]string{
+		"42": "merging/auto-merge/merged",
+		"43": "ci_pending/status/merged",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("transitions to merged = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,248 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	ghutil "github.com/patflynn/klaus/internal/github"
+)
+
+// Policy tunes the pipeline for one repository: which transitions may fire,
+// the circuit-breaker and retry limits, and the prompts and profiles given
+// to dispatched agents. The zero value is not useful; start from DefaultPolicy.
+type Policy struct {
+	// Disabled lists transition names (see TransitionNames) that never fire.
+	// Evaluation falls through to the next matching rule, so disabling e.g.
+	// "ci-passing/conflicts-dispatch-rebase" leaves conflicted PRs parked in
+	// needs_rebase without dispatching an agent.
+	Disabled map[string]bool
+
+	MaxFixAttempts       int           // CI-fix agents before the PR stalls
+	MaxRebaseAttempts    int           // rebase agents before the PR stalls
+	MaxReviewFixAttempts int           // review-fix agents before the PR stalls
+	DispatchCooldown     time.Duration // minimum time between dispatches for one PR
+	MaxLaunchRetries     int           // launch retries before the PR stalls
+	RetryBackoff         time.Duration // minimum time between launch retries
+
+	// MaxPRSpendUSD caps the cumulative cost of all runs on one PR (see
+	// PRSpend); 0 means no cap. A dispatch is refused when the PR's spend
+	// plus AgentBudgetUSD, the budget a dispatched agent may use, would
+	// exceed it.
+	MaxPRSpendUSD  float64
+	AgentBudgetUSD float64
+
+	// MainWatchdog is what happens when a merge breaks the default branch:
+	// MainWatchdogNotify (the default), MainWatchdogFix, MainWatchdogRevert
+	// or MainWatchdogOff.
+	MainWatchdog  string
+	MaxMainAgents int // watchdog agents per breakage before standing down
+
+	// RerunFailedChecks re-runs failed CI checks once before dispatching a
+	// fix agent: RerunOff (the default), RerunFlaky or RerunAlways. A check
+	// is known-flaky once FlakyThreshold of its failures passed on rerun,
+	// and unproven while it has fewer than FlakyThreshold reruns on record.
+	RerunFailedChecks string
+	FlakyThreshold    int
+
+	// CIChecks selects the checks the CI guards see: CIChecksRequired (the
+	// default) or CIChecksAll.
+	CIChecks string
+
+	// AutoResolveConflicts tries 'klaus rebase', which resolves lockfile,
+	// generated-file and rerere-known conflicts without an agent, before
+	// dispatching a rebase agent.
+	AutoResolveConflicts bool
+
+	// ClosedCleanup is what to remove for the runs on a PR closed without
+	// merging: any of CleanupWorktree, CleanupBranch, CleanupRemoteBranch and
+	// CleanupState. Removing the state also stops a reopened PR from being
+	// picked up again.
+	ClosedCleanup []string
+
+	Prompts  PromptTemplates
+	Profiles DispatchProfiles
+}
+
+// DispatchProfiles name the agent profiles (config "profiles") dispatched
+// agents run with; an empty name uses the default setup.
+type DispatchProfiles struct {
+	CIFix            string
+	Rebase           string
+	ChangesRequested string
+	TrustedComments  string
+	MainWatchdog     string
+}
+
+// PromptTemplates override the prompts given to dispatched agents. Each is a
+// text/template rendered with PromptVars; an empty template uses the built-in
+// prompt.
+type PromptTemplates struct {
+	CIFix            string
+	Rebase           string
+	ChangesRequested string
+	TrustedComments  string
+}
+
+// PromptVars are the template variables available in PromptTemplates.
+type PromptVars struct {
+	PR      string // PR number, e.g. "42"
+	PRURL   string
+	Repo    string // the PR's target repo (project short name or owner/repo)
+	Default string // the built-in prompt, for templates that extend rather than replace it
+}
+
+// DefaultPolicy returns the built-in pipeline policy.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxFixAttempts:       maxFixAttempts,
+		MaxRebaseAttempts:    maxFixAttempts,
+		MaxReviewFixAttempts: maxFixAttempts,
+		DispatchCooldown:     dispatchCooldown,
+		MaxLaunchRetries:     maxLaunchRetries,
+		RetryBackoff:         retryBackoff,
+		MainWatchdog:         MainWatchdogNotify,
+		MaxMainAgents:        maxMainAgents,
+		RerunFailedChecks:    RerunOff,
+		FlakyThreshold:       defaultFlakyThreshold,
+		CIChecks:             CIChecksRequired,
+		AutoResolveConflicts: true,
+		ClosedCleanup:        []string{CleanupWorktree, CleanupBranch},
+	}
+}
+
+// TransitionNames returns the names of all pipeline transitions in
+// evaluation order.
+func TransitionNames() []string {
+	names := make([]string, len(transitions))
+	for i, t := range transitions {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// Validate reports unknown transition names, an unknown main watchdog action,
+// rerun mode or closed-PR cleanup option and unparseable prompt templates.
+func (p Policy) Validate() error {
+	switch p.MainWatchdog {
+	case MainWatchdogOff, MainWatchdogNotify, MainWatchdogFix, MainWatchdogRevert:
+	default:
+		return fmt.Errorf("unknown main_watchdog %q (want off, notify, fix or revert)", p.MainWatchdog)
+	}
+	switch p.RerunFailedChecks {
+	case RerunOff, RerunFlaky, RerunAlways:
+	default:
+		return fmt.Errorf("unknown rerun_failed_checks %q (want off, flaky or always)", p.RerunFailedChecks)
+	}
+	switch p.CIChecks {
+	case CIChecksRequired, CIChecksAll:
+	default:
+		return fmt.Errorf("unknown ci_checks %q (want required or all)", p.CIChecks)
+	}
+	for _, opt := range p.ClosedCleanup {
+		switch opt {
+		case CleanupWorktree, CleanupBranch, CleanupRemoteBranch, CleanupState:
+		default:
+			return fmt.Errorf("unknown closed_cleanup option %q (want worktree, branch, remote_branch or state)", opt)
+		}
+	}
+	known := make(map[string]bool, len(transitions))
+	for _, t := range transitions {
+		known[t.Name] = true
+	}
+	var unknown []string
+	for name := range p.Disabled {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown pipeline transitions: %s", strings.Join(unknown, ", "))
+	}
+	for name, tmpl := range map[string]string{
+		"ci_fix":            p.Prompts.CIFix,
+		"rebase":            p.Prompts.Rebase,
+		"changes_requested": p.Prompts.ChangesRequested,
+		"trusted_comments":  p.Prompts.TrustedComments,
+	} {
+		if tmpl == "" {
+			continue
+		}
+		if _, err := template.New(name).Option("missingkey=error").Parse(tmpl); err != nil {
+			return fmt.Errorf("pipeline prompt %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// SetPolicyResolver sets the function that returns the policy for a PR's
+// target repo (PRStatus.TargetRepo). Without one, every PR uses
+// DefaultPolicy.
+func (c *Controller) SetPolicyResolver(fn func(repo string) Policy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.policyFor = fn
+}
+
+// CI check selections (Policy.CIChecks).
+const (
+	CIChecksRequired = "required" // only checks the base branch requires gate the pipeline
+	CIChecksAll      = "all"      // every check gates the pipeline
+)
+
+// gateStatus returns status with CI summarized from the checks the repo's
+// policy gates on. A failing or pending optional check (a coverage bot, a
+// preview deploy) then neither dispatches fix agents nor holds up merges.
+// When no check is required, every check counts. Callers must hold c.mu.
+func (c *Controller) gateStatus(status *PRStatus) *PRStatus {
+	if len(status.Checks) == 0 || c.policy(status.TargetRepo).CIChecks == CIChecksAll {
+		return status
+	}
+	gated := *status
+	gated.CI = ghutil.SummarizeChecks(status.Checks, true)
+	return &gated
+}
+
+// RequiredChecksOnly reports whether only the required checks count as CI
+// for PRs targeting targetRepo (Policy.CIChecks). The main watchdog reads
+// CI on merge commits the same way.
+func (c *Controller) RequiredChecksOnly(targetRepo string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.policy(targetRepo).CIChecks != CIChecksAll
+}
+
+// policy returns the policy for the given target repo.
+func (c *Controller) policy(repo string) Policy {
+	if c.policyFor == nil {
+		return DefaultPolicy()
+	}
+	return c.policyFor(repo)
+}
+
+// renderPrompt renders the override template tmpl, or returns def when no
+// override is set. A template that fails to render falls back to def so a
+// typo in config never blocks a dispatch.
+func (c *Controller) renderPrompt(tmpl, def string, ps *PRPipelineState, status *PRStatus) string {
+	if tmpl == "" {
+		return def
+	}
+	t, err := template.New("prompt").Option("missingkey=error").Parse(tmpl)
+	if err == nil {
+		var buf bytes.Buffer
+		if err = t.Execute(&buf, PromptVars{
+			PR:      ps.PRNumber,
+			PRURL:   status.PRURL,
+			Repo:    status.TargetRepo,
+			Default: def,
+		}); err == nil {
+			return buf.String()
+		}
+	}
+	c.logger.Warn("pipeline prompt template failed; using built-in prompt", "pr", ps.PRNumber, "err", err)
+	return def
+}
@@ -0,0 +1,261 @@
+package pipeline
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/patflynn/klaus/internal/event"
+	ghutil "github.com/patflynn/klaus/internal/github"
+	"github.com/patflynn/klaus/internal/run"
+)
+
+func TestPolicy_DisabledRebaseTransition(t *testing.T) {
+	c, _ := newTestController(t)
+	c.SetPolicyResolver(func(repo string) Policy {
+		p := DefaultPolicy()
+		p.AutoResolveConflicts = false
+		if repo == "docs" {
+			p.Disabled = map[string]bool{"ci-passing/conflicts-dispatch-rebase": true}
+		}
+		return p
+	})
+
+	var launched []string
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launched = append(launched, prNumber)
+		return "agent-" + prNumber, nil
+	})
+
+	statuses := map[string]*PRStatus{
+		"1": {PRNumber: "1", State: "OPEN", CI: "passing", Conflicts: "yes", TargetRepo: "docs"},
+		"2": {PRNumber: "2", State: "OPEN", CI: "passing", Conflicts: "yes", TargetRepo: "monorepo"},
+	}
+	c.HandleGHStatus(context.Background(), statuses, nil)
+
+	if len(launched) != 1 || launched[0] != "2" {
+		t.Errorf("launched agents for %v, want only PR 2 (docs disables rebase agents)", launched)
+	}
+	if got := c.PipelineStates()["1"].Stage; got != StageNeedsRebase {
+		t.Errorf("docs PR stage = %s, want needs_rebase (parked without an agent)", got)
+	}
+}
+
+func TestPolicy_MaxFixAttempts(t *testing.T) {
+	c, _ := newTestController(t)
+	p := DefaultPolicy()
+	p.MaxFixAttempts = 5
+	c.SetPolicyResolver(func(string) Policy { return p })
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		return "agent-new", nil
+	})
+
+	// Past the default limit of 3 but below the repo's 5.
+	c.mu.Lock()
+	c.prStates["42"] = &PRPipelineState{
+		PRNumber:    "42",
+		Stage:       StageCIFailed,
+		FixAttempts: maxFixAttempts,
+		LastAgentID: "agent-old",
+	}
+	c.mu.Unlock()
+
+	statuses := map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "failing", TargetRepo: "monorepo"},
+	}
+	actions := c.HandleGHStatus(context.Background(), statuses, nil)
+	if len(actions) != 1 || actions[0].Type != "launch" {
+		t.Fatalf("expected a 4th fix agent under max_fix_attempts=5, got %v", actions)
+	}
+
+	c.mu.Lock()
+	c.prStates["42"].FixAttempts = 5
+	c.prStates["42"].AgentRunning = false
+	c.mu.Unlock()
+	c.HandleGHStatus(context.Background(), statuses, nil)
+	if got := c.PipelineStates()["42"].Stage; got != StageStalled {
+		t.Errorf("stage = %s, want stalled after 5 attempts", got)
+	}
+}
+
+func TestPolicy_PromptTemplate(t *testing.T) {
+	c, _ := newTestController(t)
+	p := DefaultPolicy()
+	p.Prompts.CIFix = "Fix PR {{.PR}} in {{.Repo}}. Run `make ci` first.\n{{.Default}}"
+	c.SetPolicyResolver(func(string) Policy { return p })
+
+	var gotPrompt string
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		gotPrompt = prompt
+		return "agent-1", nil
+	})
+	c.HandleGHStatus(context.Background(), map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "failing", TargetRepo: "monorepo"},
+	}, nil)
+
+	if !strings.HasPrefix(gotPrompt, "Fix PR 42 in monorepo. Run `make ci` first.\n") {
+		t.Errorf("prompt did not use the template: %q", gotPrompt)
+	}
+	if !strings.Contains(gotPrompt, "gh run view <run-id> --log-failed") {
+		t.Errorf("{{.Default}} did not expand to the built-in prompt: %q", gotPrompt)
+	}
+}
+
+func TestPolicy_DispatchProfile(t *testing.T) {
+	c, _ := newTestController(t)
+	p := DefaultPolicy()
+	p.Profiles.CIFix = "quick-fix"
+	c.SetPolicyResolver(func(string) Policy { return p })
+
+	var gotProfile string
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		gotProfile = profile
+		return "agent-1", nil
+	})
+	c.HandleGHStatus(context.Background(), map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "failing", TargetRepo: "monorepo"},
+	}, nil)
+
+	if gotProfile != "quick-fix" {
+		t.Errorf("CI fix agent launched with profile %q, want quick-fix", gotProfile)
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	p := DefaultPolicy()
+	if err := p.Validate(); err != nil {
+		t.Fatalf("default policy invalid: %v", err)
+	}
+
+	p.Disabled = map[string]bool{"ci-failing/dispatch-fix-agent": true, "ci-failing/typo": true}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "ci-failing/typo") {
+		t.Errorf("expected unknown transition error, got %v", err)
+	}
+
+	p = DefaultPolicy()
+	p.Prompts.Rebase = "Rebase {{.PR"
+	if err := p.Validate(); err == nil {
+		t.Error("expected template parse error")
+	}
+}
+
+func TestPolicy_SpendCap(t *testing.T) {
+	c, dir := newTestController(t)
+	p := DefaultPolicy()
+	p.MaxPRSpendUSD = 10
+	p.AgentBudgetUSD = 5
+	c.SetPolicyResolver(func(string) Policy { return p })
+	launched := 0
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launched++
+		return "agent-fix", nil
+	})
+
+	cost := func(v float64) *float64 { return &v }
+	runStates := []*run.State{
+		{ID: "author", PRURL: strPtr("https://github.com/owner/repo/pull/42"), CostUSD: cost(3)},
+		{ID: "fix-1", Type: "pr-fix", PR: strPtr("42"), CostUSD: cost(2.5)},
+		{ID: "other", PR: strPtr("7"), CostUSD: cost(50)},
+	}
+	if got := PRSpend("42", runStates); got != 5.5 {
+		t.Fatalf("PRSpend = %v, want 5.5", got)
+	}
+
+	statuses := map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "failing", TargetRepo: "owner/repo"},
+	}
+	// $5.50 spent + a $5 agent would exceed the $10 cap.
+	actions := c.HandleGHStatus(context.Background(), statuses, runStates)
+	c.HandleGHStatus(context.Background(), statuses, runStates)
+	if launched != 0 {
+		t.Errorf("launched %d agents past the spend cap", launched)
+	}
+	if got := c.PipelineStates()["42"].Stage; got != StageBudgetExceeded {
+		t.Errorf("stage = %s, want budget_exceeded", got)
+	}
+	if len(actions) != 1 || actions[0].Type != "error" || !strings.Contains(actions[0].Detail, "$5.50 of its $10.00 cap") {
+		t.Errorf("actions = %v", actions)
+	}
+	events, err := event.NewLog(filepath.Join(dir, "session")).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exceeded := 0
+	for _, e := range events {
+		if e.Type == event.PRBudgetExceeded {
+			exceeded++
+		}
+	}
+	if exceeded != 1 {
+		t.Errorf("pr:budget-exceeded emitted %d times, want 1", exceeded)
+	}
+
+	// Raising the cap resumes dispatching.
+	p.MaxPRSpendUSD = 20
+	c.HandleGHStatus(context.Background(), statuses, runStates)
+	if launched != 1 {
+		t.Errorf("launched %d agents after raising the cap, want 1", launched)
+	}
+
+	// An approved, green PR over the cap can still merge.
+	p.MaxPRSpendUSD = 1
+	c.SetAutoMergeOnApproval(true)
+	merged := false
+	c.SetMergePRs(func(ctx context.Context, repo string, prNumbers []string) error {
+		merged = true
+		return nil
+	})
+	c.HandleGHStatus(context.Background(), map[string]*PRStatus{
+		"7": {PRNumber: "7", State: "OPEN", CI: "passing", Conflicts: "none", ReviewDecision: "APPROVED", TargetRepo: "owner/repo"},
+	}, runStates)
+	if !merged {
+		t.Error("spend cap should not block merging an approved PR")
+	}
+}
+
+func TestPolicy_CIChecksGateOnRequired(t *testing.T) {
+	c, _ := newTestController(t)
+	c.SetAutoMergeOnApproval(true)
+	launched, merged := 0, 0
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launched++
+		return "agent-fix", nil
+	})
+	c.SetMergePRs(func(ctx context.Context, repo string, prNumbers []string) error {
+		merged++
+		return nil
+	})
+
+	// Required test passes; the optional coverage check fails and a
+	// preview deploy is still pending.
+	status := func() map[string]*PRStatus {
+		return map[string]*PRStatus{"42": {
+			PRNumber: "42", PRURL: "https://github.com/owner/repo/pull/42", State: "OPEN",
+			CI: "failing", Conflicts: "none", ReviewDecision: "APPROVED", TargetRepo: "repo",
+			Checks: []ghutil.CheckRun{
+				{Name: "test", State: "passing", Required: true},
+				{Name: "codecov/patch", State: "failing"},
+				{Name: "preview", State: "pending"},
+			},
+		}}
+	}
+	c.HandleGHStatus(context.Background(), status(), nil)
+	if launched != 0 || merged != 1 {
+		t.Errorf("launched=%d merged=%d, want an optional failure to neither dispatch nor block the merge", launched, merged)
+	}
+
+	// ci_checks: all restores the old behavior.
+	c2, _ := newTestController(t)
+	p := DefaultPolicy()
+	p.CIChecks = CIChecksAll
+	c2.SetPolicyResolver(func(string) Policy { return p })
+	c2.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launched++
+		return "agent-fix", nil
+	})
+	c2.HandleGHStatus(context.Background(), status(), nil)
+	if launched != 1 {
+		t.Errorf("launched=%d, want a fix agent when every check counts", launched)
+	}
+}
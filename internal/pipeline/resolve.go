@@ -0,0 +1,61 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+)
+
+// ConflictResolution is an agentless conflict resolution HandleGHStatus
+// queued for a PR. Callers run it with ResolveConflicts off their event
+// loop, since 'klaus rebase' rebases and verifies the PR.
+type ConflictResolution struct {
+	PRNumber string
+	Slug     string // owner/repo
+}
+
+// PendingResolutions returns the conflict resolutions queued since the last
+// call and clears the queue. Until one is resolved, nothing else is
+// dispatched for its PR.
+func (c *Controller) PendingResolutions() []ConflictResolution {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := c.resolutions
+	c.resolutions = nil
+	return out
+}
+
+// ResolveConflicts runs a queued conflict resolution and records the
+// result for the next evaluation: a resolved PR waits out the dispatch
+// cooldown while GitHub recomputes mergeability, and one the resolver
+// couldn't handle gets the rebase agent. It runs the resolver without c.mu
+// held.
+func (c *Controller) ResolveConflicts(ctx context.Context, r ConflictResolution) []Action {
+	c.mu.Lock()
+	resolve := c.resolveConflicts
+	c.mu.Unlock()
+
+	// A new leader queues the resolution again.
+	var err error
+	if c.leading() {
+		err = resolve(ctx, r.Slug, r.PRNumber)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ps := c.prStates[r.PRNumber]
+	if ps == nil || !ps.resolving {
+		return nil
+	}
+	ps.resolving = false
+	if !c.leading() {
+		return nil
+	}
+	ps.AutoResolveTried = true
+	defer c.checkpoint()
+	if err != nil {
+		c.logger.Info("conflicts need an agent", "pr", r.PRNumber, "err", err)
+		return []Action{{Type: "resolve", Detail: fmt.Sprintf("Conflicts on PR #%s need an agent", r.PRNumber)}}
+	}
+	ps.LastDispatchAt = c.now()
+	return []Action{{Type: "resolve", Detail: fmt.Sprintf("Auto-resolved conflicts on PR #%s", r.PRNumber)}}
+}
@@ -0,0 +1,195 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/patflynn/klaus/internal/run"
+)
+
+// SimOptions configure the controller a trace is replayed through.
+type SimOptions struct {
+	AutoMergeOnApproval bool
+	PolicyFor           func(repo string) Policy // nil means DefaultPolicy
+}
+
+// SimTransition is one audited transition (see PipelineTransition events)
+// during a simulated step.
+type SimTransition struct {
+	PRNumber string
+	From     Stage
+	To       Stage
+	Rule     string
+	RunID    string // agent dispatched by the transition, if any
+}
+
+// SimStep is the outcome of replaying one trace entry.
+type SimStep struct {
+	Index       int // 1-based line in the trace
+	Time        time.Time
+	Transitions []SimTransition
+	Actions     []Action
+	Left        []string // PRs that left the pipeline, e.g. "42 (MERGED)"
+}
+
+// Simulate replays a recorded trace through a fresh controller and returns
+// what it did at each step. Nothing is executed: launches, merges, review
+// thread calls and run cleanups are stubbed to succeed, no events are
+// emitted and no state is saved. The controller's clock follows the recorded timestamps and agent
+// liveness follows the recorded running set, so cooldowns and "agent still
+// running" guards behave as they did live.
+//
+// A stubbed launch for PR N returns the first pr-fix run for N that appears
+// later in the trace and was not seen before, i.e. the run the live pipeline
+// actually dispatched, so its recorded liveness carries over. When there is
+// none it returns a synthetic sim-<N>-<k> ID, which reads as already finished.
+func Simulate(trace []TraceEntry, opts SimOptions) []SimStep {
+	c := New(nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	c.autoMergeOnApproval = opts.AutoMergeOnApproval
+	c.policyFor = opts.PolicyFor
+
+	var (
+		i       int
+		step    *SimStep
+		live    = make(map[string]bool) // tmux pane -> running
+		claimed = make(map[string]bool) // run IDs already present or handed out
+		synth   = make(map[string]int)
+	)
+	c.now = func() time.Time { return trace[i].Time }
+	c.tmuxDeps = run.TmuxDeps{
+		PaneExists: func(pane string) bool { return live[pane] },
+		PaneIsDead: func(pane string) bool { return !live[pane] },
+		PaneIsIdle: func(pane string) bool { return !live[pane] },
+	}
+	c.launchAgent = func(_ context.Context, prNumber, _, _, _, _ string) (string, error) {
+		for _, e := range trace[i+1:] {
+			for _, s := range e.RunStates {
+				if s != nil && s.Type == "pr-fix" && !claimed[s.ID] && runStateMatchesPR(s, prNumber) {
+					claimed[s.ID] = true
+					return s.ID, nil
+				}
+			}
+		}
+		synth[prNumber]++
+		return fmt.Sprintf("sim-%s-%d", prNumber, synth[prNumber]), nil
+	}
+	c.mergePRs = func(context.Context, string, []string) error { return nil }
+	c.snapshotThreads = func(string, string) ([]string, error) { return nil, nil }
+	c.resolveThread = func(string) error { return nil }
+	c.fetchCIFailures = func(context.Context, string, string) ([]CheckFailure, error) { return nil, nil }
+	c.failedChecks = func(context.Context, string, string) ([]FailedCheck, error) { return nil, nil }
+	c.rerunCheck = func(context.Context, string, int64) error { return nil }
+	c.retargetPR = func(context.Context, string, string, string) (string, error) { return "main", nil }
+	// Traces don't record what a local rebase would have done; assume the
+	// conflicts need the agent, as they did when the trace was recorded.
+	c.resolveConflicts = func(context.Context, string, string) error { return errors.New("not simulated") }
+	c.backport = func(context.Context, string, string, []string) error { return nil }
+	c.cleanupRun = func(context.Context, string, []string) error { return nil }
+	c.cleanupWorktree = func(context.Context, string) error { return nil }
+	c.onTransition = func(ft firedTransition, ps *PRPipelineState, runID string) {
+		step.Transitions = append(step.Transitions, SimTransition{
+			PRNumber: ft.prNumber,
+			From:     ft.from,
+			To:       ps.Stage,
+			Rule:     ft.rule,
+			RunID:    runID,
+		})
+	}
+
+	steps := make([]SimStep, 0, len(trace))
+	for i = range trace {
+		entry := trace[i]
+		running := make(map[string]bool, len(entry.Running))
+		for _, id := range entry.Running {
+			running[id] = true
+		}
+		live = make(map[string]bool)
+		for _, s := range entry.RunStates {
+			if s == nil {
+				continue
+			}
+			claimed[s.ID] = true
+			if running[s.ID] && s.TmuxPane != nil {
+				live[*s.TmuxPane] = true
+			}
+		}
+
+		tracked := make(map[string]bool, len(c.prStates))
+		for pr := range c.prStates {
+			tracked[pr] = true
+		}
+
+		steps = append(steps, SimStep{Index: i + 1, Time: entry.Time})
+		step = &steps[len(steps)-1]
+		step.Actions = c.HandleGHStatus(context.Background(), entry.Statuses, entry.RunStates)
+		// A live caller runs conflict resolutions off its loop and
+		// evaluates again; that evaluation is the trace's next entry.
+		for _, r := range c.PendingResolutions() {
+			step.Actions = append(step.Actions, c.ResolveConflicts(context.Background(), r)...)
+		}
+
+		for pr := range tracked {
+			if _, ok := c.prStates[pr]; !ok {
+				state := "untracked"
+				if st := entry.Statuses[pr]; st != nil {
+					state = st.State
+				}
+				step.Left = append(step.Left, fmt.Sprintf("%s (%s)", pr, state))
+			}
+		}
+
+		// HandleGHStatus walks PRs in map order; sort for a stable timeline.
+		sort.Slice(step.Transitions, func(a, b int) bool {
+			return lessPRNumber(step.Transitions[a].PRNumber, step.Transitions[b].PRNumber)
+		})
+		sort.SliceStable(step.Actions, func(a, b int) bool {
+			if step.Actions[a].Type != step.Actions[b].Type {
+				return step.Actions[a].Type < step.Actions[b].Type
+			}
+			return step.Actions[a].Detail < step.Actions[b].Detail
+		})
+		sort.Strings(step.Left)
+	}
+	return steps
+}
+
+// FormatTimeline writes the steps that did something, one block per step.
+// The output is stable for a given trace and is what golden files hold.
+func FormatTimeline(w io.Writer, steps []SimStep) {
+	for _, s := range steps {
+		if len(s.Transitions) == 0 && len(s.Actions) == 0 && len(s.Left) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "[%d] %s\n", s.Index, s.Time.UTC().Format(time.RFC3339))
+		for _, t := range s.Transitions {
+			fmt.Fprintf(w, "  PR #%s %s → %s  %s", t.PRNumber, t.From, t.To, t.Rule)
+			if t.RunID != "" {
+				fmt.Fprintf(w, "  run=%s", t.RunID)
+			}
+			fmt.Fprintln(w)
+		}
+		for _, a := range s.Actions {
+			if a.Error != "" {
+				fmt.Fprintf(w, "  %s: %s: %s\n", a.Type, a.Detail, a.Error)
+			} else {
+				fmt.Fprintf(w, "  %s: %s\n", a.Type, a.Detail)
+			}
+		}
+		for _, pr := range s.Left {
+			fmt.Fprintf(w, "  PR #%s left the pipeline\n", pr)
+		}
+	}
+}
+
+// lessPRNumber orders PR numbers numerically ("9" before "10").
+func lessPRNumber(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
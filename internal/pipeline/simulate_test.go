@@ -0,0 +1,158 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/patflynn/klaus/internal/event"
+	"github.com/patflynn/klaus/internal/run"
+)
+
+func TestTraceRecordAndSimulate(t *testing.T) {
+	c, dir := newTestController(t)
+	tracePath := filepath.Join(dir, "session", TraceFileName)
+	c.SetTraceRecorder(NewTraceRecorder(tracePath))
+	clock := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return clock }
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		return "run-fix-1", nil
+	})
+
+	failing := map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "failing", TargetRepo: "owner/repo"},
+	}
+	fixRun := &run.State{ID: "run-fix-1", Type: "pr-fix", PR: strPtr("42"), TmuxPane: strPtr("%1")}
+
+	// 1: CI fails, a fix agent is dispatched.
+	c.HandleGHStatus(context.Background(), failing, nil)
+	// 2: the agent is running; nothing to do.
+	clock = clock.Add(30 * time.Second)
+	c.HandleGHStatus(context.Background(), failing, []*run.State{fixRun})
+	// 3: the agent finished and CI passes.
+	clock = clock.Add(5 * time.Minute)
+	c.SetTmuxDeps(run.TmuxDeps{
+		PaneExists: func(string) bool { return false },
+		PaneIsDead: func(string) bool { return true },
+		PaneIsIdle: func(string) bool { return true },
+	})
+	c.HandleGHStatus(context.Background(), map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "passing", TargetRepo: "owner/repo"},
+	}, []*run.State{fixRun})
+
+	trace, err := ReadTrace(tracePath)
+	if err != nil {
+		t.Fatalf("ReadTrace: %v", err)
+	}
+	if len(trace) != 3 {
+		t.Fatalf("recorded %d entries, want 3", len(trace))
+	}
+	if got := trace[1].Running; len(got) != 1 || got[0] != "run-fix-1" {
+		t.Errorf("entry 2 running = %v, want [run-fix-1]", got)
+	}
+
+	// The replay must reproduce the live transitions, including the run ID
+	// the live pipeline dispatched.
+	events, err := event.NewLog(filepath.Join(dir, "session")).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var live []string
+	for _, e := range events {
+		if e.Type == event.PipelineTransition {
+			live = append(live, e.Data["rule"].(string))
+		}
+	}
+	var simulated []string
+	steps := Simulate(trace, SimOptions{})
+	for _, s := range steps {
+		for _, tr := range s.Transitions {
+			simulated = append(simulated, tr.Rule)
+		}
+	}
+	if strings.Join(simulated, ",") != strings.Join(live, ",") {
+		t.Errorf("simulated rules %v, live rules %v", simulated, live)
+	}
+
+	var buf bytes.Buffer
+	FormatTimeline(&buf, steps)
+	want := "[1] 2026-10-17T12:00:00Z\n" +
+		"  PR #42 ci_failed → ci_failed  ci-failing/dispatch-fix-agent  run=run-fix-1\n" +
+		"  launch: CI fix agent for PR #42\n"
+	if !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("timeline:\n%s\nwant prefix:\n%s", buf.String(), want)
+	}
+	if strings.Contains(buf.String(), "[2]") {
+		t.Errorf("idle step 2 should not appear in the timeline:\n%s", buf.String())
+	}
+}
+
+func TestSimulateFollowsRecordedClock(t *testing.T) {
+	t0 := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	failing := map[string]*PRStatus{
+		"42": {PRNumber: "42", State: "OPEN", CI: "failing", TargetRepo: "owner/repo"},
+	}
+	trace := []TraceEntry{
+		{Time: t0, Statuses: failing},
+		{Time: t0.Add(30 * time.Second), Statuses: failing}, // inside the dispatch cooldown
+		{Time: t0.Add(90 * time.Second), Statuses: failing},
+	}
+
+	steps := Simulate(trace, SimOptions{})
+	var runs []string
+	for _, s := range steps {
+		for _, tr := range s.Transitions {
+			if tr.RunID != "" {
+				runs = append(runs, tr.RunID)
+			}
+		}
+	}
+	if strings.Join(runs, ",") != "sim-42-1,sim-42-2" {
+		t.Errorf("dispatched %v, want sim-42-1 then sim-42-2 after the cooldown", runs)
+	}
+	if len(steps[1].Actions) != 0 {
+		t.Errorf("step 2 acted inside the cooldown: %v", steps[1].Actions)
+	}
+}
+
+// TestSimulateDoesNotCleanUpWorktrees replays dispatches for a CI fix and a
+// rebase while the PRs' finished runs still have worktrees on disk: the
+// live pipeline would 'klaus cleanup' them first, the simulator must not.
+func TestSimulateDoesNotCleanUpWorktrees(t *testing.T) {
+	bin := t.TempDir()
+	marker := filepath.Join(bin, "called")
+	script := "#!/bin/sh\necho \"$@\" >> " + marker + "\n"
+	if err := os.WriteFile(filepath.Join(bin, "klaus"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var runStates []*run.State
+	for _, pr := range []string{"42", "43"} {
+		runStates = append(runStates, &run.State{ID: "run-" + pr, Type: "pr-fix", PR: strPtr(pr), Worktree: t.TempDir()})
+	}
+	trace := []TraceEntry{{
+		Time: time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+		Statuses: map[string]*PRStatus{
+			"42": {PRNumber: "42", State: "OPEN", CI: "failing", TargetRepo: "owner/repo"},
+			"43": {PRNumber: "43", State: "OPEN", CI: "passing", Conflicts: "yes", TargetRepo: "owner/repo"},
+		},
+		RunStates: runStates,
+	}}
+
+	steps := Simulate(trace, SimOptions{})
+	types := make(map[string]int)
+	for _, a := range steps[0].Actions {
+		types[a.Type]++
+	}
+	if types["launch"] != 1 || types["resolve"] != 1 {
+		t.Fatalf("actions = %v, want a CI fix launch and a conflict resolution", steps[0].Actions)
+	}
+	if out, err := os.ReadFile(marker); err == nil {
+		t.Errorf("simulation ran klaus %s", out)
+	}
+}
@@ -0,0 +1,115 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	ghutil "github.com/patflynn/klaus/internal/github"
+	"github.com/patflynn/klaus/internal/run"
+)
+
+// stackRetargets returns a retarget descriptor for each PR still stacked on
+// prNumber, which has merged. Children are moved onto the branch the parent
+// merged into, which in stack order is the default branch.
+func (c *Controller) stackRetargets(prNumber string, runStates []*run.State) []ActionDescriptor {
+	var descs []ActionDescriptor
+	for _, child := range run.StackChildren(prNumber, runStates) {
+		var prURL string
+		for _, s := range runStates {
+			if s.PRNumber() == child && s.PRURL != nil {
+				prURL = *s.PRURL
+				break
+			}
+		}
+		descs = append(descs, ActionDescriptor{
+			Type:      ActionRetargetPR,
+			PRNumber:  child,
+			PRURL:     prURL,
+			PRNumbers: []string{prNumber},
+			RunStates: runStates,
+		})
+	}
+	return descs
+}
+
+// retargetResult is the outcome of an ActionRetargetPR descriptor.
+type retargetResult struct {
+	prNumber  string
+	parent    string
+	base      string // branch the PR now targets
+	runStates []*run.State
+	err       error
+}
+
+// retargetStackedPR moves a stacked PR off its merged parent's branch. It
+// runs without c.mu held.
+func (c *Controller) retargetStackedPR(ctx context.Context, desc ActionDescriptor) retargetResult {
+	res := retargetResult{prNumber: desc.PRNumber, parent: desc.PRNumbers[0], runStates: desc.RunStates}
+	res.base, res.err = c.retargetPR(ctx, ghutil.OwnerRepoFromPRURL(desc.PRURL), desc.PRNumber, res.parent)
+	return res
+}
+
+// applyRetargetResult records a retargeted PR as no longer stacked, so it
+// may merge, and remembers the branch it left for its rebase prompt. A
+// failed retarget is retried on the next poll. Callers must hold c.mu.
+func (c *Controller) applyRetargetResult(r retargetResult) []Action {
+	if r.err != nil {
+		c.logger.Warn("failed to retarget stacked PR", "pr", r.prNumber, "parent", r.parent, "err", r.err)
+		return []Action{{Type: "error", Detail: fmt.Sprintf("PR #%s: retarget after #%s merged failed", r.prNumber, r.parent), Error: truncateError(r.err.Error(), 120)}}
+	}
+	var from string
+	for _, s := range r.runStates {
+		if s.BaseBranch == nil || s.PRNumber() != r.prNumber {
+			continue
+		}
+		from = *s.BaseBranch
+		s.BaseBranch = nil
+		if c.store != nil {
+			if err := c.store.Save(s); err != nil {
+				c.logger.Error("failed to persist retargeted PR to run state", "pr", r.prNumber, "run", s.ID, "err", err)
+			}
+		}
+	}
+	if ps := c.prStates[r.prNumber]; ps != nil {
+		ps.RetargetedFrom = from
+	}
+	c.logger.Info("retargeted stacked PR", "pr", r.prNumber, "parent", r.parent, "base", r.base)
+	return []Action{{Type: "retarget", Detail: fmt.Sprintf("Retargeted PR #%s onto %s after #%s merged", r.prNumber, r.base, r.parent)}}
+}
+
+// defaultRetargetPR points prNumber at the branch parentPR merged into and
+// returns that branch.
+func (c *Controller) defaultRetargetPR(ctx context.Context, slug, prNumber, parentPR string) (string, error) {
+	client := ghutil.NewGHCLIClient(slug)
+	base, err := client.GetBaseBranch(ctx, parentPR)
+	if err != nil {
+		return "", err
+	}
+	return base, client.SetBaseBranch(ctx, prNumber, base)
+}
+
+// rebasePrompt is the default prompt of a rebase agent. The PR is rebased
+// onto the branch it targets, so a backport isn't handed the default
+// branch's unrelated work.
+func rebasePrompt(prNumber, baseBranch string) string {
+	onto := "the default branch"
+	if baseBranch != "" {
+		onto = "origin/" + baseBranch
+	}
+	return fmt.Sprintf(
+		"PR #%s has merge conflicts with the base branch. Rebase onto %s, resolve all conflicts, and push. Run tests after resolving.",
+		prNumber, onto,
+	)
+}
+
+// stackRebasePrompt is the rebase prompt for a PR whose stack parent has
+// merged: its branch still carries the parent's commits, which are already
+// on the base branch (squashed, if the parent was squash-merged).
+func stackRebasePrompt(prNumber, parentBranch string) string {
+	return fmt.Sprintf(
+		"PR #%s was stacked on %s, which has merged, and now targets the default branch. "+
+			"Rebase only this PR's own commits onto the default branch (e.g. `git rebase --onto origin/HEAD <last commit from %s>`), "+
+			"dropping the parent's commits that are already merged. Resolve all conflicts, run tests, and force-push.",
+		prNumber, parentBranch, parentBranch,
+	)
+}
@@ -0,0 +1,91 @@
+package pipeline
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/patflynn/klaus/internal/run"
+)
+
+func TestStackedPR_MergesAfterParentAndRetargets(t *testing.T) {
+	c, _ := newTestController(t)
+	c.SetAutoMergeOnApproval(true)
+	var merged []string
+	c.SetMergePRs(func(ctx context.Context, repo string, prNumbers []string) error {
+		merged = append(merged, prNumbers...)
+		return nil
+	})
+	var retargeted []string
+	c.SetRetargetPR(func(ctx context.Context, slug, prNumber, parentPR string) (string, error) {
+		if slug != "owner/repo" {
+			t.Errorf("retarget slug = %q, want owner/repo", slug)
+		}
+		retargeted = append(retargeted, prNumber+"<-"+parentPR)
+		return "main", nil
+	})
+	var prompts []string
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		prompts = append(prompts, prompt)
+		return "agent-rebase", nil
+	})
+
+	parentURL := "https://github.com/owner/repo/pull/10"
+	childURL := "https://github.com/owner/repo/pull/11"
+	parentBranch := "agent/parent"
+	runStates := []*run.State{
+		{ID: "parent", Branch: parentBranch, PRURL: &parentURL},
+		{ID: "child", Branch: "agent/child", PRURL: &childURL, ParentRunID: strPtr("parent"), BaseBranch: &parentBranch},
+	}
+	pr := func(num, url, state, conflicts string) *PRStatus {
+		return &PRStatus{PRNumber: num, PRURL: url, State: state, CI: "passing", Conflicts: conflicts, ReviewDecision: "APPROVED", TargetRepo: "repo"}
+	}
+	ctx := context.Background()
+
+	// Both approved: only the parent may merge; the child would land in
+	// the parent's branch.
+	c.HandleGHStatus(ctx, map[string]*PRStatus{
+		"10": pr("10", parentURL, "OPEN", "none"),
+		"11": pr("11", childURL, "OPEN", "none"),
+	}, runStates)
+	if len(merged) != 1 || merged[0] != "10" {
+		t.Fatalf("merged = %v, want only the parent", merged)
+	}
+	if got := c.PipelineStates()["11"].Stage; got != StageApproved {
+		t.Errorf("stacked child stage = %s, want approved (held behind parent)", got)
+	}
+
+	// The parent merged; the child is retargeted and now conflicts.
+	c.HandleGHStatus(ctx, map[string]*PRStatus{
+		"10": pr("10", parentURL, "MERGED", "none"),
+		"11": pr("11", childURL, "OPEN", "none"),
+	}, runStates)
+	if len(retargeted) != 1 || retargeted[0] != "11<-10" {
+		t.Fatalf("retargeted = %v, want [11<-10]", retargeted)
+	}
+	if runStates[1].BaseBranch != nil {
+		t.Error("child run still recorded as stacked after retarget")
+	}
+	if got := c.PipelineStates()["11"].RetargetedFrom; got != parentBranch {
+		t.Errorf("RetargetedFrom = %q, want %q", got, parentBranch)
+	}
+
+	c.HandleGHStatus(ctx, map[string]*PRStatus{
+		"10": pr("10", parentURL, "MERGED", "none"),
+		"11": pr("11", childURL, "OPEN", "yes"),
+	}, runStates)
+	if len(retargeted) != 1 {
+		t.Errorf("retargeted again: %v", retargeted)
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "stacked on agent/parent") {
+		t.Fatalf("rebase prompts = %q, want a stack-aware rebase", prompts)
+	}
+
+	// Once rebased and conflict-free it merges on its own.
+	c.HandleGHStatus(ctx, map[string]*PRStatus{
+		"11": pr("11", childURL, "OPEN", "none"),
+	}, runStates)
+	if len(merged) != 2 || merged[1] != "11" {
+		t.Errorf("merged = %v, want the child after retarget", merged)
+	}
+}
@@ -0,0 +1,120 @@
+package pipeline
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/patflynn/klaus/internal/run"
+)
+
+// TraceFileName is the name of the pipeline trace file inside a session
+// directory (e.g. ~/.klaus/sessions/<id>/pipeline-trace.jsonl).
+const TraceFileName = "pipeline-trace.jsonl"
+
+// TraceEntry is one recorded HandleGHStatus call: the PR statuses and run
+// states it was given, plus which runs were live at the time. Liveness is
+// recorded rather than re-derived on replay because it comes from tmux.
+type TraceEntry struct {
+	Time      time.Time            `json:"time"`
+	Statuses  map[string]*PRStatus `json:"statuses"`
+	RunStates []*run.State         `json:"run_states,omitempty"`
+	Running   []string             `json:"running,omitempty"` // IDs of run states whose agents were running
+}
+
+// TraceRecorder appends TraceEntry lines to a JSONL file. Replay a trace
+// with Simulate.
+type TraceRecorder struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewTraceRecorder returns a recorder appending to the file at path.
+func NewTraceRecorder(path string) *TraceRecorder {
+	return &TraceRecorder{path: path}
+}
+
+// Record appends one entry to the trace.
+func (r *TraceRecorder) Record(entry TraceEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshaling trace entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening pipeline trace: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("writing pipeline trace: %w", err)
+	}
+	return nil
+}
+
+// ReadTrace reads a trace written by a TraceRecorder.
+func ReadTrace(path string) ([]TraceEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []TraceEntry
+	scanner := bufio.NewScanner(f)
+	// Run states carry prompts; allow long lines.
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e TraceEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// SetTraceRecorder records every subsequent HandleGHStatus call to r. A nil
+// recorder disables recording.
+func (c *Controller) SetTraceRecorder(r *TraceRecorder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.trace = r
+}
+
+// recordTrace records the inputs of a HandleGHStatus call. Recording errors
+// are logged and never block evaluation.
+func (c *Controller) recordTrace(statuses map[string]*PRStatus, runStates []*run.State, running map[string]bool) {
+	if c.trace == nil {
+		return
+	}
+	ids := make([]string, 0, len(running))
+	for id := range running {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	entry := TraceEntry{
+		Time:      c.now().UTC(),
+		Statuses:  statuses,
+		RunStates: runStates,
+		Running:   ids,
+	}
+	if err := c.trace.Record(entry); err != nil {
+		c.logger.Warn("failed to record pipeline trace", "err", err)
+	}
+}
@@ -3,7 +3,6 @@ package pipeline
 import (
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/patflynn/klaus/internal/event"
 	ghutil "github.com/patflynn/klaus/internal/github"
@@ -15,13 +14,13 @@ import (
 // mutates pipeline state and returns any actions/descriptors.
 type transition struct {
 	Name  string
-	Guard func(c *Controller, ps *PRPipelineState, status *PRStatus, runStates []*run.State) bool
+	Guard guard
 	Apply func(c *Controller, ps *PRPipelineState, status *PRStatus, runStates []*run.State) ([]Action, []ActionDescriptor)
 }
 
 // transitions is the ordered list of pipeline rules. First match wins.
 var transitions = []transition{
-	// ── Terminal: merged PRs must not be re-processed ───────────────────
+	// ── Terminal: merged PRs must not be re-processed ────────
 	{
 		Name:  "terminal/merged",
 		Guard: inStage(StageMerged),
@@ -65,6 +64,36 @@ var transitions = []transition{
 		},
 	},
 
+	// ── Spend cap (before every rule that dispatches an agent) ──────────
+
+	{
+		Name: "spend-cap/exceeded",
+		Guard: allOf(
+			needsAgent,
+			agentNotRunning,
+			spendCapReached,
+		),
+		Apply: func(c *Controller, ps *PRPipelineState, status *PRStatus, runStates []*run.State) ([]Action, []ActionDescriptor) {
+			if ps.Stage == StageBudgetExceeded {
+				return nil, nil
+			}
+			ps.Stage = StageBudgetExceeded
+			spent := PRSpend(ps.PRNumber, runStates)
+			spendCap := c.policy(status.TargetRepo).MaxPRSpendUSD
+			c.logger.Warn("PR spend cap reached", "pr", ps.PRNumber, "spent", spent, "cap", spendCap)
+			c.emitEvent(ps.PRNumber, event.PRBudgetExceeded, map[string]interface{}{
+				"pr_number": ps.PRNumber,
+				"pr_url":    status.PRURL,
+				"spent_usd": spent,
+				"cap_usd":   spendCap,
+			})
+			return []Action{{
+				Type:   "error",
+				Detail: fmt.Sprintf("PR #%s: agents spent $%.2f of its $%.2f cap, not dispatching", ps.PRNumber, spent, spendCap),
+			}}, nil
+		},
+	},
+
 	// ── CI failing ──────────────────────────────────────────────────────
 
 	{
@@ -98,6 +127,36 @@ var transitions = []transition{
 			}}, nil
 		},
 	},
+	{
+		Name: "ci-failing/rerun-failed-checks",
+		Guard: allOf(
+			ciFailing,
+			agentNotRunning,
+			rerunEnabled,
+		),
+		Apply: func(c *Controller, ps *PRPipelineState, status *PRStatus, _ []*run.State) ([]Action, []ActionDescriptor) {
+			ps.Rerun = rerunRequested
+			ps.RerunAt = c.now()
+			ps.RerunFrom = ps.Stage
+			ps.Stage = StageCIRerun
+			return nil, []ActionDescriptor{{
+				Type:     ActionRerunChecks,
+				PRNumber: ps.PRNumber,
+				Repo:     status.TargetRepo,
+				PRURL:    status.PRURL,
+			}}
+		},
+	},
+	{
+		Name: "ci-failing/rerun-wait",
+		Guard: allOf(
+			ciFailing,
+			rerunInFlight,
+		),
+		Apply: func(_ *Controller, _ *PRPipelineState, _ *PRStatus, _ []*run.State) ([]Action, []ActionDescriptor) {
+			return nil, nil
+		},
+	},
 	{
 		Name: "ci-failing/dispatch-fix-agent",
 		Guard: allOf(
@@ -107,13 +166,23 @@ var transitions = []transition{
 			cooldownExpired,
 		),
 		Apply: func(c *Controller, ps *PRPipelineState, status *PRStatus, runStates []*run.State) ([]Action, []ActionDescriptor) {
+			// The rerun settled without fixing CI: pick up where it left off
+			// so attempt counting is unaffected, and allow a rerun of the
+			// next failure.
+			if ps.Stage == StageCIRerun {
+				ps.Stage = ps.RerunFrom
+			}
+			ps.Rerun = ""
+			ps.RerunChecks = nil
+
 			// Count a failed fix attempt when a previous agent finished but CI is still failing.
 			if ps.Stage == StageCIFailed && ps.LastAgentID != "" {
 				ps.FixAttempts++
 			}
 
 			// Re-check circuit breaker after incrementing.
-			if ps.FixAttempts >= maxFixAttempts {
+			pol := c.policy(status.TargetRepo)
+			if ps.FixAttempts >= pol.MaxFixAttempts {
 				ps.Stage = StageStalled
 				c.logger.Warn("fix agent circuit breaker tripped",
 					"pr", ps.PRNumber,
@@ -132,10 +201,10 @@ var transitions = []transition{
 				RunStates: runStates,
 			})
 
-			prompt := fmt.Sprintf(
+			prompt := c.renderPrompt(pol.Prompts.CIFix, fmt.Sprintf(
 				"CI is failing on PR #%s. Diagnose the failures and push fixes. Check `gh pr checks %s` for details and `gh run view <run-id> --log-failed` for error output.",
 				ps.PRNumber, ps.PRNumber,
-			)
+			), ps, status)
 			ps.pendingLaunchDetail = fmt.Sprintf("CI fix agent for PR #%s", ps.PRNumber)
 			descs = append(descs, ActionDescriptor{
 				Type:       ActionLaunchAgent,
@@ -143,6 +212,9 @@ var transitions = []transition{
 				Repo:       status.TargetRepo,
 				Prompt:     prompt,
 				ResumeFrom: ps.LastAgentID,
+				PRURL:      status.PRURL,
+				CIFailure:  true,
+				Profile:    pol.Profiles.CIFix,
 			})
 
 			ps.Stage = StageCIFailed
@@ -214,7 +286,8 @@ var transitions = []transition{
 			}
 
 			// Re-check rebase circuit breaker after incrementing.
-			if ps.RebaseAttempts >= maxFixAttempts {
+			pol := c.policy(status.TargetRepo)
+			if ps.RebaseAttempts >= pol.MaxRebaseAttempts {
 				ps.Stage = StageStalled
 				c.logger.Warn("rebase agent circuit breaker tripped",
 					"pr", ps.PRNumber,
@@ -232,10 +305,11 @@ var transitions = []transition{
 				PRNumber:  ps.PRNumber,
 				RunStates: runStates,
 			})
-			prompt := fmt.Sprintf(
-				"PR #%s has merge conflicts with the base branch. Rebase onto main, resolve all conflicts, and push. Run tests after resolving.",
-				ps.PRNumber,
-			)
+			def := rebasePrompt(ps.PRNumber, status.BaseBranch)
+			if ps.RetargetedFrom != "" {
+				def = stackRebasePrompt(ps.PRNumber, ps.RetargetedFrom)
+			}
+			prompt := c.renderPrompt(pol.Prompts.Rebase, def, ps, status)
 			ps.Stage = StageNeedsRebase
 			ps.pendingLaunchDetail = fmt.Sprintf("Rebase agent for PR #%s", ps.PRNumber)
 			descs = append(descs, ActionDescriptor{
@@ -244,6 +318,11 @@ var transitions = []transition{
 				Repo:       status.TargetRepo,
 				Prompt:     prompt,
 				ResumeFrom: ps.LastAgentID,
+				PRURL:      status.PRURL,
+				// Lockfile and generated-file conflicts don't need an
+				// agent; try those once per conflict.
+				Conflicts: pol.AutoResolveConflicts && !ps.AutoResolveTried,
+				Profile:   pol.Profiles.Rebase,
 			})
 			return nil, descs
 		},
@@ -276,6 +355,7 @@ var transitions = []transition{
 			isApproved,
 			noConflicts,
 			autoMergeEnabled,
+			notStacked,
 		),
 		Apply: func(c *Controller, ps *PRPipelineState, status *PRStatus, runStates []*run.State) ([]Action, []ActionDescriptor) {
 			ps.FixAttempts = 0
@@ -338,10 +418,11 @@ var transitions = []transition{
 				Repo:     dispatchRepo(c, status),
 			})
 
-			prompt := reviewFixPrompt(
+			pol := c.policy(status.TargetRepo)
+			prompt := c.renderPrompt(pol.Prompts.ChangesRequested, reviewFixPrompt(
 				fmt.Sprintf("PR #%s in %s has changes requested by reviewers.", ps.PRNumber, status.TargetRepo),
 				ps.PRNumber,
-			)
+			), ps, status)
 			ps.pendingLaunchDetail = fmt.Sprintf("Review fix agent for PR #%s", ps.PRNumber)
 			ps.Stage = StageReviewPending
 			descs = append(descs, ActionDescriptor{
@@ -350,6 +431,7 @@ var transitions = []transition{
 				Repo:       status.TargetRepo,
 				Prompt:     prompt,
 				ResumeFrom: ps.LastAgentID,
+				Profile:    pol.Profiles.ChangesRequested,
 			})
 			return nil, descs
 		},
@@ -424,7 +506,8 @@ var transitions = []transition{
 			}
 
 			// Re-check circuit breaker after incrementing.
-			if ps.ReviewFixAttempts >= maxFixAttempts {
+			pol := c.policy(status.TargetRepo)
+			if ps.ReviewFixAttempts >= pol.MaxReviewFixAttempts {
 				ps.Stage = StageStalled
 				c.logger.Warn("review fix agent circuit breaker tripped",
 					"pr", ps.PRNumber,
@@ -443,10 +526,10 @@ var transitions = []transition{
 				Repo:     dispatchRepo(c, status),
 			})
 
-			prompt := reviewFixPrompt(
+			prompt := c.renderPrompt(pol.Prompts.TrustedComments, reviewFixPrompt(
 				fmt.Sprintf("PR #%s in %s has review comments from a trusted reviewer that need to be addressed.", ps.PRNumber, status.TargetRepo),
 				ps.PRNumber,
-			)
+			), ps, status)
 			ps.pendingLaunchDetail = fmt.Sprintf("Review fix agent for PR #%s (trusted reviewer)", ps.PRNumber)
 			ps.Stage = StageReviewPending
 			descs = append(descs, ActionDescriptor{
@@ -455,6 +538,7 @@ var transitions = []transition{
 				Repo:       status.TargetRepo,
 				Prompt:     prompt,
 				ResumeFrom: ps.LastAgentID,
+				Profile:    pol.Profiles.TrustedComments,
 			})
 			return nil, descs
 		},
@@ -570,31 +654,48 @@ var transitions = []transition{
 
 type guardFunc = func(c *Controller, ps *PRPipelineState, status *PRStatus, runStates []*run.State) bool
 
+// guard is a named predicate over a PR's state. Names (and the parts of an
+// allOf) are kept so `klaus pipeline explain` can report which guard blocked
+// a transition.
+type guard struct {
+	name  string
+	check guardFunc
+	parts []guard // set for allOf
+}
+
+func newGuard(name string, check guardFunc) guard {
+	return guard{name: name, check: check}
+}
+
 // allOf combines multiple guards with logical AND.
-func allOf(guards ...guardFunc) guardFunc {
-	return func(c *Controller, ps *PRPipelineState, status *PRStatus, runStates []*run.State) bool {
-		for _, g := range guards {
-			if !g(c, ps, status, runStates) {
-				return false
+func allOf(guards ...guard) guard {
+	return guard{
+		name:  "allOf",
+		parts: guards,
+		check: func(c *Controller, ps *PRPipelineState, status *PRStatus, runStates []*run.State) bool {
+			for _, g := range guards {
+				if !g.check(c, ps, status, runStates) {
+					return false
+				}
 			}
-		}
-		return true
+			return true
+		},
 	}
 }
 
 // CI status guards.
 
-func ciFailing(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
+var ciFailing = newGuard("ciFailing", func(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
 	return status.CI == "failing"
-}
+})
 
-func ciPassing(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
+var ciPassing = newGuard("ciPassing", func(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
 	return status.CI == "passing"
-}
+})
 
-func ciPendingOrUnknown(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
+var ciPendingOrUnknown = newGuard("ciPendingOrUnknown", func(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
 	return status.CI != "failing" && status.CI != "passing"
-}
+})
 
 // Agent status guards.
 
@@ -603,91 +704,143 @@ func ciPendingOrUnknown(_ *Controller, _ *PRPipelineState, status *PRStatus, _ [
 // any pr-fix run in runStates that matches the PR — the latter catches
 // coordinator-launched runs (`klaus launch --pr`) that the controller did not
 // dispatch itself, preventing the pipeline from racing them with a competing
-// fix agent.
-func agentNotRunning(c *Controller, ps *PRPipelineState, _ *PRStatus, runStates []*run.State) bool {
-	if ps.AgentRunning {
+// fix agent. A conflict resolution in flight rewrites the PR's branch too, so
+// it counts as an agent.
+var agentNotRunning = newGuard("agentNotRunning", func(c *Controller, ps *PRPipelineState, _ *PRStatus, runStates []*run.State) bool {
+	if ps.AgentRunning || ps.resolving {
 		return false
 	}
 	return !c.anyPRFixRunning(ps.PRNumber, runStates)
-}
+})
 
-func cooldownExpired(_ *Controller, ps *PRPipelineState, _ *PRStatus, _ []*run.State) bool {
+var cooldownExpired = newGuard("cooldownExpired", func(c *Controller, ps *PRPipelineState, status *PRStatus, _ []*run.State) bool {
+	cooldown := c.policy(status.TargetRepo).DispatchCooldown
 	// Check both LastDispatchAt (set on success) and LastFailedAt (set on failure).
 	// Without checking LastFailedAt, a failed dispatch allows immediate re-evaluation
 	// because LastDispatchAt is never updated on failure.
-	if !ps.LastFailedAt.IsZero() && time.Since(ps.LastFailedAt) <= dispatchCooldown {
+	if !ps.LastFailedAt.IsZero() && c.now().Sub(ps.LastFailedAt) <= cooldown {
 		return false
 	}
-	return time.Since(ps.LastDispatchAt) > dispatchCooldown
-}
+	return c.now().Sub(ps.LastDispatchAt) > cooldown
+})
 
 // Fix attempt guards.
 
-func fixAttemptsExhausted(_ *Controller, ps *PRPipelineState, _ *PRStatus, _ []*run.State) bool {
-	return ps.FixAttempts >= maxFixAttempts
-}
+var fixAttemptsExhausted = newGuard("fixAttemptsExhausted", func(c *Controller, ps *PRPipelineState, status *PRStatus, _ []*run.State) bool {
+	return ps.FixAttempts >= c.policy(status.TargetRepo).MaxFixAttempts
+})
 
-func rebaseAttemptsExhausted(_ *Controller, ps *PRPipelineState, _ *PRStatus, _ []*run.State) bool {
-	return ps.RebaseAttempts >= maxFixAttempts
-}
+var rebaseAttemptsExhausted = newGuard("rebaseAttemptsExhausted", func(c *Controller, ps *PRPipelineState, status *PRStatus, _ []*run.State) bool {
+	return ps.RebaseAttempts >= c.policy(status.TargetRepo).MaxRebaseAttempts
+})
 
-func reviewFixAttemptsExhausted(_ *Controller, ps *PRPipelineState, _ *PRStatus, _ []*run.State) bool {
-	return ps.ReviewFixAttempts >= maxFixAttempts
-}
+var reviewFixAttemptsExhausted = newGuard("reviewFixAttemptsExhausted", func(c *Controller, ps *PRPipelineState, status *PRStatus, _ []*run.State) bool {
+	return ps.ReviewFixAttempts >= c.policy(status.TargetRepo).MaxReviewFixAttempts
+})
 
 // Stage guards.
 
-func inStage(s Stage) guardFunc {
-	return func(_ *Controller, ps *PRPipelineState, _ *PRStatus, _ []*run.State) bool {
+func inStage(s Stage) guard {
+	return newGuard(fmt.Sprintf("inStage(%s)", s), func(_ *Controller, ps *PRPipelineState, _ *PRStatus, _ []*run.State) bool {
 		return ps.Stage == s
-	}
+	})
 }
 
-func inStages(stages ...Stage) guardFunc {
-	return func(_ *Controller, ps *PRPipelineState, _ *PRStatus, _ []*run.State) bool {
+func inStages(stages ...Stage) guard {
+	return newGuard(fmt.Sprintf("inStages(%s)", stageList(stages)), func(_ *Controller, ps *PRPipelineState, _ *PRStatus, _ []*run.State) bool {
 		for _, s := range stages {
 			if ps.Stage == s {
 				return true
 			}
 		}
 		return false
-	}
+	})
 }
 
-func notInStages(stages ...Stage) guardFunc {
-	return func(_ *Controller, ps *PRPipelineState, _ *PRStatus, _ []*run.State) bool {
+func notInStages(stages ...Stage) guard {
+	return newGuard(fmt.Sprintf("notInStages(%s)", stageList(stages)), func(_ *Controller, ps *PRPipelineState, _ *PRStatus, _ []*run.State) bool {
 		for _, s := range stages {
 			if ps.Stage == s {
 				return false
 			}
 		}
 		return true
-	}
+	})
 }
 
 // notInStageWhileRunning returns true unless the PR is already in the given
 // stage AND the agent is currently running (meaning we're already handling it).
-func notInStageWhileRunning(s Stage) guardFunc {
-	return func(_ *Controller, ps *PRPipelineState, _ *PRStatus, _ []*run.State) bool {
+func notInStageWhileRunning(s Stage) guard {
+	return newGuard(fmt.Sprintf("notInStageWhileRunning(%s)", s), func(_ *Controller, ps *PRPipelineState, _ *PRStatus, _ []*run.State) bool {
 		return !(ps.Stage == s && ps.AgentRunning)
+	})
+}
+
+// stageList formats stages for guard names; the empty (new PR) stage is
+// shown as "new".
+func stageList(stages []Stage) string {
+	names := make([]string, len(stages))
+	for i, s := range stages {
+		names[i] = string(s)
+		if s == "" {
+			names[i] = "new"
+		}
 	}
+	return strings.Join(names, ",")
 }
 
 // Review decision guards.
 
-func isApproved(c *Controller, ps *PRPipelineState, status *PRStatus, runStates []*run.State) bool {
+var isApproved = newGuard("isApproved", func(c *Controller, ps *PRPipelineState, status *PRStatus, runStates []*run.State) bool {
 	cr := strings.EqualFold(status.ReviewDecision, "CHANGES_REQUESTED")
 	return strings.EqualFold(status.ReviewDecision, "APPROVED") ||
 		(!cr && c.hasKlausApproval(ps.PRNumber, runStates))
-}
+})
 
-func changesRequested(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
+var changesRequested = newGuard("changesRequested", func(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
 	return strings.EqualFold(status.ReviewDecision, "CHANGES_REQUESTED")
-}
+})
 
-func hasTrustedComments(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
+var hasTrustedComments = newGuard("hasTrustedComments", func(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
 	return status.HasNewTrustedComments
-}
+})
+
+// needsAgent reports whether the PR is in a state the pipeline answers by
+// dispatching an agent: failing CI, or passing CI with conflicts, requested
+// changes or trusted-reviewer comments.
+var needsAgent = newGuard("needsAgent", func(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
+	if status.CI == "failing" {
+		return true
+	}
+	return status.CI == "passing" &&
+		(status.Conflicts == "yes" || strings.EqualFold(status.ReviewDecision, "CHANGES_REQUESTED") || status.HasNewTrustedComments)
+})
+
+// Spend guards.
+
+// spendCapReached reports whether dispatching another agent could take the
+// PR's cumulative spend past the repo's cap.
+var spendCapReached = newGuard("spendCapReached", func(c *Controller, ps *PRPipelineState, status *PRStatus, runStates []*run.State) bool {
+	pol := c.policy(status.TargetRepo)
+	if pol.MaxPRSpendUSD <= 0 {
+		return false
+	}
+	return PRSpend(ps.PRNumber, runStates)+pol.AgentBudgetUSD > pol.MaxPRSpendUSD
+})
+
+// Rerun guards.
+
+// rerunEnabled gates re-running failed checks on the repo's policy and on
+// the failure not having been re-run already.
+var rerunEnabled = newGuard("rerunEnabled", func(c *Controller, ps *PRPipelineState, status *PRStatus, _ []*run.State) bool {
+	return ps.Rerun == "" && c.policy(status.TargetRepo).RerunFailedChecks != RerunOff
+})
+
+// rerunInFlight holds a failure whose checks were re-requested until CI
+// restarts, or until rerunStartTimeout passes without it restarting.
+var rerunInFlight = newGuard("rerunInFlight", func(c *Controller, ps *PRPipelineState, _ *PRStatus, _ []*run.State) bool {
+	return ps.Rerun == rerunRequested && c.now().Sub(ps.RerunAt) < rerunStartTimeout
+})
 
 // Label guards.
 
@@ -696,30 +849,39 @@ func hasTrustedComments(_ *Controller, _ *PRPipelineState, status *PRStatus, _ [
 // across klaus restarts and survives the worktree being cleaned up. Whether
 // the PR is currently draft or ready-for-review doesn't change the signal;
 // the label IS the signal.
-func isBudgetPausedDraft(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
+var isBudgetPausedDraft = newGuard("isBudgetPausedDraft", func(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
 	for _, label := range status.Labels {
 		if label == event.BudgetPausedLabel {
 			return true
 		}
 	}
 	return false
-}
+})
 
 // Conflict guards.
 
-func hasConflicts(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
+var hasConflicts = newGuard("hasConflicts", func(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
 	return status.Conflicts == "yes"
-}
+})
 
-func noConflicts(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
+var noConflicts = newGuard("noConflicts", func(_ *Controller, _ *PRPipelineState, status *PRStatus, _ []*run.State) bool {
 	return status.Conflicts != "yes"
-}
+})
+
+// Stack guards.
+
+// notStacked holds back merging a PR that still targets another PR's branch:
+// merging it would land it in the parent's branch, not the default branch.
+// It becomes mergeable once the parent merges and it is retargeted.
+var notStacked = newGuard("notStacked", func(_ *Controller, ps *PRPipelineState, _ *PRStatus, runStates []*run.State) bool {
+	return run.StackedOn(ps.PRNumber, runStates) == ""
+})
 
 // Controller config guards.
 
-func autoMergeEnabled(c *Controller, _ *PRPipelineState, _ *PRStatus, _ []*run.State) bool {
+var autoMergeEnabled = newGuard("autoMergeEnabled", func(c *Controller, _ *PRPipelineState, _ *PRStatus, _ []*run.State) bool {
 	return c.autoMergeOnApproval
-}
+})
 
 // ── Shared helpers used by Apply functions ──────────────────────────────
 
@@ -727,7 +889,7 @@ func autoMergeEnabled(c *Controller, _ *PRPipelineState, _ *PRStatus, _ []*run.S
 // stages where CI was not previously known to be passing. StageNeedsRebase is
 // excluded so that polls during an in-flight rebase don't re-emit the event.
 func emitCIPassedIfNeeded(c *Controller, ps *PRPipelineState, status *PRStatus) {
-	if ps.Stage == StageCIFailed || ps.Stage == StageCIPending || ps.Stage == StageReviewPending {
+	if ps.Stage == StageCIFailed || ps.Stage == StageCIPending || ps.Stage == StageReviewPending || ps.Stage == StageCIRerun {
 		c.emitEvent(ps.PRNumber, event.AgentCIPassed, map[string]interface{}{
 			"pr_number": ps.PRNumber,
 			"pr_url":    status.PRURL,
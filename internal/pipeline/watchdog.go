@@ -0,0 +1,253 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/patflynn/klaus/internal/event"
+	ghutil "github.com/patflynn/klaus/internal/github"
+	"github.com/patflynn/klaus/internal/run"
+)
+
+// Main watchdog actions (Policy.MainWatchdog).
+const (
+	MainWatchdogOff    = "off"    // don't watch the default branch
+	MainWatchdogNotify = "notify" // emit main:broken only (default)
+	MainWatchdogFix    = "fix"    // also dispatch an agent to fix forward
+	MainWatchdogRevert = "revert" // also dispatch an agent to open a revert PR
+)
+
+// mainWatchTimeout bounds how long the watchdog waits for CI on a merge
+// commit before giving up on it (e.g. a repo whose default branch runs no
+// checks).
+const mainWatchTimeout = 2 * time.Hour
+
+// maxMainAgents is the default number of watchdog agents dispatched for one
+// breakage before the watchdog stands down.
+const maxMainAgents = 1
+
+// MainWatch tracks a repo's default branch after a PR merged into it. The
+// watch ends when CI on the merge commit passes, or, once main is broken,
+// when CI on the branch head passes again.
+type MainWatch struct {
+	Slug       string    `json:"slug"`        // owner/repo
+	TargetRepo string    `json:"target_repo"` // PRStatus.TargetRepo of the merged PR (policy and launch --repo)
+	PRNumber   string    `json:"pr_number"`   // most recent merged PR
+	PRURL      string    `json:"pr_url,omitempty"`
+	MergedAt   time.Time `json:"merged_at"`
+
+	Broken    bool   `json:"broken,omitempty"`     // main:broken emitted for this breakage
+	BrokenPR  string `json:"broken_pr,omitempty"`  // PR whose merge commit failed CI
+	BrokenSHA string `json:"broken_sha,omitempty"` // that merge commit
+	Branch    string `json:"branch,omitempty"`
+
+	// HeadSHA is the branch head when last observed while broken. A head
+	// that moves without klaus merging anything means someone else is
+	// working on main, and the watchdog stands down.
+	HeadSHA     string `json:"head_sha,omitempty"`
+	Dispatches  int    `json:"dispatches,omitempty"` // watchdog agents dispatched for this breakage
+	LastAgentID string `json:"last_agent_id,omitempty"`
+	// DispatchedAfter is PRNumber when the last agent was dispatched. The
+	// next agent waits until another merge (e.g. of that agent's PR) lands
+	// and main is still red.
+	DispatchedAfter string `json:"dispatched_after,omitempty"`
+	StoodDown       bool   `json:"stood_down,omitempty"` // circuit breaker tripped; wait for green
+}
+
+// MainStatus is the GitHub-fetched status of a watched default branch.
+type MainStatus struct {
+	Branch   string // the merged PR's base branch
+	MergeSHA string // the watched PR's merge commit
+	MergeCI  string // CI on MergeSHA: passing, failing, pending, unknown
+	HeadSHA  string // current head of Branch
+	HeadCI   string // CI on HeadSHA
+}
+
+// watchMain starts (or moves) the default-branch watch for the repo a PR
+// merged into. A merge into a broken main keeps the breakage and its
+// circuit-breaker state, but the head moving is expected and not treated as
+// someone else's push. Merges into other branches (backports, stacked PRs)
+// leave the watch alone. Callers must hold c.mu.
+func (c *Controller) watchMain(prNumber string, status *PRStatus) {
+	slug := ghutil.OwnerRepoFromPRURL(status.PRURL)
+	if slug == "" || status.BaseBranch != "" || c.policy(status.TargetRepo).MainWatchdog == MainWatchdogOff {
+		return
+	}
+	targetRepo, prURL := status.TargetRepo, status.PRURL
+	w := c.mainWatches[slug]
+	if w == nil || !w.Broken {
+		w = &MainWatch{Slug: slug}
+		c.mainWatches[slug] = w
+	}
+	w.TargetRepo = targetRepo
+	w.PRNumber = prNumber
+	w.PRURL = prURL
+	w.MergedAt = c.now()
+	w.HeadSHA = ""
+}
+
+// MainWatches returns a snapshot of the active default-branch watches,
+// ordered by repo.
+func (c *Controller) MainWatches() []MainWatch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]MainWatch, 0, len(c.mainWatches))
+	for _, w := range c.mainWatches {
+		out = append(out, *w)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Slug < out[j].Slug })
+	return out
+}
+
+// HandleMainStatus evaluates the default-branch watches against fresh
+// statuses (keyed by owner/repo) and dispatches watchdog agents per policy.
+// Like HandleGHStatus it decides under the lock, launches without it, and
+// applies the results under it again.
+func (c *Controller) HandleMainStatus(ctx context.Context, statuses map[string]*MainStatus, runStates []*run.State) []Action {
+	c.mu.Lock()
+	var actions []Action
+	var descs []ActionDescriptor
+	for slug, st := range statuses {
+		w := c.mainWatches[slug]
+		if w == nil || st == nil {
+			continue
+		}
+		desc, ok := c.evaluateMain(w, st, runStates)
+		if !ok {
+			delete(c.mainWatches, slug)
+			continue
+		}
+		if desc != nil {
+			descs = append(descs, *desc)
+		}
+	}
+	c.mu.Unlock()
+
+	type launchResult struct {
+		slug    string
+		agentID string
+		err     error
+	}
+	var results []launchResult
+	for _, d := range descs {
+		if !c.leading() {
+			break
+		}
+		id, err := c.launchAgent(ctx, "", d.Repo, d.Prompt, "", d.Profile)
+		results = append(results, launchResult{slug: d.PRNumber, agentID: id, err: err})
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, r := range results {
+		w := c.mainWatches[r.slug]
+		if w == nil {
+			continue
+		}
+		w.Dispatches++
+		if r.err != nil {
+			c.logger.Error("failed to dispatch main watchdog agent", "repo", r.slug, "err", r.err)
+			actions = append(actions, Action{Type: "error", Detail: fmt.Sprintf("%s: main watchdog dispatch failed", r.slug), Error: truncateError(r.err.Error(), 120)})
+			continue
+		}
+		w.LastAgentID = r.agentID
+		w.DispatchedAfter = w.PRNumber
+		actions = append(actions, Action{Type: "launch", Detail: fmt.Sprintf("Main watchdog agent for %s (PR #%s)", r.slug, w.BrokenPR)})
+	}
+	c.checkpoint()
+	return actions
+}
+
+// evaluateMain advances one watch. It returns false when the watch is over,
+// and a launch descriptor (with PRNumber holding the watch's slug) when a
+// watchdog agent should be dispatched. Callers must hold c.mu.
+func (c *Controller) evaluateMain(w *MainWatch, st *MainStatus, runStates []*run.State) (*ActionDescriptor, bool) {
+	pol := c.policy(w.TargetRepo)
+
+	if !w.Broken {
+		switch st.MergeCI {
+		case "passing":
+			return nil, false
+		case "failing":
+			w.Broken = true
+			w.BrokenPR = w.PRNumber
+			w.BrokenSHA = st.MergeSHA
+			w.Branch = st.Branch
+			w.HeadSHA = st.HeadSHA
+			c.logger.Warn("merge broke the default branch", "repo", w.Slug, "pr", w.PRNumber, "sha", st.MergeSHA)
+			c.emitEvent(w.PRNumber, event.MainBroken, map[string]interface{}{
+				"repo":      w.Slug,
+				"branch":    st.Branch,
+				"sha":       st.MergeSHA,
+				"pr_number": w.PRNumber,
+				"pr_url":    w.PRURL,
+			})
+		default:
+			if c.now().Sub(w.MergedAt) > mainWatchTimeout {
+				c.logger.Info("no CI result on merge commit; ending main watch", "repo", w.Slug, "pr", w.PRNumber)
+				return nil, false
+			}
+			return nil, true
+		}
+	}
+
+	if st.HeadCI == "passing" {
+		c.logger.Info("default branch is green again", "repo", w.Slug)
+		return nil, false
+	}
+	if w.StoodDown || pol.MainWatchdog == MainWatchdogNotify || pol.MainWatchdog == MainWatchdogOff {
+		return nil, true
+	}
+
+	// Circuit breaker: never race a running watchdog agent, never fight
+	// someone else's push, dispatch again only after a merge that didn't
+	// fix main, and give up after the policy's agent budget.
+	if w.LastAgentID != "" {
+		for _, s := range runStates {
+			if s != nil && s.ID == w.LastAgentID && c.isRunning(s) {
+				return nil, true
+			}
+		}
+	}
+	if w.HeadSHA != "" && st.HeadSHA != "" && st.HeadSHA != w.HeadSHA {
+		w.StoodDown = true
+		c.logger.Info("default branch moved by someone else; main watchdog standing down", "repo", w.Slug, "head", st.HeadSHA)
+		return nil, true
+	}
+	w.HeadSHA = st.HeadSHA
+	if st.HeadCI != "failing" {
+		return nil, true
+	}
+	if w.Dispatches > 0 && w.PRNumber == w.DispatchedAfter {
+		return nil, true
+	}
+	if w.Dispatches >= pol.MaxMainAgents {
+		w.StoodDown = true
+		c.emitEvent(w.BrokenPR, event.AgentNeedsAttention, map[string]interface{}{
+			"reason":    fmt.Sprintf("%s is still broken after %d watchdog agent(s); standing down", w.Branch, w.Dispatches),
+			"repo":      w.Slug,
+			"pr_number": w.BrokenPR,
+		})
+		return nil, true
+	}
+	return &ActionDescriptor{
+		Type:     ActionLaunchAgent,
+		PRNumber: w.Slug,
+		Repo:     w.TargetRepo,
+		Prompt:   mainWatchdogPrompt(pol.MainWatchdog, w),
+		Profile:  pol.Profiles.MainWatchdog,
+	}, true
+}
+
+func mainWatchdogPrompt(action string, w *MainWatch) string {
+	broke := fmt.Sprintf("PR #%s was merged into %s of %s as commit %s, and CI on that commit is failing, so %s is broken.",
+		w.BrokenPR, w.Branch, w.Slug, w.BrokenSHA, w.Branch)
+	if action == MainWatchdogRevert {
+		return fmt.Sprintf("%s Open a PR that reverts it: branch from origin/%s, run `git revert --no-edit %s`, push, and open a PR titled \"Revert PR #%s\" that links the failing CI run. Do not try to fix the failure yourself.",
+			broke, w.Branch, w.BrokenSHA, w.BrokenPR)
+	}
+	return fmt.Sprintf("%s Find the failing checks (`gh run list --commit %s`, then `gh run view <run-id> --log-failed`), fix the failure on a new branch from origin/%s, run the tests, and open a PR. Revert PR #%s instead only if a fix is not feasible.",
+		broke, w.BrokenSHA, w.Branch, w.BrokenPR)
+}
@@ -0,0 +1,215 @@
+package pipeline
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/patflynn/klaus/internal/event"
+	"github.com/patflynn/klaus/internal/run"
+)
+
+// mergePR drives a tracked PR through a MERGED status so the controller
+// starts watching its repo's default branch.
+func mergePR(t *testing.T, c *Controller, prNumber string) {
+	t.Helper()
+	c.mu.Lock()
+	c.prStates[prNumber] = &PRPipelineState{PRNumber: prNumber, Stage: StageApproved}
+	c.mu.Unlock()
+	c.HandleGHStatus(context.Background(), map[string]*PRStatus{
+		prNumber: {PRNumber: prNumber, State: "MERGED", TargetRepo: "klaus", PRURL: "https://github.com/owner/repo/pull/" + prNumber},
+	}, nil)
+}
+
+func countEvents(t *testing.T, dir, eventType string) int {
+	t.Helper()
+	events, err := event.NewLog(filepath.Join(dir, "session")).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, e := range events {
+		if e.Type == eventType {
+			n++
+		}
+	}
+	return n
+}
+
+func TestMainWatchdog_GreenMergeEndsWatch(t *testing.T) {
+	c, dir := newTestController(t)
+	mergePR(t, c, "42")
+
+	watches := c.MainWatches()
+	if len(watches) != 1 || watches[0].Slug != "owner/repo" || watches[0].PRNumber != "42" {
+		t.Fatalf("watches = %+v, want one for owner/repo PR 42", watches)
+	}
+	c.HandleMainStatus(context.Background(), map[string]*MainStatus{
+		"owner/repo": {Branch: "main", MergeSHA: "abc", MergeCI: "pending", HeadSHA: "abc", HeadCI: "pending"},
+	}, nil)
+	if len(c.MainWatches()) != 1 {
+		t.Fatal("watch ended while CI was pending")
+	}
+	c.HandleMainStatus(context.Background(), map[string]*MainStatus{
+		"owner/repo": {Branch: "main", MergeSHA: "abc", MergeCI: "passing", HeadSHA: "abc", HeadCI: "passing"},
+	}, nil)
+	if len(c.MainWatches()) != 0 {
+		t.Error("watch should end once the merge commit passes CI")
+	}
+	if n := countEvents(t, dir, event.MainBroken); n != 0 {
+		t.Errorf("emitted %d main:broken events for a green merge", n)
+	}
+}
+
+func TestMainWatchdog_NotifyEmitsOnce(t *testing.T) {
+	c, dir := newTestController(t)
+	launched := 0
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launched++
+		return "agent-x", nil
+	})
+	mergePR(t, c, "42")
+
+	broken := map[string]*MainStatus{
+		"owner/repo": {Branch: "main", MergeSHA: "abc123", MergeCI: "failing", HeadSHA: "abc123", HeadCI: "failing"},
+	}
+	c.HandleMainStatus(context.Background(), broken, nil)
+	c.HandleMainStatus(context.Background(), broken, nil)
+
+	if n := countEvents(t, dir, event.MainBroken); n != 1 {
+		t.Errorf("main:broken emitted %d times, want 1", n)
+	}
+	if launched != 0 {
+		t.Errorf("notify mode launched %d agents", launched)
+	}
+}
+
+func TestMainWatchdog_RevertAndCircuitBreaker(t *testing.T) {
+	c, dir := newTestController(t)
+	p := DefaultPolicy()
+	p.MainWatchdog = MainWatchdogRevert
+	c.SetPolicyResolver(func(string) Policy { return p })
+
+	var prompts []string
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		if prNumber != "" || repo != "klaus" {
+			t.Errorf("watchdog launch got pr=%q repo=%q, want no PR and repo klaus", prNumber, repo)
+		}
+		prompts = append(prompts, prompt)
+		return "agent-revert", nil
+	})
+	mergePR(t, c, "42")
+
+	broken := map[string]*MainStatus{
+		"owner/repo": {Branch: "main", MergeSHA: "abc123", MergeCI: "failing", HeadSHA: "abc123", HeadCI: "failing"},
+	}
+	actions := c.HandleMainStatus(context.Background(), broken, nil)
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "git revert --no-edit abc123") {
+		t.Fatalf("expected one revert agent, got prompts %q", prompts)
+	}
+	if len(actions) != 1 || actions[0].Type != "launch" {
+		t.Errorf("actions = %v, want one launch", actions)
+	}
+
+	// The agent is running, then finishes with its revert PR still open:
+	// no second agent either way.
+	running := []*run.State{{ID: "agent-revert", TmuxPane: strPtr("%1")}}
+	c.HandleMainStatus(context.Background(), broken, running)
+	c.HandleMainStatus(context.Background(), broken, nil)
+	if len(prompts) != 1 {
+		t.Fatalf("dispatched %d agents before the revert merged", len(prompts))
+	}
+
+	// The revert PR merges but main is still red: the agent budget (1) is
+	// spent, so the watchdog stands down and asks for a human.
+	mergePR(t, c, "43")
+	c.HandleMainStatus(context.Background(), map[string]*MainStatus{
+		"owner/repo": {Branch: "main", MergeSHA: "def456", MergeCI: "failing", HeadSHA: "def456", HeadCI: "failing"},
+	}, nil)
+	if len(prompts) != 1 {
+		t.Errorf("dispatched past the agent budget: %d agents", len(prompts))
+	}
+	if n := countEvents(t, dir, event.AgentNeedsAttention); n != 1 {
+		t.Errorf("needs-attention emitted %d times, want 1", n)
+	}
+	if n := countEvents(t, dir, event.MainBroken); n != 1 {
+		t.Errorf("main:broken emitted %d times for one breakage, want 1", n)
+	}
+
+	// Main goes green: the watch ends and the breaker resets.
+	c.HandleMainStatus(context.Background(), map[string]*MainStatus{
+		"owner/repo": {Branch: "main", MergeSHA: "def456", MergeCI: "failing", HeadSHA: "fff000", HeadCI: "passing"},
+	}, nil)
+	if len(c.MainWatches()) != 0 {
+		t.Error("watch should end when main is green again")
+	}
+}
+
+func TestMainWatchdog_StandsDownWhenSomeoneElsePushes(t *testing.T) {
+	c, _ := newTestController(t)
+	p := DefaultPolicy()
+	p.MainWatchdog = MainWatchdogFix
+	p.MaxMainAgents = 3
+	c.SetPolicyResolver(func(string) Policy { return p })
+	launched := 0
+	c.SetLaunchAgent(func(ctx context.Context, prNumber, repo, prompt, resumeFrom, profile string) (string, error) {
+		launched++
+		return "agent-fix", nil
+	})
+	mergePR(t, c, "42")
+
+	c.HandleMainStatus(context.Background(), map[string]*MainStatus{
+		"owner/repo": {Branch: "main", MergeSHA: "abc", MergeCI: "failing", HeadSHA: "abc", HeadCI: "failing"},
+	}, nil)
+	// A human pushes a (still failing) commit straight to main.
+	for i := 0; i < 2; i++ {
+		c.HandleMainStatus(context.Background(), map[string]*MainStatus{
+			"owner/repo": {Branch: "main", MergeSHA: "abc", MergeCI: "failing", HeadSHA: "human1", HeadCI: "failing"},
+		}, nil)
+	}
+	if launched != 1 {
+		t.Errorf("launched %d agents, want 1 (stand down after someone else's push)", launched)
+	}
+	if w := c.MainWatches(); len(w) != 1 || !w[0].StoodDown {
+		t.Errorf("watch = %+v, want stood down", w)
+	}
+}
+
+func TestMainWatchdog_WatchesSurviveRestart(t *testing.T) {
+	c, dir := newTestController(t)
+	path := filepath.Join(dir, "session", StateFileName)
+	if err := c.LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+	mergePR(t, c, "42")
+
+	c2, _ := newTestController(t)
+	if err := c2.LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+	if w := c2.MainWatches(); len(w) != 1 || w[0].PRNumber != "42" {
+		t.Errorf("restored watches = %+v, want PR 42's", w)
+	}
+}
+
+func TestMainWatchdog_BackportMergeLeavesWatchAlone(t *testing.T) {
+	c, _ := newTestController(t)
+	mergePR(t, c, "42")
+	c.HandleMainStatus(context.Background(), map[string]*MainStatus{
+		"owner/repo": {Branch: "main", MergeSHA: "abc123", MergeCI: "failing", HeadSHA: "abc123", HeadCI: "failing"},
+	}, nil)
+
+	// A backport merging into its release branch says nothing about main.
+	c.mu.Lock()
+	c.prStates["50"] = &PRPipelineState{PRNumber: "50", Stage: StageApproved}
+	c.mu.Unlock()
+	c.HandleGHStatus(context.Background(), map[string]*PRStatus{
+		"50": {PRNumber: "50", State: "MERGED", TargetRepo: "klaus", BaseBranch: "release-1.4", PRURL: "https://github.com/owner/repo/pull/50"},
+	}, nil)
+
+	watches := c.MainWatches()
+	if len(watches) != 1 || watches[0].PRNumber != "42" || !watches[0].Broken || watches[0].BrokenPR != "42" {
+		t.Errorf("watches = %+v, want main's broken watch on PR 42 untouched", watches)
+	}
+}
@@ -0,0 +1,372 @@
+// Package plan holds task plans: batches of agent tasks declared in one
+// YAML or JSON file, some of which wait on others (klaus launch --plan).
+// Plans live in the session directory; the pipeline leader launches each
+// held task once the tasks it depends on are done.
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/patflynn/klaus/internal/run"
+	"gopkg.in/yaml.v3"
+)
+
+// When a held task's dependencies count as done (Plan.LaunchWhen).
+const (
+	LaunchWhenMerged    = "merged"    // the dependencies' PRs merged (the default)
+	LaunchWhenCompleted = "completed" // the dependencies' agents finished
+)
+
+// Plan is a batch of tasks launched together.
+type Plan struct {
+	ID         string  `json:"id" yaml:"-"`
+	Source     string  `json:"source,omitempty" yaml:"-"` // the plan file it was read from
+	CreatedAt  string  `json:"created_at" yaml:"-"`
+	LaunchWhen string  `json:"launch_when,omitempty" yaml:"launch_when"`
+	Tasks      []*Task `json:"tasks" yaml:"tasks"`
+}
+
+// Task is one agent launch in a plan.
+type Task struct {
+	ID        string   `json:"id" yaml:"id"` // defaults to task-<n>, 1-based
+	Prompt    string   `json:"prompt" yaml:"prompt"`
+	Repo      string   `json:"repo,omitempty" yaml:"repo"`
+	Issue     string   `json:"issue,omitempty" yaml:"issue"`
+	Budget    string   `json:"budget,omitempty" yaml:"budget"`
+	Profile   string   `json:"profile,omitempty" yaml:"profile"` // agent profile from config "profiles"
+	DependsOn []string `json:"depends_on,omitempty" yaml:"depends_on"`
+
+	// Set once the task is launched.
+	RunID       string `json:"run_id,omitempty" yaml:"-"`
+	LaunchedAt  string `json:"launched_at,omitempty" yaml:"-"`
+	LaunchError string `json:"launch_error,omitempty" yaml:"-"`
+	Status      Status `json:"status,omitempty" yaml:"-"` // last status seen; stands in once the run is cleaned up
+}
+
+// Launched reports whether the task has been launched (or tried to be).
+func (t *Task) Launched() bool {
+	return t.LaunchedAt != ""
+}
+
+// Parse reads a plan file. JSON is a subset of YAML, so both are read the
+// same way. Tasks without an ID are numbered.
+func Parse(data []byte) (*Plan, error) {
+	var p Plan
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parsing plan: %w", err)
+	}
+	for i, t := range p.Tasks {
+		if t == nil {
+			return nil, fmt.Errorf("task %d is empty", i+1)
+		}
+		t.ID = strings.TrimSpace(t.ID)
+		if t.ID == "" {
+			t.ID = fmt.Sprintf("task-%d", i+1)
+		}
+		t.Issue = strings.TrimPrefix(t.Issue, "#")
+	}
+	if p.LaunchWhen == "" {
+		p.LaunchWhen = LaunchWhenMerged
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Validate reports an empty plan, tasks without a prompt, duplicate IDs,
+// unknown or circular dependencies and an unknown launch_when.
+func (p *Plan) Validate() error {
+	switch p.LaunchWhen {
+	case LaunchWhenMerged, LaunchWhenCompleted:
+	default:
+		return fmt.Errorf("unknown launch_when %q (want merged or completed)", p.LaunchWhen)
+	}
+	if len(p.Tasks) == 0 {
+		return fmt.Errorf("plan has no tasks")
+	}
+	byID := make(map[string]*Task, len(p.Tasks))
+	for _, t := range p.Tasks {
+		if strings.TrimSpace(t.Prompt) == "" {
+			return fmt.Errorf("task %s has no prompt", t.ID)
+		}
+		if byID[t.ID] != nil {
+			return fmt.Errorf("duplicate task id %q", t.ID)
+		}
+		byID[t.ID] = t
+	}
+	for _, t := range p.Tasks {
+		for _, dep := range t.DependsOn {
+			if byID[dep] == nil {
+				return fmt.Errorf("task %s depends on unknown task %q", t.ID, dep)
+			}
+		}
+	}
+
+	// Depth-first search for a cycle; state is 1 while on the stack, 2 once done.
+	state := make(map[string]int, len(p.Tasks))
+	var visit func(id string, path []string) error
+	visit = func(id string, path []string) error {
+		switch state[id] {
+		case 1:
+			return fmt.Errorf("circular dependency: %s", strings.Join(append(path, id), " → "))
+		case 2:
+			return nil
+		}
+		state[id] = 1
+		for _, dep := range byID[id].DependsOn {
+			if err := visit(dep, append(path, id)); err != nil {
+				return err
+			}
+		}
+		state[id] = 2
+		return nil
+	}
+	for _, t := range p.Tasks {
+		if err := visit(t.ID, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Task returns the task with the given ID, or nil.
+func (p *Plan) Task(id string) *Task {
+	for _, t := range p.Tasks {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// Status is where a task stands.
+type Status string
+
+const (
+	StatusHeld      Status = "held"      // waiting on its dependencies
+	StatusBlocked   Status = "blocked"   // a dependency failed; it won't launch
+	StatusRunning   Status = "running"   // its agent is working
+	StatusCompleted Status = "completed" // its agent finished; its PR, if any, is open
+	StatusMerged    Status = "merged"
+	StatusFailed    Status = "failed" // the launch or the agent failed, or its PR was closed
+)
+
+// Run returns the run launched for t, or nil.
+func (p *Plan) Run(t *Task, states []*run.State) *run.State {
+	for _, s := range states {
+		if s.PlanID != nil && *s.PlanID == p.ID && s.PlanTask != nil && *s.PlanTask == t.ID {
+			return s
+		}
+	}
+	return nil
+}
+
+// Status reports where t stands given the session's runs. running says
+// whether a run's agent is still working.
+func (p *Plan) Status(t *Task, states []*run.State, running func(*run.State) bool) Status {
+	return p.status(t, states, running, make(map[string]Status))
+}
+
+func (p *Plan) status(t *Task, states []*run.State, running func(*run.State) bool, memo map[string]Status) Status {
+	if st, ok := memo[t.ID]; ok {
+		return st
+	}
+	st := p.taskStatus(t, states, running, memo)
+	memo[t.ID] = st
+	return st
+}
+
+func (p *Plan) taskStatus(t *Task, states []*run.State, running func(*run.State) bool, memo map[string]Status) Status {
+	if t.LaunchError != "" {
+		return StatusFailed
+	}
+	if t.Launched() {
+		s := p.Run(t, states)
+		switch {
+		case s == nil && t.Status != "":
+			// Still launching, or the run was cleaned up: go by what was
+			// last seen of it.
+			return t.Status
+		case s == nil:
+			return StatusCompleted
+		case s.MergedAt != nil:
+			return StatusMerged
+		case s.FailureReason != nil || s.ClosedAt != nil:
+			return StatusFailed
+		case running(s) || (s.CostUSD == nil && s.DurationMS == nil):
+			return StatusRunning
+		default:
+			return StatusCompleted
+		}
+	}
+	for _, id := range t.DependsOn {
+		switch p.status(p.Task(id), states, running, memo) {
+		case StatusFailed, StatusBlocked:
+			return StatusBlocked
+		}
+	}
+	return StatusHeld
+}
+
+// Record stores each task's current status on it, so a task keeps its
+// outcome after its run is cleaned up. It reports whether any changed.
+func (p *Plan) Record(states []*run.State, running func(*run.State) bool) bool {
+	memo := make(map[string]Status)
+	changed := false
+	for _, t := range p.Tasks {
+		if st := p.status(t, states, running, memo); st != t.Status {
+			t.Status = st
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Ready returns the tasks not yet launched whose dependencies are done, in
+// plan order.
+func (p *Plan) Ready(states []*run.State, running func(*run.State) bool) []*Task {
+	memo := make(map[string]Status)
+	var out []*Task
+	for _, t := range p.Tasks {
+		if t.Launched() || p.status(t, states, running, memo) != StatusHeld {
+			continue
+		}
+		ready := true
+		for _, id := range t.DependsOn {
+			if !p.done(p.status(p.Task(id), states, running, memo)) {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// done reports whether a dependency in status st lets its dependents launch.
+func (p *Plan) done(st Status) bool {
+	if p.LaunchWhen == LaunchWhenCompleted {
+		return st == StatusCompleted || st == StatusMerged
+	}
+	return st == StatusMerged
+}
+
+// Finished reports whether every task has run its course or can't launch.
+func (p *Plan) Finished(states []*run.State, running func(*run.State) bool) bool {
+	memo := make(map[string]Status)
+	for _, t := range p.Tasks {
+		switch p.status(t, states, running, memo) {
+		case StatusHeld, StatusRunning:
+			return false
+		case StatusCompleted:
+			if p.LaunchWhen == LaunchWhenMerged && p.hasDependents(t) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (p *Plan) hasDependents(t *Task) bool {
+	for _, o := range p.Tasks {
+		for _, id := range o.DependsOn {
+			if id == t.ID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Counts tallies the plan's tasks by status.
+func (p *Plan) Counts(states []*run.State, running func(*run.State) bool) map[Status]int {
+	memo := make(map[string]Status)
+	counts := make(map[Status]int)
+	for _, t := range p.Tasks {
+		counts[p.status(t, states, running, memo)]++
+	}
+	return counts
+}
+
+// Summary is a one-line account of the plan's progress, e.g.
+// "2/5 merged, 1 running, 2 held".
+func (p *Plan) Summary(states []*run.State, running func(*run.State) bool) string {
+	counts := p.Counts(states, running)
+	done, word := counts[StatusMerged], "merged"
+	rest := []Status{StatusRunning, StatusCompleted, StatusHeld, StatusBlocked, StatusFailed}
+	if p.LaunchWhen == LaunchWhenCompleted {
+		done, word = done+counts[StatusCompleted], "done"
+		rest = slices.DeleteFunc(rest, func(st Status) bool { return st == StatusCompleted })
+	}
+	parts := []string{fmt.Sprintf("%d/%d %s", done, len(p.Tasks), word)}
+	for _, st := range rest {
+		if n := counts[st]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, st))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Dir returns the directory plans are kept in for a session base dir.
+func Dir(baseDir string) string {
+	return filepath.Join(baseDir, "plans")
+}
+
+// Save writes the plan to the session's plan directory.
+func Save(baseDir string, p *Plan) error {
+	if err := os.MkdirAll(Dir(baseDir), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling plan: %w", err)
+	}
+	return os.WriteFile(filepath.Join(Dir(baseDir), p.ID+".json"), append(data, '\n'), 0o644)
+}
+
+// Load reads a saved plan.
+func Load(baseDir, id string) (*Plan, error) {
+	data, err := os.ReadFile(filepath.Join(Dir(baseDir), id+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parsing plan %s: %w", id, err)
+	}
+	return &p, nil
+}
+
+// List returns the session's plans, oldest first.
+func List(baseDir string) ([]*Plan, error) {
+	entries, err := os.ReadDir(Dir(baseDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var plans []*Plan
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		p, err := Load(baseDir, id)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, p)
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].CreatedAt < plans[j].CreatedAt })
+	return plans, nil
+}
@@ -0,0 +1,172 @@
+package plan
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/patflynn/klaus/internal/run"
+)
+
+func notRunning(*run.State) bool { return false }
+
+func taskIDs(tasks []*Task) []string {
+	var out []string
+	for _, t := range tasks {
+		out = append(out, t.ID)
+	}
+	return out
+}
+
+func TestParse(t *testing.T) {
+	yamlPlan := `
+tasks:
+  - id: schema
+    prompt: Add the audit_log table
+    repo: owner/repo
+    issue: "#12"
+    budget: 5
+  - prompt: Expose it over the API
+    depends_on: [schema]
+`
+	jsonPlan := `{"launch_when": "completed", "tasks": [
+		{"id": "schema", "prompt": "Add the audit_log table", "repo": "owner/repo", "issue": 12, "budget": "5"},
+		{"prompt": "Expose it over the API", "depends_on": ["schema"]}
+	]}`
+
+	for name, data := range map[string]string{"yaml": yamlPlan, "json": jsonPlan} {
+		p, err := Parse([]byte(data))
+		if err != nil {
+			t.Fatalf("%s: Parse() error = %v", name, err)
+		}
+		if got := taskIDs(p.Tasks); !reflect.DeepEqual(got, []string{"schema", "task-2"}) {
+			t.Errorf("%s: task ids = %v", name, got)
+		}
+		schema := p.Tasks[0]
+		if schema.Repo != "owner/repo" || schema.Issue != "12" || schema.Budget != "5" {
+			t.Errorf("%s: schema task = %+v", name, schema)
+		}
+		if !reflect.DeepEqual(p.Tasks[1].DependsOn, []string{"schema"}) {
+			t.Errorf("%s: depends_on = %v", name, p.Tasks[1].DependsOn)
+		}
+	}
+
+	p, _ := Parse([]byte(yamlPlan))
+	if p.LaunchWhen != LaunchWhenMerged {
+		t.Errorf("default launch_when = %q, want merged", p.LaunchWhen)
+	}
+}
+
+func TestParseRejectsBadPlans(t *testing.T) {
+	tests := map[string]struct {
+		plan string
+		want string
+	}{
+		"empty":      {`tasks: []`, "no tasks"},
+		"no prompt":  {`tasks: [{id: a}]`, "task a has no prompt"},
+		"duplicate":  {`tasks: [{id: a, prompt: x}, {id: a, prompt: y}]`, `duplicate task id "a"`},
+		"unknown":    {`tasks: [{id: a, prompt: x, depends_on: [b]}]`, `unknown task "b"`},
+		"self":       {`tasks: [{id: a, prompt: x, depends_on: [a]}]`, "circular dependency: a → a"},
+		"cycle":      {`tasks: [{id: a, prompt: x, depends_on: [c]}, {id: b, prompt: y, depends_on: [a]}, {id: c, prompt: z, depends_on: [b]}]`, "circular dependency: a → c → b → a"},
+		"launchWhen": {`{launch_when: soon, tasks: [{prompt: x}]}`, `unknown launch_when "soon"`},
+		"syntax":     {`tasks: [`, "parsing plan"},
+	}
+	for name, tt := range tests {
+		_, err := Parse([]byte(tt.plan))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Parse() error = %v, want %q", name, err, tt.want)
+		}
+	}
+}
+
+func TestReadyAndStatus(t *testing.T) {
+	p, err := Parse([]byte(`
+tasks:
+  - {id: a, prompt: x}
+  - {id: b, prompt: y, depends_on: [a]}
+  - {id: c, prompt: z, depends_on: [a, b]}
+  - {id: d, prompt: w}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.ID = "plan-1"
+	if got := taskIDs(p.Ready(nil, notRunning)); !reflect.DeepEqual(got, []string{"a", "d"}) {
+		t.Fatalf("initially ready = %v, want [a d]", got)
+	}
+
+	planID, a, d := "plan-1", "a", "d"
+	cost := 1.0
+	states := []*run.State{
+		{ID: "run-a", PlanID: &planID, PlanTask: &a, CostUSD: &cost},
+		{ID: "run-d", PlanID: &planID, PlanTask: &d},
+	}
+	p.Task("a").LaunchedAt, p.Task("a").RunID = "t", "run-a"
+	p.Task("d").LaunchedAt, p.Task("d").RunID = "t", "run-d"
+
+	if st := p.Status(p.Task("a"), states, notRunning); st != StatusCompleted {
+		t.Errorf("a = %s, want completed", st)
+	}
+	if st := p.Status(p.Task("d"), states, notRunning); st != StatusRunning {
+		t.Errorf("d = %s, want running (not finalized)", st)
+	}
+	if got := p.Ready(states, notRunning); len(got) != 0 {
+		t.Errorf("ready before a merges = %v, want none", taskIDs(got))
+	}
+
+	p.LaunchWhen = LaunchWhenCompleted
+	if got := taskIDs(p.Ready(states, notRunning)); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("ready with launch_when completed = %v, want [b]", got)
+	}
+	p.LaunchWhen = LaunchWhenMerged
+
+	merged := "2026-10-17T00:00:00Z"
+	states[0].MergedAt = &merged
+	if got := taskIDs(p.Ready(states, notRunning)); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("ready after a merges = %v, want [b]", got)
+	}
+	if got, want := p.Summary(states, notRunning), "1/4 merged, 1 running, 2 held"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+
+	// b fails to launch: c is blocked, and the plan is done once d is.
+	p.Task("b").LaunchedAt, p.Task("b").LaunchError = "t", "boom"
+	if st := p.Status(p.Task("c"), states, notRunning); st != StatusBlocked {
+		t.Errorf("c = %s, want blocked", st)
+	}
+	if p.Finished(states, notRunning) {
+		t.Error("plan finished while d is running")
+	}
+	states[1].DurationMS = new(int64)
+	if !p.Finished(states, notRunning) {
+		t.Error("plan should be finished")
+	}
+
+	// Once a's run is cleaned up, the recorded status stands in for it.
+	p.Record(states, notRunning)
+	if st := p.Status(p.Task("a"), states[1:], notRunning); st != StatusMerged {
+		t.Errorf("a after cleanup = %s, want merged", st)
+	}
+}
+
+func TestSaveLoadList(t *testing.T) {
+	dir := t.TempDir()
+	if plans, err := List(dir); err != nil || plans != nil {
+		t.Fatalf("List() on an empty session = %v, %v", plans, err)
+	}
+	for _, p := range []*Plan{
+		{ID: "p2", CreatedAt: "2026-10-17T10:00:00Z", LaunchWhen: LaunchWhenMerged, Tasks: []*Task{{ID: "a", Prompt: "x"}}},
+		{ID: "p1", CreatedAt: "2026-10-16T10:00:00Z", LaunchWhen: LaunchWhenMerged, Tasks: []*Task{{ID: "a", Prompt: "x", LaunchedAt: "t"}}},
+	} {
+		if err := Save(dir, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plans, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 2 || plans[0].ID != "p1" || !plans[0].Tasks[0].Launched() {
+		t.Errorf("List() = %+v, want p1 (launched) then p2", plans)
+	}
+}
@@ -30,6 +30,7 @@ type State struct {
 	CloneDir        *string  `json:"clone_dir,omitempty"`
 	Host            *string  `json:"host,omitempty"`
 	MergedAt        *string  `json:"merged_at,omitempty"`
+	ClosedAt        *string  `json:"closed_at,omitempty"` // set when the PR was closed without merging; cleared if it is reopened
 	DashboardPane   *string  `json:"dashboard_pane,omitempty"`
 	CoordinatorPane *string  `json:"coordinator_pane,omitempty"` // tmux pane running the coordinator/claude session
 	Approved        *bool    `json:"approved,omitempty"`
@@ -39,6 +40,36 @@ type State struct {
 	ClaudeSessionID *string  `json:"claude_session_id,omitempty"` // Claude conversation UUID for --resume
 	RepoRoot        *string  `json:"repo_root,omitempty"`         // absolute path to base repo for worktree recreation
 	FailureReason   *string  `json:"failure_reason,omitempty"`    // set when the agent crashed (e.g. error_during_execution); suppresses success events and blocks resume chaining
+	ParentRunID     *string  `json:"parent_run_id,omitempty"`     // run whose branch this run was launched on (klaus launch --base)
+	ParentPR        *string  `json:"parent_pr,omitempty"`         // PR this run's PR is stacked on, when known at launch
+	BaseBranch      *string  `json:"base_branch,omitempty"`       // branch this run's PR targets while stacked (cleared once retargeted to the default branch), or a backport's release branch
+	BackportOf      *string  `json:"backport_of,omitempty"`       // merged PR this run's PR backports onto BaseBranch (klaus backport)
+	PlanID          *string  `json:"plan_id,omitempty"`           // plan this run was launched for (klaus launch --plan)
+	PlanTask        *string  `json:"plan_task,omitempty"`         // the plan task this run carries out
+	Profile         *string  `json:"profile,omitempty"`           // agent profile the run was launched with (config "profiles")
+	IssueComment    *string  `json:"issue_comment,omitempty"`     // URL of the comment posted on Issue at launch (config issue_comments)
+	IssueLabel      *string  `json:"issue_label,omitempty"`       // label the issue watcher launched the run for (pipeline.issue_watch)
+	CandidateGroup  *string  `json:"candidate_group,omitempty"`   // best-of-N launch the run is one candidate of (klaus launch --candidates)
+	CandidateCount  int      `json:"candidate_count,omitempty"`   // how many candidates the group launched
+	CandidateDone   bool     `json:"candidate_done,omitempty"`    // the candidate's agent finished; its worktree is kept until the group is compared
+
+	CandidateResult *CandidateResult `json:"candidate_result,omitempty"` // how the candidate scored when its group was compared
+}
+
+// CandidateResult is how a best-of-N candidate scored against the others
+// launched from the same prompt.
+type CandidateResult struct {
+	Rank         int            `json:"rank"` // 1 is the winner, unless it is disqualified
+	Score        int            `json:"score"`
+	Commits      int            `json:"commits"`
+	DiffLines    int            `json:"diff_lines"`              // lines added plus lines removed
+	Verified     bool           `json:"verified"`                // every build and test command passed
+	VerifyFailed string         `json:"verify_failed,omitempty"` // the command that failed
+	LintFailed   []string       `json:"lint_failed,omitempty"`   // pre-review linters that failed
+	Findings     map[string]int `json:"findings,omitempty"`      // pre-review findings by severity
+	ReviewError  string         `json:"review_error,omitempty"`  // the pre-review couldn't run
+	Disqualified string         `json:"disqualified,omitempty"`  // why it can't win, e.g. it made no commits
+	ComparedAt   string         `json:"compared_at"`
 }
 
 // TmuxDeps abstracts tmux pane operations so callers can inject test doubles.
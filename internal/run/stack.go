@@ -0,0 +1,106 @@
+package run
+
+import "strings"
+
+// PRNumber returns the number of the PR a run opened or pushed to, taken
+// from PRURL, or "" if the run has no PR yet.
+func (s *State) PRNumber() string {
+	if s.PRURL == nil {
+		return ""
+	}
+	url := strings.TrimRight(*s.PRURL, "/")
+	if i := strings.LastIndexByte(url, '/'); i >= 0 {
+		return url[i+1:]
+	}
+	return ""
+}
+
+// StackParentPR returns the PR that s's PR is stacked on: ParentPR when it
+// was known at launch, otherwise the PR of the ParentRunID run. It returns
+// "" when s isn't stacked or its parent hasn't opened a PR yet.
+func StackParentPR(s *State, states []*State) string {
+	if s.ParentPR != nil && *s.ParentPR != "" {
+		return *s.ParentPR
+	}
+	if s.ParentRunID == nil {
+		return ""
+	}
+	for _, p := range states {
+		if p.ID == *s.ParentRunID {
+			return p.PRNumber()
+		}
+	}
+	return ""
+}
+
+// StackChildren returns the PRs still stacked on prNumber: PRs opened by
+// runs launched on its branch that haven't been retargeted yet. Each PR is
+// listed once, in first-seen order.
+func StackChildren(prNumber string, states []*State) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, s := range states {
+		if s.BaseBranch == nil || StackParentPR(s, states) != prNumber {
+			continue
+		}
+		pr := s.PRNumber()
+		if pr == "" || seen[pr] {
+			continue
+		}
+		seen[pr] = true
+		out = append(out, pr)
+	}
+	return out
+}
+
+// StackedOn returns the PR that prNumber is still stacked on, or "" when it
+// targets the default branch.
+func StackedOn(prNumber string, states []*State) string {
+	for _, s := range states {
+		if s.BaseBranch != nil && s.PRNumber() == prNumber {
+			if parent := StackParentPR(s, states); parent != "" {
+				return parent
+			}
+		}
+	}
+	return ""
+}
+
+// StackOrder reorders prNumbers so that every PR comes after the PR it is
+// stacked on, when both are listed. PRs are otherwise kept in the given
+// order.
+func StackOrder(prNumbers []string, states []*State) []string {
+	listed := make(map[string]bool, len(prNumbers))
+	for _, pr := range prNumbers {
+		listed[pr] = true
+	}
+	parent := make(map[string]string)
+	for _, s := range states {
+		if pr := s.PRNumber(); pr != "" && listed[pr] {
+			if p := StackParentPR(s, states); p != "" && listed[p] && p != pr {
+				parent[pr] = p
+			}
+		}
+	}
+
+	out := make([]string, 0, len(prNumbers))
+	placed := make(map[string]bool, len(prNumbers))
+	var place func(pr string, depth int)
+	place = func(pr string, depth int) {
+		if placed[pr] {
+			return
+		}
+		// depth bounds a (corrupt) cycle in the recorded parents.
+		if p, ok := parent[pr]; ok && depth < len(prNumbers) {
+			place(p, depth+1)
+		}
+		if !placed[pr] {
+			placed[pr] = true
+			out = append(out, pr)
+		}
+	}
+	for _, pr := range prNumbers {
+		place(pr, 0)
+	}
+	return out
+}
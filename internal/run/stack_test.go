@@ -0,0 +1,70 @@
+package run
+
+import (
+	"reflect"
+	"testing"
+)
+
+func stackRun(id, pr, parentRun, parentPR, base string) *State {
+	s := &State{ID: id}
+	if pr != "" {
+		u := "https://github.com/o/r/pull/" + pr
+		s.PRURL = &u
+	}
+	if parentRun != "" {
+		s.ParentRunID = &parentRun
+	}
+	if parentPR != "" {
+		s.ParentPR = &parentPR
+	}
+	if base != "" {
+		s.BaseBranch = &base
+	}
+	return s
+}
+
+func TestStackRelations(t *testing.T) {
+	states := []*State{
+		stackRun("a", "10", "", "", ""),
+		// b was launched on a before a opened its PR; the parent PR is
+		// resolved through the parent run.
+		stackRun("b", "11", "a", "", "agent/a"),
+		stackRun("c", "12", "", "11", "agent/b"),
+		// c's PR fix run carries no stack fields.
+		{ID: "c-fix", Type: "pr-fix", PRURL: strPtr("https://github.com/o/r/pull/12")},
+		// d was stacked on a but already retargeted.
+		stackRun("d", "13", "a", "10", ""),
+		// e is stacked on b but has no PR yet.
+		stackRun("e", "", "b", "", "agent/b"),
+	}
+
+	if got := StackParentPR(states[1], states); got != "10" {
+		t.Errorf("StackParentPR(b) = %q, want 10 via parent run", got)
+	}
+	if got := StackParentPR(states[5], states); got != "11" {
+		t.Errorf("StackParentPR(e) = %q, want 11", got)
+	}
+	if got := StackChildren("10", states); !reflect.DeepEqual(got, []string{"11"}) {
+		t.Errorf("StackChildren(10) = %v, want [11] (13 was retargeted)", got)
+	}
+	if got := StackChildren("11", states); !reflect.DeepEqual(got, []string{"12"}) {
+		t.Errorf("StackChildren(11) = %v, want [12] (e has no PR)", got)
+	}
+	if got := StackedOn("12", states); got != "11" {
+		t.Errorf("StackedOn(12) = %q, want 11", got)
+	}
+	if got := StackedOn("13", states); got != "" {
+		t.Errorf("StackedOn(13) = %q, want none after retarget", got)
+	}
+
+	order := StackOrder([]string{"12", "99", "11", "10"}, states)
+	if want := []string{"10", "11", "12", "99"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("StackOrder = %v, want %v", order, want)
+	}
+	// A parent missing from the list doesn't hold its child back.
+	if order := StackOrder([]string{"12", "13"}, states); !reflect.DeepEqual(order, []string{"12", "13"}) {
+		t.Errorf("StackOrder without parents = %v", order)
+	}
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,143 @@
+// Package verify detects and runs a project's build and test commands, so
+// klaus can check a branch still works after rewriting it (e.g. a rebase in
+// 'klaus merge').
+package verify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Result is the outcome of one verification command.
+type Result struct {
+	Command  string
+	Passed   bool
+	Output   string // combined stdout and stderr, trimmed
+	Duration time.Duration
+}
+
+// Detect returns build and test commands for the project in dir, from the
+// ecosystem marker files at its root: go.mod, package.json, Cargo.toml and
+// pyproject.toml. Projects with a flake.nix run those commands inside
+// `nix develop`, or `nix flake check` when no other marker is present. It
+// returns nil when nothing is recognized.
+func Detect(dir string) []string {
+	var cmds []string
+	if exists(dir, "go.mod") {
+		cmds = append(cmds, "go build ./...", "go test ./...")
+	}
+	if exists(dir, "package.json") {
+		cmds = append(cmds, nodeCommands(dir)...)
+	}
+	if exists(dir, "Cargo.toml") {
+		cmds = append(cmds, "cargo build", "cargo test")
+	}
+	if exists(dir, "pyproject.toml") {
+		cmds = append(cmds, pythonCommands(dir)...)
+	}
+	if exists(dir, "flake.nix") {
+		if len(cmds) == 0 {
+			return []string{"nix flake check"}
+		}
+		for i, c := range cmds {
+			cmds[i] = "nix develop --command " + c
+		}
+	}
+	return cmds
+}
+
+// nodeCommands installs dependencies with the package manager the lockfile
+// names, then runs the build and test scripts package.json defines.
+func nodeCommands(dir string) []string {
+	pm, install := "npm", "npm ci"
+	switch {
+	case exists(dir, "pnpm-lock.yaml"):
+		pm, install = "pnpm", "pnpm install --frozen-lockfile"
+	case exists(dir, "yarn.lock"):
+		pm, install = "yarn", "yarn install --frozen-lockfile"
+	case !exists(dir, "package-lock.json"):
+		install = "npm install"
+	}
+	cmds := []string{install}
+
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		_ = json.Unmarshal(data, &pkg)
+	}
+	if pkg.Scripts["build"] != "" {
+		cmds = append(cmds, pm+" run build")
+	}
+	// npm init's placeholder test script always fails.
+	if t := pkg.Scripts["test"]; t != "" && !strings.Contains(t, "no test specified") {
+		cmds = append(cmds, pm+" test")
+	}
+	return cmds
+}
+
+// pythonCommands runs the tests through the project's lockfile tool, or
+// byte-compiles the sources when there's no tool to install dependencies
+// with.
+func pythonCommands(dir string) []string {
+	switch {
+	case exists(dir, "uv.lock"):
+		return []string{"uv run pytest"}
+	case exists(dir, "poetry.lock"):
+		return []string{"poetry install --no-interaction", "poetry run pytest"}
+	default:
+		return []string{"python3 -m compileall -q ."}
+	}
+}
+
+func exists(dir, name string) bool {
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
+
+// Run runs commands in dir through sh, in order, and stops at the first
+// one that fails. The failing result, if any, is last.
+func Run(dir string, commands []string) []Result {
+	var results []Result
+	for _, c := range commands {
+		start := time.Now()
+		out, err := Exec(context.Background(), dir, "sh", "-c", c)
+		results = append(results, Result{
+			Command:  c,
+			Passed:   err == nil,
+			Output:   out,
+			Duration: time.Since(start),
+		})
+		if err != nil {
+			break
+		}
+	}
+	return results
+}
+
+// Exec runs a command in dir and returns its trimmed combined output. The
+// command is killed if ctx is cancelled.
+func Exec(ctx context.Context, dir, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	return strings.TrimSpace(out.String()), err
+}
+
+// LastLine returns the last line of command output, which for git and most
+// build tools carries the error.
+func LastLine(out string) string {
+	if i := strings.LastIndexByte(out, '\n'); i >= 0 {
+		return out[i+1:]
+	}
+	return out
+}
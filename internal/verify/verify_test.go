@@ -0,0 +1,86 @@
+package verify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{"none", nil, nil},
+		{"go", map[string]string{"go.mod": "module x"}, []string{"go build ./...", "go test ./..."}},
+		{
+			"npm with scripts",
+			map[string]string{
+				"package.json":      `{"scripts": {"build": "tsc", "test": "jest"}}`,
+				"package-lock.json": "{}",
+			},
+			[]string{"npm ci", "npm run build", "npm test"},
+		},
+		{
+			"npm placeholder test",
+			map[string]string{"package.json": `{"scripts": {"test": "echo \"Error: no test specified\" && exit 1"}}`},
+			[]string{"npm install"},
+		},
+		{
+			"pnpm",
+			map[string]string{"package.json": `{"scripts": {"test": "vitest"}}`, "pnpm-lock.yaml": ""},
+			[]string{"pnpm install --frozen-lockfile", "pnpm test"},
+		},
+		{"cargo", map[string]string{"Cargo.toml": ""}, []string{"cargo build", "cargo test"}},
+		{"uv", map[string]string{"pyproject.toml": "", "uv.lock": ""}, []string{"uv run pytest"}},
+		{"plain python", map[string]string{"pyproject.toml": ""}, []string{"python3 -m compileall -q ."}},
+		{"nix only", map[string]string{"flake.nix": ""}, []string{"nix flake check"}},
+		{
+			"nix dev shell",
+			map[string]string{"flake.nix": "", "Cargo.toml": ""},
+			[]string{"nix develop --command cargo build", "nix develop --command cargo test"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := Detect(dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Detect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunStopsAtFirstFailure(t *testing.T) {
+	results := Run(t.TempDir(), []string{"echo ok", "echo broken >&2; exit 1", "echo never"})
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2: %+v", len(results), results)
+	}
+	if !results[0].Passed || results[0].Output != "ok" {
+		t.Errorf("first result = %+v", results[0])
+	}
+	if results[1].Passed || results[1].Output != "broken" {
+		t.Errorf("second result = %+v", results[1])
+	}
+}
+
+func TestExecStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := Exec(ctx, t.TempDir(), "sleep", "10"); err == nil {
+		t.Fatal("expected the cancelled command to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Exec took %v after its context was cancelled", elapsed)
+	}
+}
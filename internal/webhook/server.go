@@ -2,10 +2,17 @@ package webhook
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Event is an invalidation signal from a GitHub webhook. It carries just
@@ -16,7 +23,23 @@ import (
 type Event struct {
 	PRNumber  string // PR number, e.g. "42"; empty for repo-wide events (e.g. push)
 	Repo      string // owner/repo
-	EventType string // "check_run", "check_suite", "pull_request", "pull_request_review", "push"
+	EventType string // "check_run", "check_suite", "pull_request", "pull_request_review", "push", "issues"
+}
+
+// signatureHeader carries GitHub's HMAC-SHA256 signature of the raw payload,
+// formatted as "sha256=<hex digest>".
+const signatureHeader = "X-Hub-Signature-256"
+
+// maxPayloadBytes caps the request body we are willing to buffer for
+// signature verification. GitHub caps webhook payloads at 25 MB.
+const maxPayloadBytes = 25 << 20
+
+// SignatureStats summarizes deliveries rejected because their
+// X-Hub-Signature-256 header did not verify against the configured secret.
+type SignatureStats struct {
+	Rejected     int       // total rejected deliveries since the server started
+	LastRejectAt time.Time // zero if nothing has been rejected
+	LastReason   string    // why the most recent delivery was rejected
 }
 
 // Server is an HTTP server that receives GitHub webhook payloads from a relay
@@ -27,6 +50,13 @@ type Server struct {
 	events   chan<- Event
 	srv      *http.Server
 	listener net.Listener
+
+	// secret is the shared webhook secret. When non-empty, every delivery
+	// must carry a valid X-Hub-Signature-256 header or it is rejected.
+	secret []byte
+
+	mu       sync.Mutex
+	sigStats SignatureStats
 }
 
 // NewServer creates a webhook server that sends parsed events to the given channel.
@@ -41,6 +71,28 @@ func NewServer(port int, path string, events chan<- Event) *Server {
 	}
 }
 
+// SetSecret configures the shared secret used to verify X-Hub-Signature-256
+// on incoming deliveries. An empty secret disables verification. Call before
+// Serve.
+func (s *Server) SetSecret(secret string) {
+	s.secret = []byte(secret)
+}
+
+// SignatureStats returns a snapshot of signature-verification rejections.
+func (s *Server) SignatureStats() SignatureStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sigStats
+}
+
+func (s *Server) recordRejection(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sigStats.Rejected++
+	s.sigStats.LastRejectAt = time.Now()
+	s.sigStats.LastReason = reason
+}
+
 // Addr returns the listener address once the server is started. Returns ""
 // if the server hasn't started yet.
 func (s *Server) Addr() string {
@@ -106,8 +158,24 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadBytes))
+	if err != nil {
+		http.Error(w, "reading body", http.StatusBadRequest)
+		return
+	}
+
+	// Verify the signature over the raw bytes before parsing anything, so a
+	// forged delivery can't influence the pipeline in any way.
+	if len(s.secret) > 0 {
+		if reason := verifySignature(s.secret, body, r.Header.Get(signatureHeader)); reason != "" {
+			s.recordRejection(reason)
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+	}
+
 	var payload json.RawMessage
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+	if err := json.Unmarshal(body, &payload); err != nil {
 		http.Error(w, "invalid JSON", http.StatusBadRequest)
 		return
 	}
@@ -124,6 +192,30 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// verifySignature checks a GitHub X-Hub-Signature-256 header value against
+// the HMAC-SHA256 of body keyed with secret. It returns "" when the signature
+// is valid, otherwise a short reason for the rejection. The digest comparison
+// is constant-time.
+func verifySignature(secret, body []byte, header string) string {
+	if header == "" {
+		return "missing " + signatureHeader
+	}
+	hexSig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return "unsupported signature algorithm"
+	}
+	got, err := hex.DecodeString(hexSig)
+	if err != nil {
+		return "malformed signature"
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return "signature mismatch"
+	}
+	return ""
+}
+
 // parseEvent dispatches to the appropriate parser based on event type.
 func parseEvent(eventType string, payload json.RawMessage) []Event {
 	switch eventType {
@@ -137,6 +229,8 @@ func parseEvent(eventType string, payload json.RawMessage) []Event {
 		return parsePullRequestReview(payload)
 	case "push":
 		return parsePush(payload)
+	case "issues":
+		return parseIssues(payload)
 	default:
 		return nil
 	}
@@ -194,6 +288,11 @@ type pushPayload struct {
 	Repository repoPayload `json:"repository"`
 }
 
+type issuesPayload struct {
+	Action     string      `json:"action"`
+	Repository repoPayload `json:"repository"`
+}
+
 type repoPayload struct {
 	FullName      string `json:"full_name"`
 	DefaultBranch string `json:"default_branch"`
@@ -303,3 +402,22 @@ func parsePush(payload json.RawMessage) []Event {
 	}}
 }
 
+func parseIssues(payload json.RawMessage) []Event {
+	var p issuesPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil
+	}
+
+	switch p.Action {
+	case "opened", "labeled", "reopened":
+		// an issue may now carry the issue watcher's label
+	default:
+		return nil
+	}
+
+	// Repo-wide: the issue watcher re-lists the repo's labelled issues.
+	return []Event{{
+		Repo:      p.Repository.FullName,
+		EventType: "issues",
+	}}
+}
@@ -3,6 +3,9 @@ package webhook
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"net"
 	"net/http"
@@ -282,6 +285,20 @@ func TestParsePush(t *testing.T) {
 	})
 }
 
+func TestParseIssues(t *testing.T) {
+	for action, want := range map[string]int{"labeled": 1, "opened": 1, "reopened": 1, "unlabeled": 0, "closed": 0} {
+		payload := `{"action": "` + action + `", "issue": {"number": 12}, "repository": {"full_name": "owner/repo"}}`
+		events := parseEvent("issues", json.RawMessage(payload))
+		if len(events) != want {
+			t.Errorf("%s: expected %d events, got %d", action, want, len(events))
+			continue
+		}
+		if want == 1 && (events[0] != Event{Repo: "owner/repo", EventType: "issues"}) {
+			t.Errorf("%s: unexpected event: %+v", action, events[0])
+		}
+	}
+}
+
 func TestServerHandleWebhook(t *testing.T) {
 	ch := make(chan Event, 10)
 	srv := NewServer(0, "/webhook/github", ch)
@@ -560,3 +577,111 @@ func TestEventIsInvalidationSignal(t *testing.T) {
 		})
 	}
 }
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestServerVerifiesSignature(t *testing.T) {
+	const secret = "s3cret"
+	payload := `{
+		"action": "completed",
+		"check_run": {"conclusion": "failure", "pull_requests": [{"number": 55}]},
+		"repository": {"full_name": "test/repo"}
+	}`
+
+	tests := []struct {
+		name       string
+		signature  string
+		wantStatus int
+		wantEvent  bool
+		wantReason string
+	}{
+		{"valid", sign(secret, payload), http.StatusOK, true, ""},
+		{"missing", "", http.StatusUnauthorized, false, "missing X-Hub-Signature-256"},
+		{"wrong secret", sign("other", payload), http.StatusUnauthorized, false, "signature mismatch"},
+		{"tampered body", sign(secret, payload+" "), http.StatusUnauthorized, false, "signature mismatch"},
+		{"sha1 only", "sha1=0123456789abcdef", http.StatusUnauthorized, false, "unsupported signature algorithm"},
+		{"not hex", "sha256=zz", http.StatusUnauthorized, false, "malformed signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := make(chan Event, 10)
+			srv := NewServer(0, "/webhook/github", ch)
+			srv.SetSecret(secret)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/webhook/github", srv.handleWebhook)
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			req, _ := http.NewRequest(http.MethodPost, ts.URL+"/webhook/github", bytes.NewBufferString(payload))
+			req.Header.Set("X-GitHub-Event", "check_run")
+			if tt.signature != "" {
+				req.Header.Set("X-Hub-Signature-256", tt.signature)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			select {
+			case ev := <-ch:
+				if !tt.wantEvent {
+					t.Errorf("forged delivery produced event: %+v", ev)
+				}
+			default:
+				if tt.wantEvent {
+					t.Error("expected event for validly signed delivery")
+				}
+			}
+
+			stats := srv.SignatureStats()
+			if tt.wantReason == "" {
+				if stats.Rejected != 0 {
+					t.Errorf("unexpected rejection recorded: %+v", stats)
+				}
+				return
+			}
+			if stats.Rejected != 1 || stats.LastRejectAt.IsZero() {
+				t.Errorf("expected one recorded rejection, got %+v", stats)
+			}
+			if stats.LastReason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", stats.LastReason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestServerWithoutSecretAcceptsUnsignedDeliveries(t *testing.T) {
+	ch := make(chan Event, 10)
+	srv := NewServer(0, "/webhook/github", ch)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhook/github", srv.handleWebhook)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	payload := `{"action": "opened", "pull_request": {"number": 5}, "repository": {"full_name": "o/r"}}`
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/webhook/github", bytes.NewBufferString(payload))
+	req.Header.Set("X-GitHub-Event", "pull_request")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+	if srv.SignatureStats().Rejected != 0 {
+		t.Error("no rejections expected when no secret is configured")
+	}
+}